@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"embyhub/config"
 	"embyhub/internal/handler"
 	"embyhub/internal/router"
+	"embyhub/internal/service"
 	"embyhub/internal/task"
 	"embyhub/internal/util"
 	"embyhub/pkg/database"
@@ -55,6 +57,20 @@ func main() {
 	defer redis.Close()
 	util.Info("Redis连接成功")
 
+	// 同步代码中声明的权限到数据库
+	if result, err := service.NewPermissionService().SyncDeclared(); err != nil {
+		util.Error(fmt.Sprintf("同步权限声明失败: %v", err))
+	} else {
+		util.Info(fmt.Sprintf("权限声明已同步: 新增%d个, 更新%d个, 未声明%d个",
+			len(result.Added), len(result.Updated), len(result.Orphaned)))
+		if len(result.Orphaned) > 0 {
+			util.Warn(fmt.Sprintf("以下权限已不再被代码声明: %s", strings.Join(result.Orphaned, ", ")))
+		}
+	}
+
+	// 订阅角色权限失效广播，多实例部署时同步清除本地权限缓存
+	service.PermCache().StartSync()
+
 	// 设置Gin模式
 	// gin.SetMode(cfg.Server.Mode)
 
@@ -0,0 +1,162 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAgentQuotaInsufficient 代理商额度不足
+var ErrAgentQuotaInsufficient = errors.New("代理商额度不足")
+
+type AgentDAO struct{}
+
+func NewAgentDAO() *AgentDAO {
+	return &AgentDAO{}
+}
+
+// GetQuota 获取代理商额度
+func (d *AgentDAO) GetQuota(userID int) (*model.AgentQuota, error) {
+	var quota model.AgentQuota
+	err := database.DB.Preload("User").Where("user_id = ?", userID).First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// ApplyTx 在事务中变动代理商额度并写入流水
+// 额度行加锁后校验余额，扣减后余额不能为负
+func (d *AgentDAO) ApplyTx(tx *gorm.DB, entry *model.AgentQuotaLedger) error {
+	now := time.Now()
+
+	// 首次充值时创建额度行
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.AgentQuota{UserID: entry.AgentID, UpdatedAt: now}).Error; err != nil {
+		return err
+	}
+
+	var quota model.AgentQuota
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", entry.AgentID).First(&quota).Error; err != nil {
+		return err
+	}
+
+	if quota.Balance+entry.Delta < 0 {
+		return ErrAgentQuotaInsufficient
+	}
+
+	updates := map[string]interface{}{
+		"balance":    quota.Balance + entry.Delta,
+		"updated_at": now,
+	}
+	switch entry.Reason {
+	case model.QuotaReasonTopUp:
+		updates["total_top_up"] = quota.TotalTopUp + entry.Delta
+	case model.QuotaReasonGenerate, model.QuotaReasonRefund:
+		updates["total_consumed"] = quota.TotalConsumed - entry.Delta
+	}
+	if err := tx.Model(&model.AgentQuota{}).Where("user_id = ?", entry.AgentID).
+		Updates(updates).Error; err != nil {
+		return err
+	}
+
+	entry.BalanceAfter = quota.Balance + entry.Delta
+	entry.CreatedAt = now
+	return tx.Create(entry).Error
+}
+
+// Apply 变动代理商额度（独立事务）
+func (d *AgentDAO) Apply(entry *model.AgentQuotaLedger) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return d.ApplyTx(tx, entry)
+	})
+}
+
+// List 获取代理商列表
+func (d *AgentDAO) List(page, pageSize int) ([]*model.AgentQuota, int64, error) {
+	var quotas []*model.AgentQuota
+	var total int64
+
+	query := database.DB.Model(&model.AgentQuota{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("User").
+		Order("updated_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&quotas).Error
+
+	return quotas, total, err
+}
+
+// ListLedger 获取代理商额度流水
+func (d *AgentDAO) ListLedger(agentID int, req *model.AgentLedgerListRequest) ([]*model.AgentQuotaLedger, int64, error) {
+	var entries []*model.AgentQuotaLedger
+	var total int64
+
+	query := database.DB.Model(&model.AgentQuotaLedger{}).Where("agent_id = ?", agentID)
+	if req.Reason != "" {
+		query = query.Where("reason = ?", req.Reason)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&entries).Error
+
+	return entries, total, err
+}
+
+// SumLedgerByReason 统计时间段内各原因的额度变动合计
+func (d *AgentDAO) SumLedgerByReason(agentID int, start, end time.Time) (map[string]int64, error) {
+	type Result struct {
+		Reason string
+		Total  int64
+	}
+	var results []Result
+
+	err := database.DB.Model(&model.AgentQuotaLedger{}).
+		Select("reason, coalesce(sum(delta), 0) as total").
+		Where("agent_id = ? AND created_at >= ? AND created_at < ?", agentID, start, end).
+		Group("reason").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]int64)
+	for _, r := range results {
+		sums[r.Reason] = r.Total
+	}
+	return sums, nil
+}
@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+type APITokenDAO struct{}
+
+func NewAPITokenDAO() *APITokenDAO {
+	return &APITokenDAO{}
+}
+
+// Create 保存访问令牌
+func (d *APITokenDAO) Create(token *model.APIToken) error {
+	return database.DB.Create(token).Error
+}
+
+// GetByHash 按摘要获取访问令牌
+func (d *APITokenDAO) GetByHash(tokenHash string) (*model.APIToken, error) {
+	var token model.APIToken
+	if err := database.DB.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByUser 获取用户未撤销的访问令牌
+func (d *APITokenDAO) ListByUser(userID int) ([]*model.APIToken, error) {
+	var tokens []*model.APIToken
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("id DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// CountActive 统计用户未撤销且未过期的访问令牌数量
+func (d *APITokenDAO) CountActive(userID int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// Revoke 撤销用户的指定访问令牌，返回是否有令牌被撤销
+func (d *APITokenDAO) Revoke(userID int, id int64) (bool, error) {
+	result := database.DB.Model(&model.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeAllByUser 撤销用户的全部访问令牌
+func (d *APITokenDAO) RevokeAllByUser(userID int) (int64, error) {
+	result := database.DB.Model(&model.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// Touch 更新最近使用时间和IP
+func (d *APITokenDAO) Touch(id int64, ip string) error {
+	return database.DB.Model(&model.APIToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ip,
+	}).Error
+}
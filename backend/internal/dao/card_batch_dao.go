@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+type CardBatchDAO struct{}
+
+func NewCardBatchDAO() *CardBatchDAO {
+	return &CardBatchDAO{}
+}
+
+// GetByID 根据ID获取批次
+func (d *CardBatchDAO) GetByID(id int) (*model.CardBatch, error) {
+	var batch model.CardBatch
+	err := database.DB.Preload("CreatedByUser").Where("id = ?", id).First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListChecksumTemplates 获取使用指定前缀且带校验位的批次模板（去重，用于兑换时的输入校验）
+func (d *CardBatchDAO) ListChecksumTemplates(prefixes []string) ([]*model.CardBatch, error) {
+	var batches []*model.CardBatch
+	if len(prefixes) == 0 {
+		return batches, nil
+	}
+	err := database.DB.Model(&model.CardBatch{}).
+		Distinct("prefix", "group_size", "code_length", "charset", "checksum").
+		Where("checksum <> ? AND prefix IN ?", "none", prefixes).
+		Find(&batches).Error
+	return batches, err
+}
+
+// List 获取批次列表
+func (d *CardBatchDAO) List(req *model.CardBatchListRequest) ([]*model.CardBatch, int64, error) {
+	var batches []*model.CardBatch
+	var total int64
+
+	query := database.DB.Model(&model.CardBatch{})
+
+	// 创建者范围
+	if req.CreatedBy > 0 {
+		query = query.Where("created_by = ?", req.CreatedBy)
+	}
+
+	// 用户组范围
+	query = scopeByGroups(query, req.ScopeGroupIDs)
+
+	// 统计总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("CreatedByUser").
+		Order("created_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&batches).Error
+
+	return batches, total, err
+}
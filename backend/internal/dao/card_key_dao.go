@@ -1,10 +1,34 @@
 package dao
 
 import (
+	"errors"
+	"time"
+
 	"embyhub/internal/model"
 	"embyhub/pkg/database"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
 )
 
+// cardKeyInsertBatchSize 批量插入时每条SQL的行数
+const cardKeyInsertBatchSize = 1000
+
+// ErrCardCodeConflict 卡密码与已有卡密重复
+var ErrCardCodeConflict = errors.New("卡密码已存在")
+
+// 卡密码唯一约束（card_code 与规范化后的 code_key）
+var cardCodeConstraints = map[string]bool{
+	"card_keys_card_code_key": true,
+	"card_keys_code_key_key":  true,
+}
+
+// isCardCodeConflict 判断是否为卡密码唯一约束冲突（unique_violation 23505）
+func isCardCodeConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && cardCodeConstraints[pgErr.ConstraintName]
+}
+
 type CardKeyDAO struct{}
 
 func NewCardKeyDAO() *CardKeyDAO {
@@ -21,6 +45,50 @@ func (d *CardKeyDAO) BatchCreate(cardKeys []*model.CardKey) error {
 	return database.DB.Create(&cardKeys).Error
 }
 
+// CreateBatchWithCards 在同一事务中创建批次及其卡密（分段插入）
+// afterBatch 在批次写入后、卡密写入前执行（如扣减代理商额度），返回错误则整体回滚
+// 卡密码与已有卡密重复时返回 ErrCardCodeConflict
+func (d *CardKeyDAO) CreateBatchWithCards(batch *model.CardBatch, cardKeys []*model.CardKey, afterBatch func(tx *gorm.DB) error) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		if afterBatch != nil {
+			if err := afterBatch(tx); err != nil {
+				return err
+			}
+		}
+		for _, cardKey := range cardKeys {
+			cardKey.BatchID = &batch.ID
+		}
+		return tx.CreateInBatches(cardKeys, cardKeyInsertBatchSize).Error
+	})
+	if isCardCodeConflict(err) {
+		return ErrCardCodeConflict
+	}
+	return err
+}
+
+// ExistingCodeKeys 返回已存在于数据库中的规范化卡密码
+func (d *CardKeyDAO) ExistingCodeKeys(codeKeys []string) ([]string, error) {
+	var existing []string
+	for start := 0; start < len(codeKeys); start += cardKeyInsertBatchSize {
+		end := start + cardKeyInsertBatchSize
+		if end > len(codeKeys) {
+			end = len(codeKeys)
+		}
+		var found []string
+		err := database.DB.Model(&model.CardKey{}).
+			Where("code_key IN ?", codeKeys[start:end]).
+			Pluck("code_key", &found).Error
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, found...)
+	}
+	return existing, nil
+}
+
 // GetByID 根据ID获取卡密
 func (d *CardKeyDAO) GetByID(id int) (*model.CardKey, error) {
 	var cardKey model.CardKey
@@ -32,11 +100,11 @@ func (d *CardKeyDAO) GetByID(id int) (*model.CardKey, error) {
 	return &cardKey, nil
 }
 
-// GetByCode 根据卡密码获取卡密
-func (d *CardKeyDAO) GetByCode(cardCode string) (*model.CardKey, error) {
+// GetByCode 根据规范化后的卡密码获取卡密
+func (d *CardKeyDAO) GetByCode(codeKey string) (*model.CardKey, error) {
 	var cardKey model.CardKey
 	err := database.DB.Preload("UsedByUser").
-		Where("card_code = ?", cardCode).First(&cardKey).Error
+		Where("code_key = ?", codeKey).First(&cardKey).Error
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +143,19 @@ func (d *CardKeyDAO) List(req *model.CardKeyListRequest) ([]*model.CardKey, int6
 		query = query.Where("card_type = ?", *req.CardType)
 	}
 
+	// 批次筛选
+	if req.BatchID > 0 {
+		query = query.Where("batch_id = ?", req.BatchID)
+	}
+
+	// 创建者范围
+	if req.CreatedBy > 0 {
+		query = query.Where("created_by = ?", req.CreatedBy)
+	}
+
+	// 用户组范围
+	query = scopeByGroups(query, req.ScopeGroupIDs)
+
 	// 关键词搜索
 	if req.Keyword != "" {
 		query = query.Where("card_code LIKE ? OR remark LIKE ?",
@@ -107,15 +188,27 @@ func (d *CardKeyDAO) List(req *model.CardKeyListRequest) ([]*model.CardKey, int6
 	return cardKeys, total, err
 }
 
-// CountByStatus 统计各状态数量
-func (d *CardKeyDAO) CountByStatus() (map[int]int64, error) {
+// ListCodesByBatch 获取批次下所有卡密（用于导出）
+func (d *CardKeyDAO) ListCodesByBatch(batchID int) ([]*model.CardKey, error) {
+	var cardKeys []*model.CardKey
+	err := database.DB.Where("batch_id = ?", batchID).Order("id ASC").Find(&cardKeys).Error
+	return cardKeys, err
+}
+
+// CountByStatus 统计各状态数量（createdBy > 0 时仅统计该创建者的卡密，groupIDs 非 nil 时仅统计这些用户组的卡密）
+func (d *CardKeyDAO) CountByStatus(createdBy int, groupIDs []int) (map[int]int64, error) {
 	type Result struct {
 		Status int
 		Count  int64
 	}
 	var results []Result
 
-	err := database.DB.Model(&model.CardKey{}).
+	query := database.DB.Model(&model.CardKey{})
+	if createdBy > 0 {
+		query = query.Where("created_by = ?", createdBy)
+	}
+	query = scopeByGroups(query, groupIDs)
+	err := query.
 		Select("status, count(*) as count").
 		Group("status").
 		Scan(&results).Error
@@ -130,3 +223,24 @@ func (d *CardKeyDAO) CountByStatus() (map[int]int64, error) {
 	}
 	return counts, nil
 }
+
+// DeleteUnusedTx 在事务中删除未使用或已禁用的卡密，返回是否删除成功
+func (d *CardKeyDAO) DeleteUnusedTx(tx *gorm.DB, id int) (bool, error) {
+	result := tx.Where("id = ? AND status IN ?", id, []int{0, 1}).Delete(&model.CardKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SumByCreator 统计创建者在时间段内的卡密数量与总天数
+// column 为时间字段（created_at / used_at）
+func (d *CardKeyDAO) SumByCreator(createdBy int, column string, start, end time.Time) (int64, int64, error) {
+	var result struct {
+		Count int64
+		Days  int64
+	}
+	err := database.DB.Model(&model.CardKey{}).
+		Select("count(*) as count, coalesce(sum(duration), 0) as days").
+		Where("created_by = ?", createdBy).
+		Where(column+" >= ? AND "+column+" < ?", start, end).
+		Scan(&result).Error
+	return result.Count, result.Days, err
+}
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+type CardRedemptionDAO struct{}
+
+func NewCardRedemptionDAO() *CardRedemptionDAO {
+	return &CardRedemptionDAO{}
+}
+
+// ListByCardKeyID 获取卡密的兑换记录
+func (d *CardRedemptionDAO) ListByCardKeyID(cardKeyID int) ([]*model.CardRedemption, error) {
+	var redemptions []*model.CardRedemption
+	err := database.DB.Preload("User").
+		Where("card_key_id = ?", cardKeyID).
+		Order("created_at DESC").
+		Find(&redemptions).Error
+	return redemptions, err
+}
+
+// ListByUserID 获取用户的兑换记录
+func (d *CardRedemptionDAO) ListByUserID(userID int) ([]*model.CardRedemption, error) {
+	var redemptions []*model.CardRedemption
+	err := database.DB.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&redemptions).Error
+	return redemptions, err
+}
+
+// List 获取兑换记录列表（CreatedBy > 0 时仅返回该创建者卡密的兑换记录）
+func (d *CardRedemptionDAO) List(req *model.CardRedemptionListRequest) ([]*model.CardRedemption, int64, error) {
+	var redemptions []*model.CardRedemption
+	var total int64
+
+	query := database.DB.Model(&model.CardRedemption{})
+	if req.CreatedBy > 0 {
+		query = query.Where("card_key_id IN (?)",
+			database.DB.Model(&model.CardKey{}).Select("id").Where("created_by = ?", req.CreatedBy))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("User").Preload("CardKey").
+		Order("created_at DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&redemptions).Error
+
+	return redemptions, total, err
+}
+
+// SumRevokedByCreator 统计创建者的卡密在时间段内被撤销的兑换数量与天数
+func (d *CardRedemptionDAO) SumRevokedByCreator(createdBy int, start, end time.Time) (int64, int64, error) {
+	var result struct {
+		Count int64
+		Days  int64
+	}
+	err := database.DB.Model(&model.CardRedemption{}).
+		Select("count(*) as count, coalesce(sum(days_granted), 0) as days").
+		Where("card_key_id IN (?)",
+			database.DB.Model(&model.CardKey{}).Select("id").Where("created_by = ?", createdBy)).
+		Where("revoked_at >= ? AND revoked_at < ?", start, end).
+		Scan(&result).Error
+	return result.Count, result.Days, err
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+type LoginHistoryDAO struct{}
+
+func NewLoginHistoryDAO() *LoginHistoryDAO {
+	return &LoginHistoryDAO{}
+}
+
+// Create 记录一次登录
+func (d *LoginHistoryDAO) Create(entry *model.LoginHistory) error {
+	return database.DB.Create(entry).Error
+}
+
+// GetByID 根据ID获取登录记录
+func (d *LoginHistoryDAO) GetByID(id int64) (*model.LoginHistory, error) {
+	var entry model.LoginHistory
+	if err := database.DB.Where("id = ?", id).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// MarkAlerted 标记已发送登录提醒
+func (d *LoginHistoryDAO) MarkAlerted(id int64) error {
+	return database.DB.Model(&model.LoginHistory{}).Where("id = ?", id).Update("alerted", true).Error
+}
+
+// Known 判断用户此前是否有登录记录，以及是否使用过该设备、该网段登录
+func (d *LoginHistoryDAO) Known(userID int, deviceHash, network string) (hasHistory, knownDevice, knownNetwork bool, err error) {
+	var row struct {
+		Total    int64
+		Devices  int64
+		Networks int64
+	}
+	err = database.DB.Model(&model.LoginHistory{}).
+		Select("COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE device_hash = ?) AS devices, "+
+			"COUNT(*) FILTER (WHERE network = ?) AS networks", deviceHash, network).
+		Where("user_id = ?", userID).
+		Scan(&row).Error
+	return row.Total > 0, row.Devices > 0, row.Networks > 0, err
+}
+
+// ListByUser 分页获取用户的登录历史
+func (d *LoginHistoryDAO) ListByUser(userID int, req *model.LoginHistoryRequest) ([]*model.LoginHistory, int64, error) {
+	var entries []*model.LoginHistory
+	var total int64
+
+	query := database.DB.Model(&model.LoginHistory{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&entries).Error
+
+	return entries, total, err
+}
@@ -0,0 +1,114 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationLogDAO struct{}
+
+func NewNotificationLogDAO() *NotificationLogDAO {
+	return &NotificationLogDAO{}
+}
+
+// notificationClaimStale 占位记录超过该时间仍为发送中，视为发送中断（如进程退出），允许重新占用
+const notificationClaimStale = time.Hour
+
+// Claim 占用一条通知记录，返回 false 表示该阶段已发送或正在发送
+// 上次发送失败或发送中断的记录可重新占用，由下次任务重试
+func (d *NotificationLogDAO) Claim(entry *model.NotificationLog) (bool, error) {
+	result := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "stage"}, {Name: "ref_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status":     entry.Status,
+			"error":      "",
+			"recipient":  entry.Recipient,
+			"updated_at": entry.UpdatedAt,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{
+			SQL:  "notification_log.status = ? OR (notification_log.status = ? AND notification_log.updated_at < ?)",
+			Vars: []interface{}{model.NotifyStatusFailed, model.NotifyStatusPending, time.Now().Add(-notificationClaimStale)},
+		}}},
+	}).Create(entry)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateStatus 更新通知发送状态
+func (d *NotificationLogDAO) UpdateStatus(id int, status, errMsg string) error {
+	return database.DB.Model(&model.NotificationLog{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"error":      errMsg,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// List 获取通知记录列表
+func (d *NotificationLogDAO) List(req *model.NotificationLogListRequest) ([]*model.NotificationLog, int64, error) {
+	var logs []*model.NotificationLog
+	var total int64
+
+	query := d.filter(req)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("user_id", "username", "email")
+	}).
+		Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&logs).Error
+
+	return logs, total, err
+}
+
+// CountByStageStatus 按阶段和状态汇总
+func (d *NotificationLogDAO) CountByStageStatus(req *model.NotificationLogListRequest) ([]*model.NotificationStageCount, error) {
+	var counts []*model.NotificationStageCount
+	err := d.filter(req).
+		Select("stage, status, count(*) as count").
+		Group("stage, status").
+		Order("stage DESC, status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// filter 构建通知记录查询条件
+func (d *NotificationLogDAO) filter(req *model.NotificationLogListRequest) *gorm.DB {
+	query := database.DB.Model(&model.NotificationLog{})
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Stage != nil {
+		query = query.Where("stage = ?", *req.Stage)
+	}
+	if req.UserID > 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.StartTime != nil {
+		query = query.Where("created_at >= ?", *req.StartTime)
+	}
+	if req.EndTime != nil {
+		query = query.Where("created_at < ?", req.EndTime.AddDate(0, 0, 1))
+	}
+	return query
+}
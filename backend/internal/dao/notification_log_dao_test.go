@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/internal/testutil"
+	"embyhub/pkg/database"
+)
+
+func TestNotificationClaim(t *testing.T) {
+	testutil.Setup(t)
+	d := NewNotificationLogDAO()
+
+	claim := func(at time.Time) (*model.NotificationLog, bool) {
+		t.Helper()
+		entry := &model.NotificationLog{
+			UserID: 1, Type: model.NotifyVipExpiry, Stage: 3, RefKey: "2026-01-01",
+			Channel: "email", Recipient: "alice@example.com", Status: model.NotifyStatusPending,
+			CreatedAt: at, UpdatedAt: at,
+		}
+		claimed, err := d.Claim(entry)
+		if err != nil {
+			t.Fatalf("占用通知记录失败: %v", err)
+		}
+		return entry, claimed
+	}
+
+	first, ok := claim(time.Now())
+	if !ok {
+		t.Fatal("首次应占用成功")
+	}
+	// 发送中：不能重复占用
+	if _, ok := claim(time.Now()); ok {
+		t.Fatal("发送中的记录不应被重复占用")
+	}
+
+	// 发送失败：下次任务可重新占用同一条记录重试
+	d.UpdateStatus(first.ID, model.NotifyStatusFailed, "smtp timeout")
+	retry, ok := claim(time.Now())
+	if !ok || retry.ID != first.ID {
+		t.Fatalf("发送失败的记录应可重新占用: %v id=%d", ok, retry.ID)
+	}
+	var log model.NotificationLog
+	database.DB.First(&log, first.ID)
+	if log.Status != model.NotifyStatusPending || log.Error != "" {
+		t.Fatalf("重新占用后应恢复为发送中: %+v", log)
+	}
+
+	// 已发送：不再占用
+	d.UpdateStatus(first.ID, model.NotifyStatusSent, "")
+	if _, ok := claim(time.Now()); ok {
+		t.Fatal("已发送的记录不应被重新占用")
+	}
+
+	// 发送中断（长时间停留在发送中）：可重新占用
+	database.DB.Model(&model.NotificationLog{}).Where("id = ?", first.ID).
+		Updates(map[string]interface{}{"status": model.NotifyStatusPending, "updated_at": time.Now().Add(-2 * time.Hour)})
+	if _, ok := claim(time.Now()); !ok {
+		t.Fatal("中断的占位记录应可重新占用")
+	}
+
+	var count int64
+	database.DB.Model(&model.NotificationLog{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("重试不应新增记录: %d", count)
+	}
+}
@@ -0,0 +1,147 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderDAO struct{}
+
+func NewOrderDAO() *OrderDAO {
+	return &OrderDAO{}
+}
+
+// CreatePlan 创建套餐
+func (d *OrderDAO) CreatePlan(plan *model.Plan) error {
+	return database.DB.Create(plan).Error
+}
+
+// UpdatePlan 更新套餐
+func (d *OrderDAO) UpdatePlan(plan *model.Plan) error {
+	return database.DB.Save(plan).Error
+}
+
+// GetPlan 获取套餐
+func (d *OrderDAO) GetPlan(id int) (*model.Plan, error) {
+	var plan model.Plan
+	if err := database.DB.Where("id = ?", id).First(&plan).Error; err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// ListPlans 获取套餐列表（onlyActive 为 true 时仅返回上架套餐）
+func (d *OrderDAO) ListPlans(onlyActive bool) ([]*model.Plan, error) {
+	var plans []*model.Plan
+	query := database.DB.Model(&model.Plan{})
+	if onlyActive {
+		query = query.Where("status = 1")
+	}
+	err := query.Order("sort_order ASC, id ASC").Find(&plans).Error
+	return plans, err
+}
+
+// CountOrdersByPlan 统计套餐下的订单数
+func (d *OrderDAO) CountOrdersByPlan(planID int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.Order{}).Where("plan_id = ?", planID).Count(&count).Error
+	return count, err
+}
+
+// DeletePlan 删除套餐
+func (d *OrderDAO) DeletePlan(id int) error {
+	return database.DB.Delete(&model.Plan{}, id).Error
+}
+
+// Create 创建订单
+func (d *OrderDAO) Create(order *model.Order) error {
+	return database.DB.Create(order).Error
+}
+
+// GetByOrderNo 根据订单号获取
+func (d *OrderDAO) GetByOrderNo(orderNo string) (*model.Order, error) {
+	var order model.Order
+	if err := database.DB.Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// LockByOrderNoTx 在事务中锁定订单
+func (d *OrderDAO) LockByOrderNoTx(tx *gorm.DB, orderNo string) (*model.Order, error) {
+	var order model.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListDeferred 获取已支付未履约、且用户已解冻的订单（支付时用户VIP冻结中）
+func (d *OrderDAO) ListDeferred(afterID, limit int) ([]*model.Order, error) {
+	var orders []*model.Order
+	err := database.DB.Model(&model.Order{}).
+		Joins("JOIN users ON users.user_id = orders.user_id").
+		Where("orders.status = ? AND orders.id > ? AND users.vip_frozen_at IS NULL", model.OrderStatusPaid, afterID).
+		Order("orders.id").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+// MarkRefunded 将退款中的订单标记为已退款
+func (d *OrderDAO) MarkRefunded(orderNo string, at time.Time) error {
+	return database.DB.Model(&model.Order{}).
+		Where("order_no = ? AND status = ?", orderNo, model.OrderStatusRefunding).
+		Updates(map[string]interface{}{
+			"status":      model.OrderStatusRefunded,
+			"refunded_at": at,
+			"updated_at":  at,
+		}).Error
+}
+
+// List 获取订单列表
+func (d *OrderDAO) List(req *model.OrderListRequest) ([]*model.Order, int64, error) {
+	var orders []*model.Order
+	var total int64
+
+	query := database.DB.Model(&model.Order{})
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.UserID > 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Keyword != "" {
+		query = query.Where("order_no LIKE ?", "%"+req.Keyword+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("user_id", "username", "email")
+	}).
+		Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&orders).Error
+
+	return orders, total, err
+}
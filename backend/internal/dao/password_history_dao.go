@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+type PasswordHistoryDAO struct{}
+
+func NewPasswordHistoryDAO() *PasswordHistoryDAO {
+	return &PasswordHistoryDAO{}
+}
+
+// Create 记录一次密码设置
+func (d *PasswordHistoryDAO) Create(entry *model.PasswordHistory) error {
+	return database.DB.Create(entry).Error
+}
+
+// Recent 获取用户最近使用过的密码摘要
+func (d *PasswordHistoryDAO) Recent(userID, limit int) ([]string, error) {
+	var hashes []string
+	err := database.DB.Model(&model.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+	return hashes, err
+}
+
+// Prune 只保留用户最近的若干条记录
+func (d *PasswordHistoryDAO) Prune(userID, keep int) error {
+	return database.DB.Exec(`
+		DELETE FROM password_history
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, userID, userID, keep).Error
+}
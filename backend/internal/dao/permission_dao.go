@@ -28,6 +28,16 @@ func (d *PermissionDAO) List() ([]*model.Permission, error) {
 	return permissions, err
 }
 
+// Create 创建权限
+func (d *PermissionDAO) Create(permission *model.Permission) error {
+	return database.DB.Create(permission).Error
+}
+
+// Update 更新权限
+func (d *PermissionDAO) Update(permission *model.Permission) error {
+	return database.DB.Save(permission).Error
+}
+
 // GetByRoleID 根据角色ID获取权限列表
 func (d *PermissionDAO) GetByRoleID(roleID int) ([]*model.Permission, error) {
 	var permissions []*model.Permission
@@ -39,13 +49,37 @@ func (d *PermissionDAO) GetByRoleID(roleID int) ([]*model.Permission, error) {
 	return permissions, err
 }
 
-// GetPermissionKeysByRoleID 根据角色ID获取权限Key列表
-func (d *PermissionDAO) GetPermissionKeysByRoleID(roleID int) ([]string, error) {
-	var keys []string
+// GetRuleSetByRoleID 获取角色的权限规则：直接允许、权限组展开后的允许以及拒绝规则
+func (d *PermissionDAO) GetRuleSetByRoleID(roleID int) (*model.RolePermissionSet, error) {
+	set := &model.RolePermissionSet{}
+
 	err := database.DB.Table("permissions").
-		Select("permissions.permission_key").
 		Joins("INNER JOIN role_permissions ON permissions.permission_id = role_permissions.permission_id").
 		Where("role_permissions.role_id = ?", roleID).
-		Pluck("permission_key", &keys).Error
-	return keys, err
+		Pluck("permissions.permission_key", &set.Allow).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var groupKeys []string
+	err = database.DB.Table("permissions").
+		Joins("INNER JOIN permission_group_items ON permissions.permission_id = permission_group_items.permission_id").
+		Joins("INNER JOIN role_permission_groups ON permission_group_items.group_id = role_permission_groups.group_id").
+		Where("role_permission_groups.role_id = ?", roleID).
+		Distinct().
+		Pluck("permissions.permission_key", &groupKeys).Error
+	if err != nil {
+		return nil, err
+	}
+	set.Allow = append(set.Allow, groupKeys...)
+
+	err = database.DB.Table("permissions").
+		Joins("INNER JOIN role_permission_denies ON permissions.permission_id = role_permission_denies.permission_id").
+		Where("role_permission_denies.role_id = ?", roleID).
+		Pluck("permissions.permission_key", &set.Deny).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
 }
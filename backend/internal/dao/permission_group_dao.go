@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type PermissionGroupDAO struct{}
+
+func NewPermissionGroupDAO() *PermissionGroupDAO {
+	return &PermissionGroupDAO{}
+}
+
+// GetByID 根据ID获取权限组（含成员权限）
+func (d *PermissionGroupDAO) GetByID(groupID int) (*model.PermissionGroup, error) {
+	var group model.PermissionGroup
+	err := database.DB.Preload("Permissions").Where("group_id = ?", groupID).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// List 获取权限组列表
+func (d *PermissionGroupDAO) List() ([]*model.PermissionGroup, error) {
+	var groups []*model.PermissionGroup
+	err := database.DB.Preload("Permissions").Order("group_id ASC").Find(&groups).Error
+	return groups, err
+}
+
+// ExistsByName 检查权限组名是否存在（排除指定ID）
+func (d *PermissionGroupDAO) ExistsByName(name string, excludeID int) (bool, error) {
+	var count int64
+	err := database.DB.Model(&model.PermissionGroup{}).
+		Where("group_name = ? AND group_id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Save 创建或更新权限组，并替换成员权限
+func (d *PermissionGroupDAO) Save(group *model.PermissionGroup, permissionIDs []int) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(group).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM permission_group_items WHERE group_id = ?", group.GroupID).Error; err != nil {
+			return err
+		}
+		for _, permID := range permissionIDs {
+			if err := tx.Exec("INSERT INTO permission_group_items (group_id, permission_id) VALUES (?, ?)",
+				group.GroupID, permID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete 删除权限组（关联的角色授权随外键级联删除）
+func (d *PermissionGroupDAO) Delete(groupID int) error {
+	return database.DB.Delete(&model.PermissionGroup{}, groupID).Error
+}
+
+// RoleIDsByGroup 获取授予了指定权限组的角色ID
+func (d *PermissionGroupDAO) RoleIDsByGroup(groupID int) ([]int, error) {
+	var roleIDs []int
+	err := database.DB.Table("role_permission_groups").
+		Where("group_id = ?", groupID).
+		Pluck("role_id", &roleIDs).Error
+	return roleIDs, err
+}
@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPointsInsufficient 积分不足
+var ErrPointsInsufficient = errors.New("积分不足")
+
+type PointsDAO struct{}
+
+func NewPointsDAO() *PointsDAO {
+	return &PointsDAO{}
+}
+
+// GetBalance 获取用户积分余额，无记录时返回零值
+func (d *PointsDAO) GetBalance(userID int) (*model.UserPoints, error) {
+	var points model.UserPoints
+	err := database.DB.Where("user_id = ?", userID).First(&points).Error
+	if err == gorm.ErrRecordNotFound {
+		return &model.UserPoints{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &points, nil
+}
+
+// ApplyTx 在事务中变动用户积分并写入流水
+// 积分行加锁后校验余额，扣减后余额不能为负
+func (d *PointsDAO) ApplyTx(tx *gorm.DB, entry *model.PointsLedger) error {
+	now := time.Now()
+
+	// 首次变动时创建积分行
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.UserPoints{UserID: entry.UserID, UpdatedAt: now}).Error; err != nil {
+		return err
+	}
+
+	var points model.UserPoints
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", entry.UserID).First(&points).Error; err != nil {
+		return err
+	}
+
+	if points.Balance+entry.Delta < 0 {
+		return ErrPointsInsufficient
+	}
+
+	updates := map[string]interface{}{
+		"balance":    points.Balance + entry.Delta,
+		"updated_at": now,
+	}
+	if entry.Delta > 0 {
+		updates["total_earned"] = points.TotalEarned + entry.Delta
+	} else {
+		updates["total_spent"] = points.TotalSpent - entry.Delta
+	}
+	if err := tx.Model(&model.UserPoints{}).Where("user_id = ?", entry.UserID).
+		Updates(updates).Error; err != nil {
+		return err
+	}
+
+	entry.BalanceAfter = points.Balance + entry.Delta
+	entry.CreatedAt = now
+	return tx.Create(entry).Error
+}
+
+// ListLedger 获取用户积分流水
+func (d *PointsDAO) ListLedger(userID int, req *model.PointsLedgerListRequest) ([]*model.PointsLedger, int64, error) {
+	var entries []*model.PointsLedger
+	var total int64
+
+	query := database.DB.Model(&model.PointsLedger{}).Where("user_id = ?", userID)
+	if req.Reason != "" {
+		query = query.Where("reason = ?", req.Reason)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&entries).Error
+
+	return entries, total, err
+}
+
+// GetCheckin 获取用户指定日期的签到记录，不存在时返回 nil
+func (d *PointsDAO) GetCheckin(userID int, date time.Time) (*model.Checkin, error) {
+	return d.GetCheckinTx(database.DB, userID, date)
+}
+
+// GetCheckinTx 在事务中获取用户指定日期的签到记录，不存在时返回 nil
+func (d *PointsDAO) GetCheckinTx(tx *gorm.DB, userID int, date time.Time) (*model.Checkin, error) {
+	var checkin model.Checkin
+	err := tx.Where("user_id = ? AND checkin_date = ?", userID, date.Format("2006-01-02")).First(&checkin).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkin, nil
+}
+
+// CreateCheckinTx 在事务中写入签到记录，同一天重复签到时返回 false
+func (d *PointsDAO) CreateCheckinTx(tx *gorm.DB, checkin *model.Checkin) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(checkin)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListItems 获取积分商品，onlyActive 为 true 时仅返回上架商品
+func (d *PointsDAO) ListItems(onlyActive bool) ([]*model.PointsItem, error) {
+	var items []*model.PointsItem
+	query := database.DB.Model(&model.PointsItem{})
+	if onlyActive {
+		query = query.Where("status = 1")
+	}
+	err := query.Order("sort_order ASC, id ASC").Find(&items).Error
+	return items, err
+}
+
+// GetItem 根据ID获取积分商品
+func (d *PointsDAO) GetItem(id int) (*model.PointsItem, error) {
+	var item model.PointsItem
+	if err := database.DB.Where("id = ?", id).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// LockItemTx 在事务中锁定积分商品
+func (d *PointsDAO) LockItemTx(tx *gorm.DB, id int) (*model.PointsItem, error) {
+	var item model.PointsItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateItem 创建积分商品
+func (d *PointsDAO) CreateItem(item *model.PointsItem) error {
+	return database.DB.Create(item).Error
+}
+
+// UpdateItem 更新积分商品
+func (d *PointsDAO) UpdateItem(item *model.PointsItem) error {
+	return database.DB.Save(item).Error
+}
+
+// DeleteItem 删除积分商品
+func (d *PointsDAO) DeleteItem(id int) error {
+	return database.DB.Delete(&model.PointsItem{}, id).Error
+}
+
+// DecrStockTx 在事务中扣减商品库存（不限库存时不变）
+func (d *PointsDAO) DecrStockTx(tx *gorm.DB, id int) error {
+	return tx.Model(&model.PointsItem{}).
+		Where("id = ? AND stock > 0", id).
+		Update("stock", gorm.Expr("stock - 1")).Error
+}
+
+// CountRedemptionsTx 统计用户兑换某商品的次数
+func (d *PointsDAO) CountRedemptionsTx(tx *gorm.DB, userID, itemID int) (int64, error) {
+	var count int64
+	err := tx.Model(&model.PointsRedemption{}).
+		Where("user_id = ? AND item_id = ?", userID, itemID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountRedemptionsByItem 统计商品的兑换次数
+func (d *PointsDAO) CountRedemptionsByItem(itemID int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.PointsRedemption{}).Where("item_id = ?", itemID).Count(&count).Error
+	return count, err
+}
+
+// CreateRedemptionTx 在事务中写入兑换记录
+func (d *PointsDAO) CreateRedemptionTx(tx *gorm.DB, redemption *model.PointsRedemption) error {
+	return tx.Create(redemption).Error
+}
+
+// AddBonusRequestQuotaTx 在事务中增加用户额外求片额度
+func (d *PointsDAO) AddBonusRequestQuotaTx(tx *gorm.DB, userID, amount int) error {
+	return tx.Model(&model.User{}).Where("user_id = ?", userID).
+		Update("bonus_request_quota", gorm.Expr("bonus_request_quota + ?", amount)).Error
+}
@@ -0,0 +1,215 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReferralDAO struct{}
+
+func NewReferralDAO() *ReferralDAO {
+	return &ReferralDAO{}
+}
+
+// GetUserByCode 根据推荐码获取用户
+func (d *ReferralDAO) GetUserByCode(code string) (*model.User, error) {
+	var user model.User
+	if err := database.DB.Where("referral_code = ?", code).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetCode 为尚无推荐码的用户设置推荐码，返回是否设置成功
+func (d *ReferralDAO) SetCode(userID int, code string) (bool, error) {
+	result := database.DB.Model(&model.User{}).
+		Where("user_id = ? AND referral_code IS NULL", userID).
+		Update("referral_code", code)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Create 创建推荐关系，同时记录用户的推荐人
+func (d *ReferralDAO) Create(referral *model.Referral) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(referral).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("user_id = ?", referral.RefereeID).
+			Update("referred_by", referral.ReferrerID).Error
+	})
+}
+
+// LockPendingByRefereeTx 在事务中锁定被推荐人待奖励的推荐关系
+func (d *ReferralDAO) LockPendingByRefereeTx(tx *gorm.DB, refereeID int) (*model.Referral, error) {
+	var referral model.Referral
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("referee_id = ? AND status = ?", refereeID, model.ReferralStatusPending).
+		First(&referral).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// CountRewardedSinceTx 统计推荐人在指定时间之后获得奖励的次数
+func (d *ReferralDAO) CountRewardedSinceTx(tx *gorm.DB, referrerID int, since time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&model.Referral{}).
+		Where("referrer_id = ? AND status = ? AND rewarded_at >= ?", referrerID, model.ReferralStatusRewarded, since).
+		Count(&count).Error
+	return count, err
+}
+
+// ReferrerUsedIP 推荐人是否在指定时间之后使用过该IP（登录日志、试用或注册记录）
+func (d *ReferralDAO) ReferrerUsedIP(referrerID int, ip string, since time.Time) (bool, error) {
+	var count int64
+	err := database.DB.Raw(`SELECT
+		(SELECT COUNT(*) FROM audit_logs WHERE user_id = ? AND action = ? AND ip_address = ? AND created_at >= ?) +
+		(SELECT COUNT(*) FROM trial_grants WHERE user_id = ? AND ip_address = ?) +
+		(SELECT COUNT(*) FROM referrals WHERE referee_id = ? AND ip_address = ?)`,
+		referrerID, model.ActionLogin, ip, since,
+		referrerID, ip,
+		referrerID, ip).Scan(&count).Error
+	return count > 0, err
+}
+
+// ReferrerUsedDevice 推荐人是否使用过该设备指纹（试用或注册记录）
+func (d *ReferralDAO) ReferrerUsedDevice(referrerID int, fingerprint string) (bool, error) {
+	var count int64
+	err := database.DB.Raw(`SELECT
+		(SELECT COUNT(*) FROM trial_grants WHERE user_id = ? AND device_fingerprint = ?) +
+		(SELECT COUNT(*) FROM referrals WHERE referee_id = ? AND device_fingerprint = ?)`,
+		referrerID, fingerprint, referrerID, fingerprint).Scan(&count).Error
+	return count > 0, err
+}
+
+// DeviceReferred 该设备指纹是否已被推荐注册过
+func (d *ReferralDAO) DeviceReferred(fingerprint string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&model.Referral{}).Where("device_fingerprint = ?", fingerprint).Count(&count).Error
+	return count > 0, err
+}
+
+// CountByStatus 按状态统计推荐人的推荐数
+func (d *ReferralDAO) CountByStatus(referrerID int) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := database.DB.Model(&model.Referral{}).
+		Select("status, COUNT(*) AS count").
+		Where("referrer_id = ?", referrerID).
+		Group("status").
+		Scan(&rows).Error
+
+	result := make(map[string]int64)
+	for _, row := range rows {
+		result[row.Status] = row.Count
+	}
+	return result, err
+}
+
+// SumRewardDays 统计推荐人获得的奖励天数
+func (d *ReferralDAO) SumRewardDays(referrerID int) (int64, error) {
+	var days int64
+	err := database.DB.Model(&model.Referral{}).
+		Select("COALESCE(SUM(referrer_reward_days), 0)").
+		Where("referrer_id = ? AND status = ?", referrerID, model.ReferralStatusRewarded).
+		Scan(&days).Error
+	return days, err
+}
+
+// ListByReferrer 获取推荐人的推荐记录
+func (d *ReferralDAO) ListByReferrer(referrerID int, req *model.ReferralListRequest) ([]*model.Referral, int64, error) {
+	var referrals []*model.Referral
+	var total int64
+
+	query := database.DB.Model(&model.Referral{}).Where("referrer_id = ?", referrerID)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("Referee", func(db *gorm.DB) *gorm.DB {
+		return db.Select("user_id", "username")
+	}).
+		Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&referrals).Error
+
+	return referrals, total, err
+}
+
+// ListActivePendingRefereeIDs 获取注册满N天、账号启用且注册后有访问记录的待奖励被推荐人
+// 已触发但因VIP冻结推迟的奖励由 ListDeferred 处理
+func (d *ReferralDAO) ListActivePendingRefereeIDs(activeDays, afterID, limit int) ([]int, error) {
+	var ids []int
+	err := database.DB.Model(&model.Referral{}).
+		Joins("JOIN users ON users.user_id = referrals.referee_id").
+		Where("referrals.status = ? AND referrals.referee_id > ?", model.ReferralStatusPending, afterID).
+		Where("referrals.reward_trigger IS NULL").
+		Where("users.status = 1 AND users.created_at <= ?", time.Now().AddDate(0, 0, -activeDays)).
+		Where("EXISTS (SELECT 1 FROM access_records WHERE access_records.user_id = referrals.referee_id AND access_records.access_time > users.created_at)").
+		Order("referrals.referee_id").
+		Limit(limit).
+		Pluck("referrals.referee_id", &ids).Error
+	return ids, err
+}
+
+// ListDeferred 获取已触发奖励但因VIP冻结推迟、且双方均已解冻的推荐关系
+func (d *ReferralDAO) ListDeferred(afterRefereeID, limit int) ([]*model.Referral, error) {
+	var referrals []*model.Referral
+	err := database.DB.Model(&model.Referral{}).
+		Joins("JOIN users referrer ON referrer.user_id = referrals.referrer_id").
+		Joins("JOIN users referee ON referee.user_id = referrals.referee_id").
+		Where("referrals.status = ? AND referrals.reward_trigger IS NOT NULL AND referrals.referee_id > ?", model.ReferralStatusPending, afterRefereeID).
+		Where("referrer.vip_frozen_at IS NULL AND referee.vip_frozen_at IS NULL").
+		Order("referrals.referee_id").
+		Limit(limit).
+		Find(&referrals).Error
+	return referrals, err
+}
+
+// Leaderboard 推荐排行榜（按已奖励推荐数排序）
+func (d *ReferralDAO) Leaderboard(req *model.ReferralLeaderboardRequest) ([]*model.ReferralLeaderboardEntry, error) {
+	limit := req.Limit
+	if limit < 1 {
+		limit = 10
+	}
+
+	query := database.DB.Model(&model.Referral{}).
+		Select("referrals.referrer_id AS user_id, users.username, COUNT(*) AS rewarded, COALESCE(SUM(referrals.referrer_reward_days), 0) AS reward_days").
+		Joins("JOIN users ON users.user_id = referrals.referrer_id").
+		Where("referrals.status = ?", model.ReferralStatusRewarded)
+	if req.StartTime != nil {
+		query = query.Where("referrals.rewarded_at >= ?", *req.StartTime)
+	}
+	if req.EndTime != nil {
+		query = query.Where("referrals.rewarded_at < ?", req.EndTime.AddDate(0, 0, 1))
+	}
+
+	var entries []*model.ReferralLeaderboardEntry
+	err := query.Group("referrals.referrer_id, users.username").
+		Order("rewarded DESC, reward_days DESC, referrals.referrer_id").
+		Limit(limit).
+		Scan(&entries).Error
+	return entries, err
+}
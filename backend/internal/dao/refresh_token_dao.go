@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefreshTokenDAO struct{}
+
+func NewRefreshTokenDAO() *RefreshTokenDAO {
+	return &RefreshTokenDAO{}
+}
+
+// Create 保存刷新令牌
+func (d *RefreshTokenDAO) Create(token *model.RefreshToken) error {
+	return d.CreateTx(database.DB, token)
+}
+
+// CreateTx 在事务中保存刷新令牌
+func (d *RefreshTokenDAO) CreateTx(tx *gorm.DB, token *model.RefreshToken) error {
+	return tx.Create(token).Error
+}
+
+// LockByHashTx 在事务中按摘要锁定刷新令牌
+func (d *RefreshTokenDAO) LockByHashTx(tx *gorm.DB, tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsedTx 在事务中标记刷新令牌已使用
+func (d *RefreshTokenDAO) MarkUsedTx(tx *gorm.DB, id int64) error {
+	return tx.Model(&model.RefreshToken{}).Where("id = ?", id).Update("used_at", time.Now()).Error
+}
@@ -19,7 +19,8 @@ func (d *RoleDAO) Create(role *model.Role) error {
 // GetByID 根据ID获取角色
 func (d *RoleDAO) GetByID(roleID int) (*model.Role, error) {
 	var role model.Role
-	err := database.DB.Preload("Permissions").Where("role_id = ?", roleID).First(&role).Error
+	err := database.DB.Preload("Permissions").Preload("DeniedPermissions").Preload("PermissionGroups").
+		Where("role_id = ?", roleID).First(&role).Error
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +50,8 @@ func (d *RoleDAO) Delete(roleID int) error {
 // List 获取角色列表
 func (d *RoleDAO) List() ([]*model.Role, error) {
 	var roles []*model.Role
-	err := database.DB.Preload("Permissions").Order("role_id ASC").Find(&roles).Error
+	err := database.DB.Preload("Permissions").Preload("DeniedPermissions").Preload("PermissionGroups").
+		Order("role_id ASC").Find(&roles).Error
 	return roles, err
 }
 
@@ -61,7 +63,8 @@ func (d *RoleDAO) ExistsByName(roleName string) (bool, error) {
 }
 
 // AssignPermissions 为角色分配权限
-func (d *RoleDAO) AssignPermissions(roleID int, permissionIDs []int) error {
+// denyIDs、groupIDs 为 nil 时保留原有拒绝规则和权限组
+func (d *RoleDAO) AssignPermissions(roleID int, permissionIDs, denyIDs, groupIDs []int) error {
 	// 开启事务
 	tx := database.DB.Begin()
 	defer func() {
@@ -87,6 +90,36 @@ func (d *RoleDAO) AssignPermissions(roleID int, permissionIDs []int) error {
 		}
 	}
 
+	// 替换拒绝规则
+	if denyIDs != nil {
+		if err := tx.Exec("DELETE FROM role_permission_denies WHERE role_id = ?", roleID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, permID := range denyIDs {
+			if err := tx.Exec("INSERT INTO role_permission_denies (role_id, permission_id) VALUES (?, ?)",
+				roleID, permID).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	// 替换权限组
+	if groupIDs != nil {
+		if err := tx.Exec("DELETE FROM role_permission_groups WHERE role_id = ?", roleID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, groupID := range groupIDs {
+			if err := tx.Exec("INSERT INTO role_permission_groups (role_id, group_id) VALUES (?, ?)",
+				roleID, groupID).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
 	return tx.Commit().Error
 }
 
@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionDAO struct{}
+
+func NewSessionDAO() *SessionDAO {
+	return &SessionDAO{}
+}
+
+// Create 创建会话
+func (d *SessionDAO) Create(session *model.UserSession) error {
+	return database.DB.Create(session).Error
+}
+
+// GetActive 获取未撤销且未过期的会话
+func (d *SessionDAO) GetActive(sessionID string) (*model.UserSession, error) {
+	var session model.UserSession
+	err := database.DB.Where("id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveByUser 获取用户的有效会话，最近活跃的在前
+func (d *SessionDAO) ListActiveByUser(userID int) ([]*model.UserSession, error) {
+	var sessions []*model.UserSession
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch 更新会话最近活跃时间和IP
+func (d *SessionDAO) Touch(sessionID, ip string) error {
+	return database.DB.Model(&model.UserSession{}).
+		Where("id = ?", sessionID).
+		Updates(map[string]interface{}{"last_seen_at": time.Now(), "ip": ip}).Error
+}
+
+// LockActiveTx 在事务中锁定未撤销且未过期的会话
+func (d *SessionDAO) LockActiveTx(tx *gorm.DB, sessionID string) (*model.UserSession, error) {
+	var session model.UserSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateExpiryTx 在事务中延长会话有效期（轮换刷新令牌时）
+func (d *SessionDAO) UpdateExpiryTx(tx *gorm.DB, sessionID string, expiresAt time.Time) error {
+	return tx.Model(&model.UserSession{}).
+		Where("id = ?", sessionID).
+		Update("expires_at", expiresAt).Error
+}
+
+// Revoke 撤销用户的指定会话，返回实际撤销的会话ID
+func (d *SessionDAO) Revoke(userID int, sessionIDs ...string) ([]string, error) {
+	return d.revoke(func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ? AND id IN ?", userID, sessionIDs)
+	})
+}
+
+// RevokeByUsers 撤销用户的全部会话，exceptID 非空时保留该会话
+func (d *SessionDAO) RevokeByUsers(userIDs []int, exceptID string) ([]string, error) {
+	return d.revoke(func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("user_id IN ?", userIDs)
+		if exceptID != "" {
+			tx = tx.Where("id <> ?", exceptID)
+		}
+		return tx
+	})
+}
+
+// revoke 撤销满足条件的有效会话，返回被撤销的会话ID
+func (d *SessionDAO) revoke(scope func(tx *gorm.DB) *gorm.DB) ([]string, error) {
+	var ids []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := scope(tx.Model(&model.UserSession{})).
+			Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&model.UserSession{}).Where("id IN ?", ids).
+			Update("revoked_at", time.Now()).Error
+	})
+	return ids, err
+}
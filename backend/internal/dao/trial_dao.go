@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type TrialDAO struct{}
+
+func NewTrialDAO() *TrialDAO {
+	return &TrialDAO{}
+}
+
+// CreateTx 在事务中写入试用发放记录
+func (d *TrialDAO) CreateTx(tx *gorm.DB, grant *model.TrialGrant) error {
+	return tx.Create(grant).Error
+}
+
+// ExistsByEmailKeyTx 规范化邮箱是否已领取过试用
+func (d *TrialDAO) ExistsByEmailKeyTx(tx *gorm.DB, emailKey string) (bool, error) {
+	var count int64
+	err := tx.Model(&model.TrialGrant{}).Where("email_key = ?", emailKey).Count(&count).Error
+	return count > 0, err
+}
+
+// ExistsByIPTx 指定时间之后该IP是否已领取过试用
+func (d *TrialDAO) ExistsByIPTx(tx *gorm.DB, ip string, since time.Time) (bool, error) {
+	var count int64
+	err := tx.Model(&model.TrialGrant{}).Where("ip_address = ? AND created_at >= ?", ip, since).Count(&count).Error
+	return count > 0, err
+}
+
+// ExistsByFingerprintTx 设备指纹是否已领取过试用
+func (d *TrialDAO) ExistsByFingerprintTx(tx *gorm.DB, fingerprint string) (bool, error) {
+	var count int64
+	err := tx.Model(&model.TrialGrant{}).Where("device_fingerprint = ?", fingerprint).Count(&count).Error
+	return count > 0, err
+}
+
+// ListUnconverted 获取在指定时间段内试用结束且尚未续费/升级的用户
+// 用户当前到期时间仍等于试用到期时间，说明试用后没有其他VIP变动
+func (d *TrialDAO) ListUnconverted(from, to time.Time, afterUserID, limit int) ([]*model.User, error) {
+	var users []*model.User
+	err := database.DB.Model(&model.User{}).
+		Select("users.user_id", "users.username", "users.email", "users.vip_level", "users.vip_expire_at").
+		Joins("JOIN trial_grants ON trial_grants.user_id = users.user_id").
+		Where("trial_grants.expire_at > ? AND trial_grants.expire_at <= ?", from, to).
+		Where("users.vip_expire_at = trial_grants.expire_at AND users.vip_frozen_at IS NULL").
+		Where("users.email <> '' AND users.vip_reminder = ?", true).
+		Where("users.user_id > ?", afterUserID).
+		Order("users.user_id").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,110 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorDAO struct{}
+
+func NewTwoFactorDAO() *TwoFactorDAO {
+	return &TwoFactorDAO{}
+}
+
+// GetTOTP 获取用户的TOTP配置
+func (d *TwoFactorDAO) GetTOTP(userID int) (*model.UserTOTP, error) {
+	var totp model.UserTOTP
+	if err := database.DB.Where("user_id = ?", userID).First(&totp).Error; err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// IsEnabled 判断用户是否已开启TOTP
+func (d *TwoFactorDAO) IsEnabled(userID int) (bool, error) {
+	var count int64
+	err := database.DB.Model(&model.UserTOTP{}).
+		Where("user_id = ? AND enabled = ?", userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SavePending 保存待确认的TOTP密钥（覆盖之前未确认的密钥）
+func (d *TwoFactorDAO) SavePending(userID int, secret string) error {
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"secret": secret, "enabled": false, "last_step": 0, "enabled_at": nil}),
+	}).Create(&model.UserTOTP{UserID: userID, Secret: secret}).Error
+}
+
+// UseStep 记录已使用的时间步，只有比上次更新的时间步才会成功（防止验证码重放）
+func (d *TwoFactorDAO) UseStep(userID int, step int64) (bool, error) {
+	result := database.DB.Model(&model.UserTOTP{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Enable 开启TOTP并替换恢复码
+func (d *TwoFactorDAO) Enable(userID int, codeHashes []string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.UserTOTP{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"enabled": true, "enabled_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return d.replaceRecoveryCodesTx(tx, userID, codeHashes)
+	})
+}
+
+// Delete 关闭TOTP并删除恢复码
+func (d *TwoFactorDAO) Delete(userID int) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.UserTOTP{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (d *TwoFactorDAO) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return d.replaceRecoveryCodesTx(tx, userID, codeHashes)
+	})
+}
+
+func (d *TwoFactorDAO) replaceRecoveryCodesTx(tx *gorm.DB, userID int, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]*model.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, &model.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 使用恢复码，成功返回 true
+func (d *TwoFactorDAO) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result := database.DB.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes 统计剩余可用的恢复码
+func (d *TwoFactorDAO) CountRecoveryCodes(userID int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"time"
+
 	"embyhub/internal/model"
 	"embyhub/pkg/database"
 )
@@ -26,6 +28,13 @@ func (d *UserDAO) GetByID(userID int) (*model.User, error) {
 	return &user, nil
 }
 
+// GetByIDs 根据ID批量获取用户（含角色）
+func (d *UserDAO) GetByIDs(userIDs []int) ([]*model.User, error) {
+	var users []*model.User
+	err := database.DB.Preload("Role").Where("user_id IN ?", userIDs).Find(&users).Error
+	return users, err
+}
+
 // GetByUsername 根据用户名获取用户
 func (d *UserDAO) GetByUsername(username string) (*model.User, error) {
 	var user model.User
@@ -59,6 +68,13 @@ func (d *UserDAO) GetByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
+// MarkEmailVerified 记录用户已验证当前邮箱（已验证的不覆盖验证时间）
+func (d *UserDAO) MarkEmailVerified(userID int) error {
+	return database.DB.Model(&model.User{}).
+		Where("user_id = ? AND email_verified_at IS NULL", userID).
+		Update("email_verified_at", time.Now()).Error
+}
+
 // Update 更新用户
 func (d *UserDAO) Update(user *model.User) error {
 	return database.DB.Save(user).Error
@@ -91,6 +107,14 @@ func (d *UserDAO) List(req *model.UserListRequest) ([]*model.User, int64, error)
 		query = query.Where("role_id = ?", req.RoleID)
 	}
 
+	// 用户组筛选
+	if req.GroupID > 0 {
+		query = query.Where("group_id = ?", req.GroupID)
+	}
+
+	// 管理范围
+	query = scopeByGroups(query, req.ScopeGroupIDs)
+
 	// 统计总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -108,7 +132,7 @@ func (d *UserDAO) List(req *model.UserListRequest) ([]*model.User, int64, error)
 	offset := (page - 1) * pageSize
 
 	// 查询列表
-	err := query.Preload("Role.Permissions").
+	err := query.Preload("Role.Permissions").Preload("Group").
 		Order("created_at DESC").
 		Limit(pageSize).
 		Offset(offset).
@@ -152,6 +176,18 @@ func (d *UserDAO) BatchUpdateStatus(userIDs []int, status int) error {
 		Update("status", status).Error
 }
 
+// GetSecurityVersion 获取用户当前的安全版本号
+func (d *UserDAO) GetSecurityVersion(userID int) (int, error) {
+	var user model.User
+	err := database.DB.Select("security_version").Where("user_id = ?", userID).First(&user).Error
+	return user.SecurityVersion, err
+}
+
+// BumpSecurityVersion 递增用户安全版本号，使已签发的Token失效
+func (d *UserDAO) BumpSecurityVersion(userIDs ...int) error {
+	return database.DB.Exec("UPDATE users SET security_version = security_version + 1 WHERE user_id IN ?", userIDs).Error
+}
+
 // GetActiveUsers 获取活跃用户（最近访问过的用户）
 func (d *UserDAO) GetActiveUsers(limit int) ([]*model.User, error) {
 	var users []*model.User
@@ -0,0 +1,140 @@
+package dao
+
+import (
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// scopeByGroups 按管理范围筛选 group_id：groupIDs 为 nil 表示不限，为空表示无可管理的用户组
+func scopeByGroups(query *gorm.DB, groupIDs []int) *gorm.DB {
+	if groupIDs == nil {
+		return query
+	}
+	if len(groupIDs) == 0 {
+		return query.Where("1 = 0")
+	}
+	return query.Where("group_id IN ?", groupIDs)
+}
+
+type UserGroupDAO struct{}
+
+func NewUserGroupDAO() *UserGroupDAO {
+	return &UserGroupDAO{}
+}
+
+// GetByID 根据ID获取用户组
+func (d *UserGroupDAO) GetByID(groupID int) (*model.UserGroup, error) {
+	var group model.UserGroup
+	if err := database.DB.Where("group_id = ?", groupID).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// List 获取用户组列表（含成员数），groupIDs 非 nil 时仅返回指定的组
+func (d *UserGroupDAO) List(groupIDs []int) ([]*model.UserGroup, error) {
+	var groups []*model.UserGroup
+	query := database.DB.Model(&model.UserGroup{})
+	if groupIDs != nil {
+		query = query.Where("group_id IN ?", groupIDs)
+	}
+	if err := query.Order("group_id ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	type countRow struct {
+		GroupID int
+		Count   int64
+	}
+	var rows []countRow
+	if err := database.DB.Model(&model.User{}).
+		Select("group_id, count(*) as count").
+		Where("group_id IS NOT NULL").
+		Group("group_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.GroupID] = row.Count
+	}
+	for _, group := range groups {
+		group.MemberCount = counts[group.GroupID]
+	}
+	return groups, nil
+}
+
+// ExistsByName 检查用户组名是否存在（排除指定ID）
+func (d *UserGroupDAO) ExistsByName(name string, excludeID int) (bool, error) {
+	var count int64
+	err := database.DB.Model(&model.UserGroup{}).
+		Where("name = ? AND group_id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Save 创建或更新用户组
+func (d *UserGroupDAO) Save(group *model.UserGroup) error {
+	return database.DB.Save(group).Error
+}
+
+// Delete 删除用户组：成员移出该组，组内授权一并删除
+func (d *UserGroupDAO) Delete(groupID int) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("group_id = ?", groupID).
+			Update("group_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&model.UserGroupGrant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.UserGroup{}, groupID).Error
+	})
+}
+
+// ListGrants 获取用户组内的授权
+func (d *UserGroupDAO) ListGrants(groupID int) ([]*model.UserGroupGrant, error) {
+	var grants []*model.UserGroupGrant
+	err := database.DB.Preload("User").Preload("Role").
+		Where("group_id = ?", groupID).
+		Order("id ASC").
+		Find(&grants).Error
+	return grants, err
+}
+
+// ListGrantsByUser 获取用户在各用户组内的授权（含角色）
+func (d *UserGroupDAO) ListGrantsByUser(userID int) ([]*model.UserGroupGrant, error) {
+	var grants []*model.UserGroupGrant
+	err := database.DB.Preload("Role").Where("user_id = ?", userID).Find(&grants).Error
+	return grants, err
+}
+
+// GrantRoleIDs 获取用户在各用户组内被授予的角色ID（去重）
+func (d *UserGroupDAO) GrantRoleIDs(userID int) ([]int, error) {
+	var roleIDs []int
+	err := database.DB.Model(&model.UserGroupGrant{}).
+		Where("user_id = ?", userID).
+		Distinct("role_id").
+		Pluck("role_id", &roleIDs).Error
+	return roleIDs, err
+}
+
+// UpsertGrant 授予或更新用户在组内的角色
+func (d *UserGroupDAO) UpsertGrant(grant *model.UserGroupGrant) error {
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role_id"}),
+	}).Create(grant).Error
+}
+
+// DeleteGrant 撤销用户在组内的授权，返回是否存在该授权
+func (d *UserGroupDAO) DeleteGrant(groupID, userID int) (bool, error) {
+	result := database.DB.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&model.UserGroupGrant{})
+	return result.RowsAffected > 0, result.Error
+}
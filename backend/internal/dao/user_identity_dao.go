@@ -0,0 +1,34 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+type UserIdentityDAO struct{}
+
+func NewUserIdentityDAO() *UserIdentityDAO {
+	return &UserIdentityDAO{}
+}
+
+// Get 根据身份提供方和外部用户标识获取绑定关系
+func (d *UserIdentityDAO) Get(provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Create 创建绑定关系
+func (d *UserIdentityDAO) Create(identity *model.UserIdentity) error {
+	return database.DB.Create(identity).Error
+}
+
+// TouchLogin 记录最近一次通过该身份登录的时间和邮箱
+func (d *UserIdentityDAO) TouchLogin(id int, email string) error {
+	return database.DB.Model(&model.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_login_at": time.Now(), "email": email}).Error
+}
@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type VipFreezeDAO struct{}
+
+func NewVipFreezeDAO() *VipFreezeDAO {
+	return &VipFreezeDAO{}
+}
+
+// CreateTx 在事务中创建冻结记录
+func (d *VipFreezeDAO) CreateTx(tx *gorm.DB, freeze *model.VipFreeze) error {
+	return tx.Create(freeze).Error
+}
+
+// GetActiveTx 在事务中获取用户未解冻的冻结记录
+func (d *VipFreezeDAO) GetActiveTx(tx *gorm.DB, userID int) (*model.VipFreeze, error) {
+	var freeze model.VipFreeze
+	if err := tx.Where("user_id = ? AND unfrozen_at IS NULL", userID).
+		Order("id DESC").First(&freeze).Error; err != nil {
+		return nil, err
+	}
+	return &freeze, nil
+}
+
+// CountSinceTx 统计用户在指定时间之后的冻结次数
+func (d *VipFreezeDAO) CountSinceTx(tx *gorm.DB, userID int, since time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&model.VipFreeze{}).Where("user_id = ? AND frozen_at >= ?", userID, since).Count(&count).Error
+	return count, err
+}
+
+// ListDueUserIDs 获取已到最晚解冻时间的冻结用户（按用户ID分批，afterUserID 为上一批最后的用户ID）
+func (d *VipFreezeDAO) ListDueUserIDs(now time.Time, afterUserID, limit int) ([]int, error) {
+	var ids []int
+	err := database.DB.Model(&model.VipFreeze{}).
+		Where("unfrozen_at IS NULL AND freeze_until <= ? AND user_id > ?", now, afterUserID).
+		Order("user_id").Limit(limit).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// ListByUser 获取用户的冻结记录
+func (d *VipFreezeDAO) ListByUser(userID int) ([]*model.VipFreeze, error) {
+	var freezes []*model.VipFreeze
+	err := database.DB.Where("user_id = ?", userID).Order("id DESC").Find(&freezes).Error
+	return freezes, err
+}
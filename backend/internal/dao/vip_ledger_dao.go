@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type VipLedgerDAO struct{}
+
+func NewVipLedgerDAO() *VipLedgerDAO {
+	return &VipLedgerDAO{}
+}
+
+// ListByUser 获取用户的VIP变动流水
+func (d *VipLedgerDAO) ListByUser(userID int, req *model.VipHistoryRequest) ([]*model.VipLedger, int64, error) {
+	var entries []*model.VipLedger
+	var total int64
+
+	query := database.DB.Model(&model.VipLedger{}).Where("user_id = ?", userID)
+	if req.Source != "" {
+		query = query.Where("source = ?", req.Source)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&entries).Error
+
+	return entries, total, err
+}
+
+// GetBySourceRefTx 在事务中获取指定来源和关联ID的最近一条流水
+func (d *VipLedgerDAO) GetBySourceRefTx(tx *gorm.DB, source, refID string) (*model.VipLedger, error) {
+	var entry model.VipLedger
+	if err := tx.Where("source = ? AND ref_id = ?", source, refID).
+		Order("id DESC").First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
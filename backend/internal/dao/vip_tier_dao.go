@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type VipTierDAO struct{}
+
+func NewVipTierDAO() *VipTierDAO {
+	return &VipTierDAO{}
+}
+
+// Create 创建VIP等级
+func (d *VipTierDAO) Create(tier *model.VipTier) error {
+	return database.DB.Create(tier).Error
+}
+
+// GetByLevel 根据等级获取
+func (d *VipTierDAO) GetByLevel(level int) (*model.VipTier, error) {
+	return d.GetByLevelTx(database.DB, level)
+}
+
+// GetByLevelTx 在事务中根据等级获取
+func (d *VipTierDAO) GetByLevelTx(tx *gorm.DB, level int) (*model.VipTier, error) {
+	var tier model.VipTier
+	if err := tx.Where("level = ?", level).First(&tier).Error; err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+// Update 更新VIP等级
+func (d *VipTierDAO) Update(tier *model.VipTier) error {
+	return database.DB.Save(tier).Error
+}
+
+// Delete 删除VIP等级
+func (d *VipTierDAO) Delete(level int) error {
+	return database.DB.Where("level = ?", level).Delete(&model.VipTier{}).Error
+}
+
+// List 获取所有VIP等级（按等级升序）
+func (d *VipTierDAO) List() ([]*model.VipTier, error) {
+	var tiers []*model.VipTier
+	err := database.DB.Order("level ASC").Find(&tiers).Error
+	return tiers, err
+}
+
+// CountUsers 统计等级为该等级的用户数
+// 包含已过期和已冻结的用户：冻结用户解冻后恢复原等级，删除等级前须确认无用户引用
+func (d *VipTierDAO) CountUsers(level int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.User{}).Where("vip_level = ?", level).Count(&count).Error
+	return count, err
+}
+
+// CountUnusedCards 统计兑换该等级的未使用卡密数
+func (d *VipTierDAO) CountUnusedCards(level int) (int64, error) {
+	var count int64
+	err := database.DB.Model(&model.CardKey{}).
+		Where("vip_level = ? AND status IN ?", level, []int{0, 1}).Count(&count).Error
+	return count, err
+}
+
+// ListEmbyUserIDs 获取当前有效等级为该等级且已绑定Emby的用户（分批）
+// 已过期或已冻结的用户有效等级为免费等级，与 service.EffectiveVipLevel 一致
+func (d *VipTierDAO) ListEmbyUserIDs(level, limit, afterUserID int, now time.Time) ([]*model.User, error) {
+	query := database.DB.Select("user_id", "emby_user_id").
+		Where("emby_user_id <> '' AND user_id > ?", afterUserID)
+	if level == model.FreeVipLevel {
+		query = query.Where("(vip_level = ? OR vip_frozen_at IS NOT NULL OR vip_expire_at IS NULL OR vip_expire_at <= ?)", level, now)
+	} else {
+		query = query.Where("vip_level = ? AND vip_frozen_at IS NULL AND vip_expire_at > ?", level, now)
+	}
+
+	var users []*model.User
+	err := query.Order("user_id ASC").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// ListByEmbyUserIDs 根据Emby用户ID获取用户的等级信息
+func (d *VipTierDAO) ListByEmbyUserIDs(embyUserIDs []string) ([]*model.User, error) {
+	var users []*model.User
+	err := database.DB.Select("user_id", "emby_user_id", "vip_level", "vip_expire_at", "vip_frozen_at").
+		Where("emby_user_id IN ?", embyUserIDs).Find(&users).Error
+	return users, err
+}
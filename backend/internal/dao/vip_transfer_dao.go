@@ -0,0 +1,77 @@
+package dao
+
+import (
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type VipTransferDAO struct{}
+
+func NewVipTransferDAO() *VipTransferDAO {
+	return &VipTransferDAO{}
+}
+
+// CreateTx 在事务中写入转赠记录
+func (d *VipTransferDAO) CreateTx(tx *gorm.DB, transfer *model.VipTransfer) error {
+	return tx.Create(transfer).Error
+}
+
+// LastSentAtTx 获取用户最近一次转出时间
+func (d *VipTransferDAO) LastSentAtTx(tx *gorm.DB, userID int) (*time.Time, error) {
+	var transfer model.VipTransfer
+	err := tx.Where("from_user_id = ?", userID).Order("id DESC").First(&transfer).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transfer.CreatedAt, nil
+}
+
+// SumSentSinceTx 统计用户在指定时间之后转出的天数
+func (d *VipTransferDAO) SumSentSinceTx(tx *gorm.DB, userID int, since time.Time) (int64, error) {
+	var days int64
+	err := tx.Model(&model.VipTransfer{}).
+		Select("COALESCE(SUM(days), 0)").
+		Where("from_user_id = ? AND created_at >= ?", userID, since).
+		Scan(&days).Error
+	return days, err
+}
+
+// ListByUser 获取用户转出和转入的记录
+func (d *VipTransferDAO) ListByUser(userID int, req *model.VipTransferListRequest) ([]*model.VipTransfer, int64, error) {
+	var transfers []*model.VipTransfer
+	var total int64
+
+	query := database.DB.Model(&model.VipTransfer{}).Where("from_user_id = ? OR to_user_id = ?", userID, userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	offset := (page - 1) * pageSize
+
+	selectName := func(db *gorm.DB) *gorm.DB {
+		return db.Select("user_id", "username")
+	}
+	err := query.Preload("FromUser", selectName).
+		Preload("ToUser", selectName).
+		Order("id DESC").
+		Limit(pageSize).
+		Offset(offset).
+		Find(&transfers).Error
+
+	return transfers, total, err
+}
@@ -0,0 +1,471 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AgentHandler struct {
+	agentService *service.AgentService
+}
+
+func NewAgentHandler() *AgentHandler {
+	return &AgentHandler{
+		agentService: service.NewAgentService(),
+	}
+}
+
+// GetQuota 获取当前代理商额度
+// @Summary 获取代理商额度
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=model.AgentQuota}
+// @Router /api/agent/quota [get]
+func (h *AgentHandler) GetQuota(c *gin.Context) {
+	agentID, _ := c.Get("user_id")
+
+	quota, err := h.agentService.GetQuota(agentID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取额度失败")
+		return
+	}
+
+	util.SuccessResponse(c, quota)
+}
+
+// ListLedger 获取当前代理商额度流水
+// @Summary 获取代理商额度流水
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param reason query string false "变动原因"
+// @Success 200 {object} model.Response{data=model.AgentLedgerListResponse}
+// @Router /api/agent/quota/ledger [get]
+func (h *AgentHandler) ListLedger(c *gin.Context) {
+	var req model.AgentLedgerListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	result, err := h.agentService.ListLedger(agentID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取额度流水失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// GenerateCards 代理商生成卡密（消耗额度）
+// @Summary 代理商生成卡密
+// @Tags 代理商
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.CardKeyCreateRequest true "生成请求"
+// @Success 200 {object} model.Response{data=[]model.CardKey}
+// @Router /api/agent/card-keys [post]
+func (h *AgentHandler) GenerateCards(c *gin.Context) {
+	var req model.CardKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	cardKeys, err := h.agentService.GenerateCards(&req, agentID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, "生成卡密失败: "+err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "生成成功", cardKeys)
+}
+
+// ListCards 获取当前代理商的卡密列表
+// @Summary 获取代理商卡密列表
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param status query int false "状态"
+// @Param batch_id query int false "批次ID"
+// @Param keyword query string false "关键词"
+// @Success 200 {object} model.Response{data=model.CardKeyListResponse}
+// @Router /api/agent/card-keys [get]
+func (h *AgentHandler) ListCards(c *gin.Context) {
+	var req model.CardKeyListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	result, err := h.agentService.ListCards(agentID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取卡密列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// GetCard 获取当前代理商的卡密详情
+// @Summary 获取代理商卡密详情
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Param id path int true "卡密ID"
+// @Success 200 {object} model.Response{data=model.CardKey}
+// @Router /api/agent/card-keys/{id} [get]
+func (h *AgentHandler) GetCard(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	cardKey, err := h.agentService.GetCard(agentID.(int), id)
+	if err != nil {
+		util.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, cardKey)
+}
+
+// DisableCard 禁用当前代理商的卡密
+// @Summary 代理商禁用卡密
+// @Tags 代理商
+// @Security Bearer
+// @Param id path int true "卡密ID"
+// @Success 200 {object} model.Response
+// @Router /api/agent/card-keys/{id}/disable [put]
+func (h *AgentHandler) DisableCard(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	if err := h.agentService.DisableCard(agentID.(int), id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "禁用成功", nil)
+}
+
+// EnableCard 启用当前代理商的卡密
+// @Summary 代理商启用卡密
+// @Tags 代理商
+// @Security Bearer
+// @Param id path int true "卡密ID"
+// @Success 200 {object} model.Response
+// @Router /api/agent/card-keys/{id}/enable [put]
+func (h *AgentHandler) EnableCard(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	if err := h.agentService.EnableCard(agentID.(int), id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "启用成功", nil)
+}
+
+// DeleteCard 删除当前代理商未使用的卡密（退还额度）
+// @Summary 代理商删除卡密
+// @Tags 代理商
+// @Security Bearer
+// @Param id path int true "卡密ID"
+// @Success 200 {object} model.Response
+// @Router /api/agent/card-keys/{id} [delete]
+func (h *AgentHandler) DeleteCard(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	if err := h.agentService.DeleteCard(agentID.(int), id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "删除成功，额度已退还", nil)
+}
+
+// GetStatistics 获取当前代理商的卡密统计
+// @Summary 代理商卡密统计
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response
+// @Router /api/agent/card-keys/statistics [get]
+func (h *AgentHandler) GetStatistics(c *gin.Context) {
+	agentID, _ := c.Get("user_id")
+
+	stats, err := h.agentService.GetStatistics(agentID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取统计失败")
+		return
+	}
+
+	util.SuccessResponse(c, stats)
+}
+
+// ListBatches 获取当前代理商的批次列表
+// @Summary 代理商批次列表
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.Response{data=model.CardBatchListResponse}
+// @Router /api/agent/batches [get]
+func (h *AgentHandler) ListBatches(c *gin.Context) {
+	var req model.CardBatchListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	result, err := h.agentService.ListBatches(agentID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取批次列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// ExportBatch 导出当前代理商的批次卡密（纯文本，每行一个）
+// @Summary 代理商导出批次卡密
+// @Tags 代理商
+// @Security Bearer
+// @Produce plain
+// @Param id path int true "批次ID"
+// @Router /api/agent/batches/{id}/export [get]
+func (h *AgentHandler) ExportBatch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	batch, cardKeys, err := h.agentService.ExportBatch(agentID.(int), id)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	var sb strings.Builder
+	for _, cardKey := range cardKeys {
+		sb.WriteString(cardKey.CardCode)
+		sb.WriteString("\n")
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=card_batch_%d.txt", batch.ID))
+	c.String(200, sb.String())
+}
+
+// ListRedemptions 获取当前代理商卡密的兑换记录
+// @Summary 代理商兑换记录
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.Response{data=model.CardRedemptionListResponse}
+// @Router /api/agent/redemptions [get]
+func (h *AgentHandler) ListRedemptions(c *gin.Context) {
+	var req model.CardRedemptionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	agentID, _ := c.Get("user_id")
+
+	result, err := h.agentService.ListRedemptions(agentID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取兑换记录失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// GetSettlement 获取当前代理商的结算报表
+// @Summary 代理商结算报表
+// @Tags 代理商
+// @Security Bearer
+// @Produce json
+// @Param start_time query string true "开始日期 2006-01-02"
+// @Param end_time query string true "结束日期 2006-01-02（包含）"
+// @Success 200 {object} model.Response{data=model.AgentSettlement}
+// @Router /api/agent/settlement [get]
+func (h *AgentHandler) GetSettlement(c *gin.Context) {
+	agentID, _ := c.Get("user_id")
+	h.settlement(c, agentID.(int))
+}
+
+// ListAgents 获取代理商列表（管理员）
+// @Summary 代理商列表
+// @Tags 代理商管理
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.Response{data=model.AgentListResponse}
+// @Router /api/agents [get]
+func (h *AgentHandler) ListAgents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	result, err := h.agentService.ListAgents(page, pageSize)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取代理商列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// AdjustQuota 为代理商充值或扣减额度（管理员）
+// @Summary 调整代理商额度
+// @Tags 代理商管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "代理商用户ID"
+// @Param request body model.AgentQuotaAdjustRequest true "调整请求"
+// @Success 200 {object} model.Response{data=model.AgentQuota}
+// @Router /api/agents/{id}/quota [post]
+func (h *AgentHandler) AdjustQuota(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req model.AgentQuotaAdjustRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	operatorName, _ := c.Get("username")
+
+	quota, err := h.agentService.AdjustQuota(id, &req, operatorID.(int), operatorName.(string),
+		c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "额度调整成功", quota)
+}
+
+// ListAgentLedger 获取指定代理商的额度流水（管理员）
+// @Summary 代理商额度流水
+// @Tags 代理商管理
+// @Security Bearer
+// @Produce json
+// @Param id path int true "代理商用户ID"
+// @Success 200 {object} model.Response{data=model.AgentLedgerListResponse}
+// @Router /api/agents/{id}/ledger [get]
+func (h *AgentHandler) ListAgentLedger(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req model.AgentLedgerListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	result, err := h.agentService.ListLedger(id, &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取额度流水失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// GetAgentSettlement 获取指定代理商的结算报表（管理员）
+// @Summary 代理商结算报表
+// @Tags 代理商管理
+// @Security Bearer
+// @Produce json
+// @Param id path int true "代理商用户ID"
+// @Param start_time query string true "开始日期 2006-01-02"
+// @Param end_time query string true "结束日期 2006-01-02（包含）"
+// @Success 200 {object} model.Response{data=model.AgentSettlement}
+// @Router /api/agents/{id}/settlement [get]
+func (h *AgentHandler) GetAgentSettlement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+	h.settlement(c, id)
+}
+
+// settlement 绑定结算参数并返回报表
+func (h *AgentHandler) settlement(c *gin.Context, agentID int) {
+	var req model.AgentSettlementRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	report, err := h.agentService.GetSettlement(agentID, &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, report)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"strconv"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	apiTokenService *service.APITokenService
+}
+
+func NewAPITokenHandler() *APITokenHandler {
+	return &APITokenHandler{
+		apiTokenService: service.NewAPITokenService(),
+	}
+}
+
+// List 获取当前用户的访问令牌
+// @Summary 我的访问令牌
+// @Tags 访问令牌
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.APIToken}
+// @Router /api/auth/tokens [get]
+func (h *APITokenHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	tokens, err := h.apiTokenService.List(userID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取访问令牌失败")
+		return
+	}
+
+	util.SuccessResponse(c, tokens)
+}
+
+// Create 创建访问令牌，令牌原文只在本次响应中返回
+// @Summary 创建访问令牌
+// @Tags 访问令牌
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.APITokenCreateRequest true "令牌名称、权限范围、IP白名单和有效期"
+// @Success 200 {object} model.Response{data=model.APITokenCreateResponse}
+// @Router /api/auth/tokens [post]
+func (h *APITokenHandler) Create(c *gin.Context) {
+	var req model.APITokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	roleID, _ := c.Get("role_id")
+
+	result, err := h.apiTokenService.Create(userID.(int), roleID.(int), &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "访问令牌已创建，请立即保存，关闭后将无法再次查看", result)
+}
+
+// Revoke 撤销访问令牌
+// @Summary 撤销访问令牌
+// @Tags 访问令牌
+// @Security Bearer
+// @Param id path int true "令牌ID"
+// @Success 200 {object} model.Response
+// @Router /api/auth/tokens/{id} [delete]
+func (h *APITokenHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := h.apiTokenService.Revoke(userID.(int), id, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "访问令牌已撤销", nil)
+}
+
+// tokenAllows 使用个人访问令牌时判断令牌范围是否包含指定权限，登录会话不受限制
+// 用于路由本身不要求权限、由服务按管理范围校验权限的接口，避免仅有 self 范围的令牌借用所有者的管理权限
+func tokenAllows(c *gin.Context, scope string) bool {
+	token, ok := c.Get("api_token")
+	return !ok || token.(*model.APIToken).HasScope(scope)
+}
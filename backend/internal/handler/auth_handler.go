@@ -33,7 +33,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.authService.Login(&req)
+	resp, err := h.authService.Login(&req, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -42,6 +42,54 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	util.SuccessResponse(c, resp)
 }
 
+// LoginTwoFactor 登录第二步：提交两步验证码
+// @Summary 两步验证登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorLoginRequest true "登录挑战和验证码"
+// @Success 200 {object} model.Response{data=model.LoginResponse}
+// @Router /api/auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req model.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.authService.LoginTwoFactor(&req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, resp)
+}
+
+// SetupTwoFactorChallenge 登录过程中绑定验证器（角色要求两步验证但尚未开启时）
+// @Summary 登录时绑定验证器
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorChallengeRequest true "登录挑战"
+// @Success 200 {object} model.Response{data=model.TwoFactorSetupResponse}
+// @Router /api/auth/login/2fa/setup [post]
+func (h *AuthHandler) SetupTwoFactorChallenge(c *gin.Context) {
+	var req model.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, setup)
+}
+
 // Logout 管理员登出
 // @Summary 管理员登出
 // @Tags 认证
@@ -51,7 +99,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	if err := h.authService.Logout(userID.(int)); err != nil {
+	if err := h.authService.Logout(userID.(int), c.GetString("session_id")); err != nil {
 		util.InternalErrorResponse(c, "登出失败")
 		return
 	}
@@ -82,34 +130,28 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	util.SuccessResponse(c, user)
 }
 
-// RefreshToken 刷新Token
+// RefreshToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
 // @Summary 刷新Token
 // @Tags 认证
-// @Security Bearer
+// @Accept json
 // @Produce json
-// @Success 200 {object} model.Response{data=object{token=string,expires_in=int64}}
+// @Param request body model.RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} model.Response{data=model.TokenResponse}
 // @Router /api/auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	// 从Header获取当前Token
-	tokenString := c.GetHeader("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
 	}
 
-	// 刷新Token
-	newToken, err := util.RefreshToken(tokenString)
+	tokens, err := h.authService.RefreshToken(req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		util.UnauthorizedResponse(c, "Token无效或已过期")
+		util.UnauthorizedResponse(c, err.Error())
 		return
 	}
 
-	// 获取新Token的剩余时间
-	expiresIn := util.GetTokenRemainingTime(newToken)
-
-	util.SuccessResponse(c, gin.H{
-		"token":      newToken,
-		"expires_in": expiresIn,
-	})
+	util.SuccessResponse(c, tokens)
 }
 
 // ChangePassword 用户修改自己的密码
@@ -119,7 +161,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body model.UserPasswordRequest true "密码请求"
-// @Success 200 {object} model.Response
+// @Success 200 {object} model.Response{data=model.TokenResponse}
 // @Router /api/auth/password [put]
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -129,10 +171,10 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	var req struct {
-		Password string `json:"password" binding:"required,min=6"`
+		Password string `json:"password" binding:"required,max=50"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		util.BadRequestResponse(c, "密码格式错误，至少6位")
+		util.BadRequestResponse(c, "请输入新密码")
 		return
 	}
 
@@ -141,5 +183,131 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	util.SuccessWithMessage(c, "密码修改成功", nil)
+	// 修改密码会撤销所有会话，为当前设备重新创建会话
+	user, err := h.authService.GetUserByID(userID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取用户信息失败")
+		return
+	}
+	tokens, err := h.authService.StartSession(user, &model.SessionMeta{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		util.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "密码修改成功", tokens)
+}
+
+// ListSessions 获取当前用户的登录会话
+// @Summary 我的登录会话
+// @Tags 认证
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.UserSession}
+// @Router /api/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	sessions, err := h.authService.ListSessions(userID.(int), c.GetString("session_id"))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取会话列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, sessions)
+}
+
+// RevokeSession 撤销当前用户的指定会话（远程下线某台设备）
+// @Summary 撤销登录会话
+// @Tags 认证
+// @Security Bearer
+// @Param id path string true "会话ID"
+// @Success 200 {object} model.Response
+// @Router /api/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.authService.RevokeSession(userID.(int), c.Param("id")); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "会话已撤销", nil)
+}
+
+// RevokeAllSessions 退出所有设备
+// @Summary 退出所有设备
+// @Tags 认证
+// @Security Bearer
+// @Param keep_current query bool false "是否保留当前会话"
+// @Success 200 {object} model.Response{data=object{count=int}}
+// @Router /api/auth/sessions [delete]
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	exceptID := ""
+	if c.Query("keep_current") == "true" {
+		exceptID = c.GetString("session_id")
+	}
+
+	count, err := h.authService.RevokeAllSessions(userID.(int), exceptID)
+	if err != nil {
+		util.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "已退出所有设备", gin.H{"count": count})
+}
+
+// LoginHistory 获取当前用户的登录历史
+// @Summary 我的登录历史
+// @Tags 认证
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.Response{data=model.LoginHistoryResponse}
+// @Router /api/auth/login-history [get]
+func (h *AuthHandler) LoginHistory(c *gin.Context) {
+	var req model.LoginHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	result, err := h.authService.LoginHistory(userID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取登录历史失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// RevokeFromLoginAlert 登录提醒邮件中的"不是我本人"链接：退出所有设备并要求重置密码
+// @Summary 这不是我本人
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body model.LoginAlertRevokeRequest true "链接令牌"
+// @Success 200 {object} model.Response
+// @Router /api/auth/login-alert/revoke [post]
+func (h *AuthHandler) RevokeFromLoginAlert(c *gin.Context) {
+	var req model.LoginAlertRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.authService.RevokeFromLoginAlert(req.Token, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "已退出所有设备，请通过找回密码重新设置密码", nil)
 }
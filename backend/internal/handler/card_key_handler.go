@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"embyhub/internal/model"
 	"embyhub/internal/service"
@@ -12,14 +15,37 @@ import (
 
 type CardKeyHandler struct {
 	cardKeyService *service.CardKeyService
+	scopeService   *service.ScopeService
 }
 
 func NewCardKeyHandler() *CardKeyHandler {
 	return &CardKeyHandler{
 		cardKeyService: service.NewCardKeyService(),
+		scopeService:   service.NewScopeService(),
 	}
 }
 
+// authorize 校验当前操作者能否以指定权限管理卡密，失败时写入响应并返回 false
+func (h *CardKeyHandler) authorize(c *gin.Context, permissionKey string, cardKeyID int) bool {
+	operatorID, _ := c.Get("user_id")
+	if err := h.cardKeyService.Authorize(operatorID.(int), permissionKey, cardKeyID); err != nil {
+		util.ForbiddenResponse(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// listScope 获取当前操作者可查看卡密的用户组范围，失败时写入响应并返回 false
+func (h *CardKeyHandler) listScope(c *gin.Context) ([]int, bool) {
+	operatorID, _ := c.Get("user_id")
+	groupIDs, err := h.scopeService.ListScope(operatorID.(int), "cardkey:view")
+	if err != nil {
+		util.InternalErrorResponse(c, "获取管理范围失败")
+		return nil, false
+	}
+	return groupIDs, true
+}
+
 // Create 批量生成卡密
 // @Summary 批量生成卡密
 // @Tags 卡密管理
@@ -41,7 +67,7 @@ func (h *CardKeyHandler) Create(c *gin.Context) {
 
 	cardKeys, err := h.cardKeyService.Create(&req, creatorID.(int))
 	if err != nil {
-		util.InternalErrorResponse(c, "生成卡密失败: "+err.Error())
+		util.BadRequestResponse(c, "生成卡密失败: "+err.Error())
 		return
 	}
 
@@ -67,6 +93,12 @@ func (h *CardKeyHandler) List(c *gin.Context) {
 		return
 	}
 
+	groupIDs, ok := h.listScope(c)
+	if !ok {
+		return
+	}
+	req.ScopeGroupIDs = groupIDs
+
 	result, err := h.cardKeyService.List(&req)
 	if err != nil {
 		util.InternalErrorResponse(c, "获取卡密列表失败")
@@ -91,6 +123,10 @@ func (h *CardKeyHandler) GetByID(c *gin.Context) {
 		return
 	}
 
+	if !h.authorize(c, "cardkey:view", id) {
+		return
+	}
+
 	cardKey, err := h.cardKeyService.GetByID(id)
 	if err != nil {
 		util.NotFoundResponse(c, "卡密不存在")
@@ -114,6 +150,10 @@ func (h *CardKeyHandler) Disable(c *gin.Context) {
 		return
 	}
 
+	if !h.authorize(c, "cardkey:edit", id) {
+		return
+	}
+
 	if err := h.cardKeyService.Disable(id); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -136,6 +176,10 @@ func (h *CardKeyHandler) Enable(c *gin.Context) {
 		return
 	}
 
+	if !h.authorize(c, "cardkey:edit", id) {
+		return
+	}
+
 	if err := h.cardKeyService.Enable(id); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -158,6 +202,10 @@ func (h *CardKeyHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if !h.authorize(c, "cardkey:delete", id) {
+		return
+	}
+
 	if err := h.cardKeyService.Delete(id); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -166,6 +214,74 @@ func (h *CardKeyHandler) Delete(c *gin.Context) {
 	util.SuccessWithMessage(c, "删除成功", nil)
 }
 
+// Revoke 撤销卡密兑换（回滚已发放的VIP时长）
+// @Summary 撤销卡密兑换
+// @Tags 卡密管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "卡密ID"
+// @Param request body model.CardRevokeRequest false "撤销原因"
+// @Success 200 {object} model.Response{data=model.CardRevokeResponse}
+// @Router /api/card-keys/{id}/revoke [post]
+func (h *CardKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req model.CardRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if !h.authorize(c, "cardkey:edit", id) {
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	operatorName, _ := c.Get("username")
+
+	result, err := h.cardKeyService.RevokeRedemption(id, req.Reason, operatorID.(int), operatorName.(string),
+		c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "撤销成功", result)
+}
+
+// ListRedemptions 获取卡密兑换记录
+// @Summary 获取卡密兑换记录
+// @Tags 卡密管理
+// @Security Bearer
+// @Produce json
+// @Param id path int true "卡密ID"
+// @Success 200 {object} model.Response{data=[]model.CardRedemption}
+// @Router /api/card-keys/{id}/redemptions [get]
+func (h *CardKeyHandler) ListRedemptions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	if !h.authorize(c, "cardkey:view", id) {
+		return
+	}
+
+	redemptions, err := h.cardKeyService.ListRedemptions(id)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取兑换记录失败")
+		return
+	}
+
+	util.SuccessResponse(c, redemptions)
+}
+
 // GetStatistics 获取卡密统计
 // @Summary 获取卡密统计
 // @Tags 卡密管理
@@ -174,7 +290,12 @@ func (h *CardKeyHandler) Delete(c *gin.Context) {
 // @Success 200 {object} model.Response
 // @Router /api/card-keys/statistics [get]
 func (h *CardKeyHandler) GetStatistics(c *gin.Context) {
-	stats, err := h.cardKeyService.GetStatistics()
+	groupIDs, ok := h.listScope(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.cardKeyService.GetStatistics(0, groupIDs)
 	if err != nil {
 		util.InternalErrorResponse(c, "获取统计失败")
 		return
@@ -183,6 +304,73 @@ func (h *CardKeyHandler) GetStatistics(c *gin.Context) {
 	util.SuccessResponse(c, stats)
 }
 
+// ListBatches 获取卡密批次列表
+// @Summary 获取卡密批次列表
+// @Tags 卡密管理
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.Response{data=model.CardBatchListResponse}
+// @Router /api/card-keys/batches [get]
+func (h *CardKeyHandler) ListBatches(c *gin.Context) {
+	var req model.CardBatchListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	groupIDs, ok := h.listScope(c)
+	if !ok {
+		return
+	}
+	req.ScopeGroupIDs = groupIDs
+
+	result, err := h.cardKeyService.ListBatches(&req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取批次列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// ExportBatch 导出批次卡密（纯文本，每行一个）
+// @Summary 导出批次卡密
+// @Tags 卡密管理
+// @Security Bearer
+// @Produce plain
+// @Param id path int true "批次ID"
+// @Router /api/card-keys/batches/{id}/export [get]
+func (h *CardKeyHandler) ExportBatch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	if err := h.cardKeyService.AuthorizeBatch(operatorID.(int), "cardkey:export", id); err != nil {
+		util.ForbiddenResponse(c, err.Error())
+		return
+	}
+
+	batch, cardKeys, err := h.cardKeyService.ExportBatch(id)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	var sb strings.Builder
+	for _, cardKey := range cardKeys {
+		sb.WriteString(cardKey.CardCode)
+		sb.WriteString("\n")
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=card_batch_%d.txt", batch.ID))
+	c.String(200, sb.String())
+}
+
 // Validate 验证卡密（公开接口，用于注册前验证）
 // @Summary 验证卡密
 // @Tags 卡密管理
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService *service.MagicLinkService
+}
+
+func NewMagicLinkHandler() *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: service.NewMagicLinkService(),
+	}
+}
+
+// Config 获取邮件登录是否启用（登录页据此显示邮件登录入口）
+// @Summary 邮件登录配置
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} model.Response
+// @Router /api/auth/magic-link/config [get]
+func (h *MagicLinkHandler) Config(c *gin.Context) {
+	util.SuccessResponse(c, gin.H{"enabled": h.magicLinkService.Enabled()})
+}
+
+// Send 发送邮件登录链接
+// @Summary 发送登录链接
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body model.MagicLinkRequest true "邮箱"
+// @Success 200 {object} model.Response
+// @Router /api/auth/magic-link [post]
+func (h *MagicLinkHandler) Send(c *gin.Context) {
+	var req model.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.magicLinkService.Send(req.Email, c.ClientIP()); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "如果该邮箱已注册，登录链接已发送，请查收邮件", nil)
+}
+
+// Login 使用邮件中的登录链接登录
+// @Summary 邮件链接登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body model.MagicLinkLoginRequest true "登录链接令牌"
+// @Success 200 {object} model.Response{data=model.LoginResponse}
+// @Router /api/auth/magic-link/login [post]
+func (h *MagicLinkHandler) Login(c *gin.Context) {
+	var req model.MagicLinkLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.magicLinkService.Login(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, resp)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler() *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: service.NewNotificationService(),
+	}
+}
+
+// ListLogs 获取通知发送记录
+// @Summary 获取通知发送记录
+// @Tags 通知
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param type query string false "通知类型"
+// @Param status query string false "状态"
+// @Param stage query int false "提醒阶段"
+// @Param user_id query int false "用户ID"
+// @Param start_time query string false "开始日期"
+// @Param end_time query string false "结束日期"
+// @Success 200 {object} model.Response{data=model.NotificationLogListResponse}
+// @Router /api/notifications/logs [get]
+func (h *NotificationHandler) ListLogs(c *gin.Context) {
+	var req model.NotificationLogListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	result, err := h.notificationService.ListLogs(&req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取通知记录失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// UpdatePreferences 更新当前用户的通知偏好
+// @Summary 更新通知偏好
+// @Tags 通知
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.NotificationPreferenceRequest true "通知偏好"
+// @Success 200 {object} model.Response
+// @Router /api/notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req model.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := h.notificationService.SetVipReminder(userID.(int), *req.VipReminder); err != nil {
+		util.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "设置成功", map[string]interface{}{
+		"vip_reminder": *req.VipReminder,
+	})
+}
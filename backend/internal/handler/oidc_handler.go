@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 保存单点登录 state 的 Cookie，只在单点登录接口下发送
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: service.NewOIDCService(),
+	}
+}
+
+// Config 获取单点登录配置（登录页据此显示单点登录按钮）
+// @Summary 单点登录配置
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} model.Response{data=model.OIDCConfigResponse}
+// @Router /api/auth/oidc/config [get]
+func (h *OIDCHandler) Config(c *gin.Context) {
+	util.SuccessResponse(c, h.oidcService.Config())
+}
+
+// Authorize 获取跳转到身份提供方的授权地址，同时将 state 写入 HttpOnly Cookie
+// @Summary 发起单点登录
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} model.Response{data=model.OIDCAuthorizeResponse}
+// @Router /api/auth/oidc/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	authURL, state, err := h.oidcService.Authorize()
+	if err != nil {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			util.NotFoundResponse(c, err.Error())
+			return
+		}
+		util.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	setOIDCStateCookie(c, state, int(service.OIDCStateTTL/time.Second))
+	util.SuccessResponse(c, &model.OIDCAuthorizeResponse{URL: authURL})
+}
+
+// Callback 身份提供方回调后完成登录，请求中的 state 须与 Cookie 中的一致
+// @Summary 单点登录回调
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body model.OIDCCallbackRequest true "授权码和state"
+// @Success 200 {object} model.Response{data=model.LoginResponse}
+// @Router /api/auth/oidc/callback [post]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req model.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	// state 只能使用一次，无论成功与否都清除 Cookie
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+
+	resp, err := h.oidcService.Callback(&req, cookieState, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, resp)
+}
+
+// setOIDCStateCookie 写入（maxAge 小于0时清除）单点登录 state Cookie
+// SameSite=Lax：从身份提供方跳转回来后前端发起的同站请求会携带，跨站请求不会携带
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, oidcStateCookiePath, "", secure, true)
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"io"
+	"strconv"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandler struct {
+	orderService *service.OrderService
+}
+
+func NewOrderHandler() *OrderHandler {
+	return &OrderHandler{
+		orderService: service.NewOrderService(),
+	}
+}
+
+// ListPlans 获取上架套餐列表
+// @Summary 获取上架套餐列表
+// @Tags 订单
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.Plan}
+// @Router /api/plans [get]
+func (h *OrderHandler) ListPlans(c *gin.Context) {
+	plans, err := h.orderService.ListPlans(true)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取套餐列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, plans)
+}
+
+// ListAllPlans 获取全部套餐（含下架）
+// @Summary 获取全部套餐
+// @Tags 订单
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.Plan}
+// @Router /api/plans/all [get]
+func (h *OrderHandler) ListAllPlans(c *gin.Context) {
+	plans, err := h.orderService.ListPlans(false)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取套餐列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, plans)
+}
+
+// CreatePlan 创建套餐
+// @Summary 创建套餐
+// @Tags 订单
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.PlanRequest true "套餐信息"
+// @Success 200 {object} model.Response{data=model.Plan}
+// @Router /api/plans [post]
+func (h *OrderHandler) CreatePlan(c *gin.Context) {
+	var req model.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	plan, err := h.orderService.CreatePlan(&req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "创建成功", plan)
+}
+
+// UpdatePlan 更新套餐
+// @Summary 更新套餐
+// @Tags 订单
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "套餐ID"
+// @Param request body model.PlanRequest true "套餐信息"
+// @Success 200 {object} model.Response{data=model.Plan}
+// @Router /api/plans/{id} [put]
+func (h *OrderHandler) UpdatePlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req model.PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	plan, err := h.orderService.UpdatePlan(id, &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "更新成功", plan)
+}
+
+// DeletePlan 删除套餐
+// @Summary 删除套餐
+// @Tags 订单
+// @Security Bearer
+// @Param id path int true "套餐ID"
+// @Success 200 {object} model.Response
+// @Router /api/plans/{id} [delete]
+func (h *OrderHandler) DeletePlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	if err := h.orderService.DeletePlan(id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Create 创建订单
+// @Summary 创建订单
+// @Tags 订单
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.OrderCreateRequest true "下单请求"
+// @Success 200 {object} model.Response{data=model.OrderCreateResponse}
+// @Router /api/orders [post]
+func (h *OrderHandler) Create(c *gin.Context) {
+	var req model.OrderCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	result, err := h.orderService.CreateOrder(userID.(int), &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "下单成功", result)
+}
+
+// MyOrders 获取我的订单
+// @Summary 获取我的订单
+// @Tags 订单
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param status query string false "状态"
+// @Success 200 {object} model.Response{data=model.OrderListResponse}
+// @Router /api/orders/my [get]
+func (h *OrderHandler) MyOrders(c *gin.Context) {
+	var req model.OrderListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	req.UserID = userID.(int)
+
+	result, err := h.orderService.ListOrders(&req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取订单列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// Get 获取我的订单详情
+// @Summary 获取订单详情
+// @Tags 订单
+// @Security Bearer
+// @Produce json
+// @Param order_no path string true "订单号"
+// @Success 200 {object} model.Response{data=model.Order}
+// @Router /api/orders/{order_no} [get]
+func (h *OrderHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	order, err := h.orderService.GetOrder(c.Param("order_no"), userID.(int))
+	if err != nil {
+		util.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, order)
+}
+
+// MockPay 模拟支付（仅模拟网关订单）
+// @Summary 模拟支付
+// @Tags 订单
+// @Security Bearer
+// @Produce json
+// @Param order_no path string true "订单号"
+// @Success 200 {object} model.Response{data=model.Order}
+// @Router /api/orders/{order_no}/mock-pay [post]
+func (h *OrderHandler) MockPay(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	order, err := h.orderService.MockPay(c.Param("order_no"), userID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "支付成功", order)
+}
+
+// List 获取订单列表（管理员）
+// @Summary 获取订单列表
+// @Tags 订单
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param status query string false "状态"
+// @Param user_id query int false "用户ID"
+// @Param keyword query string false "订单号"
+// @Success 200 {object} model.Response{data=model.OrderListResponse}
+// @Router /api/orders [get]
+func (h *OrderHandler) List(c *gin.Context) {
+	var req model.OrderListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	result, err := h.orderService.ListOrders(&req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取订单列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// Refund 订单退款（回收已发放的VIP时长）
+// @Summary 订单退款
+// @Tags 订单
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param order_no path string true "订单号"
+// @Param request body model.OrderRefundRequest false "退款原因"
+// @Success 200 {object} model.Response{data=model.Order}
+// @Router /api/orders/{order_no}/refund [post]
+func (h *OrderHandler) Refund(c *gin.Context) {
+	var req model.OrderRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	operatorName, _ := c.Get("username")
+
+	order, err := h.orderService.Refund(c.Param("order_no"), req.Reason, operatorID.(int), operatorName.(string),
+		c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "退款成功", order)
+}
+
+// Callback 支付平台异步回调（公开接口，依靠签名校验）
+// @Summary 支付回调
+// @Tags 订单
+// @Produce plain
+// @Param gateway path string true "支付网关"
+// @Router /api/payments/callback/{gateway} [post]
+func (h *OrderHandler) Callback(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.String(400, "fail")
+		return
+	}
+
+	if _, err := h.orderService.HandleCallback(c.Param("gateway"), c.Request.Form); err != nil {
+		util.Warn("支付回调处理失败: " + err.Error())
+		c.String(400, "fail")
+		return
+	}
+
+	c.String(200, "success")
+}
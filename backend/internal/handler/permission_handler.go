@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"embyhub/internal/model"
 	"embyhub/internal/service"
 	"embyhub/internal/util"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,3 +29,109 @@ func (h *PermissionHandler) List(c *gin.Context) {
 
 	util.SuccessResponse(c, resp)
 }
+
+// Check 检查角色或用户是否拥有指定权限（调试用）
+func (h *PermissionHandler) Check(c *gin.Context) {
+	var req model.PermissionCheckRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.permissionService.Check(&req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// Routes 获取各路由所需的权限，供前端生成菜单
+func (h *PermissionHandler) Routes(c *gin.Context) {
+	util.SuccessResponse(c, h.permissionService.Routes())
+}
+
+// RoleEffective 获取角色展开后实际拥有的权限
+func (h *PermissionHandler) RoleEffective(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "角色ID格式错误")
+		return
+	}
+
+	result, err := h.permissionService.Effective(id)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// ListGroups 获取权限组列表
+func (h *PermissionHandler) ListGroups(c *gin.Context) {
+	groups, err := h.permissionService.ListGroups()
+	if err != nil {
+		util.InternalErrorResponse(c, "获取权限组列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, groups)
+}
+
+// CreateGroup 创建权限组
+func (h *PermissionHandler) CreateGroup(c *gin.Context) {
+	var req model.PermissionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	group, err := h.permissionService.CreateGroup(&req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "创建权限组成功", group)
+}
+
+// UpdateGroup 更新权限组
+func (h *PermissionHandler) UpdateGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "权限组ID格式错误")
+		return
+	}
+
+	var req model.PermissionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	group, err := h.permissionService.UpdateGroup(id, &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "更新权限组成功", group)
+}
+
+// DeleteGroup 删除权限组
+func (h *PermissionHandler) DeleteGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "权限组ID格式错误")
+		return
+	}
+
+	if err := h.permissionService.DeleteGroup(id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "删除权限组成功", nil)
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"strconv"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PointsHandler struct {
+	pointsService *service.PointsService
+}
+
+func NewPointsHandler() *PointsHandler {
+	return &PointsHandler{
+		pointsService: service.NewPointsService(),
+	}
+}
+
+// Checkin 每日签到
+// @Summary 每日签到
+// @Tags 积分
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=model.CheckinResponse}
+// @Router /api/points/checkin [post]
+func (h *PointsHandler) Checkin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result, err := h.pointsService.Checkin(userID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "签到成功", result)
+}
+
+// Summary 获取我的积分和签到状态
+// @Summary 我的积分
+// @Tags 积分
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=model.PointsSummary}
+// @Router /api/points [get]
+func (h *PointsHandler) Summary(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	summary, err := h.pointsService.Summary(userID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取积分信息失败")
+		return
+	}
+
+	util.SuccessResponse(c, summary)
+}
+
+// Ledger 获取我的积分流水
+// @Summary 我的积分流水
+// @Tags 积分
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param reason query string false "变动原因"
+// @Success 200 {object} model.Response{data=model.PointsLedgerListResponse}
+// @Router /api/points/ledger [get]
+func (h *PointsHandler) Ledger(c *gin.Context) {
+	var req model.PointsLedgerListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	result, err := h.pointsService.ListLedger(userID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取积分流水失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// ListItems 获取上架的积分商品
+// @Summary 积分商城
+// @Tags 积分
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.PointsItem}
+// @Router /api/points/items [get]
+func (h *PointsHandler) ListItems(c *gin.Context) {
+	items, err := h.pointsService.ListItems(true)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取商品列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, items)
+}
+
+// Redeem 使用积分兑换商品
+// @Summary 积分兑换
+// @Tags 积分
+// @Security Bearer
+// @Produce json
+// @Param id path int true "商品ID"
+// @Success 200 {object} model.Response{data=model.PointsRedeemResponse}
+// @Router /api/points/items/{id}/redeem [post]
+func (h *PointsHandler) Redeem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	result, err := h.pointsService.Redeem(userID.(int), id)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "兑换成功", result)
+}
+
+// ListAllItems 获取全部积分商品（含下架）
+// @Summary 获取全部积分商品
+// @Tags 积分
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.PointsItem}
+// @Router /api/points/items/all [get]
+func (h *PointsHandler) ListAllItems(c *gin.Context) {
+	items, err := h.pointsService.ListItems(false)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取商品列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, items)
+}
+
+// CreateItem 创建积分商品
+// @Summary 创建积分商品
+// @Tags 积分
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.PointsItemRequest true "商品信息"
+// @Success 200 {object} model.Response{data=model.PointsItem}
+// @Router /api/points/items [post]
+func (h *PointsHandler) CreateItem(c *gin.Context) {
+	var req model.PointsItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	item, err := h.pointsService.CreateItem(&req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "创建成功", item)
+}
+
+// UpdateItem 更新积分商品
+// @Summary 更新积分商品
+// @Tags 积分
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "商品ID"
+// @Param request body model.PointsItemRequest true "商品信息"
+// @Success 200 {object} model.Response{data=model.PointsItem}
+// @Router /api/points/items/{id} [put]
+func (h *PointsHandler) UpdateItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req model.PointsItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	item, err := h.pointsService.UpdateItem(id, &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "更新成功", item)
+}
+
+// DeleteItem 删除积分商品
+// @Summary 删除积分商品
+// @Tags 积分
+// @Security Bearer
+// @Param id path int true "商品ID"
+// @Success 200 {object} model.Response
+// @Router /api/points/items/{id} [delete]
+func (h *PointsHandler) DeleteItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	if err := h.pointsService.DeleteItem(id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Adjust 调整用户积分（管理员）
+// @Summary 调整用户积分
+// @Tags 积分
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param request body model.PointsAdjustRequest true "调整请求"
+// @Success 200 {object} model.Response{data=model.PointsLedger}
+// @Router /api/users/{id}/points [post]
+func (h *PointsHandler) Adjust(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req model.PointsAdjustRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	operatorName, _ := c.Get("username")
+
+	entry, err := h.pointsService.Adjust(id, &req, operatorID.(int), operatorName.(string),
+		c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "积分调整成功", entry)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReferralHandler struct {
+	referralService *service.ReferralService
+}
+
+func NewReferralHandler() *ReferralHandler {
+	return &ReferralHandler{
+		referralService: service.NewReferralService(),
+	}
+}
+
+// Summary 获取我的推荐码和推荐统计
+// @Summary 我的推荐信息
+// @Tags 推荐
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=model.ReferralSummary}
+// @Router /api/referral [get]
+func (h *ReferralHandler) Summary(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	summary, err := h.referralService.Summary(userID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取推荐信息失败: "+err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, summary)
+}
+
+// ListMine 获取我的推荐记录
+// @Summary 我的推荐记录
+// @Tags 推荐
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param status query string false "状态"
+// @Success 200 {object} model.Response{data=model.ReferralListResponse}
+// @Router /api/referral/invitees [get]
+func (h *ReferralHandler) ListMine(c *gin.Context) {
+	var req model.ReferralListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	result, err := h.referralService.ListMine(userID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取推荐记录失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// Leaderboard 获取推荐排行榜
+// @Summary 推荐排行榜
+// @Tags 推荐
+// @Security Bearer
+// @Produce json
+// @Param limit query int false "数量（默认10）"
+// @Param start_time query string false "开始日期"
+// @Param end_time query string false "结束日期"
+// @Success 200 {object} model.Response{data=[]model.ReferralLeaderboardEntry}
+// @Router /api/referral/leaderboard [get]
+func (h *ReferralHandler) Leaderboard(c *gin.Context) {
+	var req model.ReferralLeaderboardRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	entries, err := h.referralService.Leaderboard(&req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取推荐排行榜失败")
+		return
+	}
+
+	util.SuccessResponse(c, entries)
+}
@@ -33,7 +33,7 @@ func (h *RegisterHandler) Register(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.registerService.Register(&req)
+	resp, err := h.registerService.Register(&req, c.ClientIP())
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -27,7 +27,9 @@ func (h *RoleHandler) Create(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.Create(&req)
+	operatorID, _ := c.Get("user_id")
+
+	role, err := h.roleService.Create(&req, operatorID.(int))
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -67,7 +69,9 @@ func (h *RoleHandler) Update(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.Update(id, &req)
+	operatorID, _ := c.Get("user_id")
+
+	role, err := h.roleService.Update(id, &req, operatorID.(int))
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -117,7 +121,7 @@ func (h *RoleHandler) AssignPermissions(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.AssignPermissions(id, req.PermissionIDs); err != nil {
+	if err := h.roleService.AssignPermissions(id, &req); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
 	}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler() *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: service.NewTwoFactorService(),
+	}
+}
+
+// Status 获取两步验证状态
+// @Summary 两步验证状态
+// @Tags 两步验证
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=model.TwoFactorStatus}
+// @Router /api/auth/2fa [get]
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	status, err := h.twoFactorService.Status(userID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, status)
+}
+
+// Setup 生成TOTP密钥和绑定二维码地址
+// @Summary 绑定验证器
+// @Tags 两步验证
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=model.TwoFactorSetupResponse}
+// @Router /api/auth/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	setup, err := h.twoFactorService.Setup(userID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, setup)
+}
+
+// Enable 确认绑定并开启两步验证
+// @Summary 开启两步验证
+// @Tags 两步验证
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "验证器中的验证码"
+// @Success 200 {object} model.Response{data=model.RecoveryCodesResponse}
+// @Router /api/auth/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	var req model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	codes, err := h.twoFactorService.Enable(userID.(int), req.Code)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "两步验证已开启，请妥善保存恢复码", &model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable 关闭两步验证
+// @Summary 关闭两步验证
+// @Tags 两步验证
+// @Security Bearer
+// @Accept json
+// @Param request body model.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} model.Response
+// @Router /api/auth/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var req model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := h.twoFactorService.Disable(userID.(int), req.Code); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "两步验证已关闭", nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Tags 两步验证
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} model.Response{data=model.RecoveryCodesResponse}
+// @Router /api/auth/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID.(int), req.Code)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "恢复码已重新生成，旧恢复码已作废", &model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Verify 二次验证，通过后当前会话在一段时间内可执行敏感操作
+// @Summary 敏感操作二次验证
+// @Tags 两步验证
+// @Security Bearer
+// @Accept json
+// @Param request body model.TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} model.Response
+// @Router /api/auth/2fa/verify [post]
+func (h *TwoFactorHandler) Verify(c *gin.Context) {
+	var req model.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := h.twoFactorService.Verify(userID.(int), req.Code); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+	if err := h.twoFactorService.MarkStepUp(c.GetString("session_id")); err != nil {
+		util.InternalErrorResponse(c, "记录验证状态失败")
+		return
+	}
+
+	util.SuccessWithMessage(c, "验证成功", nil)
+}
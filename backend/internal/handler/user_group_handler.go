@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"strconv"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserGroupHandler struct {
+	userGroupService *service.UserGroupService
+}
+
+func NewUserGroupHandler() *UserGroupHandler {
+	return &UserGroupHandler{
+		userGroupService: service.NewUserGroupService(),
+	}
+}
+
+// List 获取用户组列表
+// @Summary 获取用户组列表
+// @Tags 用户组
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.UserGroup}
+// @Router /api/user-groups [get]
+func (h *UserGroupHandler) List(c *gin.Context) {
+	operatorID, _ := c.Get("user_id")
+
+	groups, err := h.userGroupService.List(operatorID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取用户组列表失败")
+		return
+	}
+
+	util.SuccessResponse(c, groups)
+}
+
+// Create 创建用户组
+// @Summary 创建用户组
+// @Tags 用户组
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.UserGroupRequest true "用户组"
+// @Success 200 {object} model.Response{data=model.UserGroup}
+// @Router /api/user-groups [post]
+func (h *UserGroupHandler) Create(c *gin.Context) {
+	var req model.UserGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	group, err := h.userGroupService.Create(&req, operatorID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "创建用户组成功", group)
+}
+
+// Update 更新用户组
+// @Summary 更新用户组
+// @Tags 用户组
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "用户组ID"
+// @Param request body model.UserGroupRequest true "用户组"
+// @Success 200 {object} model.Response{data=model.UserGroup}
+// @Router /api/user-groups/{id} [put]
+func (h *UserGroupHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户组ID格式错误")
+		return
+	}
+
+	var req model.UserGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	group, err := h.userGroupService.Update(id, &req, operatorID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "更新用户组成功", group)
+}
+
+// Delete 删除用户组
+// @Summary 删除用户组
+// @Tags 用户组
+// @Security Bearer
+// @Param id path int true "用户组ID"
+// @Success 200 {object} model.Response
+// @Router /api/user-groups/{id} [delete]
+func (h *UserGroupHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户组ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	if err := h.userGroupService.Delete(id, operatorID.(int)); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "删除用户组成功", nil)
+}
+
+// ListGrants 获取用户组内的授权
+// @Summary 获取组内授权
+// @Tags 用户组
+// @Security Bearer
+// @Produce json
+// @Param id path int true "用户组ID"
+// @Success 200 {object} model.Response{data=[]model.UserGroupGrant}
+// @Router /api/user-groups/{id}/grants [get]
+func (h *UserGroupHandler) ListGrants(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户组ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	grants, err := h.userGroupService.ListGrants(id, operatorID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, grants)
+}
+
+// Grant 授予用户在组内的角色
+// @Summary 组内授权
+// @Tags 用户组
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "用户组ID"
+// @Param request body model.UserGroupGrantRequest true "授权"
+// @Success 200 {object} model.Response{data=model.UserGroupGrant}
+// @Router /api/user-groups/{id}/grants [post]
+func (h *UserGroupHandler) Grant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户组ID格式错误")
+		return
+	}
+
+	var req model.UserGroupGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	grant, err := h.userGroupService.Grant(id, &req, operatorID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "授权成功", grant)
+}
+
+// Revoke 撤销用户在组内的授权
+// @Summary 撤销组内授权
+// @Tags 用户组
+// @Security Bearer
+// @Param id path int true "用户组ID"
+// @Param user_id path int true "用户ID"
+// @Success 200 {object} model.Response
+// @Router /api/user-groups/{id}/grants/{user_id} [delete]
+func (h *UserGroupHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户组ID格式错误")
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	if err := h.userGroupService.Revoke(id, userID, operatorID.(int)); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "撤销授权成功", nil)
+}
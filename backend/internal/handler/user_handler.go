@@ -35,7 +35,9 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.Create(&req)
+	operatorID, _ := c.Get("user_id")
+
+	user, err := h.userService.Create(&req, operatorID.(int))
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -58,7 +60,13 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetByID(id)
+	operatorID, _ := c.Get("user_id")
+	if id != operatorID.(int) && !tokenAllows(c, "user:view") {
+		util.ForbiddenResponse(c, "访问令牌未授予该权限")
+		return
+	}
+
+	user, err := h.userService.View(id, operatorID.(int))
 	if err != nil {
 		util.NotFoundResponse(c, "用户不存在")
 		return
@@ -90,7 +98,9 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.Update(id, &req)
+	operatorID, _ := c.Get("user_id")
+
+	user, err := h.userService.Update(id, &req, operatorID.(int))
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -113,7 +123,9 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.Delete(id); err != nil {
+	operatorID, _ := c.Get("user_id")
+
+	if err := h.userService.Delete(id, operatorID.(int)); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
 	}
@@ -130,6 +142,7 @@ func (h *UserHandler) Delete(c *gin.Context) {
 // @Param keyword query string false "关键词"
 // @Param status query int false "状态"
 // @Param role_id query int false "角色ID"
+// @Param group_id query int false "用户组ID"
 // @Success 200 {object} model.Response{data=model.UserListResponse}
 // @Router /api/users [get]
 func (h *UserHandler) List(c *gin.Context) {
@@ -139,7 +152,14 @@ func (h *UserHandler) List(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.userService.List(&req)
+	if !tokenAllows(c, "user:view") {
+		util.ForbiddenResponse(c, "访问令牌未授予该权限")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	resp, err := h.userService.List(&req, operatorID.(int))
 	if err != nil {
 		util.InternalErrorResponse(c, "获取用户列表失败")
 		return
@@ -171,7 +191,12 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.ResetPassword(id, req.Password); err != nil {
+	operatorID, _ := c.Get("user_id")
+
+	// 默认要求用户下次登录时修改密码
+	mustChange := req.MustChange == nil || *req.MustChange
+
+	if err := h.userService.ResetPassword(id, req.Password, mustChange, operatorID.(int)); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
 	}
@@ -198,7 +223,9 @@ func (h *UserHandler) BatchUpdateStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.BatchUpdateStatus(req.UserIDs, req.Status); err != nil {
+	operatorID, _ := c.Get("user_id")
+
+	if err := h.userService.BatchUpdateStatus(req.UserIDs, req.Status, operatorID.(int)); err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
 	}
@@ -224,14 +251,17 @@ func (h *UserHandler) SetVip(c *gin.Context) {
 	}
 
 	var req struct {
-		Days int `json:"days" binding:"required,min=1,max=3650"`
+		Days     int `json:"days" binding:"required,min=1,max=3650"`
+		VipLevel int `json:"vip_level" binding:"omitempty,min=1"` // 不填时沿用当前等级
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		util.BadRequestResponse(c, "请输入有效的天数（1-3650）")
 		return
 	}
 
-	user, err := h.userService.SetVip(id, req.Days)
+	operatorID, _ := c.Get("user_id")
+
+	user, err := h.userService.SetVip(id, req.VipLevel, req.Days, operatorID.(int))
 	if err != nil {
 		util.BadRequestResponse(c, err.Error())
 		return
@@ -242,3 +272,82 @@ func (h *UserHandler) SetVip(c *gin.Context) {
 		"vip_expire_at": user.VipExpireAt,
 	})
 }
+
+// ListSessions 查看用户的登录会话
+// @Summary 查看用户登录会话
+// @Tags 用户管理
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} model.Response{data=[]model.UserSession}
+// @Router /api/users/{id}/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+
+	sessions, err := h.userService.ListSessions(id, operatorID.(int))
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessResponse(c, sessions)
+}
+
+// RevokeSessions 强制用户下线：撤销指定会话，未指定会话时撤销全部
+// @Summary 强制用户下线
+// @Tags 用户管理
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Param session_id path string false "会话ID"
+// @Success 200 {object} model.Response{data=object{count=int}}
+// @Router /api/users/{id}/sessions [delete]
+// @Router /api/users/{id}/sessions/{session_id} [delete]
+func (h *UserHandler) RevokeSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	operatorName, _ := c.Get("username")
+
+	count, err := h.userService.RevokeSessions(id, c.Param("session_id"), operatorID.(int), operatorName.(string),
+		c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "已强制下线", gin.H{"count": count})
+}
+
+// ResetTwoFactor 重置用户的两步验证
+// @Summary 重置用户两步验证
+// @Tags 用户管理
+// @Security Bearer
+// @Param id path int true "用户ID"
+// @Success 200 {object} model.Response
+// @Router /api/users/{id}/2fa [delete]
+func (h *UserHandler) ResetTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	operatorName, _ := c.Get("username")
+
+	if err := h.userService.ResetTwoFactor(id, operatorID.(int), operatorName.(string), c.ClientIP(), c.Request.UserAgent()); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "两步验证已重置", nil)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"embyhub/internal/middleware"
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/testutil"
+	"embyhub/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupUserRoutes 准备用户查看接口：管理员角色拥有 user:view，普通角色没有任何权限
+func setupUserRoutes(t *testing.T) (*gin.Engine, *model.User, *model.User) {
+	t.Helper()
+	testutil.Setup(t)
+	gin.SetMode(gin.TestMode)
+
+	if _, ok := service.Permissions().Get("user:view"); !ok {
+		service.Permissions().Declare(model.PermissionDef{Key: "user:view", Name: "查看用户", Category: "用户管理"})
+	}
+	perm := &model.Permission{PermissionName: "查看用户", PermissionKey: "user:view"}
+	roles := []*model.Role{
+		{RoleID: 1, RoleName: "admin", Rank: 100, Permissions: []*model.Permission{perm}},
+		{RoleID: 3, RoleName: "user"},
+	}
+	for _, role := range roles {
+		if err := database.DB.Create(role).Error; err != nil {
+			t.Fatalf("创建角色失败: %v", err)
+		}
+	}
+
+	users := make([]*model.User, 0, 2)
+	for i, name := range []string{"admin", "alice"} {
+		user := &model.User{Username: name, PasswordHash: "x", Email: name + "@example.com", RoleID: roles[i].RoleID, Status: 1}
+		if err := database.DB.Create(user).Error; err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+		users = append(users, user)
+	}
+
+	r := gin.New()
+	api := r.Group("/api", middleware.AuthMiddleware())
+	h := NewUserHandler()
+	api.GET("/users", h.List)
+	api.GET("/users/:id", h.GetByID)
+	return r, users[0], users[1]
+}
+
+// get 发起请求并返回响应体中的业务状态码
+func get(r *gin.Engine, path, token string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+
+	var resp model.Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Code
+}
+
+func createToken(t *testing.T, user *model.User, scopes ...string) *model.APITokenCreateResponse {
+	t.Helper()
+	result, err := service.NewAPITokenService().Create(user.UserID, user.RoleID,
+		&model.APITokenCreateRequest{Name: "test", Scopes: scopes}, "", "")
+	if err != nil {
+		t.Fatalf("创建访问令牌失败: %v", err)
+	}
+	return result
+}
+
+func TestUserViewSessionAndTokenScopes(t *testing.T) {
+	r, admin, alice := setupUserRoutes(t)
+	adminPath := fmt.Sprintf("/api/users/%d", admin.UserID)
+	alicePath := fmt.Sprintf("/api/users/%d", alice.UserID)
+
+	// 登录会话：本人可查看自己，没有 user:view 权限时不能查看他人
+	session, err := service.NewAuthService().StartSession(alice, &model.SessionMeta{})
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	if code := get(r, alicePath, session.Token); code != http.StatusOK {
+		t.Fatalf("登录会话应能查看本人: %d", code)
+	}
+	if code := get(r, adminPath, session.Token); code == http.StatusOK {
+		t.Fatal("没有 user:view 权限时不应能查看他人")
+	}
+
+	// 仅有 self 范围的令牌：可查看本人，不能借用所有者的 user:view 权限
+	selfOnly := createToken(t, admin, model.APITokenScopeSelf)
+	if code := get(r, adminPath, selfOnly.Token); code != http.StatusOK {
+		t.Fatalf("self 令牌应能查看本人: %d", code)
+	}
+	for _, path := range []string{"/api/users", alicePath} {
+		if code := get(r, path, selfOnly.Token); code != http.StatusForbidden {
+			t.Fatalf("self 令牌访问 %s 应被拒绝: %d", path, code)
+		}
+	}
+
+	// 授予 user:view 的令牌可查看列表和他人
+	viewer := createToken(t, admin, model.APITokenScopeSelf, "user:view")
+	for _, path := range []string{"/api/users", alicePath} {
+		if code := get(r, path, viewer.Token); code != http.StatusOK {
+			t.Fatalf("user:view 令牌访问 %s 应成功: %d", path, code)
+		}
+	}
+
+	// 所有者没有的权限不能授予令牌
+	if _, err := service.NewAPITokenService().Create(alice.UserID, alice.RoleID,
+		&model.APITokenCreateRequest{Name: "test", Scopes: []string{"user:view"}}, "", ""); err == nil {
+		t.Fatal("不应授予所有者没有的权限")
+	}
+
+	// 撤销后令牌立即失效
+	if err := service.NewAPITokenService().Revoke(admin.UserID, viewer.APIToken.ID, "", ""); err != nil {
+		t.Fatalf("撤销令牌失败: %v", err)
+	}
+	if code := get(r, adminPath, viewer.Token); code != http.StatusUnauthorized {
+		t.Fatalf("已撤销的令牌应被拒绝: %d", code)
+	}
+}
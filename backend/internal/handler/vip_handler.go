@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"strconv"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VipHandler struct {
+	vipService         *service.VipService
+	vipTransferService *service.VipTransferService
+	scopeService       *service.ScopeService
+}
+
+func NewVipHandler() *VipHandler {
+	return &VipHandler{
+		vipService:         service.NewVipService(),
+		vipTransferService: service.NewVipTransferService(),
+		scopeService:       service.NewScopeService(),
+	}
+}
+
+// MyHistory 获取当前用户的VIP变动历史
+// @Summary 我的VIP历史
+// @Tags VIP
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param source query string false "来源"
+// @Success 200 {object} model.Response{data=model.VipHistoryResponse}
+// @Router /api/vip/history [get]
+func (h *VipHandler) MyHistory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	h.history(c, userID.(int))
+}
+
+// UserHistory 获取指定用户的VIP变动历史
+// @Summary 用户VIP历史
+// @Tags 用户管理
+// @Security Bearer
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param source query string false "来源"
+// @Success 200 {object} model.Response{data=model.VipHistoryResponse}
+// @Router /api/users/{id}/vip-history [get]
+func (h *VipHandler) UserHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	if _, _, err := h.scopeService.AuthorizeUser(operatorID.(int), "user:view", id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+	h.history(c, id)
+}
+
+// history 绑定分页参数并返回VIP历史
+func (h *VipHandler) history(c *gin.Context, userID int) {
+	var req model.VipHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	result, err := h.vipService.History(userID, &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取VIP历史失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
+
+// Freeze 冻结当前用户的VIP
+// @Summary 冻结VIP
+// @Tags VIP
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.VipFreezeRequest true "冻结请求"
+// @Success 200 {object} model.Response{data=model.VipFreeze}
+// @Router /api/vip/freeze [post]
+func (h *VipHandler) Freeze(c *gin.Context) {
+	var req model.VipFreezeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	freeze, err := h.vipService.Freeze(userID.(int), &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "冻结成功", freeze)
+}
+
+// Unfreeze 解冻当前用户的VIP
+// @Summary 解冻VIP
+// @Tags VIP
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response
+// @Router /api/vip/unfreeze [post]
+func (h *VipHandler) Unfreeze(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	id := userID.(int)
+
+	user, err := h.vipService.Unfreeze(id, model.UnfreezeByUser, &id)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "解冻成功", map[string]interface{}{
+		"vip_level":     user.VipLevel,
+		"vip_expire_at": user.VipExpireAt,
+	})
+}
+
+// MyFreezes 获取当前用户的冻结记录
+// @Summary 我的冻结记录
+// @Tags VIP
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.VipFreeze}
+// @Router /api/vip/freezes [get]
+func (h *VipHandler) MyFreezes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	freezes, err := h.vipService.ListFreezes(userID.(int))
+	if err != nil {
+		util.InternalErrorResponse(c, "获取冻结记录失败")
+		return
+	}
+
+	util.SuccessResponse(c, freezes)
+}
+
+// UnfreezeUser 管理员解冻指定用户的VIP
+// @Summary 解冻用户VIP
+// @Tags 用户管理
+// @Security Bearer
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {object} model.Response
+// @Router /api/users/{id}/vip/unfreeze [post]
+func (h *VipHandler) UnfreezeUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		util.BadRequestResponse(c, "用户ID格式错误")
+		return
+	}
+
+	operatorID, _ := c.Get("user_id")
+	opID := operatorID.(int)
+
+	if _, _, err := h.scopeService.AuthorizeUser(opID, "user:edit", id); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	user, err := h.vipService.Unfreeze(id, model.UnfreezeByAdmin, &opID)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "解冻成功", map[string]interface{}{
+		"vip_level":     user.VipLevel,
+		"vip_expire_at": user.VipExpireAt,
+	})
+}
+
+// SendTransferCode 发送VIP转赠确认验证码到当前用户邮箱
+// @Summary 发送转赠验证码
+// @Tags VIP
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response
+// @Router /api/vip/transfer/code [post]
+func (h *VipHandler) SendTransferCode(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.vipTransferService.SendCode(userID.(int)); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "验证码已发送", nil)
+}
+
+// Transfer 将VIP天数转赠给其他用户
+// @Summary 转赠VIP
+// @Tags VIP
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.VipTransferRequest true "转赠请求"
+// @Success 200 {object} model.Response{data=model.VipTransfer}
+// @Router /api/vip/transfer [post]
+func (h *VipHandler) Transfer(c *gin.Context) {
+	var req model.VipTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	transfer, err := h.vipTransferService.Transfer(userID.(int), &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "转赠成功", transfer)
+}
+
+// MyTransfers 获取当前用户的转赠记录
+// @Summary 我的转赠记录
+// @Tags VIP
+// @Security Bearer
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.Response{data=model.VipTransferListResponse}
+// @Router /api/vip/transfers [get]
+func (h *VipHandler) MyTransfers(c *gin.Context) {
+	var req model.VipTransferListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	result, err := h.vipTransferService.List(userID.(int), &req)
+	if err != nil {
+		util.InternalErrorResponse(c, "获取转赠记录失败")
+		return
+	}
+
+	util.SuccessResponse(c, result)
+}
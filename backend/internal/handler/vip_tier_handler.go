@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"strconv"
+
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VipTierHandler struct {
+	vipTierService *service.VipTierService
+}
+
+func NewVipTierHandler() *VipTierHandler {
+	return &VipTierHandler{
+		vipTierService: service.NewVipTierService(),
+	}
+}
+
+// List 获取VIP等级列表
+// @Summary 获取VIP等级列表
+// @Tags VIP等级
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} model.Response{data=[]model.VipTier}
+// @Router /api/vip-tiers [get]
+func (h *VipTierHandler) List(c *gin.Context) {
+	tiers, err := h.vipTierService.List()
+	if err != nil {
+		util.InternalErrorResponse(c, "获取VIP等级失败")
+		return
+	}
+
+	util.SuccessResponse(c, tiers)
+}
+
+// Create 创建VIP等级
+// @Summary 创建VIP等级
+// @Tags VIP等级
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body model.VipTierCreateRequest true "等级信息"
+// @Success 200 {object} model.Response{data=model.VipTier}
+// @Router /api/vip-tiers [post]
+func (h *VipTierHandler) Create(c *gin.Context) {
+	var req model.VipTierCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	tier, err := h.vipTierService.Create(&req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "创建成功", tier)
+}
+
+// Update 更新VIP等级
+// @Summary 更新VIP等级
+// @Tags VIP等级
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param level path int true "等级"
+// @Param request body model.VipTierUpdateRequest true "等级信息"
+// @Success 200 {object} model.Response{data=model.VipTier}
+// @Router /api/vip-tiers/{level} [put]
+func (h *VipTierHandler) Update(c *gin.Context) {
+	level, err := strconv.Atoi(c.Param("level"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的等级")
+		return
+	}
+
+	var req model.VipTierUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	tier, err := h.vipTierService.Update(level, &req)
+	if err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "更新成功", tier)
+}
+
+// Delete 删除VIP等级
+// @Summary 删除VIP等级
+// @Tags VIP等级
+// @Security Bearer
+// @Param level path int true "等级"
+// @Success 200 {object} model.Response
+// @Router /api/vip-tiers/{level} [delete]
+func (h *VipTierHandler) Delete(c *gin.Context) {
+	level, err := strconv.Atoi(c.Param("level"))
+	if err != nil {
+		util.BadRequestResponse(c, "无效的等级")
+		return
+	}
+
+	if err := h.vipTierService.Delete(level); err != nil {
+		util.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	util.SuccessWithMessage(c, "删除成功", nil)
+}
@@ -1,16 +1,27 @@
 package middleware
 
 import (
+	"embyhub/internal/model"
 	"embyhub/internal/service"
 	"embyhub/internal/util"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware JWT认证中间件
+// passwordChangeAllowed 须修改密码的用户在修改前仍可访问的接口
+var passwordChangeAllowed = map[string]bool{
+	"/api/auth/password": true,
+	"/api/auth/current":  true,
+	"/api/auth/logout":   true,
+}
+
+// AuthMiddleware JWT认证中间件，同时接受个人访问令牌
 func AuthMiddleware() gin.HandlerFunc {
 	authService := service.NewAuthService()
+	apiTokenService := service.NewAPITokenService()
 
 	return func(c *gin.Context) {
 		// 获取Authorization头
@@ -31,19 +42,90 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		token := parts[1]
 
+		if strings.HasPrefix(token, model.APITokenPrefix) {
+			authenticateAPIToken(c, apiTokenService, token)
+			return
+		}
+
 		// 验证Token
 		claims, err := authService.ValidateToken(token)
+		if errors.Is(err, util.ErrTokenExpired) {
+			util.TokenExpiredResponse(c, "Token已过期")
+			c.Abort()
+			return
+		}
 		if err != nil {
 			util.UnauthorizedResponse(c, "Token无效或已过期")
 			c.Abort()
 			return
 		}
 
+		// 管理员重置密码或密码过期后，须先修改密码
+		if claims.MustChangePassword && !passwordChangeAllowed[c.FullPath()] {
+			util.PasswordChangeRequiredResponse(c, "请先修改密码")
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存入上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role_id", claims.RoleID)
+		c.Set("session_id", claims.SessionID)
+
+		authService.TouchSession(claims.SessionID, c.ClientIP())
+
+		c.Next()
+	}
+}
+
+// authenticateAPIToken 使用个人访问令牌认证
+// 需要权限的接口由 PermissionMiddleware 校验令牌范围，无需权限的个人接口要求令牌包含 self 范围，
+// 其中由服务按管理范围校验权限的接口（如用户列表）还需令牌包含对应权限，由处理器校验；
+// 每次使用都以令牌所有者身份记录审计日志
+func authenticateAPIToken(c *gin.Context, apiTokenService *service.APITokenService, raw string) {
+	token, user, err := apiTokenService.Authenticate(raw, c.ClientIP())
+	if err != nil {
+		util.UnauthorizedResponse(c, err.Error())
+		c.Abort()
+		return
+	}
+
+	if key, _ := service.Permissions().RouteKey(c.Request.Method, c.FullPath()); key == "" && !token.HasScope(model.APITokenScopeSelf) {
+		util.ForbiddenResponse(c, "访问令牌未授权访问该接口")
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", user.UserID)
+	c.Set("username", user.Username)
+	c.Set("role_id", user.RoleID)
+	c.Set("session_id", "")
+	c.Set("api_token", token)
+
+	c.Next()
 
+	status := "success"
+	if c.Writer.Status() >= 400 {
+		status = "failed"
+	}
+	service.Audit(&user.UserID, user.Username, model.ActionUseToken, model.TargetAPIToken, fmt.Sprint(token.ID),
+		map[string]interface{}{
+			"token_name": token.Name,
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+		}, c.ClientIP(), c.Request.UserAgent(), status)
+}
+
+// SessionOnlyMiddleware 账号安全相关接口（修改密码、会话、两步验证、访问令牌管理）只允许登录会话访问，拒绝个人访问令牌
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_token"); ok {
+			util.ForbiddenResponse(c, "该接口不支持使用访问令牌")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -1,20 +1,19 @@
 package middleware
 
 import (
-	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/service"
 	"embyhub/internal/util"
-	"fmt"
-	"time"
-
-	"embyhub/pkg/redis"
 
 	"github.com/gin-gonic/gin"
 )
 
 // PermissionMiddleware 权限校验中间件
+// 角色权限集合通过 service.PermCache 读取（进程内LRU + Redis），角色权限变更时自动失效；
+// 全局角色未授予时再检查用户组内授予的角色，具体作用范围由各业务服务按用户组校验；
+// 使用个人访问令牌时还要求令牌范围包含该权限
 func PermissionMiddleware(requiredPermission string) gin.HandlerFunc {
-	permissionDAO := dao.NewPermissionDAO()
-
+	scopeService := service.NewScopeService()
 	return func(c *gin.Context) {
 		// 获取用户角色ID
 		roleID, exists := c.Get("role_id")
@@ -24,39 +23,26 @@ func PermissionMiddleware(requiredPermission string) gin.HandlerFunc {
 			return
 		}
 
-		roleIDInt := roleID.(int)
-
-		// 先从缓存获取权限列表
-		cacheKey := fmt.Sprintf("emby_ums:role:perms:%d", roleIDInt)
-		var permissions []string
-
-		// 尝试从Redis获取
-		// 简化处理：直接从数据库查询
-		permissions, err := permissionDAO.GetPermissionKeysByRoleID(roleIDInt)
+		userID, _ := c.Get("user_id")
+		hasPermission, err := scopeService.HasPermission(userID.(int), roleID.(int), requiredPermission)
 		if err != nil {
 			util.InternalErrorResponse(c, "获取权限信息失败")
 			c.Abort()
 			return
 		}
 
-		// 缓存权限列表（这里简化处理）
-		redis.Set(cacheKey, "cached", 2*time.Hour)
-
-		// 检查是否有所需权限
-		hasPermission := false
-		for _, perm := range permissions {
-			if perm == requiredPermission {
-				hasPermission = true
-				break
-			}
-		}
-
 		if !hasPermission {
 			util.ForbiddenResponse(c, "权限不足")
 			c.Abort()
 			return
 		}
 
+		if token, ok := c.Get("api_token"); ok && !token.(*model.APIToken).HasScope(requiredPermission) {
+			util.ForbiddenResponse(c, "访问令牌未授予该权限")
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
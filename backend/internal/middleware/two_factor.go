@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"embyhub/internal/service"
+	"embyhub/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StepUpMiddleware 敏感操作二次验证中间件
+// 已开启两步验证的用户需在最近一段时间内通过 /api/auth/2fa/verify 验证，否则返回 CodeStepUpRequired
+func StepUpMiddleware() gin.HandlerFunc {
+	twoFactorService := service.NewTwoFactorService()
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		ok, err := twoFactorService.CheckStepUp(userID.(int), c.GetString("session_id"))
+		if err != nil {
+			util.InternalErrorResponse(c, "获取两步验证状态失败")
+			c.Abort()
+			return
+		}
+
+		if !ok {
+			util.StepUpRequiredResponse(c, "敏感操作需要进行两步验证")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import "time"
+
+// AgentQuota 代理商卡密额度（单位：VIP天数）
+type AgentQuota struct {
+	UserID        int       `gorm:"column:user_id;primaryKey" json:"user_id"`
+	Balance       int       `gorm:"column:balance;not null;default:0" json:"balance"`               // 当前余额
+	TotalTopUp    int       `gorm:"column:total_top_up;not null;default:0" json:"total_top_up"`     // 累计充值
+	TotalConsumed int       `gorm:"column:total_consumed;not null;default:0" json:"total_consumed"` // 累计消耗（扣除退还）
+	UpdatedAt     time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (AgentQuota) TableName() string {
+	return "agent_quotas"
+}
+
+// AgentQuotaLedger 代理商额度变动流水（只增不改）
+type AgentQuotaLedger struct {
+	ID           int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	AgentID      int       `gorm:"column:agent_id;not null;index" json:"agent_id"`
+	Delta        int       `gorm:"column:delta;not null" json:"delta"`
+	BalanceAfter int       `gorm:"column:balance_after;not null" json:"balance_after"`
+	Reason       string    `gorm:"column:reason;type:varchar(20);not null" json:"reason"`
+	BatchID      *int      `gorm:"column:batch_id" json:"batch_id,omitempty"`
+	CardKeyID    *int      `gorm:"column:card_key_id" json:"card_key_id,omitempty"`
+	OperatorID   *int      `gorm:"column:operator_id" json:"operator_id,omitempty"`
+	Remark       string    `gorm:"column:remark;type:varchar(200)" json:"remark"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (AgentQuotaLedger) TableName() string {
+	return "agent_quota_ledger"
+}
+
+// 额度变动原因
+const (
+	QuotaReasonTopUp    = "topup"    // 管理员充值
+	QuotaReasonAdjust   = "adjust"   // 管理员扣减/调整
+	QuotaReasonGenerate = "generate" // 生成卡密消耗
+	QuotaReasonRefund   = "refund"   // 删除未使用卡密退还
+)
+
+// AgentQuotaAdjustRequest 调整代理商额度请求
+type AgentQuotaAdjustRequest struct {
+	Delta  int    `json:"delta" binding:"required,ne=0"` // 正数充值，负数扣减
+	Remark string `json:"remark" binding:"omitempty,max=200"`
+}
+
+// AgentLedgerListRequest 额度流水查询请求
+type AgentLedgerListRequest struct {
+	Page     int    `form:"page" binding:"omitempty,gt=0"`
+	PageSize int    `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Reason   string `form:"reason" binding:"omitempty,oneof=topup adjust generate refund"`
+}
+
+// AgentLedgerListResponse 额度流水列表响应
+type AgentLedgerListResponse struct {
+	Total int                 `json:"total"`
+	List  []*AgentQuotaLedger `json:"list"`
+}
+
+// AgentListResponse 代理商列表响应
+type AgentListResponse struct {
+	Total int           `json:"total"`
+	List  []*AgentQuota `json:"list"`
+}
+
+// AgentSettlementRequest 结算报表请求
+type AgentSettlementRequest struct {
+	StartTime time.Time `form:"start_time" binding:"required" time_format:"2006-01-02"`
+	EndTime   time.Time `form:"end_time" binding:"required" time_format:"2006-01-02"`
+}
+
+// AgentSettlement 代理商结算报表
+type AgentSettlement struct {
+	AgentID        int       `json:"agent_id"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	CardsGenerated int64     `json:"cards_generated"` // 期间生成卡密数
+	DaysGenerated  int64     `json:"days_generated"`  // 期间生成卡密总天数
+	CardsRedeemed  int64     `json:"cards_redeemed"`  // 期间被兑换卡密数
+	DaysRedeemed   int64     `json:"days_redeemed"`   // 期间被兑换总天数
+	CardsRevoked   int64     `json:"cards_revoked"`   // 期间被撤销兑换数
+	DaysRevoked    int64     `json:"days_revoked"`    // 期间被撤销总天数
+	QuotaTopUp     int64     `json:"quota_top_up"`    // 期间充值额度
+	QuotaConsumed  int64     `json:"quota_consumed"`  // 期间消耗额度
+	QuotaRefunded  int64     `json:"quota_refunded"`  // 期间退还额度
+	BalanceEnd     int       `json:"balance_end"`     // 当前余额
+}
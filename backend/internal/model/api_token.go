@@ -0,0 +1,79 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// APITokenPrefix 个人访问令牌前缀，认证中间件据此区分访问令牌与JWT
+const APITokenPrefix = "ehp_"
+
+// APITokenScopeSelf 访问无需权限的个人接口（本人信息、VIP、积分等）的令牌范围
+const APITokenScopeSelf = "self"
+
+// APIToken 个人访问令牌（只保存摘要），供机器人和脚本调用接口
+// 令牌范围是所有者权限的子集，使用时同时校验所有者当前权限和令牌范围
+type APIToken struct {
+	ID          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID      int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Name        string     `gorm:"column:name;type:varchar(50);not null" json:"name"`
+	TokenPrefix string     `gorm:"column:token_prefix;type:varchar(16);not null" json:"token_prefix"` // 令牌开头几位，便于识别
+	TokenHash   string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes      string     `gorm:"column:scopes;type:text;not null" json:"scopes"`  // 权限键（逗号分隔）
+	AllowedIPs  string     `gorm:"column:allowed_ips;type:text" json:"allowed_ips"` // 允许的IP或网段（逗号分隔），空=不限
+	ExpiresAt   *time.Time `gorm:"column:expires_at" json:"expires_at"`             // 空=永不过期
+	LastUsedAt  *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP  string     `gorm:"column:last_used_ip;type:varchar(50)" json:"last_used_ip"`
+	RevokedAt   *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ScopeList 返回令牌范围列表
+func (t *APIToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+// AllowedIPList 返回允许的IP或网段列表，空表示不限
+func (t *APIToken) AllowedIPList() []string {
+	return splitList(t.AllowedIPs)
+}
+
+// HasScope 令牌是否包含指定范围
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList 拆分逗号分隔的列表
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// APITokenCreateRequest 创建个人访问令牌请求
+type APITokenCreateRequest struct {
+	Name          string   `json:"name" binding:"required,max=50"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	AllowedIPs    []string `json:"allowed_ips"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=0,max=3650"` // 0=永不过期
+}
+
+// APITokenCreateResponse 创建个人访问令牌响应，令牌原文只返回这一次
+type APITokenCreateResponse struct {
+	Token    string    `json:"token"`
+	APIToken *APIToken `json:"api_token"`
+}
@@ -23,31 +23,47 @@ func (AuditLog) TableName() string {
 
 // 操作类型常量
 const (
-	ActionLogin       = "login"
-	ActionLogout      = "logout"
-	ActionLoginFailed = "login_failed"
-	ActionCreateUser  = "create_user"
-	ActionUpdateUser  = "update_user"
-	ActionDeleteUser  = "delete_user"
-	ActionResetPwd    = "reset_password"
-	ActionSetVip      = "set_vip"
-	ActionCreateCard  = "create_card_key"
-	ActionDeleteCard  = "delete_card_key"
-	ActionDisableCard = "disable_card_key"
-	ActionEnableCard  = "enable_card_key"
-	ActionUseVipCard  = "use_vip_card"
-	ActionCreateRole  = "create_role"
-	ActionUpdateRole  = "update_role"
-	ActionDeleteRole  = "delete_role"
-	ActionAssignPerms = "assign_permissions"
+	ActionLogin        = "login"
+	ActionLogout       = "logout"
+	ActionLoginFailed  = "login_failed"
+	ActionCreateUser   = "create_user"
+	ActionUpdateUser   = "update_user"
+	ActionDeleteUser   = "delete_user"
+	ActionResetPwd     = "reset_password"
+	ActionSetVip       = "set_vip"
+	ActionCreateCard   = "create_card_key"
+	ActionDeleteCard   = "delete_card_key"
+	ActionDisableCard  = "disable_card_key"
+	ActionEnableCard   = "enable_card_key"
+	ActionUseVipCard   = "use_vip_card"
+	ActionRevokeCard   = "revoke_card_key"
+	ActionAgentQuota   = "adjust_agent_quota"
+	ActionRefundOrder  = "refund_order"
+	ActionVipTransfer  = "vip_transfer"
+	ActionAdjustPoint  = "adjust_points"
+	ActionCreateRole   = "create_role"
+	ActionUpdateRole   = "update_role"
+	ActionDeleteRole   = "delete_role"
+	ActionAssignPerms  = "assign_permissions"
+	ActionRevokeSess   = "revoke_session"
+	ActionTokenReuse   = "refresh_token_reuse"
+	ActionReset2FA     = "reset_two_factor"
+	ActionLinkIdentity = "link_identity"
+	ActionNotMe        = "login_alert_not_me"
+	ActionCreateToken  = "create_api_token"
+	ActionRevokeToken  = "revoke_api_token"
+	ActionUseToken     = "api_token_use"
 )
 
 // 目标类型常量
 const (
-	TargetUser    = "user"
-	TargetRole    = "role"
-	TargetCardKey = "card_key"
-	TargetSystem  = "system"
+	TargetUser     = "user"
+	TargetRole     = "role"
+	TargetCardKey  = "card_key"
+	TargetAgent    = "agent"
+	TargetOrder    = "order"
+	TargetSystem   = "system"
+	TargetAPIToken = "api_token"
 )
 
 // AuditLogQuery 审计日志查询请求
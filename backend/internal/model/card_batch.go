@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// CardBatch 卡密批次（记录生成模板）
+type CardBatch struct {
+	ID         int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CardType   int       `gorm:"column:card_type;type:smallint;not null" json:"card_type"`
+	Duration   int       `gorm:"column:duration;not null" json:"duration"`
+	VipLevel   int       `gorm:"column:vip_level;not null;default:1" json:"vip_level"`
+	Count      int       `gorm:"column:count;not null" json:"count"`
+	Prefix     string    `gorm:"column:prefix;type:varchar(8)" json:"prefix"`
+	GroupSize  int       `gorm:"column:group_size;not null;default:0" json:"group_size"`
+	CodeLength int       `gorm:"column:code_length;not null" json:"code_length"`
+	Charset    string    `gorm:"column:charset;type:varchar(64);not null" json:"charset"`
+	Checksum   string    `gorm:"column:checksum;type:varchar(10);not null;default:none" json:"checksum"`
+	Remark     string    `gorm:"column:remark;type:varchar(200)" json:"remark"`
+	CreatedBy  int       `gorm:"column:created_by;not null" json:"created_by"`
+	GroupID    *int      `gorm:"column:group_id;index" json:"group_id,omitempty"` // 所属用户组，空表示全局批次
+	CreatedAt  time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// 关联
+	CreatedByUser *User `gorm:"foreignKey:CreatedBy" json:"created_by_user,omitempty"`
+}
+
+// TableName 指定表名
+func (CardBatch) TableName() string {
+	return "card_batches"
+}
+
+// CardBatchListRequest 卡密批次列表请求
+type CardBatchListRequest struct {
+	Page     int `form:"page" binding:"omitempty,gt=0"`
+	PageSize int `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+
+	CreatedBy     int   `form:"-"` // 按创建者限定范围
+	ScopeGroupIDs []int `form:"-"` // 操作者可管理的用户组，nil 表示不限
+}
+
+// CardBatchListResponse 卡密批次列表响应
+type CardBatchListResponse struct {
+	Total int          `json:"total"`
+	List  []*CardBatch `json:"list"`
+}
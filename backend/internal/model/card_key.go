@@ -5,15 +5,19 @@ import "time"
 // CardKey 卡密模型
 type CardKey struct {
 	ID        int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	CardCode  string     `gorm:"column:card_code;type:varchar(32);not null;uniqueIndex" json:"card_code"`
+	CardCode  string     `gorm:"column:card_code;type:varchar(64);not null;uniqueIndex" json:"card_code"`
+	CodeKey   string     `gorm:"column:code_key;type:varchar(64);not null;uniqueIndex" json:"-"`     // 规范化卡密码（大写、去除分隔符），用于兑换查找
+	BatchID   *int       `gorm:"column:batch_id;index" json:"batch_id,omitempty"`                    // 所属批次
 	CardType  int        `gorm:"column:card_type;type:smallint;not null;default:1" json:"card_type"` // 1=注册码 2=VIP升级码
 	Duration  int        `gorm:"column:duration;not null;default:30" json:"duration"`                // 有效期（天）
+	VipLevel  int        `gorm:"column:vip_level;not null;default:1" json:"vip_level"`               // 兑换的VIP等级
 	Status    int        `gorm:"column:status;type:smallint;not null;default:1" json:"status"`       // 0=已禁用 1=未使用 2=已使用
 	UsedBy    *int       `gorm:"column:used_by" json:"used_by,omitempty"`                            // 使用者用户ID
 	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at,omitempty"`                            // 使用时间
 	ExpireAt  *time.Time `gorm:"column:expire_at" json:"expire_at,omitempty"`                        // 过期时间
 	Remark    string     `gorm:"column:remark;type:varchar(200)" json:"remark"`                      // 备注
 	CreatedBy int        `gorm:"column:created_by;not null" json:"created_by"`                       // 创建者
+	GroupID   *int       `gorm:"column:group_id;index" json:"group_id,omitempty"`                    // 所属用户组，空表示全局卡密
 	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 
 	// 关联
@@ -27,20 +31,39 @@ func (CardKey) TableName() string {
 }
 
 // CardKeyCreateRequest 创建卡密请求
+// 未指定任何模板字段时沿用旧格式（TL|24位字符）
 type CardKeyCreateRequest struct {
-	Count    int    `json:"count" binding:"required,min=1,max=100"`    // 生成数量
+	Count    int    `json:"count" binding:"required,min=1,max=50000"`  // 生成数量
 	CardType int    `json:"card_type" binding:"required,oneof=1"`      // 卡密类型（1=VIP会员码）
 	Duration int    `json:"duration" binding:"required,min=1,max=365"` // 有效期（天）
+	VipLevel int    `json:"vip_level" binding:"omitempty,min=1"`       // 兑换的VIP等级（默认1）
 	Remark   string `json:"remark" binding:"omitempty,max=200"`        // 备注
+	GroupID  *int   `json:"group_id" binding:"omitempty,gt=0"`         // 所属用户组（组管理员必填）
+
+	// 卡密码模板
+	Prefix     string `json:"prefix" binding:"omitempty,max=8"`                   // 前缀
+	GroupSize  int    `json:"group_size" binding:"omitempty,min=0,max=32"`        // 分组长度
+	CodeLength int    `json:"code_length" binding:"omitempty,min=6,max=32"`       // 随机字符长度
+	Charset    string `json:"charset" binding:"omitempty,max=64"`                 // 字符集
+	Checksum   string `json:"checksum" binding:"omitempty,oneof=none luhn crc32"` // 校验位算法
+}
+
+// HasTemplate 是否指定了卡密码模板
+func (r *CardKeyCreateRequest) HasTemplate() bool {
+	return r.Prefix != "" || r.GroupSize > 0 || r.CodeLength > 0 || r.Charset != "" || r.Checksum != ""
 }
 
 // CardKeyListRequest 卡密列表请求
 type CardKeyListRequest struct {
 	Page     int    `form:"page" binding:"omitempty,gt=0"`
 	PageSize int    `form:"page_size" binding:"omitempty,gt=0,lte=100"`
-	Status   *int   `form:"status" binding:"omitempty,oneof=0 1 2"`
+	Status   *int   `form:"status" binding:"omitempty,oneof=0 1 2 3"`
 	CardType *int   `form:"card_type" binding:"omitempty,oneof=1 2"`
+	BatchID  int    `form:"batch_id" binding:"omitempty,gt=0"`
 	Keyword  string `form:"keyword"`
+
+	CreatedBy     int   `form:"-"` // 按创建者限定范围（代理商仅能查看自己的卡密）
+	ScopeGroupIDs []int `form:"-"` // 操作者可管理的用户组，nil 表示不限
 }
 
 // CardKeyListResponse 卡密列表响应
@@ -73,6 +96,8 @@ func CardStatusText(status int) string {
 		return "未使用"
 	case 2:
 		return "已使用"
+	case 3:
+		return "已撤销"
 	default:
 		return "未知"
 	}
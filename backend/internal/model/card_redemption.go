@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// CardRedemption 卡密兑换记录（记录每次兑换实际发放的VIP天数，用于撤销回滚）
+type CardRedemption struct {
+	ID             int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CardKeyID      int        `gorm:"column:card_key_id;not null;index" json:"card_key_id"`
+	UserID         int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	DaysGranted    int        `gorm:"column:days_granted;not null" json:"days_granted"`                 // 实际增加的天数（跨等级折算后，四舍五入）
+	GrantedSeconds int64      `gorm:"column:granted_seconds;not null;default:0" json:"granted_seconds"` // 实际增加的时长（秒），撤销时按此回滚
+	ExpireBefore   *time.Time `gorm:"column:expire_before" json:"expire_before,omitempty"`              // 兑换前VIP到期时间
+	ExpireAfter    time.Time  `gorm:"column:expire_after;not null" json:"expire_after"`                 // 兑换后VIP到期时间
+	LevelBefore    int        `gorm:"column:level_before;not null;default:0" json:"level_before"`       // 兑换前VIP等级
+	LevelAfter     int        `gorm:"column:level_after;not null;default:1" json:"level_after"`         // 兑换后VIP等级
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	RevokedBy      *int       `gorm:"column:revoked_by" json:"revoked_by,omitempty"`
+	RevokeReason   string     `gorm:"column:revoke_reason;type:varchar(200)" json:"revoke_reason,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// 关联
+	User    *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CardKey *CardKey `gorm:"foreignKey:CardKeyID" json:"card_key,omitempty"`
+}
+
+// CardRedemptionListRequest 兑换记录查询请求
+type CardRedemptionListRequest struct {
+	Page     int `form:"page" binding:"omitempty,gt=0"`
+	PageSize int `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+
+	CreatedBy int `form:"-"` // 按卡密创建者限定范围
+}
+
+// CardRedemptionListResponse 兑换记录列表响应
+type CardRedemptionListResponse struct {
+	Total int               `json:"total"`
+	List  []*CardRedemption `json:"list"`
+}
+
+// TableName 指定表名
+func (CardRedemption) TableName() string {
+	return "card_redemptions"
+}
+
+// CardRevokeRequest 撤销兑换请求
+type CardRevokeRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=200"`
+}
+
+// CardRevokeResponse 撤销兑换结果
+type CardRevokeResponse struct {
+	UserID       int        `json:"user_id"`
+	DaysRevoked  int        `json:"days_revoked"`
+	ExpireBefore *time.Time `json:"expire_before,omitempty"`
+	ExpireAfter  *time.Time `json:"expire_after,omitempty"`
+	VipLevel     int        `json:"vip_level"`
+}
@@ -1,5 +1,16 @@
 package model
 
+// 业务响应码（HTTP 状态码之外的约定）
+const (
+	// CodeTokenExpired 访问令牌已过期：客户端应使用刷新令牌调用 /api/auth/refresh 后重试原请求；
+	// 其余 401 表示登录状态已失效，需要重新登录
+	CodeTokenExpired = 4011
+	// CodeStepUpRequired 敏感操作需要二次验证：客户端应调用 /api/auth/2fa/verify 后重试原请求
+	CodeStepUpRequired = 4031
+	// CodePasswordChangeRequired 须先修改密码（管理员重置密码或密码已过期）：客户端应调用 PUT /api/auth/password 后重试原请求
+	CodePasswordChangeRequired = 4032
+)
+
 // Response 统一响应结构
 type Response struct {
 	Code    int         `json:"code"`
@@ -10,13 +21,22 @@ type Response struct {
 // LoginRequest 登录请求
 type LoginRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6,max=50"`
+	Password string `json:"password" binding:"required,max=50"`
+	Device   string `json:"device" binding:"omitempty,max=100"` // 设备名称，不填时由UA推断
+
+	DeviceFingerprint string `json:"device_fingerprint" binding:"omitempty,max=64"` // 客户端持久化的设备标识
 }
 
 // LoginResponse 登录响应
+// 需要两步验证时只返回 challenge_token，客户端提交验证码（POST /api/auth/login/2fa）后才签发令牌
 type LoginResponse struct {
-	Token    string `json:"token"`
-	UserInfo *User  `json:"user_info"`
+	*TokenResponse
+	UserInfo *User `json:"user_info,omitempty"`
+
+	TwoFactorRequired bool     `json:"two_factor_required,omitempty"`
+	TwoFactorSetup    bool     `json:"two_factor_setup,omitempty"` // 角色要求两步验证但尚未开启，需先绑定
+	ChallengeToken    string   `json:"challenge_token,omitempty"`
+	RecoveryCodes     []string `json:"recovery_codes,omitempty"` // 登录时完成绑定返回的恢复码
 }
 
 // StatisticsResponse 统计数据响应
@@ -42,8 +62,11 @@ type GrowthTrendItem struct {
 type VipStatistics struct {
 	TotalVip     int64 `json:"total_vip"`      // 有效VIP总数
 	ExpiredVip   int64 `json:"expired_vip"`    // 已过期VIP
+	FrozenVip    int64 `json:"frozen_vip"`     // 冻结中VIP
 	Expiring3Day int64 `json:"expiring_3_day"` // 3天内到期
 	Expiring7Day int64 `json:"expiring_7_day"` // 7天内到期
+
+	ByLevel map[int]int64 `json:"by_level"` // 各等级有效VIP数
 }
 
 // CardKeyStatistics 卡密统计
@@ -21,6 +21,7 @@ func (EmailCode) TableName() string {
 const (
 	CodeTypeRegister      = "register"
 	CodeTypeResetPassword = "reset_password"
+	CodeTypeVipTransfer   = "vip_transfer" // VIP转赠确认
 )
 
 // SendCodeRequest 发送验证码请求
@@ -40,5 +41,18 @@ type RegisterWithEmailRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Code     string `json:"code" binding:"required,len=6"`
 	Username string `json:"username" binding:"required,min=3,max=50"`
-	Password string `json:"password" binding:"required,min=6,max=50"`
+	Password string `json:"password" binding:"required,max=50"`
+}
+
+// MagicLinkRequest 请求邮件登录链接
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkLoginRequest 使用邮件登录链接登录
+type MagicLinkLoginRequest struct {
+	Token  string `json:"token" binding:"required"`
+	Device string `json:"device"`
+
+	DeviceFingerprint string `json:"device_fingerprint" binding:"omitempty,max=64"`
 }
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// 登录方式
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodOIDC      = "oidc"
+	LoginMethodMagicLink = "magic_link"
+)
+
+// LoginHistory 登录历史，每次成功登录（创建会话）记录一条
+type LoginHistory struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID     int       `gorm:"column:user_id;not null;index" json:"user_id"`
+	SessionID  string    `gorm:"column:session_id;type:varchar(32)" json:"session_id"`
+	Method     string    `gorm:"column:method;type:varchar(20);not null" json:"method"`
+	IP         string    `gorm:"column:ip;type:varchar(50)" json:"ip"`
+	Network    string    `gorm:"column:network;type:varchar(64)" json:"-"` // IP所在网段
+	UserAgent  string    `gorm:"column:user_agent;type:varchar(500)" json:"user_agent"`
+	Device     string    `gorm:"column:device;type:varchar(100)" json:"device"`
+	DeviceHash string    `gorm:"column:device_hash;type:varchar(64)" json:"-"` // 设备指纹
+	NewDevice  bool      `gorm:"column:new_device;not null;default:false" json:"new_device"`
+	NewNetwork bool      `gorm:"column:new_network;not null;default:false" json:"new_network"`
+	Alerted    bool      `gorm:"column:alerted;not null;default:false" json:"alerted"` // 是否已发送登录提醒邮件
+	CreatedAt  time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (LoginHistory) TableName() string {
+	return "login_history"
+}
+
+// LoginHistoryRequest 登录历史查询请求
+type LoginHistoryRequest struct {
+	Page     int `form:"page" binding:"omitempty,gt=0"`
+	PageSize int `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+}
+
+// LoginHistoryResponse 登录历史响应
+type LoginHistoryResponse struct {
+	Total int             `json:"total"`
+	List  []*LoginHistory `json:"list"`
+}
+
+// LoginAlertRevokeRequest 登录提醒邮件中"不是我本人"链接的令牌
+type LoginAlertRevokeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
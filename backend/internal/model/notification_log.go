@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// NotificationLog 通知发送记录
+// (user_id, type, stage, ref_key) 唯一，保证同一阶段的通知最多发送一次
+type NotificationLog struct {
+	ID        int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID    int       `gorm:"column:user_id;not null;uniqueIndex:uk_notification_log" json:"user_id"`
+	Type      string    `gorm:"column:type;type:varchar(30);not null;uniqueIndex:uk_notification_log" json:"type"`
+	Stage     int       `gorm:"column:stage;not null;uniqueIndex:uk_notification_log" json:"stage"`                      // 提醒阶段（到期前天数，0=到期当天）
+	RefKey    string    `gorm:"column:ref_key;type:varchar(50);not null;uniqueIndex:uk_notification_log" json:"ref_key"` // 去重键（如VIP到期日期）
+	Channel   string    `gorm:"column:channel;type:varchar(20);not null" json:"channel"`
+	Recipient string    `gorm:"column:recipient;type:varchar(100)" json:"recipient"`
+	Status    string    `gorm:"column:status;type:varchar(20);not null" json:"status"` // pending/sent/failed
+	Error     string    `gorm:"column:error;type:text" json:"error,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (NotificationLog) TableName() string {
+	return "notification_log"
+}
+
+// 通知类型
+const (
+	NotifyVipExpiry = "vip_expiry" // VIP到期提醒
+	NotifyTrialEnd  = "trial_end"  // 试用结束转化提醒
+)
+
+// 通知状态
+const (
+	NotifyStatusPending = "pending"
+	NotifyStatusSent    = "sent"
+	NotifyStatusFailed  = "failed"
+)
+
+// NotificationLogListRequest 通知记录查询请求
+type NotificationLogListRequest struct {
+	Page      int        `form:"page" binding:"omitempty,gt=0"`
+	PageSize  int        `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Type      string     `form:"type"`
+	Status    string     `form:"status" binding:"omitempty,oneof=pending sent failed"`
+	Stage     *int       `form:"stage" binding:"omitempty,min=0"`
+	UserID    int        `form:"user_id" binding:"omitempty,gt=0"`
+	StartTime *time.Time `form:"start_time" time_format:"2006-01-02"`
+	EndTime   *time.Time `form:"end_time" time_format:"2006-01-02"`
+}
+
+// NotificationLogListResponse 通知记录列表响应
+type NotificationLogListResponse struct {
+	Total   int                       `json:"total"`
+	List    []*NotificationLog        `json:"list"`
+	Summary []*NotificationStageCount `json:"summary"` // 按阶段和状态汇总
+}
+
+// NotificationStageCount 按阶段和状态统计的发送数量
+type NotificationStageCount struct {
+	Stage  int    `json:"stage"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+// NotificationPreferenceRequest 通知偏好设置请求
+type NotificationPreferenceRequest struct {
+	VipReminder *bool `json:"vip_reminder" binding:"required"`
+}
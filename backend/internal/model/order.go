@@ -0,0 +1,102 @@
+package model
+
+import "time"
+
+// Plan 套餐（VIP等级 + 时长 + 价格）
+type Plan struct {
+	ID        int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"column:name;type:varchar(50);not null" json:"name"`
+	VipLevel  int       `gorm:"column:vip_level;not null" json:"vip_level"`
+	Days      int       `gorm:"column:days;not null" json:"days"`
+	Price     int64     `gorm:"column:price;not null" json:"price"`                           // 价格（分）
+	Status    int       `gorm:"column:status;type:smallint;not null;default:1" json:"status"` // 0=下架 1=上架
+	SortOrder int       `gorm:"column:sort_order;not null;default:0" json:"sort_order"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Plan) TableName() string {
+	return "plans"
+}
+
+// Order 订单
+type Order struct {
+	ID          int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	OrderNo     string     `gorm:"column:order_no;type:varchar(32);not null;uniqueIndex" json:"order_no"`
+	UserID      int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	PlanID      int        `gorm:"column:plan_id;not null" json:"plan_id"`
+	PlanName    string     `gorm:"column:plan_name;type:varchar(50);not null" json:"plan_name"` // 下单时的套餐快照
+	VipLevel    int        `gorm:"column:vip_level;not null" json:"vip_level"`
+	Days        int        `gorm:"column:days;not null" json:"days"`
+	Amount      int64      `gorm:"column:amount;not null" json:"amount"` // 金额（分）
+	Status      string     `gorm:"column:status;type:varchar(20);not null;index" json:"status"`
+	Gateway     string     `gorm:"column:gateway;type:varchar(20);not null" json:"gateway"`
+	TradeNo     string     `gorm:"column:trade_no;type:varchar(64)" json:"trade_no,omitempty"` // 支付平台交易号
+	PaidAt      *time.Time `gorm:"column:paid_at" json:"paid_at,omitempty"`
+	FulfilledAt *time.Time `gorm:"column:fulfilled_at" json:"fulfilled_at,omitempty"`
+	RefundedAt  *time.Time `gorm:"column:refunded_at" json:"refunded_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (Order) TableName() string {
+	return "orders"
+}
+
+// 订单状态：created -> paid -> fulfilled -> refunding -> refunded
+// paid 表示已支付、VIP尚未发放（支付时用户VIP冻结中，解冻后补发）；
+// refunding 表示VIP已回收、等待支付平台退款，支付平台退款失败时停留在该状态，可重试
+const (
+	OrderStatusCreated   = "created"
+	OrderStatusPaid      = "paid"
+	OrderStatusFulfilled = "fulfilled"
+	OrderStatusRefunding = "refunding"
+	OrderStatusRefunded  = "refunded"
+)
+
+// PlanRequest 创建/更新套餐请求
+type PlanRequest struct {
+	Name      string `json:"name" binding:"required,max=50"`
+	VipLevel  int    `json:"vip_level" binding:"required,min=1"`
+	Days      int    `json:"days" binding:"required,min=1,max=3650"`
+	Price     int64  `json:"price" binding:"required,min=1"`
+	Status    *int   `json:"status" binding:"omitempty,oneof=0 1"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// OrderCreateRequest 创建订单请求
+type OrderCreateRequest struct {
+	PlanID  int    `json:"plan_id" binding:"required,gt=0"`
+	Gateway string `json:"gateway"` // 为空使用默认网关
+}
+
+// OrderCreateResponse 创建订单响应
+type OrderCreateResponse struct {
+	Order      *Order `json:"order"`
+	PaymentURL string `json:"payment_url"`
+}
+
+// OrderListRequest 订单列表请求
+type OrderListRequest struct {
+	Page     int    `form:"page" binding:"omitempty,gt=0"`
+	PageSize int    `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Status   string `form:"status" binding:"omitempty,oneof=created paid fulfilled refunding refunded"`
+	UserID   int    `form:"user_id" binding:"omitempty,gt=0"`
+	Keyword  string `form:"keyword"` // 订单号
+}
+
+// OrderListResponse 订单列表响应
+type OrderListResponse struct {
+	Total int      `json:"total"`
+	List  []*Order `json:"list"`
+}
+
+// OrderRefundRequest 订单退款请求
+type OrderRefundRequest struct {
+	Reason string `json:"reason" binding:"max=200"`
+}
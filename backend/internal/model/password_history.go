@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// PasswordHistory 用户使用过的密码（只保存bcrypt摘要），用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID       int       `gorm:"column:user_id;not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"column:password_hash;type:varchar(100);not null" json:"-"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
@@ -1,11 +1,15 @@
 package model
 
+import "time"
+
 // Permission 权限模型
 type Permission struct {
 	PermissionID   int    `gorm:"column:permission_id;primaryKey;autoIncrement" json:"permission_id"`
 	PermissionName string `gorm:"column:permission_name;type:varchar(50);not null;uniqueIndex" json:"permission_name"`
 	PermissionKey  string `gorm:"column:permission_key;type:varchar(50);not null;uniqueIndex" json:"permission_key"`
 	Description    string `gorm:"column:description;type:varchar(200)" json:"description"`
+	Category       string `gorm:"column:category;type:varchar(50)" json:"category"`       // 权限分组（如 用户管理），由代码声明同步
+	Orphaned       bool   `gorm:"column:orphaned;not null;default:false" json:"orphaned"` // 代码中已不再声明的权限（启动同步时标记）
 }
 
 // TableName 指定表名
@@ -13,8 +17,91 @@ func (Permission) TableName() string {
 	return "permissions"
 }
 
+// PermissionDef 代码中声明的权限
+type PermissionDef struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+// RoutePermission 路由及其所需权限（未要求权限时 permission_key 为空）
+type RoutePermission struct {
+	Method         string `json:"method"`
+	Path           string `json:"path"`
+	PermissionKey  string `json:"permission_key,omitempty"`
+	PermissionName string `json:"permission_name,omitempty"`
+	Category       string `json:"category,omitempty"`
+}
+
+// PermissionSyncResult 权限声明同步结果
+type PermissionSyncResult struct {
+	Added    []string `json:"added"`    // 新增的权限
+	Updated  []string `json:"updated"`  // 名称、描述或分组有变化的权限
+	Orphaned []string `json:"orphaned"` // 数据库中存在但代码未声明的权限
+}
+
 // PermissionListResponse 权限列表响应
 type PermissionListResponse struct {
 	Total int           `json:"total"`
 	List  []*Permission `json:"list"`
 }
+
+// PermissionGroup 权限组，将多个权限（可含通配模式）打包后整体授予角色
+type PermissionGroup struct {
+	GroupID     int       `gorm:"column:group_id;primaryKey;autoIncrement" json:"group_id"`
+	GroupName   string    `gorm:"column:group_name;type:varchar(50);not null;uniqueIndex" json:"group_name"`
+	Description string    `gorm:"column:description;type:varchar(200)" json:"description"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// 关联
+	Permissions []*Permission `gorm:"many2many:permission_group_items;foreignKey:GroupID;joinForeignKey:GroupID;References:PermissionID;joinReferences:PermissionID" json:"permissions,omitempty"`
+}
+
+// TableName 指定表名
+func (PermissionGroup) TableName() string {
+	return "permission_groups"
+}
+
+// PermissionGroupRequest 创建/更新权限组请求
+type PermissionGroupRequest struct {
+	GroupName     string `json:"group_name" binding:"required,min=2,max=50"`
+	Description   string `json:"description" binding:"omitempty,max=200"`
+	PermissionIDs []int  `json:"permission_ids" binding:"required"`
+}
+
+// RolePermissionSet 角色生效的权限规则（权限组已展开），拒绝规则优先于允许规则
+type RolePermissionSet struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// PermissionCheckRequest 权限检查请求（role_id 与 user_id 二选一）
+type PermissionCheckRequest struct {
+	RoleID int    `form:"role_id" binding:"omitempty,gt=0"`
+	UserID int    `form:"user_id" binding:"omitempty,gt=0"`
+	Key    string `form:"key" binding:"required,max=50"`
+}
+
+// PermissionCheckResult 权限检查结果
+type PermissionCheckResult struct {
+	RoleID      int    `json:"role_id"`
+	Key         string `json:"key"`
+	Allowed     bool   `json:"allowed"`
+	Effect      string `json:"effect,omitempty"`       // 命中规则的效果：allow / deny，未命中时为空
+	MatchedRule string `json:"matched_rule,omitempty"` // 命中的权限模式
+}
+
+// 权限规则效果
+const (
+	PermissionEffectAllow = "allow"
+	PermissionEffectDeny  = "deny"
+)
+
+// RoleEffectivePermissions 角色实际拥有的权限
+type RoleEffectivePermissions struct {
+	RoleID    int           `json:"role_id"`
+	Allow     []string      `json:"allow"`     // 允许规则（含权限组展开）
+	Deny      []string      `json:"deny"`      // 拒绝规则
+	Effective []*Permission `json:"effective"` // 规则展开后实际拥有的具体权限
+}
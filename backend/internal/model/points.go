@@ -0,0 +1,159 @@
+package model
+
+import "time"
+
+// UserPoints 用户积分余额
+type UserPoints struct {
+	UserID      int       `gorm:"column:user_id;primaryKey" json:"user_id"`
+	Balance     int       `gorm:"column:balance;not null;default:0" json:"balance"`
+	TotalEarned int       `gorm:"column:total_earned;not null;default:0" json:"total_earned"`
+	TotalSpent  int       `gorm:"column:total_spent;not null;default:0" json:"total_spent"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserPoints) TableName() string {
+	return "user_points"
+}
+
+// PointsLedger 积分流水（只增不改）
+type PointsLedger struct {
+	ID           int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID       int       `gorm:"column:user_id;not null;index" json:"user_id"`
+	Delta        int       `gorm:"column:delta;not null" json:"delta"`
+	BalanceAfter int       `gorm:"column:balance_after;not null" json:"balance_after"`
+	Reason       string    `gorm:"column:reason;type:varchar(20);not null" json:"reason"`
+	RefID        string    `gorm:"column:ref_id;type:varchar(50)" json:"ref_id,omitempty"`
+	OperatorID   *int      `gorm:"column:operator_id" json:"operator_id,omitempty"`
+	Remark       string    `gorm:"column:remark;type:varchar(200)" json:"remark"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (PointsLedger) TableName() string {
+	return "points_ledger"
+}
+
+// 积分变动原因
+const (
+	PointsReasonCheckin = "checkin" // 每日签到
+	PointsReasonRedeem  = "redeem"  // 积分兑换
+	PointsReasonAdjust  = "adjust"  // 管理员调整
+)
+
+// Checkin 签到记录（每个用户每天一条）
+type Checkin struct {
+	ID          int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID      int       `gorm:"column:user_id;not null" json:"user_id"`
+	CheckinDate time.Time `gorm:"column:checkin_date;type:date;not null" json:"checkin_date"`
+	Streak      int       `gorm:"column:streak;not null" json:"streak"` // 连续签到天数
+	Points      int       `gorm:"column:points;not null" json:"points"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Checkin) TableName() string {
+	return "checkins"
+}
+
+// PointsItem 积分商城商品
+type PointsItem struct {
+	ID           int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name         string    `gorm:"column:name;type:varchar(50);not null" json:"name"`
+	Description  string    `gorm:"column:description;type:varchar(200)" json:"description"`
+	ItemType     string    `gorm:"column:item_type;type:varchar(20);not null" json:"item_type"`
+	Cost         int       `gorm:"column:cost;not null" json:"cost"`
+	VipLevel     int       `gorm:"column:vip_level;not null;default:1" json:"vip_level"` // vip_days/invite_code 的VIP等级
+	Amount       int       `gorm:"column:amount;not null" json:"amount"`                 // VIP天数或求片额度
+	Stock        int       `gorm:"column:stock;not null;default:-1" json:"stock"`        // 库存，-1=不限
+	PerUserLimit int       `gorm:"column:per_user_limit;not null;default:0" json:"per_user_limit"`
+	Status       int       `gorm:"column:status;type:smallint;not null;default:1" json:"status"` // 0=下架 1=上架
+	SortOrder    int       `gorm:"column:sort_order;not null;default:0" json:"sort_order"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PointsItem) TableName() string {
+	return "points_items"
+}
+
+// 商品类型
+const (
+	PointsItemVipDays      = "vip_days"      // 直接发放VIP天数
+	PointsItemInviteCode   = "invite_code"   // 生成一张VIP卡密（可赠送他人）
+	PointsItemRequestQuota = "request_quota" // 增加求片额度
+)
+
+// PointsRedemption 积分兑换记录
+type PointsRedemption struct {
+	ID        int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID    int       `gorm:"column:user_id;not null;index" json:"user_id"`
+	ItemID    int       `gorm:"column:item_id;not null;index" json:"item_id"`
+	ItemName  string    `gorm:"column:item_name;type:varchar(50);not null" json:"item_name"`
+	ItemType  string    `gorm:"column:item_type;type:varchar(20);not null" json:"item_type"`
+	Cost      int       `gorm:"column:cost;not null" json:"cost"`
+	BatchID   *int      `gorm:"column:batch_id" json:"batch_id,omitempty"` // invite_code 类商品生成的卡密批次
+	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (PointsRedemption) TableName() string {
+	return "points_redemptions"
+}
+
+// CheckinResponse 签到结果
+type CheckinResponse struct {
+	Streak  int `json:"streak"`
+	Points  int `json:"points"`
+	Balance int `json:"balance"`
+}
+
+// PointsSummary 我的积分信息
+type PointsSummary struct {
+	Balance        int  `json:"balance"`
+	TotalEarned    int  `json:"total_earned"`
+	TotalSpent     int  `json:"total_spent"`
+	CheckedInToday bool `json:"checked_in_today"`
+	Streak         int  `json:"streak"` // 当前连续签到天数（今天或昨天签到过才算连续）
+}
+
+// PointsItemRequest 创建/更新商品请求
+type PointsItemRequest struct {
+	Name         string `json:"name" binding:"required,max=50"`
+	Description  string `json:"description" binding:"max=200"`
+	ItemType     string `json:"item_type" binding:"required,oneof=vip_days invite_code request_quota"`
+	Cost         int    `json:"cost" binding:"required,min=1"`
+	VipLevel     int    `json:"vip_level" binding:"omitempty,min=1"`
+	Amount       int    `json:"amount" binding:"required,min=1,max=365"`
+	Stock        *int   `json:"stock" binding:"omitempty,min=-1"`
+	PerUserLimit int    `json:"per_user_limit" binding:"omitempty,min=0"`
+	Status       *int   `json:"status" binding:"omitempty,oneof=0 1"`
+	SortOrder    int    `json:"sort_order"`
+}
+
+// PointsRedeemResponse 积分兑换结果
+type PointsRedeemResponse struct {
+	Redemption *PointsRedemption `json:"redemption"`
+	Balance    int               `json:"balance"`
+	CardCode   string            `json:"card_code,omitempty"` // invite_code 类商品生成的卡密
+}
+
+// PointsAdjustRequest 管理员调整积分请求
+type PointsAdjustRequest struct {
+	Delta  int    `json:"delta" binding:"required,ne=0"`
+	Remark string `json:"remark" binding:"max=200"`
+}
+
+// PointsLedgerListRequest 积分流水请求
+type PointsLedgerListRequest struct {
+	Page     int    `form:"page" binding:"omitempty,gt=0"`
+	PageSize int    `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Reason   string `form:"reason" binding:"omitempty,oneof=checkin redeem adjust"`
+}
+
+// PointsLedgerListResponse 积分流水响应
+type PointsLedgerListResponse struct {
+	Total int             `json:"total"`
+	List  []*PointsLedger `json:"list"`
+}
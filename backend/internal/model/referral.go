@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+// Referral 推荐关系（每个被推荐人只能有一个推荐人）
+type Referral struct {
+	ID                 int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ReferrerID         int        `gorm:"column:referrer_id;not null;index" json:"referrer_id"`
+	RefereeID          int        `gorm:"column:referee_id;not null;uniqueIndex" json:"referee_id"`
+	Status             string     `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	RejectReason       string     `gorm:"column:reject_reason;type:varchar(100)" json:"reject_reason,omitempty"`
+	IPAddress          string     `gorm:"column:ip_address;type:varchar(45)" json:"-"` // 被推荐人注册IP
+	DeviceFingerprint  string     `gorm:"column:device_fingerprint;type:varchar(128)" json:"-"`
+	Trigger            string     `gorm:"column:reward_trigger;type:varchar(20)" json:"trigger,omitempty"` // 奖励触发方式（待奖励时表示已触发、因VIP冻结推迟）
+	ReferrerRewardDays int        `gorm:"column:referrer_reward_days;not null;default:0" json:"referrer_reward_days"`
+	RefereeRewardDays  int        `gorm:"column:referee_reward_days;not null;default:0" json:"referee_reward_days"`
+	RewardedAt         *time.Time `gorm:"column:rewarded_at" json:"rewarded_at,omitempty"`
+	CreatedAt          time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// 关联
+	Referee *User `gorm:"foreignKey:RefereeID" json:"referee,omitempty"`
+}
+
+// TableName 指定表名
+func (Referral) TableName() string {
+	return "referrals"
+}
+
+// 推荐状态
+const (
+	ReferralStatusPending  = "pending"  // 等待满足奖励条件
+	ReferralStatusRewarded = "rewarded" // 已发放奖励
+	ReferralStatusRejected = "rejected" // 未通过反作弊检查，不发放奖励
+)
+
+// 奖励触发方式
+const (
+	ReferralTriggerCard   = "card"   // 被推荐人首次兑换卡密
+	ReferralTriggerActive = "active" // 被推荐人持续活跃满N天
+)
+
+// ReferralConfig 推荐奖励配置（来自 system_configs）
+type ReferralConfig struct {
+	Enabled      bool
+	ReferrerDays int // 推荐人奖励天数
+	RefereeDays  int // 被推荐人奖励天数
+	VipLevel     int // 奖励VIP等级
+	RewardOnCard bool
+	ActiveDays   int // 注册满N天且期间有访问时发放，0 表示不按活跃发放
+	IPWindowDays int // 推荐人近N天登录过相同IP视为同一人
+	MonthlyLimit int // 推荐人每月最多获得奖励次数，0 表示不限制
+}
+
+// ReferralSummary 我的推荐信息
+type ReferralSummary struct {
+	ReferralCode string `json:"referral_code"`
+	Total        int64  `json:"total"`
+	Pending      int64  `json:"pending"`
+	Rewarded     int64  `json:"rewarded"`
+	Rejected     int64  `json:"rejected"`
+	RewardDays   int64  `json:"reward_days"`
+	ReferrerDays int    `json:"referrer_days"` // 当前每次推荐奖励天数
+	RefereeDays  int    `json:"referee_days"`  // 当前被推荐人奖励天数
+}
+
+// ReferralListRequest 推荐记录列表请求
+type ReferralListRequest struct {
+	Page     int    `form:"page" binding:"omitempty,gt=0"`
+	PageSize int    `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending rewarded rejected"`
+}
+
+// ReferralListResponse 推荐记录列表响应
+type ReferralListResponse struct {
+	Total int         `json:"total"`
+	List  []*Referral `json:"list"`
+}
+
+// ReferralLeaderboardRequest 推荐排行榜请求
+type ReferralLeaderboardRequest struct {
+	Limit     int        `form:"limit" binding:"omitempty,gt=0,lte=100"`
+	StartTime *time.Time `form:"start_time" time_format:"2006-01-02"`
+	EndTime   *time.Time `form:"end_time" time_format:"2006-01-02"`
+}
+
+// ReferralLeaderboardEntry 推荐排行榜条目
+type ReferralLeaderboardEntry struct {
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username"`
+	Rewarded   int64  `json:"rewarded"`
+	RewardDays int64  `json:"reward_days"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌（只保存摘要），一次性使用，刷新时轮换为新令牌
+// 同一会话内轮换产生的令牌属于同一族，已使用的令牌再次出现时撤销整个会话
+type RefreshToken struct {
+	ID        int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	SessionID string     `gorm:"column:session_id;type:varchar(32);not null;index" json:"session_id"`
+	UserID    int        `gorm:"column:user_id;not null" json:"user_id"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at,omitempty"` // 已轮换的时间，非空表示已失效
+	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse 令牌响应
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+	SessionID    string `json:"session_id"`
+
+	MustChangePassword bool `json:"must_change_password,omitempty"` // 须先修改密码，其余接口在修改前不可用
+}
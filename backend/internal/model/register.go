@@ -5,7 +5,10 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`           // 邮箱（必填）
 	Code     string `json:"code" binding:"required,len=6"`            // 验证码（必填）
 	Username string `json:"username" binding:"required,min=3,max=50"` // 用户名
-	Password string `json:"password" binding:"required,min=6,max=50"` // 密码
+	Password string `json:"password" binding:"required,max=50"`       // 密码
+
+	DeviceFingerprint string `json:"device_fingerprint" binding:"max=128"` // 设备指纹（可选，用于试用和推荐防滥用）
+	ReferralCode      string `json:"referral_code" binding:"max=16"`       // 推荐码（可选）
 }
 
 // RegisterResponse 注册响应
@@ -15,4 +18,6 @@ type RegisterResponse struct {
 	Email      string `json:"email"`
 	EmbyUserID string `json:"emby_user_id,omitempty"`
 	Message    string `json:"message"`
+
+	Trial *TrialInfo `json:"trial,omitempty"` // 注册试用（未发放时为空）
 }
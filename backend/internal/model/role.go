@@ -7,10 +7,14 @@ type Role struct {
 	RoleID      int       `gorm:"column:role_id;primaryKey;autoIncrement" json:"role_id"`
 	RoleName    string    `gorm:"column:role_name;type:varchar(50);not null;uniqueIndex" json:"role_name"`
 	Description string    `gorm:"column:description;type:varchar(200)" json:"description"`
+	Rank        int       `gorm:"column:role_rank;not null;default:0" json:"rank"`              // 角色等级，不能操作等级高于自己的用户
+	Require2FA  bool      `gorm:"column:require_2fa;not null;default:false" json:"require_2fa"` // 是否要求该角色的用户开启两步验证
 	CreatedAt   time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 
 	// 关联
-	Permissions []*Permission `gorm:"many2many:role_permissions;foreignKey:RoleID;joinForeignKey:RoleID;References:PermissionID;joinReferences:PermissionID" json:"permissions,omitempty"`
+	Permissions       []*Permission      `gorm:"many2many:role_permissions;foreignKey:RoleID;joinForeignKey:RoleID;References:PermissionID;joinReferences:PermissionID" json:"permissions,omitempty"`
+	DeniedPermissions []*Permission      `gorm:"many2many:role_permission_denies;foreignKey:RoleID;joinForeignKey:RoleID;References:PermissionID;joinReferences:PermissionID" json:"denied_permissions,omitempty"`
+	PermissionGroups  []*PermissionGroup `gorm:"many2many:role_permission_groups;foreignKey:RoleID;joinForeignKey:RoleID;References:GroupID;joinReferences:GroupID" json:"permission_groups,omitempty"`
 }
 
 // TableName 指定表名
@@ -22,17 +26,24 @@ func (Role) TableName() string {
 type RoleCreateRequest struct {
 	RoleName    string `json:"role_name" binding:"required,min=2,max=50"`
 	Description string `json:"description" binding:"omitempty,max=200"`
+	Rank        int    `json:"rank" binding:"omitempty,min=0,max=1000"`
+	Require2FA  bool   `json:"require_2fa"`
 }
 
 // RoleUpdateRequest 更新角色请求
 type RoleUpdateRequest struct {
 	RoleName    string `json:"role_name" binding:"omitempty,min=2,max=50"`
 	Description string `json:"description" binding:"omitempty,max=200"`
+	Rank        *int   `json:"rank" binding:"omitempty,min=0,max=1000"`
+	Require2FA  *bool  `json:"require_2fa"`
 }
 
 // RolePermissionRequest 角色权限分配请求
+// deny_permission_ids 和 group_ids 未传时保持不变，传空数组表示清空
 type RolePermissionRequest struct {
-	PermissionIDs []int `json:"permission_ids" binding:"required"`
+	PermissionIDs     []int `json:"permission_ids" binding:"required"`
+	DenyPermissionIDs []int `json:"deny_permission_ids"`
+	GroupIDs          []int `json:"group_ids"`
 }
 
 // RoleListResponse 角色列表响应
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// UserSession 登录会话，每次登录创建一个，Token 中携带会话ID
+type UserSession struct {
+	ID         string     `gorm:"column:id;type:varchar(32);primaryKey" json:"id"`
+	UserID     int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Device     string     `gorm:"column:device;type:varchar(100)" json:"device"` // 设备名称（客户端提供或由UA推断）
+	IP         string     `gorm:"column:ip;type:varchar(50)" json:"ip"`
+	UserAgent  string     `gorm:"column:user_agent;type:varchar(500)" json:"user_agent"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at;not null;default:CURRENT_TIMESTAMP" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+
+	Current bool `gorm:"-" json:"current"` // 是否为发起请求的会话
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// SessionMeta 创建会话时的客户端信息
+type SessionMeta struct {
+	Device            string
+	DeviceFingerprint string // 客户端持久化的设备标识，用于识别新设备登录
+	IP                string
+	UserAgent         string
+	Method            string // 登录方式
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// TrialGrant 试用VIP发放记录（用于防止重复领取）
+type TrialGrant struct {
+	ID                int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID            int       `gorm:"column:user_id;not null;uniqueIndex" json:"user_id"`
+	EmailKey          string    `gorm:"column:email_key;type:varchar(100);not null;uniqueIndex" json:"email_key"` // 规范化后的邮箱
+	IPAddress         string    `gorm:"column:ip_address;type:varchar(45)" json:"ip_address"`
+	DeviceFingerprint string    `gorm:"column:device_fingerprint;type:varchar(128)" json:"device_fingerprint"`
+	VipLevel          int       `gorm:"column:vip_level;not null" json:"vip_level"`
+	Days              int       `gorm:"column:days;not null" json:"days"`
+	ExpireAt          time.Time `gorm:"column:expire_at;not null" json:"expire_at"`
+	CreatedAt         time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (TrialGrant) TableName() string {
+	return "trial_grants"
+}
+
+// TrialConfig 试用配置（来自 system_configs）
+type TrialConfig struct {
+	Days                 int      // 试用天数，0 表示关闭
+	VipLevel             int      // 试用等级
+	RequireVerifiedEmail bool     // 是否要求邮箱已验证
+	IPWindowDays         int      // 同一IP在该天数内只能领取一次，0 表示不限制
+	BlockedEmailDomains  []string // 禁止试用的邮箱域名模式（支持通配符，如 *.temp-mail.org）
+	ReminderHours        int      // 试用结束前多少小时发送转化提醒，0 表示不提醒
+}
+
+// TrialInfo 注册时发放的试用信息
+type TrialInfo struct {
+	VipLevel int       `json:"vip_level"`
+	Days     int       `json:"days"`
+	ExpireAt time.Time `json:"expire_at"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// UserTOTP 用户的TOTP两步验证配置，开启前为待确认状态
+type UserTOTP struct {
+	UserID    int        `gorm:"column:user_id;primaryKey" json:"user_id"`
+	Secret    string     `gorm:"column:secret;type:varchar(64);not null" json:"-"`
+	Enabled   bool       `gorm:"column:enabled;not null;default:false" json:"enabled"`
+	LastStep  int64      `gorm:"column:last_step;not null;default:0" json:"-"` // 最近一次使用的时间步，防止验证码重放
+	EnabledAt *time.Time `gorm:"column:enabled_at" json:"enabled_at,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode 两步验证恢复码（只保存摘要，每个只能使用一次）
+type RecoveryCode struct {
+	ID        int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID    int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"column:code_hash;type:varchar(64);not null" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// TwoFactorCodeRequest 两步验证码请求（TOTP验证码或恢复码）
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,min=6,max=20"`
+}
+
+// TwoFactorLoginRequest 登录第二步请求
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,min=6,max=20"`
+}
+
+// TwoFactorChallengeRequest 登录过程中开启两步验证的请求
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// TwoFactorSetupResponse 两步验证绑定信息
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// 地址，前端生成二维码
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"` // 所属角色要求开启
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// RecoveryCodesResponse 新生成的恢复码（仅展示一次）
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -4,20 +4,32 @@ import "time"
 
 // User 用户模型
 type User struct {
-	UserID       int        `gorm:"column:user_id;primaryKey;autoIncrement" json:"user_id"`
-	Username     string     `gorm:"column:username;type:varchar(50);not null;uniqueIndex" json:"username"`
-	PasswordHash string     `gorm:"column:password_hash;type:varchar(100);not null" json:"-"`
-	Email        string     `gorm:"column:email;type:varchar(100);uniqueIndex" json:"email"`
-	EmbyUserID   string     `gorm:"column:emby_user_id;type:varchar(50);index" json:"emby_user_id"`
-	RoleID       int        `gorm:"column:role_id;not null" json:"role_id"`
-	Status       int        `gorm:"column:status;type:smallint;not null;default:1" json:"status"`
-	VipLevel     int        `gorm:"column:vip_level;default:0" json:"vip_level"`         // VIP等级：0=普通用户 1=VIP会员
-	VipExpireAt  *time.Time `gorm:"column:vip_expire_at" json:"vip_expire_at,omitempty"` // VIP到期时间
-	CreatedAt    time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt    time.Time  `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	UserID            int        `gorm:"column:user_id;primaryKey;autoIncrement" json:"user_id"`
+	Username          string     `gorm:"column:username;type:varchar(50);not null;uniqueIndex" json:"username"`
+	PasswordHash      string     `gorm:"column:password_hash;type:varchar(100);not null" json:"-"`
+	Email             string     `gorm:"column:email;type:varchar(100);uniqueIndex" json:"email"`
+	EmailVerifiedAt   *time.Time `gorm:"column:email_verified_at" json:"email_verified_at,omitempty"` // 邮箱验证时间，空表示未验证（未验证的邮箱不用于单点登录关联账号）
+	EmbyUserID        string     `gorm:"column:emby_user_id;type:varchar(50);index" json:"emby_user_id"`
+	RoleID            int        `gorm:"column:role_id;not null" json:"role_id"`
+	GroupID           *int       `gorm:"column:group_id;index" json:"group_id,omitempty"` // 所属用户组，空表示不属于任何组
+	Status            int        `gorm:"column:status;type:smallint;not null;default:1" json:"status"`
+	VipLevel          int        `gorm:"column:vip_level;default:0" json:"vip_level"`                                      // VIP等级，对应 vip_tiers.level，0=免费用户
+	VipExpireAt       *time.Time `gorm:"column:vip_expire_at" json:"vip_expire_at,omitempty"`                              // VIP到期时间
+	VipReminder       bool       `gorm:"column:vip_reminder;not null;default:true" json:"vip_reminder"`                    // 是否接收VIP到期提醒
+	VipFrozenAt       *time.Time `gorm:"column:vip_frozen_at" json:"vip_frozen_at,omitempty"`                              // VIP冻结时间，非空表示冻结中
+	ReferralCode      *string    `gorm:"column:referral_code;type:varchar(16);uniqueIndex" json:"referral_code,omitempty"` // 推荐码（首次查看时生成）
+	ReferredBy        *int       `gorm:"column:referred_by" json:"referred_by,omitempty"`                                  // 推荐人
+	BonusRequestQuota int        `gorm:"column:bonus_request_quota;not null;default:0" json:"bonus_request_quota"`         // 额外求片额度（积分兑换等）
+	SecurityVersion   int        `gorm:"column:security_version;not null;default:0;<-:create" json:"-"`                    // 安全版本号，角色/状态/密码变更时递增（只通过 BumpSecurityVersion 更新）
+	CreatedAt         time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	MustChangePassword bool       `gorm:"column:must_change_password;not null;default:false" json:"must_change_password"` // 下次登录须修改密码（如管理员重置密码后）
+	PasswordChangedAt  *time.Time `gorm:"column:password_changed_at" json:"-"`                                            // 最近一次修改密码的时间，空表示自注册后未修改
 
 	// 关联
-	Role *Role `gorm:"foreignKey:RoleID;references:RoleID" json:"role,omitempty"`
+	Role  *Role      `gorm:"foreignKey:RoleID;references:RoleID" json:"role,omitempty"`
+	Group *UserGroup `gorm:"foreignKey:GroupID;references:GroupID" json:"group,omitempty"`
 }
 
 // TableName 指定表名
@@ -28,10 +40,11 @@ func (User) TableName() string {
 // UserCreateRequest 创建用户请求
 type UserCreateRequest struct {
 	Username   string `json:"username" binding:"required,min=3,max=50"`
-	Password   string `json:"password" binding:"required,min=6,max=50"`
+	Password   string `json:"password" binding:"required,max=50"`
 	Email      string `json:"email" binding:"omitempty,email"`
 	EmbyUserID string `json:"emby_user_id" binding:"omitempty"`
 	RoleID     int    `json:"role_id" binding:"required,gt=0"`
+	GroupID    *int   `json:"group_id" binding:"omitempty,gt=0"`
 }
 
 // UserUpdateRequest 更新用户请求
@@ -40,6 +53,7 @@ type UserUpdateRequest struct {
 	EmbyUserID string `json:"emby_user_id" binding:"omitempty"`
 	RoleID     int    `json:"role_id" binding:"omitempty,gt=0"`
 	Status     *int   `json:"status" binding:"omitempty,oneof=0 1"`
+	GroupID    *int   `json:"group_id" binding:"omitempty,min=0"` // 0 表示移出用户组
 }
 
 // UserListRequest 用户列表查询请求
@@ -49,11 +63,15 @@ type UserListRequest struct {
 	Keyword  string `form:"keyword"`
 	Status   *int   `form:"status" binding:"omitempty,oneof=0 1"`
 	RoleID   int    `form:"role_id" binding:"omitempty,gt=0"`
+	GroupID  int    `form:"group_id" binding:"omitempty,gt=0"`
+
+	ScopeGroupIDs []int `form:"-"` // 操作者可管理的用户组，nil 表示不限
 }
 
 // UserPasswordRequest 修改密码请求
 type UserPasswordRequest struct {
-	Password string `json:"password" binding:"required,min=6,max=50"`
+	Password   string `json:"password" binding:"required,max=50"`
+	MustChange *bool  `json:"must_change"` // 是否要求用户下次登录时修改密码，不填时默认要求
 }
 
 // UserListResponse 用户列表响应
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// UserGroup 用户组（子社区），用户归属于一个组，管理员按组授权
+type UserGroup struct {
+	GroupID     int       `gorm:"column:group_id;primaryKey;autoIncrement" json:"group_id"`
+	Name        string    `gorm:"column:name;type:varchar(50);not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"column:description;type:varchar(200)" json:"description"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	MemberCount int64 `gorm:"-" json:"member_count"`
+}
+
+// TableName 指定表名
+func (UserGroup) TableName() string {
+	return "user_groups"
+}
+
+// UserGroupGrant 组内授权：某用户在指定用户组内拥有某角色的权限
+type UserGroupGrant struct {
+	ID        int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	GroupID   int       `gorm:"column:group_id;not null;uniqueIndex:idx_user_group_grant" json:"group_id"`
+	UserID    int       `gorm:"column:user_id;not null;uniqueIndex:idx_user_group_grant" json:"user_id"`
+	RoleID    int       `gorm:"column:role_id;not null" json:"role_id"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// 关联
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role *Role `gorm:"foreignKey:RoleID;references:RoleID" json:"role,omitempty"`
+}
+
+// TableName 指定表名
+func (UserGroupGrant) TableName() string {
+	return "user_group_grants"
+}
+
+// UserGroupRequest 创建/更新用户组请求
+type UserGroupRequest struct {
+	Name        string `json:"name" binding:"required,min=2,max=50"`
+	Description string `json:"description" binding:"omitempty,max=200"`
+}
+
+// UserGroupGrantRequest 组内授权请求
+type UserGroupGrantRequest struct {
+	UserID int `json:"user_id" binding:"required,gt=0"`
+	RoleID int `json:"role_id" binding:"required,gt=0"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// UserIdentity 外部身份（单点登录）与本地用户的绑定关系
+type UserIdentity struct {
+	ID          int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID      int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Provider    string     `gorm:"column:provider;type:varchar(255);not null;uniqueIndex:uk_identity_subject" json:"provider"` // 身份提供方（issuer）
+	Subject     string     `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:uk_identity_subject" json:"subject"`   // 身份提供方中的用户标识（sub）
+	Email       string     `gorm:"column:email;type:varchar(100)" json:"email"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at" json:"last_login_at,omitempty"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// OIDCConfigResponse 登录页使用的单点登录配置
+type OIDCConfigResponse struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name"` // 登录按钮上显示的身份提供方名称
+}
+
+// OIDCAuthorizeResponse 单点登录跳转地址
+type OIDCAuthorizeResponse struct {
+	URL string `json:"url"`
+}
+
+// OIDCCallbackRequest 身份提供方回调参数
+type OIDCCallbackRequest struct {
+	Code   string `json:"code" binding:"required"`
+	State  string `json:"state" binding:"required"`
+	Device string `json:"device"`
+
+	DeviceFingerprint string `json:"device_fingerprint" binding:"omitempty,max=64"`
+}
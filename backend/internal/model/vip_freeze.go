@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// VipFreeze VIP冻结记录
+// 冻结时保存剩余时长，解冻时从解冻时刻起恢复剩余时长
+type VipFreeze struct {
+	ID               int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID           int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	VipLevel         int        `gorm:"column:vip_level;not null" json:"vip_level"`
+	RemainingSeconds int64      `gorm:"column:remaining_seconds;not null" json:"remaining_seconds"` // 冻结时剩余的VIP时长
+	FrozenAt         time.Time  `gorm:"column:frozen_at;not null" json:"frozen_at"`
+	FreezeUntil      time.Time  `gorm:"column:freeze_until;not null" json:"freeze_until"` // 最晚解冻时间（到期自动解冻）
+	UnfrozenAt       *time.Time `gorm:"column:unfrozen_at" json:"unfrozen_at,omitempty"`
+	UnfreezeBy       string     `gorm:"column:unfreeze_by;type:varchar(20)" json:"unfreeze_by,omitempty"` // user / admin / auto
+	Reason           string     `gorm:"column:reason;type:varchar(200)" json:"reason,omitempty"`
+	CreatedAt        time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (VipFreeze) TableName() string {
+	return "vip_freezes"
+}
+
+// 解冻方式
+const (
+	UnfreezeByUser  = "user"
+	UnfreezeByAdmin = "admin"
+	UnfreezeByAuto  = "auto"
+)
+
+// VipFreezeRequest 冻结VIP请求
+type VipFreezeRequest struct {
+	Days   int    `json:"days" binding:"required,min=1,max=365"` // 计划冻结天数（不超过等级允许的最长天数，到期自动解冻）
+	Reason string `json:"reason" binding:"max=200"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// VipLedger VIP时长变动流水（只增不改，记录每次VIP变更的来源和前后状态）
+type VipLedger struct {
+	ID           int        `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID       int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Source       string     `gorm:"column:source;type:varchar(20);not null" json:"source"`
+	DeltaDays    int        `gorm:"column:delta_days;not null" json:"delta_days"` // 变动天数（发放为正，回收为负，到期为0）
+	LevelBefore  int        `gorm:"column:level_before;not null" json:"level_before"`
+	LevelAfter   int        `gorm:"column:level_after;not null" json:"level_after"`
+	ExpireBefore *time.Time `gorm:"column:expire_before" json:"expire_before,omitempty"`
+	ExpireAfter  *time.Time `gorm:"column:expire_after" json:"expire_after,omitempty"`
+	OperatorID   *int       `gorm:"column:operator_id" json:"operator_id,omitempty"`        // 操作人（系统任务为空）
+	RefID        string     `gorm:"column:ref_id;type:varchar(50)" json:"ref_id,omitempty"` // 关联对象ID（如卡密ID）
+	Remark       string     `gorm:"column:remark;type:varchar(200)" json:"remark"`
+	CreatedAt    time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (VipLedger) TableName() string {
+	return "vip_ledger"
+}
+
+// VIP变动来源
+const (
+	VipSourceCard     = "card"     // 卡密兑换
+	VipSourceOrder    = "order"    // 订单购买
+	VipSourceAdmin    = "admin"    // 管理员设置
+	VipSourceTrial    = "trial"    // 新用户试用
+	VipSourceReferral = "referral" // 邀请奖励
+	VipSourceRefund   = "refund"   // 退款/撤销回收
+	VipSourceExpiry   = "expiry"   // 到期降级
+	VipSourceFreeze   = "freeze"   // 冻结
+	VipSourceUnfreeze = "unfreeze" // 解冻
+	VipSourceTransfer = "transfer" // 用户间转赠
+	VipSourcePoints   = "points"   // 积分兑换
+)
+
+// VipChange 一次VIP变更请求
+type VipChange struct {
+	UserID     int
+	Level      int // 发放的等级
+	Days       int // 发放天数
+	Source     string
+	OperatorID *int
+	RefID      string
+	Remark     string
+}
+
+// VipHistoryRequest VIP历史查询请求
+type VipHistoryRequest struct {
+	Page     int    `form:"page" binding:"omitempty,gt=0"`
+	PageSize int    `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+	Source   string `form:"source" binding:"omitempty,oneof=card order admin trial referral refund expiry freeze unfreeze transfer points"`
+}
+
+// VipHistoryResponse VIP历史响应
+type VipHistoryResponse struct {
+	Total int          `json:"total"`
+	List  []*VipLedger `json:"list"`
+}
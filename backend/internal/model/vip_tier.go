@@ -0,0 +1,76 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// FreeVipLevel 免费等级（VIP到期后降级到该等级）
+const FreeVipLevel = 0
+
+// VipTier VIP等级及权益
+type VipTier struct {
+	Level          int       `gorm:"column:level;primaryKey" json:"level"` // 等级，0=免费用户
+	Name           string    `gorm:"column:name;type:varchar(50);not null" json:"name"`
+	Description    string    `gorm:"column:description;type:varchar(200)" json:"description"`
+	AllowPlayback  bool      `gorm:"column:allow_playback;not null;default:true" json:"allow_playback"`  // 是否允许播放
+	StreamLimit    int       `gorm:"column:stream_limit;not null;default:0" json:"stream_limit"`         // 同时播放数，0=不限
+	BitrateLimit   int       `gorm:"column:bitrate_limit;not null;default:0" json:"bitrate_limit"`       // 远程码率上限（kbps），0=不限
+	LibraryIDs     string    `gorm:"column:library_ids;type:text" json:"library_ids"`                    // 可访问媒体库ID（逗号分隔），空=全部
+	DeviceQuota    int       `gorm:"column:device_quota;not null;default:0" json:"device_quota"`         // 设备数上限（开启 emby_device_quota_enforce 后超出时移除最久未活跃的Emby设备），0=不限
+	RequestQuota   int       `gorm:"column:request_quota;not null;default:0" json:"request_quota"`       // 每月求片数量，0=不可求片
+	DayValue       int       `gorm:"column:day_value;not null;default:100" json:"day_value"`             // 每天的价值，用于跨等级折算剩余时长
+	MaxFreezeDays  int       `gorm:"column:max_freeze_days;not null;default:0" json:"max_freeze_days"`   // 单次冻结最长天数，0=不可冻结
+	FreezesPerYear int       `gorm:"column:freezes_per_year;not null;default:0" json:"freezes_per_year"` // 每年可冻结次数
+	Status         int       `gorm:"column:status;type:smallint;not null;default:1" json:"status"`       // 0=停用 1=启用
+	CreatedAt      time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (VipTier) TableName() string {
+	return "vip_tiers"
+}
+
+// Libraries 返回可访问的媒体库ID列表，空表示全部
+func (t *VipTier) Libraries() []string {
+	var ids []string
+	for _, id := range strings.Split(t.LibraryIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// VipTierCreateRequest 创建VIP等级请求
+type VipTierCreateRequest struct {
+	Level          int      `json:"level" binding:"required,min=1,max=99"`
+	Name           string   `json:"name" binding:"required,max=50"`
+	Description    string   `json:"description" binding:"omitempty,max=200"`
+	AllowPlayback  *bool    `json:"allow_playback"`
+	StreamLimit    int      `json:"stream_limit" binding:"omitempty,min=0,max=100"`
+	BitrateLimit   int      `json:"bitrate_limit" binding:"omitempty,min=0"`
+	LibraryIDs     []string `json:"library_ids"`
+	DeviceQuota    int      `json:"device_quota" binding:"omitempty,min=0,max=100"`
+	RequestQuota   int      `json:"request_quota" binding:"omitempty,min=0,max=10000"`
+	DayValue       int      `json:"day_value" binding:"required,min=1"`
+	MaxFreezeDays  int      `json:"max_freeze_days" binding:"omitempty,min=0,max=365"`
+	FreezesPerYear int      `json:"freezes_per_year" binding:"omitempty,min=0,max=12"`
+}
+
+// VipTierUpdateRequest 更新VIP等级请求
+type VipTierUpdateRequest struct {
+	Name           string   `json:"name" binding:"omitempty,max=50"`
+	Description    *string  `json:"description" binding:"omitempty,max=200"`
+	AllowPlayback  *bool    `json:"allow_playback"`
+	StreamLimit    *int     `json:"stream_limit" binding:"omitempty,min=0,max=100"`
+	BitrateLimit   *int     `json:"bitrate_limit" binding:"omitempty,min=0"`
+	LibraryIDs     []string `json:"library_ids"`
+	DeviceQuota    *int     `json:"device_quota" binding:"omitempty,min=0,max=100"`
+	RequestQuota   *int     `json:"request_quota" binding:"omitempty,min=0,max=10000"`
+	DayValue       *int     `json:"day_value" binding:"omitempty,min=1"`
+	MaxFreezeDays  *int     `json:"max_freeze_days" binding:"omitempty,min=0,max=365"`
+	FreezesPerYear *int     `json:"freezes_per_year" binding:"omitempty,min=0,max=12"`
+	Status         *int     `json:"status" binding:"omitempty,oneof=0 1"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// VipTransfer VIP时长转赠记录
+type VipTransfer struct {
+	ID         int       `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	FromUserID int       `gorm:"column:from_user_id;not null;index" json:"from_user_id"`
+	ToUserID   int       `gorm:"column:to_user_id;not null;index" json:"to_user_id"`
+	Days       int       `gorm:"column:days;not null" json:"days"`
+	VipLevel   int       `gorm:"column:vip_level;not null" json:"vip_level"` // 转出方的等级，接收方按该等级折算
+	Remark     string    `gorm:"column:remark;type:varchar(200)" json:"remark,omitempty"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// 关联
+	FromUser *User `gorm:"foreignKey:FromUserID" json:"from_user,omitempty"`
+	ToUser   *User `gorm:"foreignKey:ToUserID" json:"to_user,omitempty"`
+}
+
+// TableName 指定表名
+func (VipTransfer) TableName() string {
+	return "vip_transfers"
+}
+
+// VipTransferConfig 转赠限制（来自 system_configs）
+type VipTransferConfig struct {
+	Enabled          bool
+	MinRemainingDays int // 转出后转出方至少保留的天数
+	CooldownHours    int // 两次转出之间的间隔
+	YearlyDays       int // 每个自然年最多转出天数，0 表示不限制
+}
+
+// VipTransferRequest 转赠请求
+type VipTransferRequest struct {
+	ToUsername string `json:"to_username" binding:"required"`
+	Days       int    `json:"days" binding:"required,min=1,max=3650"`
+	Code       string `json:"code" binding:"required,len=6"` // 邮箱验证码
+	Remark     string `json:"remark" binding:"max=200"`
+}
+
+// VipTransferListRequest 转赠记录请求
+type VipTransferListRequest struct {
+	Page     int `form:"page" binding:"omitempty,gt=0"`
+	PageSize int `form:"page_size" binding:"omitempty,gt=0,lte=100"`
+}
+
+// VipTransferListResponse 转赠记录响应
+type VipTransferListResponse struct {
+	Total int            `json:"total"`
+	List  []*VipTransfer `json:"list"`
+}
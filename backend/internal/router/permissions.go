@@ -0,0 +1,177 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"embyhub/internal/middleware"
+	"embyhub/internal/model"
+	"embyhub/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 权限分组
+const (
+	categoryUser       = "用户管理"
+	categoryRole       = "角色管理"
+	categoryPermission = "权限管理"
+	categorySystem     = "系统设置"
+	categoryStats      = "访问统计"
+	categoryEmby       = "Emby同步"
+	categoryCardKey    = "卡密管理"
+	categoryAgent      = "代理商"
+	categoryOrder      = "订单"
+	categoryPoints     = "积分"
+)
+
+// declare 声明权限并返回权限键
+func declare(key, name, description, category string) string {
+	return service.Permissions().Declare(model.PermissionDef{
+		Key:         key,
+		Name:        name,
+		Description: description,
+		Category:    category,
+	})
+}
+
+// 权限声明：路由使用的权限必须在此声明，启动时同步到 permissions 表
+var (
+	permUserView   = declare("user:view", "查看用户", "查看用户列表和详情", categoryUser)
+	permUserCreate = declare("user:create", "创建用户", "创建新用户", categoryUser)
+	permUserEdit   = declare("user:edit", "编辑用户", "编辑用户信息", categoryUser)
+	permUserDelete = declare("user:delete", "删除用户", "删除用户", categoryUser)
+	_              = declare("user:import", "导入用户", "批量导入用户", categoryUser)
+	_              = declare("user:export", "导出用户", "批量导出用户", categoryUser)
+	permGroup      = declare("group:manage", "管理用户组", "管理用户组及组内授权", categoryUser)
+
+	_              = declare("role:view", "查看角色", "查看角色列表和详情", categoryRole)
+	permRoleCreate = declare("role:create", "创建角色", "创建新角色", categoryRole)
+	permRoleEdit   = declare("role:edit", "编辑角色", "编辑角色信息", categoryRole)
+	permRoleDelete = declare("role:delete", "删除角色", "删除角色", categoryRole)
+
+	permPermView   = declare("permission:view", "查看权限", "查看权限列表", categoryPermission)
+	permPermAssign = declare("permission:assign", "分配权限", "为角色分配权限", categoryPermission)
+
+	permSystemView = declare("system:view", "查看系统设置", "查看系统配置", categorySystem)
+	permSystemEdit = declare("system:edit", "修改系统设置", "修改系统配置", categorySystem)
+
+	permStatsView = declare("stats:view", "查看统计数据", "查看访问统计数据", categoryStats)
+	_             = declare("stats:export", "导出统计数据", "导出访问日志", categoryStats)
+
+	permEmbyView   = declare("emby:view", "查看同步状态", "查看Emby同步状态", categoryEmby)
+	permEmbySync   = declare("emby:sync", "执行同步", "手动触发Emby数据同步", categoryEmby)
+	permEmbyConfig = declare("emby:config", "配置Emby", "配置Emby连接参数", categoryEmby)
+
+	permCardView   = declare("cardkey:view", "查看卡密", "查看卡密列表", categoryCardKey)
+	permCardCreate = declare("cardkey:create", "生成卡密", "生成新卡密", categoryCardKey)
+	permCardEdit   = declare("cardkey:edit", "编辑卡密", "禁用、启用卡密及撤销兑换", categoryCardKey)
+	permCardDelete = declare("cardkey:delete", "删除卡密", "删除卡密", categoryCardKey)
+	permCardExport = declare("cardkey:export", "导出卡密", "导出卡密列表", categoryCardKey)
+
+	permAgentCard   = declare("agent:card", "代理商卡密", "使用额度生成并管理自己的卡密", categoryAgent)
+	permAgentManage = declare("agent:manage", "管理代理商", "为代理商充值额度并查看结算", categoryAgent)
+
+	permOrderView   = declare("order:view", "查看订单", "查看所有用户的订单", categoryOrder)
+	permOrderManage = declare("order:manage", "管理订单", "管理套餐并处理订单退款", categoryOrder)
+
+	permPointsManage = declare("points:manage", "管理积分", "管理积分商城商品并调整用户积分", categoryPoints)
+)
+
+// routePermissions 路由注册期间登记的路由权限
+type routePermissions struct {
+	routes   map[string]string // "METHOD 完整路径" -> 权限键
+	prefixes map[string]string // 整组要求权限的路由组前缀 -> 权限键
+}
+
+var registered = &routePermissions{
+	routes:   make(map[string]string),
+	prefixes: make(map[string]string),
+}
+
+// requirePermission 返回权限校验中间件，使用未声明的权限视为编程错误
+func requirePermission(key string) gin.HandlerFunc {
+	if _, ok := service.Permissions().Get(key); !ok {
+		panic(fmt.Sprintf("路由使用了未声明的权限: %s", key))
+	}
+	return middleware.PermissionMiddleware(key)
+}
+
+// securedRoutes 包装路由组：注册需要权限的路由时同时登记路由与权限的对应关系
+type securedRoutes struct {
+	group *gin.RouterGroup
+}
+
+// secure 包装路由组
+func secure(group *gin.RouterGroup) securedRoutes {
+	return securedRoutes{group: group}
+}
+
+// secureGroup 整个路由组要求同一权限
+func secureGroup(group *gin.RouterGroup, key string) {
+	group.Use(requirePermission(key))
+	registered.prefixes[group.BasePath()] = key
+}
+
+func (s securedRoutes) handle(method, relativePath, key string, handlers ...gin.HandlerFunc) {
+	chain := append([]gin.HandlerFunc{requirePermission(key)}, handlers...)
+	s.group.Handle(method, relativePath, chain...)
+	registered.routes[method+" "+joinPath(s.group.BasePath(), relativePath)] = key
+}
+
+func (s securedRoutes) GET(relativePath, key string, handlers ...gin.HandlerFunc) {
+	s.handle(http.MethodGet, relativePath, key, handlers...)
+}
+
+func (s securedRoutes) POST(relativePath, key string, handlers ...gin.HandlerFunc) {
+	s.handle(http.MethodPost, relativePath, key, handlers...)
+}
+
+func (s securedRoutes) PUT(relativePath, key string, handlers ...gin.HandlerFunc) {
+	s.handle(http.MethodPut, relativePath, key, handlers...)
+}
+
+func (s securedRoutes) DELETE(relativePath, key string, handlers ...gin.HandlerFunc) {
+	s.handle(http.MethodDelete, relativePath, key, handlers...)
+}
+
+// joinPath 拼接路由组前缀与相对路径（与 gin 的拼接规则一致）
+func joinPath(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	joined := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
+
+// collectRoutes 汇总 /api 下所有路由及其所需权限，登记到全局权限登记表
+func collectRoutes(r *gin.Engine) {
+	var routes []*model.RoutePermission
+	for _, info := range r.Routes() {
+		if !strings.HasPrefix(info.Path, "/api/") || strings.HasPrefix(info.Path, "/api/setup") {
+			continue
+		}
+		route := &model.RoutePermission{Method: info.Method, Path: info.Path}
+		key, ok := registered.routes[info.Method+" "+info.Path]
+		if !ok {
+			for prefix, prefixKey := range registered.prefixes {
+				if info.Path == prefix || strings.HasPrefix(info.Path, prefix+"/") {
+					key = prefixKey
+					break
+				}
+			}
+		}
+		if def, ok := service.Permissions().Get(key); ok {
+			route.PermissionKey = def.Key
+			route.PermissionName = def.Name
+			route.Category = def.Category
+		}
+		routes = append(routes, route)
+	}
+	service.Permissions().SetRoutes(routes)
+}
@@ -5,6 +5,7 @@ import (
 
 	"embyhub/internal/handler"
 	"embyhub/internal/middleware"
+	"embyhub/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -35,6 +36,24 @@ func SetupRouter() *gin.Engine {
 	systemConfigHandler := handler.NewSystemConfigHandler()
 	embyHandler := handler.NewEmbyHandler()
 	cardKeyHandler := handler.NewCardKeyHandler()
+	agentHandler := handler.NewAgentHandler()
+	vipTierHandler := handler.NewVipTierHandler()
+	vipHandler := handler.NewVipHandler()
+	notificationHandler := handler.NewNotificationHandler()
+	orderHandler := handler.NewOrderHandler()
+	referralHandler := handler.NewReferralHandler()
+	pointsHandler := handler.NewPointsHandler()
+	userGroupHandler := handler.NewUserGroupHandler()
+	twoFactorHandler := handler.NewTwoFactorHandler()
+	oidcHandler := handler.NewOIDCHandler()
+	magicLinkHandler := handler.NewMagicLinkHandler()
+	apiTokenHandler := handler.NewAPITokenHandler()
+
+	// 敏感操作（生成卡密、删除用户、修改系统配置等）要求已开启两步验证的用户先完成二次验证
+	stepUp := middleware.StepUpMiddleware()
+
+	// 账号安全相关操作只允许登录会话访问，个人访问令牌不可用
+	sessionOnly := middleware.SessionOnlyMiddleware()
 
 	// 初始化邮件处理器
 	emailHandler := handler.NewEmailHandler()
@@ -46,6 +65,16 @@ func SetupRouter() *gin.Engine {
 		auth := api.Group("/auth")
 		{
 			auth.POST("/login", middleware.LoginRateLimitMiddleware(), authHandler.Login)
+			auth.POST("/refresh", authHandler.RefreshToken) // 刷新令牌换取新的访问令牌
+			auth.POST("/login/2fa", middleware.LoginRateLimitMiddleware(), authHandler.LoginTwoFactor)
+			auth.POST("/login/2fa/setup", middleware.LoginRateLimitMiddleware(), authHandler.SetupTwoFactorChallenge)
+			auth.GET("/oidc/config", oidcHandler.Config) // 单点登录（OIDC）
+			auth.GET("/oidc/authorize", middleware.LoginRateLimitMiddleware(), oidcHandler.Authorize)
+			auth.POST("/oidc/callback", middleware.LoginRateLimitMiddleware(), oidcHandler.Callback)
+			auth.GET("/magic-link/config", magicLinkHandler.Config) // 邮件登录链接
+			auth.POST("/magic-link", middleware.LoginRateLimitMiddleware(), magicLinkHandler.Send)
+			auth.POST("/magic-link/login", middleware.LoginRateLimitMiddleware(), magicLinkHandler.Login)
+			auth.POST("/login-alert/revoke", middleware.LoginRateLimitMiddleware(), authHandler.RevokeFromLoginAlert) // 登录提醒中的"不是我本人"
 			auth.POST("/register", middleware.LoginRateLimitMiddleware(), registerHandler.Register)
 		}
 
@@ -62,67 +91,114 @@ func SetupRouter() *gin.Engine {
 		authorized.Use(middleware.AuthMiddleware())
 		{
 			// 认证相关
-			authorized.POST("/auth/logout", authHandler.Logout)
+			authorized.POST("/auth/logout", sessionOnly, authHandler.Logout)
 			authorized.GET("/auth/current", authHandler.GetCurrentUser)
-			authorized.PUT("/auth/password", authHandler.ChangePassword) // 用户修改自己的密码
-			authorized.POST("/auth/refresh", authHandler.RefreshToken)   // 刷新Token
+			authorized.PUT("/auth/password", sessionOnly, authHandler.ChangePassword) // 用户修改自己的密码
+			authorized.GET("/auth/sessions", sessionOnly, authHandler.ListSessions)
+			authorized.DELETE("/auth/sessions", sessionOnly, authHandler.RevokeAllSessions) // 退出所有设备
+			authorized.DELETE("/auth/sessions/:id", sessionOnly, authHandler.RevokeSession)
+			authorized.GET("/auth/login-history", sessionOnly, authHandler.LoginHistory)
+
+			// 两步验证
+			authorized.GET("/auth/2fa", sessionOnly, twoFactorHandler.Status)
+			authorized.POST("/auth/2fa/setup", sessionOnly, twoFactorHandler.Setup)
+			authorized.POST("/auth/2fa/enable", sessionOnly, twoFactorHandler.Enable)
+			authorized.POST("/auth/2fa/disable", sessionOnly, twoFactorHandler.Disable)
+			authorized.POST("/auth/2fa/recovery-codes", sessionOnly, twoFactorHandler.RegenerateRecoveryCodes)
+			authorized.POST("/auth/2fa/verify", sessionOnly, middleware.LoginRateLimitMiddleware(), twoFactorHandler.Verify)
+
+			// 个人访问令牌（供机器人和脚本使用）
+			authorized.GET("/auth/tokens", sessionOnly, apiTokenHandler.List)
+			authorized.POST("/auth/tokens", sessionOnly, apiTokenHandler.Create)
+			authorized.DELETE("/auth/tokens/:id", sessionOnly, apiTokenHandler.Revoke)
 
 			// 用户管理
 			users := authorized.Group("/users")
 			{
-				users.GET("", userHandler.List)
-				users.POST("", middleware.PermissionMiddleware("user:create"), userHandler.Create)
-				users.GET("/:id", userHandler.GetByID)
-				users.PUT("/:id", middleware.PermissionMiddleware("user:edit"), userHandler.Update)
-				users.DELETE("/:id", middleware.PermissionMiddleware("user:delete"), userHandler.Delete)
-				users.PUT("/:id/password", middleware.PermissionMiddleware("user:edit"), userHandler.ResetPassword)
-				users.PUT("/:id/vip", middleware.PermissionMiddleware("user:edit"), userHandler.SetVip)
-				users.PUT("/batch/status", middleware.PermissionMiddleware("user:edit"), userHandler.BatchUpdateStatus)
+				users.GET("", userHandler.List) // 权限由服务按管理范围校验
+				secure(users).POST("", permUserCreate, userHandler.Create)
+				users.GET("/:id", userHandler.GetByID) // 本人可直接查看
+				secure(users).PUT("/:id", permUserEdit, userHandler.Update)
+				secure(users).DELETE("/:id", permUserDelete, stepUp, userHandler.Delete)
+				secure(users).PUT("/:id/password", permUserEdit, userHandler.ResetPassword)
+				secure(users).PUT("/:id/vip", permUserEdit, userHandler.SetVip)
+				secure(users).GET("/:id/vip-history", permUserView, vipHandler.UserHistory)
+				secure(users).POST("/:id/vip/unfreeze", permUserEdit, vipHandler.UnfreezeUser)
+				secure(users).POST("/:id/points", permPointsManage, pointsHandler.Adjust)
+				secure(users).PUT("/batch/status", permUserEdit, userHandler.BatchUpdateStatus)
+				secure(users).GET("/:id/sessions", permUserView, userHandler.ListSessions)
+				secure(users).DELETE("/:id/sessions", permUserEdit, userHandler.RevokeSessions)
+				secure(users).DELETE("/:id/sessions/:session_id", permUserEdit, userHandler.RevokeSessions)
+				secure(users).DELETE("/:id/2fa", permUserEdit, stepUp, userHandler.ResetTwoFactor)
+			}
+
+			// 用户组（组管理员仅能管理被授权的用户组，具体范围由服务层校验）
+			userGroups := authorized.Group("/user-groups")
+			{
+				secure(userGroups).GET("", permUserView, userGroupHandler.List)
+				secure(userGroups).POST("", permGroup, userGroupHandler.Create)
+				secure(userGroups).PUT("/:id", permGroup, userGroupHandler.Update)
+				secure(userGroups).DELETE("/:id", permGroup, userGroupHandler.Delete)
+				secure(userGroups).GET("/:id/grants", permGroup, userGroupHandler.ListGrants)
+				secure(userGroups).POST("/:id/grants", permGroup, userGroupHandler.Grant)
+				secure(userGroups).DELETE("/:id/grants/:user_id", permGroup, userGroupHandler.Revoke)
 			}
 
 			// 角色管理
 			roles := authorized.Group("/roles")
 			{
 				roles.GET("", roleHandler.List)
-				roles.POST("", middleware.PermissionMiddleware("role:create"), roleHandler.Create)
+				secure(roles).POST("", permRoleCreate, roleHandler.Create)
 				roles.GET("/:id", roleHandler.GetByID)
-				roles.PUT("/:id", middleware.PermissionMiddleware("role:edit"), roleHandler.Update)
-				roles.DELETE("/:id", middleware.PermissionMiddleware("role:delete"), roleHandler.Delete)
-				roles.POST("/:id/permissions", middleware.PermissionMiddleware("permission:assign"), roleHandler.AssignPermissions)
+				secure(roles).PUT("/:id", permRoleEdit, roleHandler.Update)
+				secure(roles).DELETE("/:id", permRoleDelete, roleHandler.Delete)
+				secure(roles).POST("/:id/permissions", permPermAssign, roleHandler.AssignPermissions)
+				secure(roles).GET("/:id/effective-permissions", permPermView, permissionHandler.RoleEffective)
 			}
 
 			// 权限管理
 			permissions := authorized.Group("/permissions")
 			{
 				permissions.GET("", permissionHandler.List)
+				secure(permissions).GET("/check", permPermView, permissionHandler.Check)
+				secure(permissions).GET("/routes", permPermView, permissionHandler.Routes)
+			}
+
+			// 权限组
+			permissionGroups := authorized.Group("/permission-groups")
+			{
+				secure(permissionGroups).GET("", permPermView, permissionHandler.ListGroups)
+				secure(permissionGroups).POST("", permPermAssign, permissionHandler.CreateGroup)
+				secure(permissionGroups).PUT("/:id", permPermAssign, permissionHandler.UpdateGroup)
+				secure(permissionGroups).DELETE("/:id", permPermAssign, permissionHandler.DeleteGroup)
 			}
 
 			// 访问记录
 			accessRecords := authorized.Group("/access-records")
 			{
-				accessRecords.GET("", middleware.PermissionMiddleware("stats:view"), accessRecordHandler.List)
+				secure(accessRecords).GET("", permStatsView, accessRecordHandler.List)
 				accessRecords.POST("", accessRecordHandler.Create)
 			}
 
 			// 统计数据
-			authorized.GET("/statistics", middleware.PermissionMiddleware("stats:view"), accessRecordHandler.GetStatistics)
+			secure(authorized).GET("/statistics", permStatsView, accessRecordHandler.GetStatistics)
 
 			// 系统配置
 			configs := authorized.Group("/configs")
 			{
-				configs.GET("", middleware.PermissionMiddleware("system:view"), systemConfigHandler.List)
-				configs.PUT("/:key", middleware.PermissionMiddleware("system:edit"), systemConfigHandler.Update)
+				secure(configs).GET("", permSystemView, systemConfigHandler.List)
+				secure(configs).PUT("/:key", permSystemEdit, stepUp, systemConfigHandler.Update)
 			}
 
 			// 邮件测试（需要系统配置权限）
-			authorized.POST("/email/test", middleware.PermissionMiddleware("system:edit"), emailHandler.TestConfig)
+			secure(authorized).POST("/email/test", permSystemEdit, emailHandler.TestConfig)
 
 			// Emby同步
 			emby := authorized.Group("/emby")
 			{
-				emby.POST("/test", middleware.PermissionMiddleware("emby:config"), embyHandler.TestConnection)
-				emby.POST("/sync", middleware.PermissionMiddleware("emby:sync"), embyHandler.SyncUsers)
-				emby.GET("/users", middleware.PermissionMiddleware("emby:view"), embyHandler.GetUsers)
+				secure(emby).POST("/test", permEmbyConfig, embyHandler.TestConnection)
+				secure(emby).POST("/sync", permEmbySync, embyHandler.SyncUsers)
+				secure(emby).GET("/users", permEmbyView, embyHandler.GetUsers)
 			}
 
 			// 媒体库（所有登录用户可访问）
@@ -139,19 +215,124 @@ func SetupRouter() *gin.Engine {
 			// 卡密管理
 			cardKeys := authorized.Group("/card-keys")
 			{
-				cardKeys.GET("", cardKeyHandler.List)
-				cardKeys.POST("", middleware.PermissionMiddleware("cardkey:create"), cardKeyHandler.Create)
-				cardKeys.GET("/statistics", cardKeyHandler.GetStatistics)
+				secure(cardKeys).GET("", permCardView, cardKeyHandler.List)
+				secure(cardKeys).POST("", permCardCreate, stepUp, cardKeyHandler.Create)
+				secure(cardKeys).GET("/statistics", permCardView, cardKeyHandler.GetStatistics)
+				secure(cardKeys).GET("/batches", permCardView, cardKeyHandler.ListBatches)
+				secure(cardKeys).GET("/batches/:id/export", permCardExport, cardKeyHandler.ExportBatch)
 				cardKeys.POST("/use-vip", cardKeyHandler.UseVipCard) // 使用VIP升级码
-				cardKeys.GET("/:id", cardKeyHandler.GetByID)
-				cardKeys.PUT("/:id/disable", middleware.PermissionMiddleware("cardkey:edit"), cardKeyHandler.Disable)
-				cardKeys.PUT("/:id/enable", middleware.PermissionMiddleware("cardkey:edit"), cardKeyHandler.Enable)
-				cardKeys.DELETE("/:id", middleware.PermissionMiddleware("cardkey:delete"), cardKeyHandler.Delete)
+				secure(cardKeys).GET("/:id", permCardView, cardKeyHandler.GetByID)
+				secure(cardKeys).PUT("/:id/disable", permCardEdit, cardKeyHandler.Disable)
+				secure(cardKeys).PUT("/:id/enable", permCardEdit, cardKeyHandler.Enable)
+				secure(cardKeys).POST("/:id/revoke", permCardEdit, cardKeyHandler.Revoke)
+				secure(cardKeys).GET("/:id/redemptions", permCardView, cardKeyHandler.ListRedemptions)
+				secure(cardKeys).DELETE("/:id", permCardDelete, cardKeyHandler.Delete)
+			}
+
+			// VIP历史、冻结与转赠
+			authorized.GET("/vip/history", vipHandler.MyHistory)
+			authorized.POST("/vip/freeze", vipHandler.Freeze)
+			authorized.POST("/vip/unfreeze", vipHandler.Unfreeze)
+			authorized.GET("/vip/freezes", vipHandler.MyFreezes)
+			authorized.POST("/vip/transfer/code", sessionOnly, middleware.LoginRateLimitMiddleware(), vipHandler.SendTransferCode)
+			authorized.POST("/vip/transfer", sessionOnly, vipHandler.Transfer)
+			authorized.GET("/vip/transfers", vipHandler.MyTransfers)
+
+			// 通知
+			notifications := authorized.Group("/notifications")
+			{
+				secure(notifications).GET("/logs", permSystemView, notificationHandler.ListLogs)
+				notifications.PUT("/preferences", notificationHandler.UpdatePreferences)
+			}
+
+			// VIP等级
+			vipTiers := authorized.Group("/vip-tiers")
+			{
+				vipTiers.GET("", vipTierHandler.List)
+				secure(vipTiers).POST("", permSystemEdit, vipTierHandler.Create)
+				secure(vipTiers).PUT("/:level", permSystemEdit, vipTierHandler.Update)
+				secure(vipTiers).DELETE("/:level", permSystemEdit, vipTierHandler.Delete)
+			}
+
+			// 代理商（仅能操作自己生成的卡密）
+			agent := authorized.Group("/agent")
+			secureGroup(agent, permAgentCard)
+			{
+				agent.GET("/quota", agentHandler.GetQuota)
+				agent.GET("/quota/ledger", agentHandler.ListLedger)
+				agent.POST("/card-keys", agentHandler.GenerateCards)
+				agent.GET("/card-keys", agentHandler.ListCards)
+				agent.GET("/card-keys/statistics", agentHandler.GetStatistics)
+				agent.GET("/card-keys/:id", agentHandler.GetCard)
+				agent.PUT("/card-keys/:id/disable", agentHandler.DisableCard)
+				agent.PUT("/card-keys/:id/enable", agentHandler.EnableCard)
+				agent.DELETE("/card-keys/:id", agentHandler.DeleteCard)
+				agent.GET("/batches", agentHandler.ListBatches)
+				agent.GET("/batches/:id/export", agentHandler.ExportBatch)
+				agent.GET("/redemptions", agentHandler.ListRedemptions)
+				agent.GET("/settlement", agentHandler.GetSettlement)
+			}
+
+			// 代理商管理
+			agents := authorized.Group("/agents")
+			secureGroup(agents, permAgentManage)
+			{
+				agents.GET("", agentHandler.ListAgents)
+				agents.POST("/:id/quota", agentHandler.AdjustQuota)
+				agents.GET("/:id/ledger", agentHandler.ListAgentLedger)
+				agents.GET("/:id/settlement", agentHandler.GetAgentSettlement)
+			}
+
+			// 套餐
+			plans := authorized.Group("/plans")
+			{
+				plans.GET("", orderHandler.ListPlans)
+				secure(plans).GET("/all", permOrderManage, orderHandler.ListAllPlans)
+				secure(plans).POST("", permOrderManage, orderHandler.CreatePlan)
+				secure(plans).PUT("/:id", permOrderManage, orderHandler.UpdatePlan)
+				secure(plans).DELETE("/:id", permOrderManage, orderHandler.DeletePlan)
+			}
+
+			// 订单
+			orders := authorized.Group("/orders")
+			{
+				orders.POST("", orderHandler.Create)
+				orders.GET("/my", orderHandler.MyOrders)
+				orders.GET("/:order_no", orderHandler.Get)
+				if service.MockPaymentEnabled() {
+					orders.POST("/:order_no/mock-pay", orderHandler.MockPay) // 本地模拟支付（仅开发和测试时启用）
+				}
+				secure(orders).GET("", permOrderView, orderHandler.List)
+				secure(orders).POST("/:order_no/refund", permOrderManage, orderHandler.Refund)
+			}
+
+			// 推荐
+			referral := authorized.Group("/referral")
+			{
+				referral.GET("", referralHandler.Summary)
+				referral.GET("/invitees", referralHandler.ListMine)
+				referral.GET("/leaderboard", referralHandler.Leaderboard)
+			}
+
+			// 签到与积分商城
+			points := authorized.Group("/points")
+			{
+				points.GET("", pointsHandler.Summary)
+				points.POST("/checkin", pointsHandler.Checkin)
+				points.GET("/ledger", pointsHandler.Ledger)
+				points.GET("/items", pointsHandler.ListItems)
+				points.POST("/items/:id/redeem", pointsHandler.Redeem)
+				secure(points).GET("/items/all", permPointsManage, pointsHandler.ListAllItems)
+				secure(points).POST("/items", permPointsManage, pointsHandler.CreateItem)
+				secure(points).PUT("/items/:id", permPointsManage, pointsHandler.UpdateItem)
+				secure(points).DELETE("/items/:id", permPointsManage, pointsHandler.DeleteItem)
 			}
 		}
 
 		// 公开接口（无需认证）
 		api.POST("/card-keys/validate", cardKeyHandler.Validate)
+		api.POST("/payments/callback/:gateway", orderHandler.Callback) // 支付回调（签名校验）
+		api.GET("/payments/callback/:gateway", orderHandler.Callback)  // 部分平台（如易支付）以GET方式通知
 	}
 
 	// 静态文件服务（支持多种运行环境）
@@ -171,5 +352,8 @@ func SetupRouter() *gin.Engine {
 		})
 	}
 
+	// 汇总路由所需权限，供管理端按路由生成菜单
+	collectRoutes(r)
+
 	return r
 }
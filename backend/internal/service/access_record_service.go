@@ -118,24 +118,44 @@ func (s *AccessRecordService) getVipStatistics() *model.VipStatistics {
 
 	// 有效VIP总数
 	database.DB.Model(&model.User{}).
-		Where("vip_level = 1 AND vip_expire_at > ?", now).
+		Where("vip_level > 0 AND vip_expire_at > ? AND vip_frozen_at IS NULL", now).
 		Count(&stats.TotalVip)
 
-	// 已过期VIP
+	// 已过期VIP（含已降为免费等级的用户）
 	database.DB.Model(&model.User{}).
-		Where("vip_level = 1 AND vip_expire_at <= ?", now).
+		Where("vip_expire_at IS NOT NULL AND vip_expire_at <= ? AND vip_frozen_at IS NULL", now).
 		Count(&stats.ExpiredVip)
 
+	// 冻结中
+	database.DB.Model(&model.User{}).
+		Where("vip_frozen_at IS NOT NULL").
+		Count(&stats.FrozenVip)
+
 	// 3天内到期
 	database.DB.Model(&model.User{}).
-		Where("vip_level = 1 AND vip_expire_at > ? AND vip_expire_at <= ?", now, now.AddDate(0, 0, 3)).
+		Where("vip_level > 0 AND vip_expire_at > ? AND vip_expire_at <= ? AND vip_frozen_at IS NULL", now, now.AddDate(0, 0, 3)).
 		Count(&stats.Expiring3Day)
 
 	// 7天内到期
 	database.DB.Model(&model.User{}).
-		Where("vip_level = 1 AND vip_expire_at > ? AND vip_expire_at <= ?", now, now.AddDate(0, 0, 7)).
+		Where("vip_level > 0 AND vip_expire_at > ? AND vip_expire_at <= ? AND vip_frozen_at IS NULL", now, now.AddDate(0, 0, 7)).
 		Count(&stats.Expiring7Day)
 
+	// 各等级有效VIP数
+	var levels []struct {
+		VipLevel int
+		Count    int64
+	}
+	database.DB.Model(&model.User{}).
+		Select("vip_level, count(*) as count").
+		Where("vip_level > 0 AND vip_expire_at > ? AND vip_frozen_at IS NULL", now).
+		Group("vip_level").
+		Scan(&levels)
+	stats.ByLevel = make(map[int]int64, len(levels))
+	for _, l := range levels {
+		stats.ByLevel[l.VipLevel] = l.Count
+	}
+
 	return stats
 }
 
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// AgentService 代理商服务
+// 代理商使用充值的额度（VIP天数）生成卡密，只能查看和操作自己生成的卡密
+type AgentService struct {
+	agentDAO          *dao.AgentDAO
+	cardKeyDAO        *dao.CardKeyDAO
+	cardBatchDAO      *dao.CardBatchDAO
+	cardRedemptionDAO *dao.CardRedemptionDAO
+	userDAO           *dao.UserDAO
+	cardKeyService    *CardKeyService
+	vipTierService    *VipTierService
+}
+
+func NewAgentService() *AgentService {
+	return &AgentService{
+		agentDAO:          dao.NewAgentDAO(),
+		cardKeyDAO:        dao.NewCardKeyDAO(),
+		cardBatchDAO:      dao.NewCardBatchDAO(),
+		cardRedemptionDAO: dao.NewCardRedemptionDAO(),
+		userDAO:           dao.NewUserDAO(),
+		cardKeyService:    NewCardKeyService(),
+		vipTierService:    NewVipTierService(),
+	}
+}
+
+// GetQuota 获取代理商额度（未充值过时返回零额度）
+func (s *AgentService) GetQuota(agentID int) (*model.AgentQuota, error) {
+	quota, err := s.agentDAO.GetQuota(agentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.AgentQuota{UserID: agentID}, nil
+	}
+	return quota, err
+}
+
+// quotaCost 计算生成卡密消耗的额度
+// 额度以1级VIP天数计，高等级卡密按 DayValue 比例折算（向上取整）
+func (s *AgentService) quotaCost(req *model.CardKeyCreateRequest) (int, error) {
+	if req.VipLevel == 0 {
+		req.VipLevel = 1
+	}
+	tier, err := s.vipTierService.GetGrantable(req.VipLevel)
+	if err != nil {
+		return 0, err
+	}
+	base, err := s.vipTierService.Get(1)
+	if err != nil || base.DayValue <= 0 {
+		return req.Count * req.Duration, nil
+	}
+	value := req.Count * req.Duration * tier.DayValue
+	return (value + base.DayValue - 1) / base.DayValue, nil
+}
+
+// GenerateCards 代理商生成卡密，按 数量×天数（按等级折算）扣减额度
+// 额度扣减与批次、卡密写入在同一事务中，任一失败整体回滚
+func (s *AgentService) GenerateCards(req *model.CardKeyCreateRequest, agentID int) ([]*model.CardKey, error) {
+	req.GroupID = nil // 代理商卡密不归属用户组
+	cost, err := s.quotaCost(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cardKeys, err := s.cardKeyService.CreateWithHook(req, agentID, func(tx *gorm.DB, batch *model.CardBatch) error {
+		return s.agentDAO.ApplyTx(tx, &model.AgentQuotaLedger{
+			AgentID:    agentID,
+			Delta:      -cost,
+			Reason:     model.QuotaReasonGenerate,
+			BatchID:    &batch.ID,
+			OperatorID: &agentID,
+			Remark:     fmt.Sprintf("生成%d张%d级%d天卡密", req.Count, req.VipLevel, req.Duration),
+		})
+	})
+	if errors.Is(err, dao.ErrAgentQuotaInsufficient) {
+		quota, _ := s.GetQuota(agentID)
+		balance := 0
+		if quota != nil {
+			balance = quota.Balance
+		}
+		return nil, fmt.Errorf("额度不足：需要%d天，剩余%d天", cost, balance)
+	}
+	return cardKeys, err
+}
+
+// getOwnCard 获取代理商自己的卡密，不属于该代理商时与不存在同样处理
+func (s *AgentService) getOwnCard(agentID, cardKeyID int) (*model.CardKey, error) {
+	cardKey, err := s.cardKeyDAO.GetByID(cardKeyID)
+	if err != nil || cardKey.CreatedBy != agentID {
+		return nil, errors.New("卡密不存在")
+	}
+	return cardKey, nil
+}
+
+// ListCards 获取代理商自己的卡密列表
+func (s *AgentService) ListCards(agentID int, req *model.CardKeyListRequest) (*model.CardKeyListResponse, error) {
+	req.CreatedBy = agentID
+	return s.cardKeyService.List(req)
+}
+
+// GetCard 获取代理商自己的卡密详情
+func (s *AgentService) GetCard(agentID, cardKeyID int) (*model.CardKey, error) {
+	return s.getOwnCard(agentID, cardKeyID)
+}
+
+// DisableCard 禁用代理商自己的卡密
+func (s *AgentService) DisableCard(agentID, cardKeyID int) error {
+	if _, err := s.getOwnCard(agentID, cardKeyID); err != nil {
+		return err
+	}
+	return s.cardKeyService.Disable(cardKeyID)
+}
+
+// EnableCard 启用代理商自己的卡密
+func (s *AgentService) EnableCard(agentID, cardKeyID int) error {
+	if _, err := s.getOwnCard(agentID, cardKeyID); err != nil {
+		return err
+	}
+	return s.cardKeyService.Enable(cardKeyID)
+}
+
+// DeleteCard 删除代理商自己未使用的卡密，并退还对应额度
+func (s *AgentService) DeleteCard(agentID, cardKeyID int) error {
+	cardKey, err := s.getOwnCard(agentID, cardKeyID)
+	if err != nil {
+		return err
+	}
+
+	refund := cardKey.Duration
+	if tier, err := s.vipTierService.Get(cardKey.VipLevel); err == nil {
+		if base, err := s.vipTierService.Get(1); err == nil && base.DayValue > 0 {
+			// 按生成时的折算方式退还（单张向下取整，不多退）
+			refund = cardKey.Duration * tier.DayValue / base.DayValue
+		}
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		deleted, err := s.cardKeyDAO.DeleteUnusedTx(tx, cardKey.ID)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return errors.New("卡密已被使用，无法删除")
+		}
+		return s.agentDAO.ApplyTx(tx, &model.AgentQuotaLedger{
+			AgentID:    agentID,
+			Delta:      refund,
+			Reason:     model.QuotaReasonRefund,
+			BatchID:    cardKey.BatchID,
+			CardKeyID:  &cardKey.ID,
+			OperatorID: &agentID,
+			Remark:     "删除未使用卡密退还",
+		})
+	})
+}
+
+// ListBatches 获取代理商自己的批次列表
+func (s *AgentService) ListBatches(agentID int, req *model.CardBatchListRequest) (*model.CardBatchListResponse, error) {
+	req.CreatedBy = agentID
+	return s.cardKeyService.ListBatches(req)
+}
+
+// ExportBatch 导出代理商自己的批次
+func (s *AgentService) ExportBatch(agentID, batchID int) (*model.CardBatch, []*model.CardKey, error) {
+	batch, cardKeys, err := s.cardKeyService.ExportBatch(batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if batch.CreatedBy != agentID {
+		return nil, nil, errors.New("批次不存在")
+	}
+	return batch, cardKeys, nil
+}
+
+// ListRedemptions 获取代理商卡密的兑换记录
+func (s *AgentService) ListRedemptions(agentID int, req *model.CardRedemptionListRequest) (*model.CardRedemptionListResponse, error) {
+	req.CreatedBy = agentID
+	redemptions, total, err := s.cardRedemptionDAO.List(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CardRedemptionListResponse{
+		Total: int(total),
+		List:  redemptions,
+	}, nil
+}
+
+// GetStatistics 获取代理商卡密统计
+func (s *AgentService) GetStatistics(agentID int) (map[string]interface{}, error) {
+	stats, err := s.cardKeyService.GetStatistics(agentID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := s.GetQuota(agentID)
+	if err != nil {
+		return nil, err
+	}
+	stats["quota_balance"] = quota.Balance
+	return stats, nil
+}
+
+// ListLedger 获取代理商额度流水
+func (s *AgentService) ListLedger(agentID int, req *model.AgentLedgerListRequest) (*model.AgentLedgerListResponse, error) {
+	entries, total, err := s.agentDAO.ListLedger(agentID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AgentLedgerListResponse{
+		Total: int(total),
+		List:  entries,
+	}, nil
+}
+
+// GetSettlement 获取代理商在指定时间段内的结算报表（结束日期包含当天）
+func (s *AgentService) GetSettlement(agentID int, req *model.AgentSettlementRequest) (*model.AgentSettlement, error) {
+	start := req.StartTime
+	end := req.EndTime.AddDate(0, 0, 1)
+	if !end.After(start) {
+		return nil, errors.New("结束日期不能早于开始日期")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, errors.New("结算周期不能超过一年")
+	}
+
+	report := &model.AgentSettlement{
+		AgentID:     agentID,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+
+	var err error
+	if report.CardsGenerated, report.DaysGenerated, err = s.cardKeyDAO.SumByCreator(agentID, "created_at", start, end); err != nil {
+		return nil, err
+	}
+	if report.CardsRedeemed, report.DaysRedeemed, err = s.cardKeyDAO.SumByCreator(agentID, "used_at", start, end); err != nil {
+		return nil, err
+	}
+	if report.CardsRevoked, report.DaysRevoked, err = s.cardRedemptionDAO.SumRevokedByCreator(agentID, start, end); err != nil {
+		return nil, err
+	}
+
+	sums, err := s.agentDAO.SumLedgerByReason(agentID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	report.QuotaTopUp = sums[model.QuotaReasonTopUp] + sums[model.QuotaReasonAdjust]
+	report.QuotaConsumed = -sums[model.QuotaReasonGenerate]
+	report.QuotaRefunded = sums[model.QuotaReasonRefund]
+
+	quota, err := s.GetQuota(agentID)
+	if err != nil {
+		return nil, err
+	}
+	report.BalanceEnd = quota.Balance
+
+	return report, nil
+}
+
+// ListAgents 获取代理商列表
+func (s *AgentService) ListAgents(page, pageSize int) (*model.AgentListResponse, error) {
+	quotas, total, err := s.agentDAO.List(page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AgentListResponse{
+		Total: int(total),
+		List:  quotas,
+	}, nil
+}
+
+// AdjustQuota 管理员为代理商充值或扣减额度
+func (s *AgentService) AdjustQuota(agentID int, req *model.AgentQuotaAdjustRequest, operatorID int, operatorName, ip, ua string) (*model.AgentQuota, error) {
+	if _, err := s.userDAO.GetByID(agentID); err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	reason := model.QuotaReasonTopUp
+	if req.Delta < 0 {
+		reason = model.QuotaReasonAdjust
+	}
+
+	entry := &model.AgentQuotaLedger{
+		AgentID:    agentID,
+		Delta:      req.Delta,
+		Reason:     reason,
+		OperatorID: &operatorID,
+		Remark:     req.Remark,
+	}
+	err := s.agentDAO.Apply(entry)
+	if errors.Is(err, dao.ErrAgentQuotaInsufficient) {
+		err = errors.New("扣减后额度不能为负数")
+	}
+
+	detail := map[string]interface{}{"delta": req.Delta, "remark": req.Remark}
+	if err != nil {
+		detail["error"] = err.Error()
+		Audit(&operatorID, operatorName, model.ActionAgentQuota, model.TargetAgent, fmt.Sprint(agentID),
+			detail, ip, ua, "failed")
+		return nil, err
+	}
+
+	detail["balance_after"] = entry.BalanceAfter
+	Audit(&operatorID, operatorName, model.ActionAgentQuota, model.TargetAgent, fmt.Sprint(agentID),
+		detail, ip, ua, "success")
+
+	return s.GetQuota(agentID)
+}
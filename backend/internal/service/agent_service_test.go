@@ -0,0 +1,138 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+// setupAgentTest 准备VIP等级和一个充值了指定额度的代理商
+func setupAgentTest(t *testing.T, balance int) (*AgentService, *model.User) {
+	t.Helper()
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	createTestTier(t, 2, 150)
+
+	agent := createTestUser(t, "agent")
+	s := NewAgentService()
+	if _, err := s.AdjustQuota(agent.UserID, &model.AgentQuotaAdjustRequest{Delta: balance}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("充值额度失败: %v", err)
+	}
+	return s, agent
+}
+
+func assertQuota(t *testing.T, s *AgentService, agentID, balance, consumed int) {
+	t.Helper()
+	quota, err := s.GetQuota(agentID)
+	if err != nil {
+		t.Fatalf("获取额度失败: %v", err)
+	}
+	if quota.Balance != balance || quota.TotalConsumed != consumed {
+		t.Fatalf("额度应为余额%d、消耗%d，实际为余额%d、消耗%d", balance, consumed, quota.Balance, quota.TotalConsumed)
+	}
+}
+
+func countCards(t *testing.T, agentID int) (cards, batches int64) {
+	t.Helper()
+	database.DB.Model(&model.CardKey{}).Where("created_by = ?", agentID).Count(&cards)
+	database.DB.Model(&model.CardBatch{}).Where("created_by = ?", agentID).Count(&batches)
+	return cards, batches
+}
+
+func TestAgentQuotaExhausted(t *testing.T) {
+	s, agent := setupAgentTest(t, 100)
+
+	if _, err := s.GenerateCards(&model.CardKeyCreateRequest{Count: 3, CardType: 1, Duration: 30}, agent.UserID); err != nil {
+		t.Fatalf("生成卡密失败: %v", err)
+	}
+	assertQuota(t, s, agent.UserID, 10, 90)
+
+	// 额度不足：整批不生成，余额不变
+	_, err := s.GenerateCards(&model.CardKeyCreateRequest{Count: 1, CardType: 1, Duration: 30}, agent.UserID)
+	if err == nil || !strings.Contains(err.Error(), "需要30天，剩余10天") {
+		t.Fatalf("额度不足时应拒绝生成: %v", err)
+	}
+	assertQuota(t, s, agent.UserID, 10, 90)
+	if cards, batches := countCards(t, agent.UserID); cards != 3 || batches != 1 {
+		t.Fatalf("额度不足时不应写入卡密或批次: %d张 %d批", cards, batches)
+	}
+
+	// 扣减后额度不能为负
+	if _, err := s.AdjustQuota(agent.UserID, &model.AgentQuotaAdjustRequest{Delta: -11}, 1, "admin", "", ""); err == nil {
+		t.Fatal("扣减超过余额时应拒绝")
+	}
+	assertQuota(t, s, agent.UserID, 10, 90)
+}
+
+func TestAgentQuotaTierConversion(t *testing.T) {
+	s, agent := setupAgentTest(t, 100)
+
+	// 2级卡密按价值折算：7天×150/100=10.5，向上取整扣11天
+	cards, err := s.GenerateCards(&model.CardKeyCreateRequest{Count: 1, CardType: 1, Duration: 7, VipLevel: 2}, agent.UserID)
+	if err != nil {
+		t.Fatalf("生成卡密失败: %v", err)
+	}
+	assertQuota(t, s, agent.UserID, 89, 11)
+
+	// 删除未使用的卡密按向下取整退还10天，不多退
+	if err := s.DeleteCard(agent.UserID, cards[0].ID); err != nil {
+		t.Fatalf("删除卡密失败: %v", err)
+	}
+	assertQuota(t, s, agent.UserID, 99, 1)
+
+	// 已使用的卡密不能删除，也不退还额度
+	cards, _ = s.GenerateCards(&model.CardKeyCreateRequest{Count: 1, CardType: 1, Duration: 10}, agent.UserID)
+	if _, err := NewCardKeyService().UseVipCard(cards[0].CardCode, createTestUser(t, "alice").UserID); err != nil {
+		t.Fatalf("兑换卡密失败: %v", err)
+	}
+	if err := s.DeleteCard(agent.UserID, cards[0].ID); err == nil {
+		t.Fatal("已使用的卡密不应能删除")
+	}
+	assertQuota(t, s, agent.UserID, 89, 11)
+
+	// 其他代理商的卡密视为不存在
+	other := createTestUser(t, "other")
+	if err := s.DeleteCard(other.UserID, cards[0].ID); err == nil || err.Error() != "卡密不存在" {
+		t.Fatalf("不应能操作其他代理商的卡密: %v", err)
+	}
+}
+
+func TestAgentQuotaConcurrentGenerate(t *testing.T) {
+	s, agent := setupAgentTest(t, 100)
+
+	// 并发生成时额度不能超扣：100天额度最多生成3批30天卡密
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.GenerateCards(&model.CardKeyCreateRequest{Count: 1, CardType: 1, Duration: 30}, agent.UserID); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 3 {
+		t.Fatalf("应恰好成功3次: %d", succeeded)
+	}
+	assertQuota(t, s, agent.UserID, 10, 90)
+	if cards, batches := countCards(t, agent.UserID); cards != 3 || batches != 3 {
+		t.Fatalf("卡密和批次数量应与成功次数一致: %d张 %d批", cards, batches)
+	}
+
+	var ledgerSum int
+	database.DB.Model(&model.AgentQuotaLedger{}).Where("agent_id = ?", agent.UserID).
+		Select("COALESCE(SUM(delta), 0)").Scan(&ledgerSum)
+	if ledgerSum != 10 {
+		t.Fatalf("额度流水合计应等于余额: %d", ledgerSum)
+	}
+}
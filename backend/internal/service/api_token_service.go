@@ -0,0 +1,248 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/redis"
+)
+
+// 访问令牌相关错误
+var (
+	ErrAPITokenInvalid  = errors.New("访问令牌无效或已过期")
+	ErrAPITokenIPDenied = errors.New("访问令牌不允许从当前IP使用")
+)
+
+// 访问令牌配置
+const (
+	defaultMaxAPITokens = 10          // 每个用户默认最多保留的有效令牌数
+	apiTokenTouchEvery  = time.Minute // 最近使用时间的最小更新间隔
+)
+
+// APITokenService 个人访问令牌服务
+// 令牌原文只在创建时返回一次，服务端只保存摘要；令牌范围必须是所有者当前权限的子集，
+// 使用时再次按所有者当前权限校验，所有者权限被收回后令牌随之失去对应访问能力
+type APITokenService struct {
+	tokenDAO     *dao.APITokenDAO
+	userDAO      *dao.UserDAO
+	configDAO    *dao.SystemConfigDAO
+	scopeService *ScopeService
+}
+
+func NewAPITokenService() *APITokenService {
+	return &APITokenService{
+		tokenDAO:     dao.NewAPITokenDAO(),
+		userDAO:      dao.NewUserDAO(),
+		configDAO:    dao.NewSystemConfigDAO(),
+		scopeService: NewScopeService(),
+	}
+}
+
+// maxTokens 每个用户最多保留的有效令牌数
+func (s *APITokenService) maxTokens() int {
+	if cfg, err := s.configDAO.Get("api_token_max_per_user"); err == nil {
+		if v, err := strconv.Atoi(strings.TrimSpace(cfg.ConfigValue)); err == nil && v > 0 {
+			return v
+		}
+	}
+	return defaultMaxAPITokens
+}
+
+// Create 为用户创建访问令牌，返回令牌原文（只返回这一次）
+func (s *APITokenService) Create(userID, roleID int, req *model.APITokenCreateRequest, ip, ua string) (*model.APITokenCreateResponse, error) {
+	scopes, err := s.checkScopes(userID, roleID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.tokenDAO.CountActive(userID)
+	if err != nil {
+		return nil, err
+	}
+	if limit := s.maxTokens(); count >= int64(limit) {
+		return nil, fmt.Errorf("最多只能保留%d个有效的访问令牌，请先撤销不再使用的令牌", limit)
+	}
+
+	secret, err := util.RandomToken(24)
+	if err != nil {
+		return nil, fmt.Errorf("生成访问令牌失败: %w", err)
+	}
+	raw := model.APITokenPrefix + secret
+
+	token := &model.APIToken{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		TokenPrefix: raw[:len(model.APITokenPrefix)+8],
+		TokenHash:   util.HashToken(raw),
+		Scopes:      strings.Join(scopes, ","),
+		AllowedIPs:  strings.Join(allowedIPs, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.tokenDAO.Create(token); err != nil {
+		return nil, fmt.Errorf("创建访问令牌失败: %w", err)
+	}
+
+	username := ""
+	if user, err := s.userDAO.GetByID(userID); err == nil {
+		username = user.Username
+	}
+	Audit(&userID, username, model.ActionCreateToken, model.TargetAPIToken, fmt.Sprint(token.ID),
+		map[string]interface{}{"name": token.Name, "scopes": scopes, "allowed_ips": allowedIPs, "expires_at": token.ExpiresAt}, ip, ua, "success")
+
+	return &model.APITokenCreateResponse{Token: raw, APIToken: token}, nil
+}
+
+// checkScopes 校验令牌范围：必须是已声明的权限且所有者当前拥有，或为个人接口范围
+func (s *APITokenService) checkScopes(userID, roleID int, requested []string) ([]string, error) {
+	seen := make(map[string]bool, len(requested))
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		if scope != model.APITokenScopeSelf {
+			if _, ok := Permissions().Get(scope); !ok {
+				return nil, fmt.Errorf("未知的权限: %s", scope)
+			}
+			allowed, err := s.scopeService.HasPermission(userID, roleID, scope)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, fmt.Errorf("不能授予自己没有的权限: %s", scope)
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("请至少选择一个权限")
+	}
+	return scopes, nil
+}
+
+// normalizeAllowedIPs 校验并规范化IP白名单，支持单个IP和CIDR网段
+func normalizeAllowedIPs(items []string) ([]string, error) {
+	var allowed []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			_, network, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, fmt.Errorf("无效的IP网段: %s", item)
+			}
+			allowed = append(allowed, network.String())
+			continue
+		}
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return nil, fmt.Errorf("无效的IP地址: %s", item)
+		}
+		allowed = append(allowed, ip.String())
+	}
+	return allowed, nil
+}
+
+// ipAllowed 判断IP是否在白名单内，白名单为空表示不限
+func ipAllowed(allowed []string, ipStr string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, item := range allowed {
+		if strings.Contains(item, "/") {
+			if _, network, err := net.ParseCIDR(item); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if other := net.ParseIP(item); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// List 获取用户未撤销的访问令牌
+func (s *APITokenService) List(userID int) ([]*model.APIToken, error) {
+	return s.tokenDAO.ListByUser(userID)
+}
+
+// Revoke 撤销用户的指定访问令牌
+func (s *APITokenService) Revoke(userID int, id int64, ip, ua string) error {
+	revoked, err := s.tokenDAO.Revoke(userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("访问令牌不存在或已撤销")
+	}
+
+	username := ""
+	if user, err := s.userDAO.GetByID(userID); err == nil {
+		username = user.Username
+	}
+	Audit(&userID, username, model.ActionRevokeToken, model.TargetAPIToken, fmt.Sprint(id), nil, ip, ua, "success")
+	return nil
+}
+
+// Authenticate 校验访问令牌，返回令牌及其所有者
+// 令牌已撤销、已过期、IP不在白名单内、所有者已被禁用或须先修改密码时拒绝
+func (s *APITokenService) Authenticate(raw, ip string) (*model.APIToken, *model.User, error) {
+	token, err := s.tokenDAO.GetByHash(util.HashToken(raw))
+	if err != nil {
+		return nil, nil, ErrAPITokenInvalid
+	}
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)) {
+		return nil, nil, ErrAPITokenInvalid
+	}
+	if !ipAllowed(token.AllowedIPList(), ip) {
+		return nil, nil, ErrAPITokenIPDenied
+	}
+
+	user, err := s.userDAO.GetByID(token.UserID)
+	if err != nil || user.Status != 1 {
+		return nil, nil, ErrAPITokenInvalid
+	}
+	if user.MustChangePassword {
+		return nil, nil, errors.New("账号须先修改密码，访问令牌暂不可用")
+	}
+
+	s.touch(token.ID, ip)
+	return token, user, nil
+}
+
+// touch 更新令牌最近使用时间和IP，同一令牌每分钟最多写一次数据库
+func (s *APITokenService) touch(id int64, ip string) {
+	if ok, err := redis.SetNX(fmt.Sprintf("emby_ums:api_token:seen:%d", id), 1, apiTokenTouchEvery); err != nil || !ok {
+		return
+	}
+	if err := s.tokenDAO.Touch(id, ip); err != nil {
+		util.Warn(fmt.Sprintf("更新访问令牌使用时间失败: %v", err))
+	}
+}
+
+// RevokeAll 撤销用户的全部访问令牌（账号疑似被盗时调用）
+func (s *APITokenService) RevokeAll(userID int) (int64, error) {
+	return s.tokenDAO.RevokeAllByUser(userID)
+}
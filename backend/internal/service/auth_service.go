@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"embyhub/config"
@@ -14,12 +15,20 @@ import (
 )
 
 type AuthService struct {
-	userDAO *dao.UserDAO
+	userDAO           *dao.UserDAO
+	sessionService    *SessionService
+	twoFactorService  *TwoFactorService
+	loginAlertService *LoginAlertService
+	passwordService   *PasswordPolicyService
 }
 
 func NewAuthService() *AuthService {
 	return &AuthService{
-		userDAO: dao.NewUserDAO(),
+		userDAO:           dao.NewUserDAO(),
+		sessionService:    NewSessionService(),
+		twoFactorService:  NewTwoFactorService(),
+		loginAlertService: NewLoginAlertService(),
+		passwordService:   NewPasswordPolicyService(),
 	}
 }
 
@@ -29,8 +38,9 @@ const (
 	lockDuration     = 15 * time.Minute // 锁定时长
 )
 
-// Login 用户登录
-func (s *AuthService) Login(req *model.LoginRequest) (*model.LoginResponse, error) {
+// Login 用户登录，成功后为当前设备创建会话
+// 已开启两步验证（或角色要求开启）的用户只返回登录挑战，需通过 LoginTwoFactor 完成第二步
+func (s *AuthService) Login(req *model.LoginRequest, ip, ua string) (*model.LoginResponse, error) {
 	// 检查账号是否被锁定
 	lockKey := fmt.Sprintf("emby_ums:login:lock:%s", req.Username)
 	if locked, _ := redis.ExistsKey(lockKey); locked {
@@ -41,7 +51,7 @@ func (s *AuthService) Login(req *model.LoginRequest) (*model.LoginResponse, erro
 	// 查询用户
 	user, err := s.userDAO.GetByUsername(req.Username)
 	if err != nil {
-		s.recordLoginFailure(req.Username, "", "")
+		s.recordLoginFailure(req.Username, ip, ua)
 		return nil, errors.New("用户名或密码错误")
 	}
 
@@ -52,28 +62,148 @@ func (s *AuthService) Login(req *model.LoginRequest) (*model.LoginResponse, erro
 
 	// 验证密码
 	if !util.CheckPassword(req.Password, user.PasswordHash) {
-		s.recordLoginFailure(req.Username, "", "")
+		s.recordLoginFailure(req.Username, ip, ua)
 		return nil, errors.New("用户名或密码错误")
 	}
 
 	// 登录成功，清除失败记录
 	s.clearLoginFailure(req.Username)
 
-	// 生成Token
-	token, err := util.GenerateToken(user.UserID, user.Username, user.RoleID)
+	return s.loginUser(user, &model.SessionMeta{
+		Device:            req.Device,
+		DeviceFingerprint: req.DeviceFingerprint,
+		IP:                ip,
+		UserAgent:         ua,
+		Method:            model.LoginMethodPassword,
+	})
+}
+
+// loginUser 第一步认证（密码或单点登录）通过后继续登录：
+// 需要两步验证时返回登录挑战，否则直接创建会话
+func (s *AuthService) loginUser(user *model.User, meta *model.SessionMeta) (*model.LoginResponse, error) {
+	enabled, err := s.twoFactorService.Enabled(user.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("生成Token失败: %w", err)
+		return nil, fmt.Errorf("查询两步验证状态失败: %w", err)
 	}
+	if enabled || s.twoFactorService.Required(user) {
+		challenge, err := s.twoFactorService.CreateChallenge(user.UserID, meta)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResponse{
+			TwoFactorRequired: true,
+			TwoFactorSetup:    !enabled,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	tokens, err := s.completeLogin(user, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResponse{
+		TokenResponse: tokens,
+		UserInfo:      user,
+	}, nil
+}
 
-	// 将Token存入Redis
-	tokenKey := fmt.Sprintf("emby_ums:jwt:token:%d", user.UserID)
-	if err := redis.Set(tokenKey, token, 24*time.Hour); err != nil {
-		util.Warn("存储Token到Redis失败")
+// SetupTwoFactorChallenge 登录过程中为角色要求两步验证但尚未开启的用户生成绑定信息
+func (s *AuthService) SetupTwoFactorChallenge(challengeToken string) (*model.TwoFactorSetupResponse, error) {
+	userID, _, err := s.twoFactorService.LoadChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactorService.Setup(userID)
+}
+
+// LoginTwoFactor 登录第二步：校验验证码或恢复码后创建会话
+// 尚未开启两步验证的用户在此确认绑定，响应中返回恢复码
+func (s *AuthService) LoginTwoFactor(req *model.TwoFactorLoginRequest) (*model.LoginResponse, error) {
+	userID, meta, err := s.twoFactorService.LoadChallenge(req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil || user.Status != 1 {
+		return nil, errors.New("账号不存在或已被禁用")
+	}
+
+	enabled, err := s.twoFactorService.Enabled(userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询两步验证状态失败: %w", err)
 	}
+	var recoveryCodes []string
+	if enabled {
+		err = s.twoFactorService.Verify(userID, req.Code)
+	} else {
+		recoveryCodes, err = s.twoFactorService.Enable(userID, req.Code)
+	}
+	if err != nil {
+		Audit(&userID, user.Username, model.ActionLoginFailed, model.TargetUser, fmt.Sprint(userID),
+			map[string]interface{}{"reason": "两步验证失败"}, meta.IP, meta.UserAgent, "failed")
+		return nil, err
+	}
+	s.twoFactorService.ConsumeChallenge(req.ChallengeToken)
 
+	tokens, err := s.completeLogin(user, meta)
+	if err != nil {
+		return nil, err
+	}
 	return &model.LoginResponse{
-		Token:    token,
-		UserInfo: user,
+		TokenResponse: tokens,
+		UserInfo:      user,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// StartSession 为用户创建新会话，签发访问令牌和刷新令牌
+func (s *AuthService) StartSession(user *model.User, meta *model.SessionMeta) (*model.TokenResponse, error) {
+	_, tokens, err := s.startSession(user, meta)
+	return tokens, err
+}
+
+// completeLogin 登录完成：创建会话并记录登录历史（新设备或新网络登录时发送提醒）
+func (s *AuthService) completeLogin(user *model.User, meta *model.SessionMeta) (*model.TokenResponse, error) {
+	session, tokens, err := s.startSession(user, meta)
+	if err != nil {
+		return nil, err
+	}
+	s.loginAlertService.Record(user, session, meta)
+	return tokens, nil
+}
+
+func (s *AuthService) startSession(user *model.User, meta *model.SessionMeta) (*model.UserSession, *model.TokenResponse, error) {
+	session, err := s.sessionService.Create(user.UserID, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshToken, err := s.sessionService.IssueRefreshToken(session)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, err := s.issueTokens(user, session.ID, refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, tokens, nil
+}
+
+// issueTokens 按用户当前的角色和安全版本为指定会话签发短期访问令牌
+// 须修改密码的用户签发受限令牌，修改密码前只能访问修改密码等少数接口
+func (s *AuthService) issueTokens(user *model.User, sessionID, refreshToken string) (*model.TokenResponse, error) {
+	ttl := s.sessionService.AccessTokenTTL()
+	mustChange := s.passwordService.MustChange(user)
+	token, err := util.GenerateToken(user.UserID, user.Username, user.RoleID, user.SecurityVersion, sessionID, mustChange, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("生成Token失败: %w", err)
+	}
+	return &model.TokenResponse{
+		Token:              token,
+		RefreshToken:       refreshToken,
+		ExpiresIn:          int64(ttl.Seconds()),
+		SessionID:          sessionID,
+		MustChangePassword: mustChange,
 	}, nil
 }
 
@@ -106,10 +236,12 @@ func (s *AuthService) clearLoginFailure(username string) {
 	redis.Del(attemptsKey)
 }
 
-// Logout 用户登出
-func (s *AuthService) Logout(userID int) error {
-	tokenKey := fmt.Sprintf("emby_ums:jwt:token:%d", userID)
-	return redis.Del(tokenKey)
+// Logout 用户登出，撤销当前会话
+func (s *AuthService) Logout(userID int, sessionID string) error {
+	if err := s.sessionService.Revoke(userID, sessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	return nil
 }
 
 // ValidateToken 验证Token
@@ -119,37 +251,141 @@ func (s *AuthService) ValidateToken(token string) (*util.Claims, error) {
 		return nil, err
 	}
 
-	// 检查Redis中是否存在
-	tokenKey := fmt.Sprintf("emby_ums:jwt:token:%d", claims.UserID)
-	storedToken, err := redis.Get(tokenKey)
-	if err != nil || storedToken != token {
+	// 会话被撤销（登出、远程下线）后Token立即失效
+	if err := s.sessionService.Validate(claims.UserID, claims.SessionID); err != nil {
 		return nil, errors.New("Token已失效")
 	}
 
+	// 角色、状态或密码变更后安全版本号递增，旧Token立即失效
+	version, err := s.securityVersion(claims.UserID)
+	if err != nil || version != claims.Version {
+		return nil, errors.New("登录状态已失效，请重新登录")
+	}
+
 	return claims, nil
 }
 
+// securityVersionKey 用户安全版本号缓存键
+func securityVersionKey(userID int) string {
+	return fmt.Sprintf("emby_ums:user:secver:%d", userID)
+}
+
+// securityVersion 获取用户当前的安全版本号，优先读取Redis缓存
+func (s *AuthService) securityVersion(userID int) (int, error) {
+	key := securityVersionKey(userID)
+	if data, err := redis.Get(key); err == nil {
+		if version, err := strconv.Atoi(data); err == nil {
+			return version, nil
+		}
+	}
+
+	version, err := s.userDAO.GetSecurityVersion(userID)
+	if err != nil {
+		return 0, err
+	}
+	redis.Set(key, version, time.Hour)
+	return version, nil
+}
+
+// BumpSecurityVersion 递增用户安全版本号并撤销全部会话，用户需重新登录
+// 在角色、状态、密码变更后调用
+func BumpSecurityVersion(userIDs ...int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	if err := dao.NewUserDAO().BumpSecurityVersion(userIDs...); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, securityVersionKey(userID))
+	}
+	if err := redis.Del(keys...); err != nil {
+		util.Warn(fmt.Sprintf("清除用户安全版本缓存失败: %v", err))
+	}
+	if _, err := NewSessionService().RevokeAll(userIDs, ""); err != nil {
+		util.Warn(fmt.Sprintf("撤销用户会话失败: %v", err))
+	}
+	return nil
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌和刷新令牌（刷新令牌一次性使用）
+// 已使用过的刷新令牌再次出现时撤销整个会话并记录审计日志
+func (s *AuthService) RefreshToken(refreshToken, ip, ua string) (*model.TokenResponse, error) {
+	current, newRefreshToken, err := s.sessionService.Rotate(refreshToken)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		username := ""
+		if user, err := s.userDAO.GetByID(current.UserID); err == nil {
+			username = user.Username
+		}
+		util.Warn(fmt.Sprintf("检测到刷新令牌重用，已撤销会话: user_id=%d, session_id=%s", current.UserID, current.SessionID))
+		Audit(&current.UserID, username, model.ActionTokenReuse, model.TargetUser, fmt.Sprint(current.UserID),
+			map[string]interface{}{"session_id": current.SessionID}, ip, ua, "failed")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userDAO.GetByID(current.UserID)
+	if err != nil || user.Status != 1 {
+		s.sessionService.Revoke(current.UserID, current.SessionID)
+		return nil, errors.New("账号不存在或已被禁用")
+	}
+	return s.issueTokens(user, current.SessionID, newRefreshToken)
+}
+
+// TouchSession 记录会话最近活跃时间和IP
+func (s *AuthService) TouchSession(sessionID, ip string) {
+	s.sessionService.Touch(sessionID, ip)
+}
+
+// ListSessions 获取用户的有效会话
+func (s *AuthService) ListSessions(userID int, currentSessionID string) ([]*model.UserSession, error) {
+	return s.sessionService.List(userID, currentSessionID)
+}
+
+// RevokeSession 撤销用户的指定会话
+func (s *AuthService) RevokeSession(userID int, sessionID string) error {
+	return s.sessionService.Revoke(userID, sessionID)
+}
+
+// RevokeAllSessions 撤销用户的全部会话，exceptID 非空时保留该会话
+func (s *AuthService) RevokeAllSessions(userID int, exceptID string) (int, error) {
+	return s.sessionService.RevokeAll([]int{userID}, exceptID)
+}
+
+// LoginHistory 分页获取用户的登录历史
+func (s *AuthService) LoginHistory(userID int, req *model.LoginHistoryRequest) (*model.LoginHistoryResponse, error) {
+	return s.loginAlertService.History(userID, req)
+}
+
+// RevokeFromLoginAlert 登录提醒邮件中的"不是我本人"：退出所有设备并要求重置密码
+func (s *AuthService) RevokeFromLoginAlert(token, ip, ua string) error {
+	return s.loginAlertService.RevokeFromAlert(token, ip, ua)
+}
+
 // GetUserByID 根据ID获取用户（包含角色和权限）
 func (s *AuthService) GetUserByID(userID int) (*model.User, error) {
 	return s.userDAO.GetByID(userID)
 }
 
-// ChangePassword 用户修改自己的密码
+// ChangePassword 用户修改自己的密码，新密码需符合密码策略且不能与最近使用过的密码相同
 func (s *AuthService) ChangePassword(userID int, newPassword string) error {
 	user, err := s.userDAO.GetByID(userID)
 	if err != nil {
 		return fmt.Errorf("用户不存在")
 	}
 
-	// 加密新密码
-	hashedPassword, err := util.HashPassword(newPassword)
-	if err != nil {
-		return fmt.Errorf("密码加密失败")
+	if err := s.passwordService.Check(user, newPassword); err != nil {
+		return err
 	}
-
-	user.PasswordHash = hashedPassword
-	if err := s.userDAO.Update(user); err != nil {
-		return fmt.Errorf("更新密码失败")
+	if err := s.passwordService.Apply(user, newPassword, false); err != nil {
+		return err
+	}
+	if err := BumpSecurityVersion(userID); err != nil {
+		util.Warn(fmt.Sprintf("递增安全版本号失败: %v", err))
 	}
 
 	// 同步更新Emby密码
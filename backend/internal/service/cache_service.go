@@ -3,6 +3,7 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"embyhub/internal/model"
@@ -19,11 +20,12 @@ func NewCacheService() *CacheService {
 
 // 缓存键前缀
 const (
-	CacheKeyUserInfo   = "emby_ums:cache:user:%d"    // 用户信息缓存
-	CacheKeyUserPerms  = "emby_ums:cache:perms:%d"   // 用户权限缓存
-	CacheKeyStatistics = "emby_ums:cache:statistics" // 统计数据缓存
-	CacheKeyCardStats  = "emby_ums:cache:card_stats" // 卡密统计缓存
-	CacheKeyVipStats   = "emby_ums:cache:vip_stats"  // VIP统计缓存
+	CacheKeyUserInfo   = "emby_ums:cache:user:%d"     // 用户信息缓存
+	CacheKeyRolePerms  = "emby_ums:role:perms:%d:v%d" // 角色权限缓存（按版本号区分）
+	CacheKeyPermsVer   = "emby_ums:role:perms:ver:%d" // 角色权限版本号
+	CacheKeyStatistics = "emby_ums:cache:statistics"  // 统计数据缓存
+	CacheKeyCardStats  = "emby_ums:cache:card_stats"  // 卡密统计缓存
+	CacheKeyVipStats   = "emby_ums:cache:vip_stats"   // VIP统计缓存
 )
 
 // 缓存过期时间
@@ -66,35 +68,51 @@ func (s *CacheService) InvalidateUserInfo(userID int) {
 	redis.Del(key)
 }
 
-// GetUserPermissions 获取用户权限（带缓存）
-func (s *CacheService) GetUserPermissions(userID int) ([]string, error) {
-	key := fmt.Sprintf(CacheKeyUserPerms, userID)
+// RolePermissionsVersion 获取角色权限的当前版本号，不存在时为0
+func (s *CacheService) RolePermissionsVersion(roleID int) (int64, error) {
+	data, err := redis.Get(fmt.Sprintf(CacheKeyPermsVer, roleID))
+	if err != nil {
+		if redis.IsNil(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(data, 10, 64)
+}
+
+// GetRolePermissions 获取指定版本的角色权限规则缓存
+func (s *CacheService) GetRolePermissions(roleID int, version int64) (*model.RolePermissionSet, error) {
+	key := fmt.Sprintf(CacheKeyRolePerms, roleID, version)
 
 	data, err := redis.Get(key)
 	if err == nil && data != "" {
-		var perms []string
-		if err := json.Unmarshal([]byte(data), &perms); err == nil {
-			return perms, nil
+		var rules model.RolePermissionSet
+		if err := json.Unmarshal([]byte(data), &rules); err == nil {
+			return &rules, nil
 		}
 	}
 
 	return nil, fmt.Errorf("缓存未命中")
 }
 
-// SetUserPermissions 设置用户权限缓存
-func (s *CacheService) SetUserPermissions(userID int, permissions []string) error {
-	key := fmt.Sprintf(CacheKeyUserPerms, userID)
-	data, err := json.Marshal(permissions)
+// SetRolePermissions 设置指定版本的角色权限规则缓存
+// 加载期间权限发生变更时版本号已递增，旧版本的写入不会被读到
+func (s *CacheService) SetRolePermissions(roleID int, version int64, rules *model.RolePermissionSet) error {
+	key := fmt.Sprintf(CacheKeyRolePerms, roleID, version)
+	data, err := json.Marshal(rules)
 	if err != nil {
 		return err
 	}
 	return redis.Set(key, string(data), PermsCacheTTL)
 }
 
-// InvalidateUserPermissions 使用户权限缓存失效
-func (s *CacheService) InvalidateUserPermissions(userID int) {
-	key := fmt.Sprintf(CacheKeyUserPerms, userID)
-	redis.Del(key)
+// InvalidateRolePermissions 递增角色权限版本号使缓存失效，并删除旧版本缓存
+func (s *CacheService) InvalidateRolePermissions(roleID int) error {
+	version, err := redis.Incr(fmt.Sprintf(CacheKeyPermsVer, roleID))
+	if err != nil {
+		return err
+	}
+	return redis.Del(fmt.Sprintf(CacheKeyRolePerms, roleID, version-1))
 }
 
 // GetStatistics 获取统计数据（带缓存）
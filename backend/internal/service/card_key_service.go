@@ -4,25 +4,53 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"embyhub/internal/dao"
 	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CardKeyService struct {
-	cardKeyDAO *dao.CardKeyDAO
-	userDAO    *dao.UserDAO
+	cardKeyDAO        *dao.CardKeyDAO
+	cardBatchDAO      *dao.CardBatchDAO
+	cardRedemptionDAO *dao.CardRedemptionDAO
+	userDAO           *dao.UserDAO
+	userGroupDAO      *dao.UserGroupDAO
+	vipTierService    *VipTierService
+	vipService        *VipService
+	referralService   *ReferralService
+	scopeService      *ScopeService
 }
 
 func NewCardKeyService() *CardKeyService {
 	return &CardKeyService{
-		cardKeyDAO: dao.NewCardKeyDAO(),
-		userDAO:    dao.NewUserDAO(),
+		cardKeyDAO:        dao.NewCardKeyDAO(),
+		cardBatchDAO:      dao.NewCardBatchDAO(),
+		cardRedemptionDAO: dao.NewCardRedemptionDAO(),
+		userDAO:           dao.NewUserDAO(),
+		userGroupDAO:      dao.NewUserGroupDAO(),
+		vipTierService:    NewVipTierService(),
+		vipService:        NewVipService(),
+		referralService:   NewReferralService(),
+		scopeService:      NewScopeService(),
 	}
 }
 
+// 批量生成相关配置
+const (
+	cardCodeMinEntropyBits = 30 // 单张卡密被猜中的概率上限 2^-30
+	cardCodeMaxRounds      = 10 // 去重最大轮数
+	cardCodeInsertRetries  = 3  // 并发插入冲突时的重试次数
+)
+
 // GenerateCardCode 生成卡密码（TL|24位字符）
 func generateCardCode() string {
 	bytes := make([]byte, 12)
@@ -32,27 +60,204 @@ func generateCardCode() string {
 	return "TL|" + code
 }
 
-// Create 批量创建卡密
+// Create 管理员批量创建卡密，组管理员只能为自己管理的用户组生成
+// 每次生成记录为一个批次，卡密码按批次模板生成并在库内去重
 func (s *CardKeyService) Create(req *model.CardKeyCreateRequest, creatorID int) ([]*model.CardKey, error) {
-	cardKeys := make([]*model.CardKey, req.Count)
-
-	for i := 0; i < req.Count; i++ {
-		cardKeys[i] = &model.CardKey{
-			CardCode:  generateCardCode(),
-			CardType:  req.CardType,
-			Duration:  req.Duration,
-			Status:    1, // 未使用
-			Remark:    req.Remark,
-			CreatedBy: creatorID,
-			CreatedAt: time.Now(),
+	if req.GroupID != nil {
+		if _, err := s.userGroupDAO.GetByID(*req.GroupID); err != nil {
+			return nil, errors.New("用户组不存在")
 		}
 	}
+	scope, err := s.scopeService.Load(creatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := scope.CheckGroup("cardkey:create", req.GroupID); err != nil {
+		return nil, err
+	}
+	return s.CreateWithHook(req, creatorID, nil)
+}
 
-	if err := s.cardKeyDAO.BatchCreate(cardKeys); err != nil {
+// Authorize 校验操作者能否以指定权限管理卡密（卡密所属用户组需在其管理范围内）
+func (s *CardKeyService) Authorize(operatorID int, permissionKey string, cardKeyID int) error {
+	cardKey, err := s.cardKeyDAO.GetByID(cardKeyID)
+	if err != nil {
+		return errors.New("卡密不存在")
+	}
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return err
+	}
+	return scope.CheckGroup(permissionKey, cardKey.GroupID)
+}
+
+// AuthorizeBatch 校验操作者能否以指定权限管理卡密批次
+func (s *CardKeyService) AuthorizeBatch(operatorID int, permissionKey string, batchID int) error {
+	batch, err := s.cardBatchDAO.GetByID(batchID)
+	if err != nil {
+		return errors.New("批次不存在")
+	}
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return err
+	}
+	return scope.CheckGroup(permissionKey, batch.GroupID)
+}
+
+// CreateWithHook 批量创建卡密，afterBatch 与批次写入在同一事务中执行（如扣减代理商额度）
+func (s *CardKeyService) CreateWithHook(req *model.CardKeyCreateRequest, creatorID int, afterBatch func(tx *gorm.DB, batch *model.CardBatch) error) ([]*model.CardKey, error) {
+	if req.VipLevel == 0 {
+		req.VipLevel = 1
+	}
+	if _, err := s.vipTierService.GetGrantable(req.VipLevel); err != nil {
 		return nil, err
 	}
 
-	return cardKeys, nil
+	now := time.Now()
+	batch := &model.CardBatch{
+		CardType:  req.CardType,
+		Duration:  req.Duration,
+		VipLevel:  req.VipLevel,
+		Count:     req.Count,
+		Remark:    req.Remark,
+		CreatedBy: creatorID,
+		GroupID:   req.GroupID,
+		CreatedAt: now,
+	}
+
+	var generate func() (string, error)
+	if req.HasTemplate() {
+		tpl := &util.CardCodeTemplate{
+			Prefix:    req.Prefix,
+			GroupSize: req.GroupSize,
+			Length:    req.CodeLength,
+			Charset:   req.Charset,
+			Checksum:  req.Checksum,
+		}
+		if err := tpl.Normalize(); err != nil {
+			return nil, err
+		}
+		// 组合空间需足够大，既避免生成时频繁碰撞，也防止卡密被枚举
+		if tpl.Capacity() < math.Log2(float64(req.Count))+cardCodeMinEntropyBits {
+			return nil, errors.New("卡密组合空间不足，请增加长度或扩充字符集")
+		}
+		batch.Prefix = tpl.Prefix
+		batch.GroupSize = tpl.GroupSize
+		batch.CodeLength = tpl.Length
+		batch.Charset = tpl.Charset
+		batch.Checksum = tpl.Checksum
+		generate = tpl.Generate
+	} else {
+		// 旧格式：TL|24位十六进制字符
+		batch.Prefix = "TL|"
+		batch.CodeLength = 24
+		batch.Charset = "0123456789ABCDEF"
+		batch.Checksum = util.CardChecksumNone
+		generate = func() (string, error) { return generateCardCode(), nil }
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < cardCodeInsertRetries; attempt++ {
+		codes, err := s.generateUniqueCodes(req.Count, generate)
+		if err != nil {
+			return nil, err
+		}
+
+		cardKeys := make([]*model.CardKey, 0, len(codes))
+		for codeKey, code := range codes {
+			cardKeys = append(cardKeys, &model.CardKey{
+				CardCode:  code,
+				CodeKey:   codeKey,
+				CardType:  req.CardType,
+				Duration:  req.Duration,
+				VipLevel:  req.VipLevel,
+				Status:    1, // 未使用
+				Remark:    req.Remark,
+				CreatedBy: creatorID,
+				GroupID:   req.GroupID,
+				CreatedAt: now,
+			})
+		}
+
+		batch.ID = 0
+		var hook func(tx *gorm.DB) error
+		if afterBatch != nil {
+			hook = func(tx *gorm.DB) error { return afterBatch(tx, batch) }
+		}
+		lastErr = s.cardKeyDAO.CreateBatchWithCards(batch, cardKeys, hook)
+		if lastErr == nil {
+			return cardKeys, nil
+		}
+		// 与并发生成的卡密撞码时重新生成整批
+		if !errors.Is(lastErr, dao.ErrCardCodeConflict) {
+			return nil, lastErr
+		}
+	}
+
+	return nil, lastErr
+}
+
+// generateUniqueCodes 生成指定数量互不重复且库中不存在的卡密码
+// 返回 规范化卡密码 -> 展示卡密码
+func (s *CardKeyService) generateUniqueCodes(count int, generate func() (string, error)) (map[string]string, error) {
+	codes := make(map[string]string, count)
+
+	for round := 0; round < cardCodeMaxRounds && len(codes) < count; round++ {
+		fresh := make([]string, 0, count-len(codes))
+		for len(codes) < count {
+			code, err := generate()
+			if err != nil {
+				return nil, fmt.Errorf("生成卡密失败: %w", err)
+			}
+			codeKey := util.NormalizeCardCode(code)
+			if _, ok := codes[codeKey]; ok {
+				continue
+			}
+			codes[codeKey] = code
+			fresh = append(fresh, codeKey)
+		}
+
+		existing, err := s.cardKeyDAO.ExistingCodeKeys(fresh)
+		if err != nil {
+			return nil, fmt.Errorf("检查卡密重复失败: %w", err)
+		}
+		for _, codeKey := range existing {
+			delete(codes, codeKey)
+		}
+	}
+
+	if len(codes) < count {
+		return nil, errors.New("卡密重复率过高，请增加长度或扩充字符集")
+	}
+	return codes, nil
+}
+
+// findByCode 根据用户输入查找卡密（忽略大小写、横线和空格）
+func (s *CardKeyService) findByCode(cardCode string) (*model.CardKey, error) {
+	codeKey := util.NormalizeCardCode(cardCode)
+	cardKey, err := s.cardKeyDAO.GetByCode(codeKey)
+	if err == nil {
+		return cardKey, nil
+	}
+
+	// 未找到时检查是否为输入错误（前缀匹配但校验位均不符），只查询与输入前缀相同的批次模板
+	if batches, err := s.cardBatchDAO.ListChecksumTemplates(util.CardCodePrefixes(codeKey)); err == nil && len(batches) > 0 {
+		for _, batch := range batches {
+			tpl := &util.CardCodeTemplate{
+				Prefix:    batch.Prefix,
+				GroupSize: batch.GroupSize,
+				Length:    batch.CodeLength,
+				Charset:   batch.Charset,
+				Checksum:  batch.Checksum,
+			}
+			if tpl.Verify(codeKey) {
+				return nil, errors.New("卡密不存在")
+			}
+		}
+		return nil, errors.New("卡密校验失败，请检查是否输入有误")
+	}
+
+	return nil, errors.New("卡密不存在")
 }
 
 // GetByID 获取卡密详情
@@ -62,7 +267,7 @@ func (s *CardKeyService) GetByID(id int) (*model.CardKey, error) {
 
 // GetByCode 根据卡密码获取
 func (s *CardKeyService) GetByCode(cardCode string) (*model.CardKey, error) {
-	return s.cardKeyDAO.GetByCode(cardCode)
+	return s.cardKeyDAO.GetByCode(util.NormalizeCardCode(cardCode))
 }
 
 // List 获取卡密列表
@@ -80,9 +285,9 @@ func (s *CardKeyService) List(req *model.CardKeyListRequest) (*model.CardKeyList
 
 // Use 使用卡密
 func (s *CardKeyService) Use(cardCode string, userID int) (*model.CardKey, error) {
-	cardKey, err := s.cardKeyDAO.GetByCode(cardCode)
+	cardKey, err := s.findByCode(cardCode)
 	if err != nil {
-		return nil, errors.New("卡密不存在")
+		return nil, err
 	}
 
 	// 检查状态
@@ -92,6 +297,9 @@ func (s *CardKeyService) Use(cardCode string, userID int) (*model.CardKey, error
 	if cardKey.Status == 2 {
 		return nil, errors.New("卡密已被使用")
 	}
+	if cardKey.Status == 3 {
+		return nil, errors.New("卡密已被撤销")
+	}
 
 	// 检查过期时间
 	if cardKey.ExpireAt != nil && time.Now().After(*cardKey.ExpireAt) {
@@ -118,7 +326,7 @@ func (s *CardKeyService) Disable(id int) error {
 		return errors.New("卡密不存在")
 	}
 
-	if cardKey.Status == 2 {
+	if cardKey.Status == 2 || cardKey.Status == 3 {
 		return errors.New("卡密已被使用，无法禁用")
 	}
 
@@ -133,7 +341,7 @@ func (s *CardKeyService) Enable(id int) error {
 		return errors.New("卡密不存在")
 	}
 
-	if cardKey.Status == 2 {
+	if cardKey.Status == 2 || cardKey.Status == 3 {
 		return errors.New("卡密已被使用，无法启用")
 	}
 
@@ -148,7 +356,7 @@ func (s *CardKeyService) Delete(id int) error {
 		return errors.New("卡密不存在")
 	}
 
-	if cardKey.Status == 2 {
+	if cardKey.Status == 2 || cardKey.Status == 3 {
 		return errors.New("卡密已被使用，无法删除")
 	}
 
@@ -163,7 +371,7 @@ func (s *CardKeyService) BatchDelete(ids []int) (int, error) {
 		if err != nil {
 			continue
 		}
-		if cardKey.Status != 2 {
+		if cardKey.Status != 2 && cardKey.Status != 3 {
 			if err := s.cardKeyDAO.Delete(id); err == nil {
 				deleted++
 			}
@@ -172,26 +380,54 @@ func (s *CardKeyService) BatchDelete(ids []int) (int, error) {
 	return deleted, nil
 }
 
-// GetStatistics 获取卡密统计
-func (s *CardKeyService) GetStatistics() (map[string]interface{}, error) {
-	counts, err := s.cardKeyDAO.CountByStatus()
+// ListBatches 获取卡密批次列表
+func (s *CardKeyService) ListBatches(req *model.CardBatchListRequest) (*model.CardBatchListResponse, error) {
+	batches, total, err := s.cardBatchDAO.List(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CardBatchListResponse{
+		Total: int(total),
+		List:  batches,
+	}, nil
+}
+
+// ExportBatch 导出批次下的所有卡密码
+func (s *CardKeyService) ExportBatch(batchID int) (*model.CardBatch, []*model.CardKey, error) {
+	batch, err := s.cardBatchDAO.GetByID(batchID)
+	if err != nil {
+		return nil, nil, errors.New("批次不存在")
+	}
+
+	cardKeys, err := s.cardKeyDAO.ListCodesByBatch(batchID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batch, cardKeys, nil
+}
+
+// GetStatistics 获取卡密统计（createdBy > 0 时仅统计该创建者的卡密，groupIDs 非 nil 时仅统计这些用户组的卡密）
+func (s *CardKeyService) GetStatistics(createdBy int, groupIDs []int) (map[string]interface{}, error) {
+	counts, err := s.cardKeyDAO.CountByStatus(createdBy, groupIDs)
 	if err != nil {
 		return nil, err
 	}
 
 	return map[string]interface{}{
-		"total":    counts[0] + counts[1] + counts[2],
+		"total":    counts[0] + counts[1] + counts[2] + counts[3],
 		"unused":   counts[1],
 		"used":     counts[2],
 		"disabled": counts[0],
+		"revoked":  counts[3],
 	}, nil
 }
 
 // ValidateCardCode 验证卡密是否可用（用于注册验证）
 func (s *CardKeyService) ValidateCardCode(cardCode string) (*model.CardKey, error) {
-	cardKey, err := s.cardKeyDAO.GetByCode(cardCode)
+	cardKey, err := s.findByCode(cardCode)
 	if err != nil {
-		return nil, errors.New("卡密不存在")
+		return nil, err
 	}
 
 	if cardKey.Status == 0 {
@@ -200,6 +436,9 @@ func (s *CardKeyService) ValidateCardCode(cardCode string) (*model.CardKey, erro
 	if cardKey.Status == 2 {
 		return nil, errors.New("卡密已被使用")
 	}
+	if cardKey.Status == 3 {
+		return nil, errors.New("卡密已被撤销")
+	}
 	if cardKey.ExpireAt != nil && time.Now().After(*cardKey.ExpireAt) {
 		return nil, errors.New("卡密已过期")
 	}
@@ -208,6 +447,7 @@ func (s *CardKeyService) ValidateCardCode(cardCode string) (*model.CardKey, erro
 }
 
 // UseVipCard 使用VIP升级码
+// 卡密状态、用户VIP和兑换记录在同一事务中更新
 func (s *CardKeyService) UseVipCard(cardCode string, userID int) (*model.User, error) {
 	// 验证卡密
 	cardKey, err := s.ValidateCardCode(cardCode)
@@ -215,41 +455,164 @@ func (s *CardKeyService) UseVipCard(cardCode string, userID int) (*model.User, e
 		return nil, err
 	}
 
-	// 检查是否为VIP升级码
-	if cardKey.CardType != 2 {
+	// 检查是否为VIP码（1=VIP会员码，2=旧版VIP升级码）
+	if cardKey.CardType != 1 && cardKey.CardType != 2 {
 		return nil, errors.New("该卡密不是VIP升级码")
 	}
 
-	// 获取用户
-	user, err := s.userDAO.GetByID(userID)
+	var user *model.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// 条件更新，防止同一卡密被并发重复兑换
+		result := tx.Model(&model.CardKey{}).
+			Where("id = ? AND status = 1", cardKey.ID).
+			Updates(map[string]interface{}{"status": 2, "used_by": userID, "used_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("卡密已被使用")
+		}
+
+		// 按卡密等级发放VIP（跨等级时折算剩余时长），同时写入VIP流水
+		var entry *model.VipLedger
+		user, entry, err = s.vipService.GrantTx(tx, &model.VipChange{
+			UserID:     userID,
+			Level:      cardKey.VipLevel,
+			Days:       cardKey.Duration,
+			Source:     model.VipSourceCard,
+			OperatorID: &userID,
+			RefID:      fmt.Sprint(cardKey.ID),
+			Remark:     "卡密兑换",
+		})
+		if err != nil {
+			return fmt.Errorf("升级VIP失败: %w", err)
+		}
+
+		// 记录本次兑换实际增加的时长及前后状态（跨等级折算后可能与卡密天数不同）
+		granted := GrantedDuration(entry, cardKey.Duration)
+		return tx.Create(&model.CardRedemption{
+			CardKeyID:      cardKey.ID,
+			UserID:         userID,
+			DaysGranted:    int(math.Round(granted.Hours() / 24)),
+			GrantedSeconds: int64(granted / time.Second),
+			ExpireBefore:   entry.ExpireBefore,
+			ExpireAfter:    *entry.ExpireAfter,
+			LevelBefore:    EffectiveVipLevel(&model.User{VipLevel: entry.LevelBefore, VipExpireAt: entry.ExpireBefore}, now),
+			LevelAfter:     entry.LevelAfter,
+			CreatedAt:      now,
+		}).Error
+	})
 	if err != nil {
-		return nil, errors.New("用户不存在")
+		return nil, err
 	}
 
-	// 计算VIP到期时间
-	now := time.Now()
-	var vipExpireAt time.Time
-	if user.VipExpireAt != nil && user.VipExpireAt.After(now) {
-		// 如果当前VIP未过期，在原基础上增加
-		vipExpireAt = user.VipExpireAt.AddDate(0, 0, cardKey.Duration)
-	} else {
-		// 从现在开始计算
-		vipExpireAt = now.AddDate(0, 0, cardKey.Duration)
+	s.vipService.AfterCommit(user)
+	Cache().InvalidateStatistics()
+
+	// 首次兑换卡密时发放推荐奖励（失败不影响兑换结果）
+	s.referralService.Reward(userID, model.ReferralTriggerCard)
+	return user, nil
+}
+
+// RevokeRedemption 撤销卡密兑换，回滚该次兑换发放的VIP天数
+// 兑换后未再变动时恢复兑换前的等级和到期时间；回滚后已到期则降为免费等级，并按等级同步Emby策略
+func (s *CardKeyService) RevokeRedemption(cardKeyID int, reason string, operatorID int, operatorName, ip, ua string) (*model.CardRevokeResponse, error) {
+	var redemption model.CardRedemption
+	var user *model.User
+	var expired bool
+	resp := &model.CardRevokeResponse{}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("card_key_id = ? AND revoked_at IS NULL", cardKeyID).
+			First(&redemption).Error; err != nil {
+			return errors.New("该卡密没有可撤销的兑换记录")
+		}
+
+		var err error
+		if user, err = s.vipService.LockUserTx(tx, redemption.UserID); err != nil {
+			return errors.New("兑换用户不存在")
+		}
+		if user.VipFrozenAt != nil {
+			return ErrVipFrozen
+		}
+
+		expireBefore := user.VipExpireAt
+		levelBefore := user.VipLevel
+		level := user.VipLevel
+		expireAt := user.VipExpireAt
+		if user.VipLevel == redemption.LevelAfter && user.VipExpireAt != nil &&
+			user.VipExpireAt.Equal(redemption.ExpireAfter) {
+			// 兑换后VIP未再变动：整体恢复到兑换前的等级和到期时间
+			level = redemption.LevelBefore
+			expireAt = redemption.ExpireBefore
+		} else if user.VipExpireAt != nil {
+			// 兑换后又有变动：从当前到期时间中扣除该次兑换实际增加的时长
+			rolled := user.VipExpireAt.Add(-time.Duration(redemption.GrantedSeconds) * time.Second)
+			expireAt = &rolled
+		}
+
+		// 回滚后已到期则降为免费等级
+		if expireAt == nil || !expireAt.After(now) {
+			level = model.FreeVipLevel
+			expireAt = &now
+		}
+		expired = levelBefore != model.FreeVipLevel && level == model.FreeVipLevel
+
+		if _, err := s.vipService.ApplyTx(tx, user, level, expireAt, -redemption.DaysGranted, &model.VipChange{
+			Source:     model.VipSourceRefund,
+			OperatorID: &operatorID,
+			RefID:      fmt.Sprint(cardKeyID),
+			Remark:     "撤销卡密兑换: " + reason,
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&redemption).Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoked_by":    operatorID,
+			"revoke_reason": reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		resp.UserID = user.UserID
+		resp.DaysRevoked = redemption.DaysGranted
+		resp.ExpireBefore = expireBefore
+		resp.ExpireAfter = user.VipExpireAt
+		resp.VipLevel = user.VipLevel
+
+		return tx.Model(&model.CardKey{}).Where("id = ?", cardKeyID).Update("status", 3).Error
+	})
+	if err != nil {
+		Audit(&operatorID, operatorName, model.ActionRevokeCard, model.TargetCardKey, fmt.Sprint(cardKeyID),
+			map[string]interface{}{"reason": reason, "error": err.Error()}, ip, ua, "failed")
+		return nil, err
 	}
 
-	// 更新用户VIP状态
-	user.VipLevel = 1
-	user.VipExpireAt = &vipExpireAt
-	user.UpdatedAt = now
-	if err := s.userDAO.Update(user); err != nil {
-		return nil, errors.New("升级VIP失败")
+	detail := map[string]interface{}{
+		"user_id":       resp.UserID,
+		"days_revoked":  resp.DaysRevoked,
+		"expire_before": resp.ExpireBefore,
+		"expire_after":  resp.ExpireAfter,
+		"vip_expired":   expired,
+		"reason":        reason,
 	}
 
-	// 标记卡密为已使用
-	cardKey.Status = 2
-	cardKey.UsedBy = &userID
-	cardKey.UsedAt = &now
-	s.cardKeyDAO.Update(cardKey)
+	// 按回滚后的等级同步Emby权限
+	s.vipService.AfterCommit(user)
+	Cache().InvalidateStatistics()
+	Audit(&operatorID, operatorName, model.ActionRevokeCard, model.TargetCardKey, fmt.Sprint(cardKeyID),
+		detail, ip, ua, "success")
 
-	return user, nil
+	return resp, nil
+}
+
+// ListRedemptions 获取卡密的兑换记录
+func (s *CardKeyService) ListRedemptions(cardKeyID int) ([]*model.CardRedemption, error) {
+	return s.cardRedemptionDAO.ListByCardKeyID(cardKeyID)
 }
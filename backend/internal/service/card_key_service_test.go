@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+)
+
+// createTestCards 按模板生成一批卡密
+func createTestCards(t *testing.T, req *model.CardKeyCreateRequest) []*model.CardKey {
+	t.Helper()
+	req.CardType, req.Duration = 1, 30
+	cards, err := NewCardKeyService().CreateWithHook(req, 1, nil)
+	if err != nil {
+		t.Fatalf("生成卡密失败: %v", err)
+	}
+	return cards
+}
+
+// changeLast 将卡密最后一位替换为字符集中的下一个字符
+func changeLast(code string) string {
+	key := util.NormalizeCardCode(code)
+	i := strings.IndexByte(util.CardCodeCharset, key[len(key)-1])
+	return key[:len(key)-1] + string(util.CardCodeCharset[(i+1)%len(util.CardCodeCharset)])
+}
+
+func TestCardCodeLookup(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, 1, 100)
+	s := NewCardKeyService()
+
+	luhn := createTestCards(t, &model.CardKeyCreateRequest{Count: 3, Prefix: "vip", GroupSize: 4, Checksum: util.CardChecksumLuhn})
+	createTestCards(t, &model.CardKeyCreateRequest{Count: 1, Prefix: "VIPX", Checksum: util.CardChecksumCRC})
+	plain := createTestCards(t, &model.CardKeyCreateRequest{Count: 1, Prefix: "GIFT", Checksum: util.CardChecksumNone})
+
+	// 忽略大小写、横线和空白
+	code := luhn[0].CardCode
+	if !strings.HasPrefix(code, "VIP-") {
+		t.Fatalf("前缀应规范化为大写: %s", code)
+	}
+	for _, input := range []string{code, strings.ToLower(code), " " + strings.ReplaceAll(code, "-", " ") + "\n"} {
+		card, err := s.ValidateCardCode(input)
+		if err != nil || card.ID != luhn[0].ID {
+			t.Fatalf("输入 %q 应找到卡密: %v", input, err)
+		}
+	}
+
+	// 校验位错误提示输入有误
+	if _, err := s.ValidateCardCode(changeLast(code)); err == nil || !strings.Contains(err.Error(), "校验失败") {
+		t.Fatalf("校验位错误应提示输入有误: %v", err)
+	}
+
+	// 校验位正确但不存在（即使另一前缀更长的模板校验不符）、无校验位的批次、未知前缀：均提示不存在
+	tpl := &util.CardCodeTemplate{Prefix: "VIP", GroupSize: 4, Checksum: util.CardChecksumLuhn}
+	tpl.Normalize()
+	var unknown string
+	for !strings.HasPrefix(unknown, "VIPX") { // 同时匹配 VIPX 模板前缀
+		code, _ := tpl.Generate()
+		unknown = util.NormalizeCardCode(code)
+	}
+	for _, input := range []string{unknown, changeLast(plain[0].CardCode), "NOPE-1234-5678", "V"} {
+		if _, err := s.ValidateCardCode(input); err == nil || err.Error() != "卡密不存在" {
+			t.Fatalf("输入 %q 应提示卡密不存在: %v", input, err)
+		}
+	}
+}
+
+// redeemTestCard 生成并兑换一张指定等级和天数的VIP卡密，返回卡密ID
+func redeemTestCard(t *testing.T, userID, level, days int) int {
+	t.Helper()
+	s := NewCardKeyService()
+	cards, err := s.CreateWithHook(&model.CardKeyCreateRequest{Count: 1, CardType: 1, Duration: days, VipLevel: level}, 1, nil)
+	if err != nil {
+		t.Fatalf("生成卡密失败: %v", err)
+	}
+	if _, err := s.UseVipCard(cards[0].CardCode, userID); err != nil {
+		t.Fatalf("兑换卡密失败: %v", err)
+	}
+	return cards[0].ID
+}
+
+// setTestVip 直接设置用户VIP等级和到期时间
+func setTestVip(t *testing.T, userID, level int, expireAt time.Time) {
+	t.Helper()
+	if err := database.DB.Model(&model.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"vip_level": level, "vip_expire_at": expireAt}).Error; err != nil {
+		t.Fatalf("设置VIP失败: %v", err)
+	}
+}
+
+func assertExpireAt(t *testing.T, user *model.User, level int, want time.Time) {
+	t.Helper()
+	if user.VipLevel != level || user.VipExpireAt == nil || user.VipExpireAt.Sub(want).Abs() > time.Second {
+		t.Fatalf("VIP应为等级%d、%s到期，实际为等级%d、%v", level, want, user.VipLevel, user.VipExpireAt)
+	}
+}
+
+func TestRevokeRedemptionRestoresUntouchedGrant(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	s := NewCardKeyService()
+
+	// 兑换后未再变动：恢复兑换前的等级和到期时间
+	alice := createTestUser(t, "alice")
+	before := time.Now().Add(5 * 24 * time.Hour)
+	setTestVip(t, alice.UserID, 1, before)
+	cardID := redeemTestCard(t, alice.UserID, 1, 30)
+	resp, err := s.RevokeRedemption(cardID, "退款", 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("撤销兑换失败: %v", err)
+	}
+	if resp.DaysRevoked != 30 {
+		t.Fatalf("应回收30天: %d", resp.DaysRevoked)
+	}
+	assertExpireAt(t, reloadUser(t, alice.UserID), 1, before)
+
+	var card model.CardKey
+	database.DB.First(&card, cardID)
+	if card.Status != 3 {
+		t.Fatalf("卡密应标记为已撤销: %d", card.Status)
+	}
+	if _, err := s.RevokeRedemption(cardID, "重复", 1, "admin", "", ""); err == nil {
+		t.Fatal("同一兑换不应重复撤销")
+	}
+
+	// 兑换前无VIP：撤销后降为免费等级
+	bob := createTestUser(t, "bob")
+	cardID = redeemTestCard(t, bob.UserID, 1, 30)
+	if _, err := s.RevokeRedemption(cardID, "退款", 1, "admin", "", ""); err != nil {
+		t.Fatalf("撤销兑换失败: %v", err)
+	}
+	if user := reloadUser(t, bob.UserID); EffectiveVipLevel(user, time.Now()) != model.FreeVipLevel {
+		t.Fatalf("撤销后应降为免费等级: %+v", user)
+	}
+}
+
+func TestRevokeRedemptionRollsBackGrantedSeconds(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	createTestTier(t, 2, 300)
+	s := NewCardKeyService()
+
+	// VIP2 用户兑换 VIP1 卡：30天按价值折算为10天并入VIP2
+	user := createTestUser(t, "alice")
+	setTestVip(t, user.UserID, 2, time.Now().Add(10*24*time.Hour))
+	cardID := redeemTestCard(t, user.UserID, 1, 30)
+
+	var redemption model.CardRedemption
+	database.DB.Where("card_key_id = ?", cardID).First(&redemption)
+	if redemption.GrantedSeconds != 10*24*3600 || redemption.DaysGranted != 10 {
+		t.Fatalf("应记录折算后的时长: %d秒 %d天", redemption.GrantedSeconds, redemption.DaysGranted)
+	}
+
+	// 兑换后又续期：只扣除该次兑换实际增加的时长，保留之后的续期
+	redeemTestCard(t, user.UserID, 2, 5)
+	current := *reloadUser(t, user.UserID).VipExpireAt
+	if _, err := s.RevokeRedemption(cardID, "退款", 1, "admin", "", ""); err != nil {
+		t.Fatalf("撤销兑换失败: %v", err)
+	}
+	assertExpireAt(t, reloadUser(t, user.UserID), 2, current.Add(-10*24*time.Hour))
+
+	// 兑换后到期时间被缩短，扣除后已过期：降为免费等级
+	bob := createTestUser(t, "bob")
+	cardID = redeemTestCard(t, bob.UserID, 1, 30)
+	setTestVip(t, bob.UserID, 1, time.Now().Add(24*time.Hour))
+	if _, err := s.RevokeRedemption(cardID, "退款", 1, "admin", "", ""); err != nil {
+		t.Fatalf("撤销兑换失败: %v", err)
+	}
+	if got := reloadUser(t, bob.UserID); got.VipLevel != model.FreeVipLevel {
+		t.Fatalf("扣除后已到期应降为免费等级: %+v", got)
+	}
+}
+
+func TestRevokeRedemptionWhileFrozen(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	s := NewCardKeyService()
+
+	user := createTestUser(t, "alice")
+	cardID := redeemTestCard(t, user.UserID, 1, 30)
+	expireAt := *reloadUser(t, user.UserID).VipExpireAt
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("vip_frozen_at", time.Now())
+
+	if _, err := s.RevokeRedemption(cardID, "退款", 1, "admin", "", ""); !errors.Is(err, ErrVipFrozen) {
+		t.Fatalf("冻结期间不应撤销兑换: %v", err)
+	}
+	var redemption model.CardRedemption
+	database.DB.Where("card_key_id = ?", cardID).First(&redemption)
+	if redemption.RevokedAt != nil {
+		t.Fatal("撤销失败时兑换记录不应变更")
+	}
+	assertExpireAt(t, reloadUser(t, user.UserID), 1, expireAt)
+}
@@ -191,9 +191,13 @@ func (s *EmailService) ResetPassword(emailAddr, code, newPassword string) error
 		return fmt.Errorf("用户不存在")
 	}
 
-	// 更新密码
+	// 验证码发送到该邮箱，重置成功即视为邮箱已验证
 	authService := NewAuthService()
-	return authService.ChangePassword(user.UserID, newPassword)
+	if err := authService.ChangePassword(user.UserID, newPassword); err != nil {
+		return err
+	}
+	s.userDAO.MarkEmailVerified(user.UserID)
+	return nil
 }
 
 // TestEmailConfig 测试邮件配置
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/config"
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/emby"
+	"embyhub/pkg/redis"
+)
+
+// 登录提醒配置
+const (
+	loginAlertLinkTTL    = 72 * time.Hour // "不是我本人"链接有效期
+	loginAlertUsedPrefix = "emby_ums:login_alert:used:"
+)
+
+// LoginAlertService 登录历史与新设备登录提醒
+// 每次登录记录设备指纹和网段；来自新设备或新网络的登录向用户发送提醒邮件，
+// 邮件中的"不是我本人"链接可撤销全部会话并要求重置密码
+type LoginAlertService struct {
+	historyDAO   *dao.LoginHistoryDAO
+	userDAO      *dao.UserDAO
+	configDAO    *dao.SystemConfigDAO
+	emailService *EmailService
+}
+
+func NewLoginAlertService() *LoginAlertService {
+	return &LoginAlertService{
+		historyDAO:   dao.NewLoginHistoryDAO(),
+		userDAO:      dao.NewUserDAO(),
+		configDAO:    dao.NewSystemConfigDAO(),
+		emailService: NewEmailService(),
+	}
+}
+
+// Record 记录一次登录，来自新设备或新网络时异步发送提醒邮件（首次登录不提醒）
+func (s *LoginAlertService) Record(user *model.User, session *model.UserSession, meta *model.SessionMeta) {
+	method := meta.Method
+	if method == "" {
+		method = model.LoginMethodPassword
+	}
+	entry := &model.LoginHistory{
+		UserID:     user.UserID,
+		SessionID:  session.ID,
+		Method:     method,
+		IP:         meta.IP,
+		Network:    util.NetworkOf(meta.IP),
+		UserAgent:  session.UserAgent,
+		Device:     session.Device,
+		DeviceHash: util.DeviceHash(meta.DeviceFingerprint, meta.UserAgent),
+	}
+
+	hasHistory, knownDevice, knownNetwork, err := s.historyDAO.Known(user.UserID, entry.DeviceHash, entry.Network)
+	if err != nil {
+		util.Warn(fmt.Sprintf("查询登录历史失败: user_id=%d, %v", user.UserID, err))
+		return
+	}
+	entry.NewDevice = hasHistory && !knownDevice
+	entry.NewNetwork = hasHistory && !knownNetwork
+
+	if err := s.historyDAO.Create(entry); err != nil {
+		util.Warn(fmt.Sprintf("记录登录历史失败: user_id=%d, %v", user.UserID, err))
+		return
+	}
+	if (entry.NewDevice || entry.NewNetwork) && user.Email != "" {
+		go s.sendAlert(user, entry)
+	}
+}
+
+// sendAlert 发送登录提醒邮件
+func (s *LoginAlertService) sendAlert(user *model.User, entry *model.LoginHistory) {
+	values, err := s.configDAO.BatchGet([]string{"login_alert_enabled", "site_url"})
+	if err != nil || values["login_alert_enabled"] == "false" {
+		return
+	}
+
+	revokeLink := ""
+	if siteURL := strings.TrimRight(strings.TrimSpace(values["site_url"]), "/"); siteURL != "" {
+		jti, err := util.RandomToken(16)
+		if err != nil {
+			return
+		}
+		subject := fmt.Sprintf("%d:%d", user.UserID, entry.ID)
+		token, err := util.GenerateActionToken(util.AudienceLoginAlert, subject, jti, loginAlertLinkTTL)
+		if err != nil {
+			util.Warn(fmt.Sprintf("生成登录提醒链接失败: %v", err))
+			return
+		}
+		revokeLink = siteURL + "/not-me?token=" + url.QueryEscape(token)
+	}
+
+	client, err := s.emailService.GetEmailClient()
+	if err != nil {
+		return
+	}
+	// 设备名称可由客户端提交，写入邮件前转义
+	if err := client.SendLoginAlertEmail(user.Email, html.EscapeString(user.Username), html.EscapeString(entry.IP),
+		html.EscapeString(entry.Device), entry.CreatedAt.Format("2006-01-02 15:04:05"), revokeLink); err != nil {
+		util.Warn(fmt.Sprintf("发送登录提醒失败: user_id=%d, %v", user.UserID, err))
+		return
+	}
+	s.historyDAO.MarkAlerted(entry.ID)
+}
+
+// History 分页获取用户的登录历史
+func (s *LoginAlertService) History(userID int, req *model.LoginHistoryRequest) (*model.LoginHistoryResponse, error) {
+	entries, total, err := s.historyDAO.ListByUser(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginHistoryResponse{Total: int(total), List: entries}, nil
+}
+
+// RevokeFromAlert 处理登录提醒邮件中的"不是我本人"链接：
+// 撤销全部会话和访问令牌，并将本系统和Emby的密码重置为随机值，用户需通过找回密码重新设置
+func (s *LoginAlertService) RevokeFromAlert(token, ip, ua string) error {
+	claims, err := util.ParseActionToken(token, util.AudienceLoginAlert)
+	if err != nil {
+		return err
+	}
+	parts := strings.SplitN(claims.Subject, ":", 2)
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return errors.New("链接无效或已过期")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time) + time.Minute
+	if ok, err := redis.SetNX(loginAlertUsedPrefix+claims.ID, 1, ttl); err != nil {
+		return fmt.Errorf("校验链接失败: %w", err)
+	} else if !ok {
+		return errors.New("该链接已使用，账号已退出所有设备")
+	}
+
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+
+	randomPassword, err := util.RandomToken(24)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := util.HashPassword(randomPassword)
+	if err != nil {
+		return fmt.Errorf("密码加密失败: %w", err)
+	}
+	user.PasswordHash = hashedPassword
+	if err := s.userDAO.Update(user); err != nil {
+		return fmt.Errorf("重置密码失败: %w", err)
+	}
+	if err := BumpSecurityVersion(userID); err != nil {
+		util.Warn(fmt.Sprintf("递增安全版本号失败: %v", err))
+	}
+	if _, err := NewAPITokenService().RevokeAll(userID); err != nil {
+		util.Warn(fmt.Sprintf("撤销访问令牌失败: user_id=%d, %v", userID, err))
+	}
+	if user.EmbyUserID != "" {
+		embyClient := emby.NewClient(&config.GlobalConfig.Emby)
+		if err := embyClient.SetUserPassword(user.EmbyUserID, randomPassword); err != nil {
+			util.Warn(fmt.Sprintf("重置Emby密码失败: user_id=%d, %v", userID, err))
+		}
+	}
+
+	detail := map[string]interface{}{}
+	if len(parts) == 2 {
+		if historyID, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
+			if entry, err := s.historyDAO.GetByID(historyID); err == nil {
+				detail["login_ip"] = entry.IP
+				detail["login_device"] = entry.Device
+				detail["login_at"] = entry.CreatedAt
+			}
+		}
+	}
+	Audit(&userID, user.Username, model.ActionNotMe, model.TargetUser, fmt.Sprint(userID), detail, ip, ua, "success")
+	return nil
+}
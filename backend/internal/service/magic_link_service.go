@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/redis"
+)
+
+// 邮件登录配置项
+var magicLinkConfigKeys = []string{
+	"magic_link_enabled",
+	"magic_link_ttl_minutes",
+	"magic_link_hourly_limit",
+	"site_url",
+}
+
+// 邮件登录默认值
+const (
+	defaultMagicLinkTTL   = 15 // 登录链接有效期（分钟）
+	defaultMagicLinkLimit = 5  // 同一邮箱每小时最多发送次数
+	magicLinkCooldown     = time.Minute
+	magicLinkUsedPrefix   = "emby_ums:magic_link:used:"
+	magicLinkRatePrefix   = "emby_ums:magic_link:rate:"
+	magicLinkCoolPrefix   = "emby_ums:magic_link:cooldown:"
+)
+
+// magicLinkConfig 邮件登录配置
+type magicLinkConfig struct {
+	Enabled     bool
+	TTLMinutes  int
+	HourlyLimit int
+	SiteURL     string
+}
+
+// MagicLinkService 邮件登录链接服务
+// 链接中携带签名的短期令牌，令牌只能使用一次，兑换后与密码登录一样创建会话（开启两步验证的用户仍需完成第二步）
+// 链接需要不可猜测的长令牌，因此不复用 email_codes 的6位验证码，而与登录提醒链接一样使用操作令牌，由 Redis 原子占用 jti 保证只用一次
+type MagicLinkService struct {
+	configDAO    *dao.SystemConfigDAO
+	userDAO      *dao.UserDAO
+	emailService *EmailService
+	authService  *AuthService
+}
+
+func NewMagicLinkService() *MagicLinkService {
+	return &MagicLinkService{
+		configDAO:    dao.NewSystemConfigDAO(),
+		userDAO:      dao.NewUserDAO(),
+		emailService: NewEmailService(),
+		authService:  NewAuthService(),
+	}
+}
+
+// config 读取邮件登录配置，未配置站点地址时无法生成链接，视为未启用
+func (s *MagicLinkService) config() *magicLinkConfig {
+	values, err := s.configDAO.BatchGet(magicLinkConfigKeys)
+	if err != nil {
+		return &magicLinkConfig{}
+	}
+
+	atoi := func(key string, def int) int {
+		if v, err := strconv.Atoi(strings.TrimSpace(values[key])); err == nil && v > 0 {
+			return v
+		}
+		return def
+	}
+	cfg := &magicLinkConfig{
+		Enabled:     values["magic_link_enabled"] == "true",
+		TTLMinutes:  atoi("magic_link_ttl_minutes", defaultMagicLinkTTL),
+		HourlyLimit: atoi("magic_link_hourly_limit", defaultMagicLinkLimit),
+		SiteURL:     strings.TrimRight(strings.TrimSpace(values["site_url"]), "/"),
+	}
+	if cfg.SiteURL == "" {
+		cfg.Enabled = false
+	}
+	return cfg
+}
+
+// Enabled 是否启用邮件登录
+func (s *MagicLinkService) Enabled() bool {
+	return s.config().Enabled
+}
+
+// Send 向邮箱发送登录链接
+// 为避免暴露邮箱是否已注册，邮箱未注册或账号已禁用时同样返回成功，只是不发送邮件
+func (s *MagicLinkService) Send(emailAddr, ip string) error {
+	cfg := s.config()
+	if !cfg.Enabled {
+		return errors.New("未启用邮件登录")
+	}
+	emailAddr = strings.TrimSpace(emailAddr)
+	if err := s.checkRateLimit(strings.ToLower(emailAddr), cfg.HourlyLimit); err != nil {
+		return err
+	}
+
+	user, err := s.userDAO.GetByEmail(emailAddr)
+	if err != nil || user == nil || user.Status != 1 {
+		util.Info(fmt.Sprintf("邮件登录请求未发送（邮箱未注册或账号不可用）: email=%s, ip=%s", emailAddr, ip))
+		return nil
+	}
+
+	jti, err := util.RandomToken(16)
+	if err != nil {
+		return fmt.Errorf("生成登录链接失败: %w", err)
+	}
+	token, err := util.GenerateActionToken(util.AudienceMagicLink, user.Email, jti, time.Duration(cfg.TTLMinutes)*time.Minute)
+	if err != nil {
+		return fmt.Errorf("生成登录链接失败: %w", err)
+	}
+	link := cfg.SiteURL + "/magic-login?token=" + url.QueryEscape(token)
+
+	client, err := s.emailService.GetEmailClient()
+	if err != nil {
+		return err
+	}
+	if err := client.SendMagicLinkEmail(user.Email, link, cfg.TTLMinutes); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
+
+// checkRateLimit 同一邮箱每分钟最多发送一次，每小时不超过上限
+func (s *MagicLinkService) checkRateLimit(emailKey string, hourlyLimit int) error {
+	if ok, err := redis.SetNX(magicLinkCoolPrefix+emailKey, 1, magicLinkCooldown); err == nil && !ok {
+		return errors.New("发送过于频繁，请1分钟后重试")
+	}
+
+	rateKey := magicLinkRatePrefix + emailKey
+	count, err := redis.Incr(rateKey)
+	if err != nil {
+		return nil
+	}
+	if count == 1 {
+		redis.Expire(rateKey, time.Hour)
+	}
+	if count > int64(hourlyLimit) {
+		return errors.New("该邮箱请求登录链接的次数过多，请稍后再试")
+	}
+	return nil
+}
+
+// Login 使用登录链接令牌登录，令牌只能使用一次
+func (s *MagicLinkService) Login(req *model.MagicLinkLoginRequest, ip, ua string) (*model.LoginResponse, error) {
+	if !s.Enabled() {
+		return nil, errors.New("未启用邮件登录")
+	}
+	claims, err := util.ParseActionToken(req.Token, util.AudienceMagicLink)
+	if err != nil {
+		return nil, errors.New("登录链接无效或已过期")
+	}
+
+	// 占用 jti，保留到令牌过期后即可
+	ttl := time.Until(claims.ExpiresAt.Time) + time.Minute
+	used, err := redis.SetNX(magicLinkUsedPrefix+claims.ID, 1, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("校验登录链接失败: %w", err)
+	}
+	if !used {
+		Audit(nil, claims.Subject, model.ActionLoginFailed, model.TargetUser, "",
+			map[string]interface{}{"reason": "登录链接重复使用"}, ip, ua, "failed")
+		return nil, errors.New("登录链接已使用，请重新获取")
+	}
+
+	user, err := s.userDAO.GetByEmail(claims.Subject)
+	if err != nil || user == nil {
+		return nil, errors.New("账号不存在")
+	}
+	if user.Status != 1 {
+		return nil, errors.New("账号已被禁用")
+	}
+	// 登录链接发送到该邮箱，使用即视为邮箱已验证
+	s.userDAO.MarkEmailVerified(user.UserID)
+	// 重新加载以带上角色信息（两步验证要求取决于角色）
+	user, err = s.userDAO.GetByID(user.UserID)
+	if err != nil {
+		return nil, errors.New("账号不存在")
+	}
+
+	return s.authService.loginUser(user, &model.SessionMeta{
+		Device:            req.Device,
+		DeviceFingerprint: req.DeviceFingerprint,
+		IP:                ip,
+		UserAgent:         ua,
+		Method:            model.LoginMethodMagicLink,
+	})
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+)
+
+// setupMagicLinkTest 开启邮件登录并创建一个用户
+func setupMagicLinkTest(t *testing.T) (*MagicLinkService, *model.User) {
+	t.Helper()
+	setupTestEnv(t)
+	for key, value := range map[string]string{"magic_link_enabled": "true", "site_url": "https://site.example.com"} {
+		database.DB.Create(&model.SystemConfig{ConfigKey: key, ConfigValue: value})
+	}
+	return NewMagicLinkService(), createTestUser(t, "alice")
+}
+
+// magicLinkToken 签发登录链接令牌
+func magicLinkToken(t *testing.T, audience, email string, ttl time.Duration) string {
+	t.Helper()
+	jti, _ := util.RandomToken(16)
+	token, err := util.GenerateActionToken(audience, email, jti, ttl)
+	if err != nil {
+		t.Fatalf("签发令牌失败: %v", err)
+	}
+	return token
+}
+
+func TestMagicLinkLoginOnce(t *testing.T) {
+	s, user := setupMagicLinkTest(t)
+	token := magicLinkToken(t, util.AudienceMagicLink, user.Email, 15*time.Minute)
+
+	resp, err := s.Login(&model.MagicLinkLoginRequest{Token: token}, "127.0.0.1", "test")
+	if err != nil || resp.UserInfo == nil || resp.UserInfo.UserID != user.UserID || resp.Token == "" {
+		t.Fatalf("登录链接应能登录: %+v %v", resp, err)
+	}
+	if reloadUser(t, user.UserID).EmailVerifiedAt == nil {
+		t.Fatal("使用登录链接后邮箱应视为已验证")
+	}
+
+	// 同一链接不能再次使用
+	if _, err := s.Login(&model.MagicLinkLoginRequest{Token: token}, "127.0.0.1", "test"); err == nil || !strings.Contains(err.Error(), "已使用") {
+		t.Fatalf("重复使用的登录链接应被拒绝: %v", err)
+	}
+}
+
+func TestMagicLinkLoginRejects(t *testing.T) {
+	s, user := setupMagicLinkTest(t)
+	valid := magicLinkToken(t, util.AudienceMagicLink, user.Email, 15*time.Minute)
+
+	cases := map[string]string{
+		"已过期":    magicLinkToken(t, util.AudienceMagicLink, user.Email, -time.Minute),
+		"其他用途令牌": magicLinkToken(t, util.AudienceLoginAlert, user.Email, 15*time.Minute),
+		"被篡改":    valid + "x",
+	}
+	for name, token := range cases {
+		if _, err := s.Login(&model.MagicLinkLoginRequest{Token: token}, "", ""); err == nil || err.Error() != "登录链接无效或已过期" {
+			t.Fatalf("%s: 应拒绝登录: %v", name, err)
+		}
+	}
+
+	// 账号已禁用
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("status", 0)
+	if _, err := s.Login(&model.MagicLinkLoginRequest{Token: valid}, "", ""); err == nil {
+		t.Fatal("账号禁用后不应能登录")
+	}
+
+	// 关闭邮件登录后已发出的链接失效
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("status", 1)
+	database.DB.Model(&model.SystemConfig{}).Where("config_key = ?", "magic_link_enabled").Update("config_value", "false")
+	token := magicLinkToken(t, util.AudienceMagicLink, user.Email, 15*time.Minute)
+	if _, err := s.Login(&model.MagicLinkLoginRequest{Token: token}, "", ""); err == nil {
+		t.Fatal("关闭邮件登录后不应能登录")
+	}
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+	"embyhub/pkg/email"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// 默认VIP到期提醒阶段（到期前天数，0=到期当天）
+const defaultVipReminderStages = "7,3,1,0"
+
+// NotificationService 通知服务
+type NotificationService struct {
+	configDAO          *dao.SystemConfigDAO
+	notificationLogDAO *dao.NotificationLogDAO
+	emailService       *EmailService
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		configDAO:          dao.NewSystemConfigDAO(),
+		notificationLogDAO: dao.NewNotificationLogDAO(),
+		emailService:       NewEmailService(),
+	}
+}
+
+// VipReminderStages 读取提醒阶段配置（vip_reminder_stages，逗号分隔，升序去重）
+func (s *NotificationService) VipReminderStages() []int {
+	value := defaultVipReminderStages
+	if cfg, err := s.configDAO.Get("vip_reminder_stages"); err == nil {
+		value = cfg.ConfigValue
+	}
+
+	seen := make(map[int]bool)
+	var stages []int
+	for _, part := range strings.Split(value, ",") {
+		stage, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || stage < 0 || stage > 60 || seen[stage] {
+			continue
+		}
+		seen[stage] = true
+		stages = append(stages, stage)
+	}
+	sort.Ints(stages)
+	return stages
+}
+
+// pickVipReminderStage 根据剩余时长选择应发送的阶段
+// 已到期对应阶段0；未到期时选择剩余时长所在的最小阶段，避免首次运行时补发多个较早阶段
+func pickVipReminderStage(stages []int, remaining time.Duration) (int, bool) {
+	if remaining <= 0 {
+		return 0, len(stages) > 0 && stages[0] == 0
+	}
+	for _, stage := range stages {
+		if stage > 0 && remaining <= time.Duration(stage)*24*time.Hour {
+			return stage, true
+		}
+	}
+	return 0, false
+}
+
+// SendVipReminders 按阶段发送VIP到期提醒，返回成功发送数量
+// 每个用户每个到期日期的每个阶段最多成功发送一次（由 notification_log 唯一键保证）
+func (s *NotificationService) SendVipReminders() int {
+	stages := s.VipReminderStages()
+	if len(stages) == 0 {
+		return 0
+	}
+
+	client, err := s.emailService.GetEmailClient()
+	if err != nil {
+		return 0
+	}
+
+	const batchSize = 500
+	now := time.Now()
+	maxStage := stages[len(stages)-1]
+
+	query := database.DB.Model(&model.User{}).
+		Select("user_id", "username", "email", "vip_level", "vip_expire_at").
+		Where("email <> '' AND vip_reminder = ? AND vip_frozen_at IS NULL", true)
+	if stages[0] == 0 {
+		// 阶段0：过去一天内到期的用户（到期任务可能已将其降为免费等级）
+		query = query.Where("(vip_level > 0 AND vip_expire_at > ? AND vip_expire_at <= ?) OR (vip_expire_at > ? AND vip_expire_at <= ?)",
+			now, now.AddDate(0, 0, maxStage), now.Add(-24*time.Hour), now)
+	} else {
+		query = query.Where("vip_level > 0 AND vip_expire_at > ? AND vip_expire_at <= ?", now, now.AddDate(0, 0, maxStage))
+	}
+
+	sent := 0
+	lastID := 0
+	for {
+		var users []*model.User
+		if err := query.Session(&gorm.Session{}).Where("user_id > ?", lastID).
+			Order("user_id").Limit(batchSize).Find(&users).Error; err != nil {
+			util.Warn(fmt.Sprintf("查询VIP提醒用户失败: %v", err))
+			break
+		}
+
+		for _, user := range users {
+			lastID = user.UserID
+			stage, ok := pickVipReminderStage(stages, user.VipExpireAt.Sub(now))
+			if !ok {
+				continue
+			}
+			if s.sendVipReminder(client, user, stage, now) {
+				sent++
+			}
+		}
+
+		if len(users) < batchSize {
+			break
+		}
+	}
+
+	return sent
+}
+
+// sendVipReminder 发送单个用户的某阶段提醒，先写入记录占位再发送，已发送过则跳过；发送失败的记录下次任务重试
+func (s *NotificationService) sendVipReminder(client *email.Client, user *model.User, stage int, now time.Time) bool {
+	entry := &model.NotificationLog{
+		UserID:    user.UserID,
+		Type:      model.NotifyVipExpiry,
+		Stage:     stage,
+		RefKey:    user.VipExpireAt.Format("2006-01-02"),
+		Channel:   "email",
+		Recipient: user.Email,
+		Status:    model.NotifyStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	claimed, err := s.notificationLogDAO.Claim(entry)
+	if err != nil {
+		util.Warn(fmt.Sprintf("写入通知记录失败 user=%d stage=%d: %v", user.UserID, stage, err))
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	expireDate := user.VipExpireAt.Format("2006年01月02日 15:04")
+	if stage == 0 {
+		err = client.SendVipExpiredEmail(user.Email, user.Username, expireDate)
+	} else {
+		daysLeft := int(math.Ceil(user.VipExpireAt.Sub(now).Hours() / 24))
+		err = client.SendVipExpiringEmail(user.Email, user.Username, expireDate, daysLeft)
+	}
+
+	if err != nil {
+		util.Warn(fmt.Sprintf("发送VIP到期提醒失败 user=%s stage=%d: %v", user.Username, stage, err))
+		s.notificationLogDAO.UpdateStatus(entry.ID, model.NotifyStatusFailed, err.Error())
+		return false
+	}
+	s.notificationLogDAO.UpdateStatus(entry.ID, model.NotifyStatusSent, "")
+	return true
+}
+
+// SendTrialReminders 向试用即将结束且尚未续费的用户发送转化提醒，返回成功发送数量
+// 提醒时间由 trial_reminder_hours 配置，每次试用最多发送一次
+func (s *NotificationService) SendTrialReminders() int {
+	hours := NewTrialService().Config().ReminderHours
+	if hours <= 0 {
+		return 0
+	}
+
+	client, err := s.emailService.GetEmailClient()
+	if err != nil {
+		return 0
+	}
+
+	const batchSize = 500
+	now := time.Now()
+	trialDAO := dao.NewTrialDAO()
+
+	sent := 0
+	lastID := 0
+	for {
+		users, err := trialDAO.ListUnconverted(now, now.Add(time.Duration(hours)*time.Hour), lastID, batchSize)
+		if err != nil {
+			util.Warn(fmt.Sprintf("查询试用提醒用户失败: %v", err))
+			break
+		}
+
+		for _, user := range users {
+			lastID = user.UserID
+			if s.sendTrialReminder(client, user, now) {
+				sent++
+			}
+		}
+
+		if len(users) < batchSize {
+			break
+		}
+	}
+
+	return sent
+}
+
+// sendTrialReminder 发送单个用户的试用结束提醒，已发送过则跳过；发送失败的记录下次任务重试
+func (s *NotificationService) sendTrialReminder(client *email.Client, user *model.User, now time.Time) bool {
+	entry := &model.NotificationLog{
+		UserID:    user.UserID,
+		Type:      model.NotifyTrialEnd,
+		RefKey:    user.VipExpireAt.Format("2006-01-02"),
+		Channel:   "email",
+		Recipient: user.Email,
+		Status:    model.NotifyStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	claimed, err := s.notificationLogDAO.Claim(entry)
+	if err != nil {
+		util.Warn(fmt.Sprintf("写入通知记录失败 user=%d type=%s: %v", user.UserID, entry.Type, err))
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	hoursLeft := int(math.Ceil(user.VipExpireAt.Sub(now).Hours()))
+	err = client.SendTrialEndingEmail(user.Email, user.Username, user.VipExpireAt.Format("2006年01月02日 15:04"), hoursLeft)
+	if err != nil {
+		util.Warn(fmt.Sprintf("发送试用结束提醒失败 user=%s: %v", user.Username, err))
+		s.notificationLogDAO.UpdateStatus(entry.ID, model.NotifyStatusFailed, err.Error())
+		return false
+	}
+	s.notificationLogDAO.UpdateStatus(entry.ID, model.NotifyStatusSent, "")
+	return true
+}
+
+// ListLogs 获取通知发送记录及汇总
+func (s *NotificationService) ListLogs(req *model.NotificationLogListRequest) (*model.NotificationLogListResponse, error) {
+	logs, total, err := s.notificationLogDAO.List(req)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.notificationLogDAO.CountByStageStatus(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NotificationLogListResponse{
+		Total:   int(total),
+		List:    logs,
+		Summary: summary,
+	}, nil
+}
+
+// SetVipReminder 设置用户是否接收VIP到期提醒
+func (s *NotificationService) SetVipReminder(userID int, enabled bool) error {
+	if err := database.DB.Model(&model.User{}).Where("user_id = ?", userID).
+		Update("vip_reminder", enabled).Error; err != nil {
+		return fmt.Errorf("更新通知设置失败: %w", err)
+	}
+	redis.Del(fmt.Sprintf("emby_ums:user:info:%d", userID))
+	return nil
+}
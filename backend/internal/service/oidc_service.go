@@ -0,0 +1,256 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/oidc"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// 单点登录相关错误
+var (
+	ErrOIDCDisabled        = errors.New("未启用单点登录")
+	ErrOIDCState           = errors.New("登录请求已过期，请重新登录")
+	ErrOIDCUnverifiedLocal = errors.New("该邮箱对应的账号尚未验证邮箱，无法自动关联，请使用密码或登录链接登录")
+)
+
+// OIDCStateTTL 单点登录请求（state）的有效期
+const OIDCStateTTL = 10 * time.Minute
+
+const oidcStateKeyPrefix = "emby_ums:oidc:state:"
+
+// oidcState 跳转到身份提供方前保存的授权请求参数
+type oidcState struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// oidcSettings 单点登录系统配置
+type oidcSettings struct {
+	Enabled       bool
+	Name          string
+	AutoProvision bool
+	Client        oidc.Config
+}
+
+// OIDCService OIDC单点登录服务
+// 外部身份按 issuer + sub 绑定本地用户；首次登录时按已验证的邮箱关联现有账号，或按配置自动开通账号
+type OIDCService struct {
+	configDAO       *dao.SystemConfigDAO
+	identityDAO     *dao.UserIdentityDAO
+	userDAO         *dao.UserDAO
+	authService     *AuthService
+	registerService *RegisterService
+}
+
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		configDAO:       dao.NewSystemConfigDAO(),
+		identityDAO:     dao.NewUserIdentityDAO(),
+		userDAO:         dao.NewUserDAO(),
+		authService:     NewAuthService(),
+		registerService: NewRegisterService(),
+	}
+}
+
+// settings 读取单点登录配置，启用但配置不完整时视为未启用
+func (s *OIDCService) settings() *oidcSettings {
+	configs, err := s.configDAO.BatchGet([]string{
+		"oidc_enabled", "oidc_provider_name", "oidc_issuer", "oidc_client_id",
+		"oidc_client_secret", "oidc_redirect_url", "oidc_scopes", "oidc_auto_provision",
+	})
+	if err != nil {
+		return &oidcSettings{}
+	}
+
+	settings := &oidcSettings{
+		Enabled:       configs["oidc_enabled"] == "true",
+		Name:          strings.TrimSpace(configs["oidc_provider_name"]),
+		AutoProvision: configs["oidc_auto_provision"] == "true",
+		Client: oidc.Config{
+			Issuer:       strings.TrimSpace(configs["oidc_issuer"]),
+			ClientID:     strings.TrimSpace(configs["oidc_client_id"]),
+			ClientSecret: configs["oidc_client_secret"],
+			RedirectURL:  strings.TrimSpace(configs["oidc_redirect_url"]),
+			Scopes:       strings.Fields(configs["oidc_scopes"]),
+		},
+	}
+	if settings.Client.Issuer == "" || settings.Client.ClientID == "" || settings.Client.RedirectURL == "" {
+		settings.Enabled = false
+	}
+	if settings.Name == "" {
+		settings.Name = "单点登录"
+	}
+	return settings
+}
+
+// Config 登录页使用的单点登录配置
+func (s *OIDCService) Config() *model.OIDCConfigResponse {
+	settings := s.settings()
+	return &model.OIDCConfigResponse{Enabled: settings.Enabled, Name: settings.Name}
+}
+
+// Authorize 生成 state、nonce 和 PKCE code_verifier，返回身份提供方的授权地址和 state
+// state 需由调用方同时写入浏览器 Cookie，回调时与请求中的 state 比对，防止登录CSRF
+func (s *OIDCService) Authorize() (string, string, error) {
+	settings := s.settings()
+	if !settings.Enabled {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state, err := util.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := util.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := util.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := oidc.NewClient(settings.Client).AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		util.Error(fmt.Sprintf("生成单点登录地址失败: %v", err))
+		return "", "", errors.New("身份提供方暂不可用，请稍后重试")
+	}
+
+	data, err := json.Marshal(&oidcState{Nonce: nonce, CodeVerifier: verifier})
+	if err != nil {
+		return "", "", err
+	}
+	if err := redis.Set(oidcStateKeyPrefix+state, string(data), OIDCStateTTL); err != nil {
+		return "", "", fmt.Errorf("保存登录请求失败: %w", err)
+	}
+	return authURL, state, nil
+}
+
+// Callback 处理身份提供方回调：校验 state，用授权码换取并校验ID令牌，找到（或开通）本地用户后继续登录
+// cookieState 为发起登录时写入浏览器 Cookie 的 state，必须与回调中的 state 一致，
+// 避免攻击者诱导受害者使用攻击者的授权码登录攻击者的账号
+// 与密码登录一样，开启两步验证的用户需完成第二步
+func (s *OIDCService) Callback(req *model.OIDCCallbackRequest, cookieState, ip, ua string) (*model.LoginResponse, error) {
+	settings := s.settings()
+	if !settings.Enabled {
+		return nil, ErrOIDCDisabled
+	}
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(req.State)) != 1 {
+		return nil, ErrOIDCState
+	}
+
+	// state 只能使用一次
+	key := oidcStateKeyPrefix + req.State
+	data, err := redis.Get(key)
+	if err != nil {
+		return nil, ErrOIDCState
+	}
+	redis.Del(key)
+	var state oidcState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, ErrOIDCState
+	}
+
+	idToken, err := oidc.NewClient(settings.Client).Exchange(req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		util.Warn(fmt.Sprintf("单点登录失败: %v", err))
+		Audit(nil, "", model.ActionLoginFailed, model.TargetUser, "",
+			map[string]interface{}{"reason": "单点登录令牌校验失败"}, ip, ua, "failed")
+		return nil, errors.New("单点登录验证失败，请重新登录")
+	}
+
+	user, err := s.resolveUser(settings, idToken, ip, ua)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != 1 {
+		return nil, errors.New("账号已被禁用")
+	}
+
+	return s.authService.loginUser(user, &model.SessionMeta{
+		Device:            req.Device,
+		DeviceFingerprint: req.DeviceFingerprint,
+		IP:                ip,
+		UserAgent:         ua,
+		Method:            model.LoginMethodOIDC,
+	})
+}
+
+// resolveUser 查找外部身份对应的本地用户：已绑定的直接使用；
+// 未绑定时按双方都已验证的邮箱关联现有账号，找不到且允许自动开通时创建新账号
+func (s *OIDCService) resolveUser(settings *oidcSettings, idToken *oidc.IDToken, ip, ua string) (*model.User, error) {
+	provider := settings.Client.Issuer
+	identity, err := s.identityDAO.Get(provider, idToken.Subject)
+	if err == nil {
+		s.identityDAO.TouchLogin(identity.ID, idToken.Email)
+		user, err := s.userDAO.GetByID(identity.UserID)
+		if err != nil {
+			return nil, errors.New("绑定的账号不存在")
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 只有身份提供方确认过的邮箱才能用于关联，避免通过伪造邮箱接管账号；
+	// 本地账号的邮箱也必须已验证，否则他人可先用该邮箱占用本地账号，再等待真正的邮箱所有者单点登录时被关联
+	var user *model.User
+	if idToken.EmailVerified && idToken.Email != "" {
+		user, err = s.userDAO.GetByEmail(idToken.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if user != nil && user.EmailVerifiedAt == nil {
+			Audit(&user.UserID, user.Username, model.ActionLoginFailed, model.TargetUser, fmt.Sprint(user.UserID),
+				map[string]interface{}{"reason": "本地邮箱未验证，拒绝自动关联外部身份", "provider": provider, "subject": idToken.Subject}, ip, ua, "failed")
+			return nil, ErrOIDCUnverifiedLocal
+		}
+	}
+
+	provisioned := false
+	if user == nil {
+		if !settings.AutoProvision {
+			return nil, errors.New("该身份尚未关联账号，请先使用相同邮箱注册")
+		}
+		preferred := idToken.PreferredUsername
+		if preferred == "" && idToken.EmailVerified {
+			preferred = strings.SplitN(idToken.Email, "@", 2)[0]
+		}
+		if preferred == "" {
+			preferred = idToken.Name
+		}
+		user, err = s.registerService.ProvisionExternal(preferred, idToken.Email, idToken.EmailVerified, ip)
+		if err != nil {
+			return nil, err
+		}
+		provisioned = true
+	}
+
+	now := time.Now()
+	if err := s.identityDAO.Create(&model.UserIdentity{
+		UserID:      user.UserID,
+		Provider:    provider,
+		Subject:     idToken.Subject,
+		Email:       idToken.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, fmt.Errorf("绑定外部身份失败: %w", err)
+	}
+	Audit(&user.UserID, user.Username, model.ActionLinkIdentity, model.TargetUser, fmt.Sprint(user.UserID),
+		map[string]interface{}{"provider": provider, "subject": idToken.Subject, "provisioned": provisioned}, ip, ua, "success")
+
+	// 重新加载以带上角色信息（两步验证要求取决于角色）
+	return s.userDAO.GetByID(user.UserID)
+}
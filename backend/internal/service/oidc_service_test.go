@@ -0,0 +1,233 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"embyhub/config"
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+	"embyhub/pkg/oidc/oidctest"
+)
+
+// fakeEmby 模拟开通账号时用到的Emby接口
+type fakeEmby struct {
+	mu    sync.Mutex
+	users []string
+}
+
+func newFakeEmby(t *testing.T) *fakeEmby {
+	t.Helper()
+	f := &fakeEmby{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/Users":
+			users := make([]map[string]string, 0, len(f.users))
+			for i, name := range f.users {
+				users = append(users, map[string]string{"Id": fmt.Sprintf("emby-%d", i+1), "Name": name})
+			}
+			json.NewEncoder(w).Encode(users)
+		case r.Method == http.MethodPost && r.URL.Path == "/Users/New":
+			var body struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&body)
+			f.users = append(f.users, body.Name)
+			json.NewEncoder(w).Encode(map[string]string{"Id": fmt.Sprintf("emby-%d", len(f.users)), "Name": body.Name})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	config.GlobalConfig.Emby.ServerURL = server.URL
+	config.GlobalConfig.Emby.Timeout = 5
+	return f
+}
+
+// setupOIDCTest 准备身份提供方、Emby和单点登录配置
+func setupOIDCTest(t *testing.T, autoProvision bool) (*OIDCService, *oidctest.Provider) {
+	t.Helper()
+	setupTestEnv(t)
+	newFakeEmby(t)
+
+	if err := database.DB.Create(&model.Role{RoleID: 3, RoleName: "user"}).Error; err != nil {
+		t.Fatalf("创建角色失败: %v", err)
+	}
+
+	p := oidctest.NewProvider(t, "embyhub", "client-secret")
+	configs := map[string]string{
+		"oidc_enabled":        "true",
+		"oidc_issuer":         p.Issuer(),
+		"oidc_client_id":      p.ClientID,
+		"oidc_client_secret":  p.ClientSecret,
+		"oidc_redirect_url":   "https://site.example.com/oidc/callback",
+		"oidc_auto_provision": fmt.Sprint(autoProvision),
+	}
+	for key, value := range configs {
+		if err := database.DB.Create(&model.SystemConfig{ConfigKey: key, ConfigValue: value}).Error; err != nil {
+			t.Fatalf("写入配置失败: %v", err)
+		}
+	}
+	return NewOIDCService(), p
+}
+
+// oidcLogin 发起单点登录、在身份提供方登录后携带 state Cookie 回调
+func oidcLogin(t *testing.T, s *OIDCService, p *oidctest.Provider, identity oidctest.Identity) (*model.LoginResponse, error) {
+	t.Helper()
+	authURL, state, err := s.Authorize()
+	if err != nil {
+		t.Fatalf("发起单点登录失败: %v", err)
+	}
+	code, err := p.Authorize(authURL, identity)
+	if err != nil {
+		t.Fatalf("身份提供方授权失败: %v", err)
+	}
+	return s.Callback(&model.OIDCCallbackRequest{Code: code, State: state}, state, "127.0.0.1", "test")
+}
+
+// createLocalUser 创建本地用户，verified 表示邮箱是否已验证
+func createLocalUser(t *testing.T, username, email string, verified bool) *model.User {
+	t.Helper()
+	user := &model.User{Username: username, PasswordHash: "x", Email: email, RoleID: 3, Status: 1}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+func countIdentities(t *testing.T, userID int) int64 {
+	t.Helper()
+	var count int64
+	database.DB.Model(&model.UserIdentity{}).Where("user_id = ?", userID).Count(&count)
+	return count
+}
+
+func TestOIDCLinkByVerifiedEmail(t *testing.T) {
+	s, p := setupOIDCTest(t, false)
+	local := createLocalUser(t, "alice", "alice@example.com", true)
+
+	identity := oidctest.Identity{Subject: "sub-alice", Email: "alice@example.com", EmailVerified: true}
+	resp, err := oidcLogin(t, s, p, identity)
+	if err != nil {
+		t.Fatalf("单点登录失败: %v", err)
+	}
+	if resp.UserInfo == nil || resp.UserInfo.UserID != local.UserID || resp.TokenResponse == nil || resp.Token == "" {
+		t.Fatalf("应登录到同邮箱的本地账号: %+v", resp)
+	}
+	if countIdentities(t, local.UserID) != 1 {
+		t.Fatal("应绑定外部身份")
+	}
+
+	// 已绑定的身份按 sub 登录，不再依赖邮箱
+	identity.Email = "alice@new.example.com"
+	resp, err = oidcLogin(t, s, p, identity)
+	if err != nil || resp.UserInfo.UserID != local.UserID {
+		t.Fatalf("已绑定身份应直接登录: %+v %v", resp, err)
+	}
+}
+
+func TestOIDCRejectsUnverifiedEmails(t *testing.T) {
+	s, p := setupOIDCTest(t, true)
+	local := createLocalUser(t, "bob", "bob@example.com", false)
+
+	// 本地邮箱未验证：不自动关联，也不为该邮箱开通新账号
+	_, err := oidcLogin(t, s, p, oidctest.Identity{Subject: "sub-bob", Email: "bob@example.com", EmailVerified: true})
+	if !errors.Is(err, ErrOIDCUnverifiedLocal) {
+		t.Fatalf("本地邮箱未验证时应拒绝关联: %v", err)
+	}
+	if countIdentities(t, local.UserID) != 0 {
+		t.Fatal("不应绑定到邮箱未验证的本地账号")
+	}
+
+	// 身份提供方未确认邮箱：不按邮箱关联，开通的新账号不使用该邮箱
+	verified := createLocalUser(t, "carol", "carol@example.com", true)
+	resp, err := oidcLogin(t, s, p, oidctest.Identity{Subject: "sub-carol", Email: "carol@example.com", EmailVerified: false, PreferredUsername: "carol-sso"})
+	if err != nil {
+		t.Fatalf("单点登录失败: %v", err)
+	}
+	if resp.UserInfo.UserID == verified.UserID || resp.UserInfo.Email != "" {
+		t.Fatalf("未确认的邮箱不应用于关联账号: %+v", resp.UserInfo)
+	}
+}
+
+func TestOIDCProvisionNewUser(t *testing.T) {
+	s, p := setupOIDCTest(t, true)
+
+	identity := oidctest.Identity{Subject: "sub-dave", Email: "dave@example.com", EmailVerified: true, PreferredUsername: "dave"}
+	resp, err := oidcLogin(t, s, p, identity)
+	if err != nil {
+		t.Fatalf("单点登录失败: %v", err)
+	}
+	user := reloadUser(t, resp.UserInfo.UserID)
+	if user.Username != "dave" || user.Email != "dave@example.com" || user.EmailVerifiedAt == nil || user.EmbyUserID == "" {
+		t.Fatalf("开通的账号不符: %+v", user)
+	}
+	if countIdentities(t, user.UserID) != 1 {
+		t.Fatal("开通账号后应绑定外部身份")
+	}
+
+	// 再次登录使用同一账号
+	again, err := oidcLogin(t, s, p, identity)
+	if err != nil || again.UserInfo.UserID != user.UserID {
+		t.Fatalf("再次登录应使用已开通的账号: %+v %v", again, err)
+	}
+}
+
+func TestOIDCProvisionDisabled(t *testing.T) {
+	s, p := setupOIDCTest(t, false)
+
+	if _, err := oidcLogin(t, s, p, oidctest.Identity{Subject: "sub-erin", Email: "erin@example.com", EmailVerified: true}); err == nil {
+		t.Fatal("未开启自动开通时不应创建账号")
+	}
+	var count int64
+	database.DB.Model(&model.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("未开启自动开通时不应创建账号: %d", count)
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	s, p := setupOIDCTest(t, true)
+	identity := oidctest.Identity{Subject: "sub-frank", Email: "frank@example.com", EmailVerified: true}
+
+	authURL, state, err := s.Authorize()
+	if err != nil {
+		t.Fatalf("发起单点登录失败: %v", err)
+	}
+	code, _ := p.Authorize(authURL, identity)
+
+	// 缺少或不匹配的 state Cookie（如攻击者诱导受害者提交攻击者的授权码）
+	for _, cookie := range []string{"", "other-state"} {
+		if _, err := s.Callback(&model.OIDCCallbackRequest{Code: code, State: state}, cookie, "", ""); !errors.Is(err, ErrOIDCState) {
+			t.Fatalf("state Cookie 为 %q 时应拒绝: %v", cookie, err)
+		}
+	}
+
+	if _, err := s.Callback(&model.OIDCCallbackRequest{Code: code, State: state}, state, "", ""); err != nil {
+		t.Fatalf("state 匹配时应登录成功: %v", err)
+	}
+	// state 只能使用一次
+	if _, err := s.Callback(&model.OIDCCallbackRequest{Code: code, State: state}, state, "", ""); !errors.Is(err, ErrOIDCState) {
+		t.Fatalf("重复使用的 state 应被拒绝: %v", err)
+	}
+
+	// 伪造的 state（未经 Authorize 发起）
+	if _, err := s.Callback(&model.OIDCCallbackRequest{Code: code, State: "forged"}, "forged", "", ""); !errors.Is(err, ErrOIDCState) {
+		t.Fatalf("伪造的 state 应被拒绝: %v", err)
+	}
+	if !strings.HasPrefix(authURL, p.Issuer()) {
+		t.Fatalf("授权地址应指向身份提供方: %s", authURL)
+	}
+}
@@ -0,0 +1,569 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+	"embyhub/pkg/payment"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+var paymentInitOnce sync.Once
+
+// 支付网关配置项
+var paymentConfigKeys = []string{
+	"payment_epay_url",
+	"payment_epay_pid",
+	"payment_epay_key",
+	"payment_mock_enabled",
+	"payment_mock_secret",
+}
+
+// initPaymentGateways 按配置注册支付网关（修改配置后重启生效）
+// 易支付在配置平台地址、商户ID和密钥后注册；本地模拟网关可以任意完成支付，
+// 只在 payment_mock_enabled 为 true 时注册，仅用于开发和测试
+func initPaymentGateways() {
+	paymentInitOnce.Do(func() {
+		values, err := dao.NewSystemConfigDAO().BatchGet(paymentConfigKeys)
+		if err != nil {
+			util.Warn(fmt.Sprintf("读取支付配置失败: %v", err))
+			return
+		}
+
+		apiURL := strings.TrimSpace(values["payment_epay_url"])
+		pid := strings.TrimSpace(values["payment_epay_pid"])
+		key := strings.TrimSpace(values["payment_epay_key"])
+		if apiURL != "" && pid != "" && key != "" {
+			payment.Register(payment.NewEPayGateway(apiURL, pid, key))
+		}
+
+		if strings.TrimSpace(values["payment_mock_enabled"]) == "true" {
+			secret := values["payment_mock_secret"]
+			if secret == "" {
+				buf := make([]byte, 32)
+				rand.Read(buf)
+				secret = hex.EncodeToString(buf)
+			}
+			payment.Register(payment.NewMockGateway(secret))
+			util.Warn("本地模拟支付已启用，任何用户都可以免费完成支付，请勿在生产环境开启")
+		}
+	})
+}
+
+// MockPaymentEnabled 是否启用了本地模拟支付
+func MockPaymentEnabled() bool {
+	initPaymentGateways()
+	_, ok := payment.Get(payment.MockGatewayName)
+	return ok
+}
+
+// OrderService 订单服务
+// 订单状态流转：created -> paid -> fulfilled -> refunding -> refunded
+// 支付回调验签后在同一事务中锁定订单并发放VIP，重复回调不会重复发放；
+// 支付时用户VIP冻结中则订单停留在已支付，解冻后由 FulfillDeferredOrders 补发
+type OrderService struct {
+	orderDAO       *dao.OrderDAO
+	userDAO        *dao.UserDAO
+	configDAO      *dao.SystemConfigDAO
+	vipLedgerDAO   *dao.VipLedgerDAO
+	vipService     *VipService
+	vipTierService *VipTierService
+}
+
+func NewOrderService() *OrderService {
+	initPaymentGateways()
+	return &OrderService{
+		orderDAO:       dao.NewOrderDAO(),
+		userDAO:        dao.NewUserDAO(),
+		configDAO:      dao.NewSystemConfigDAO(),
+		vipLedgerDAO:   dao.NewVipLedgerDAO(),
+		vipService:     NewVipService(),
+		vipTierService: NewVipTierService(),
+	}
+}
+
+// ListPlans 获取套餐列表
+func (s *OrderService) ListPlans(onlyActive bool) ([]*model.Plan, error) {
+	return s.orderDAO.ListPlans(onlyActive)
+}
+
+// CreatePlan 创建套餐
+func (s *OrderService) CreatePlan(req *model.PlanRequest) (*model.Plan, error) {
+	if _, err := s.vipTierService.GetGrantable(req.VipLevel); err != nil {
+		return nil, err
+	}
+
+	plan := &model.Plan{
+		Name:      req.Name,
+		VipLevel:  req.VipLevel,
+		Days:      req.Days,
+		Price:     req.Price,
+		Status:    1,
+		SortOrder: req.SortOrder,
+	}
+	if req.Status != nil {
+		plan.Status = *req.Status
+	}
+	if err := s.orderDAO.CreatePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// UpdatePlan 更新套餐（已创建的订单保留下单时的等级、天数和金额）
+func (s *OrderService) UpdatePlan(id int, req *model.PlanRequest) (*model.Plan, error) {
+	plan, err := s.orderDAO.GetPlan(id)
+	if err != nil {
+		return nil, errors.New("套餐不存在")
+	}
+	if _, err := s.vipTierService.GetGrantable(req.VipLevel); err != nil {
+		return nil, err
+	}
+
+	plan.Name = req.Name
+	plan.VipLevel = req.VipLevel
+	plan.Days = req.Days
+	plan.Price = req.Price
+	plan.SortOrder = req.SortOrder
+	if req.Status != nil {
+		plan.Status = *req.Status
+	}
+	plan.UpdatedAt = time.Now()
+	if err := s.orderDAO.UpdatePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// DeletePlan 删除套餐（已有订单的套餐只能下架）
+func (s *OrderService) DeletePlan(id int) error {
+	if _, err := s.orderDAO.GetPlan(id); err != nil {
+		return errors.New("套餐不存在")
+	}
+	count, err := s.orderDAO.CountOrdersByPlan(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该套餐已有订单，请改为下架")
+	}
+	return s.orderDAO.DeletePlan(id)
+}
+
+// gateway 获取支付网关，name 为空时使用 payment_gateway 配置的默认网关
+func (s *OrderService) gateway(name string) (payment.Gateway, error) {
+	if name == "" {
+		name = payment.EPayGatewayName
+		if cfg, err := s.configDAO.Get("payment_gateway"); err == nil && cfg.ConfigValue != "" {
+			name = cfg.ConfigValue
+		}
+	}
+	g, ok := payment.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("支付方式不可用: %s", name)
+	}
+	return g, nil
+}
+
+// generateOrderNo 生成订单号（时间戳 + 6位随机数）
+func generateOrderNo() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	return fmt.Sprintf("%s%06d", time.Now().Format("20060102150405"), n.Int64())
+}
+
+// CreateOrder 创建订单并发起支付
+func (s *OrderService) CreateOrder(userID int, req *model.OrderCreateRequest) (*model.OrderCreateResponse, error) {
+	plan, err := s.orderDAO.GetPlan(req.PlanID)
+	if err != nil || plan.Status != 1 {
+		return nil, errors.New("套餐不存在或已下架")
+	}
+	if _, err := s.vipTierService.GetGrantable(plan.VipLevel); err != nil {
+		return nil, err
+	}
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.VipFrozenAt != nil {
+		return nil, ErrVipFrozen
+	}
+
+	g, err := s.gateway(req.Gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &model.Order{
+		OrderNo:  generateOrderNo(),
+		UserID:   userID,
+		PlanID:   plan.ID,
+		PlanName: plan.Name,
+		VipLevel: plan.VipLevel,
+		Days:     plan.Days,
+		Amount:   plan.Price,
+		Status:   model.OrderStatusCreated,
+		Gateway:  g.Name(),
+	}
+	if err := s.orderDAO.Create(order); err != nil {
+		return nil, fmt.Errorf("创建订单失败: %w", err)
+	}
+
+	// 回调地址需为支付平台可访问的完整URL（模拟网关只在本地使用）
+	siteURL := ""
+	if cfg, err := s.configDAO.Get("site_url"); err == nil {
+		siteURL = strings.TrimRight(strings.TrimSpace(cfg.ConfigValue), "/")
+	}
+	payReq := &payment.CreateRequest{
+		OrderNo:   order.OrderNo,
+		Amount:    order.Amount,
+		Subject:   plan.Name,
+		NotifyURL: siteURL + "/api/payments/callback/" + g.Name(),
+	}
+	if siteURL != "" {
+		payReq.ReturnURL = siteURL + "/"
+	}
+	result, err := g.CreatePayment(payReq)
+	if err != nil {
+		return nil, fmt.Errorf("发起支付失败: %w", err)
+	}
+
+	return &model.OrderCreateResponse{Order: order, PaymentURL: result.PaymentURL}, nil
+}
+
+// GetOrder 获取订单（userID 大于0时只能查看自己的订单）
+func (s *OrderService) GetOrder(orderNo string, userID int) (*model.Order, error) {
+	order, err := s.orderDAO.GetByOrderNo(orderNo)
+	if err != nil || (userID > 0 && order.UserID != userID) {
+		return nil, errors.New("订单不存在")
+	}
+	return order, nil
+}
+
+// ListOrders 获取订单列表
+func (s *OrderService) ListOrders(req *model.OrderListRequest) (*model.OrderListResponse, error) {
+	orders, total, err := s.orderDAO.List(req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OrderListResponse{Total: int(total), List: orders}, nil
+}
+
+// HandleCallback 处理支付回调
+// 验签后锁定订单：已履约/已退款的订单直接返回（幂等），否则标记已支付并在同一事务中发放VIP；
+// 用户VIP冻结中时只标记已支付并向支付平台确认收到通知，VIP待解冻后补发
+func (s *OrderService) HandleCallback(gatewayName string, params url.Values) (*model.Order, error) {
+	g, ok := payment.Get(gatewayName)
+	if !ok {
+		return nil, fmt.Errorf("支付方式不可用: %s", gatewayName)
+	}
+	notify, err := g.VerifyCallback(params)
+	if err != nil {
+		return nil, err
+	}
+	if !notify.Paid {
+		return nil, errors.New("订单未支付")
+	}
+
+	var order *model.Order
+	var user *model.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = s.orderDAO.LockByOrderNoTx(tx, notify.OrderNo); err != nil {
+			return errors.New("订单不存在")
+		}
+		if order.Status == model.OrderStatusFulfilled || order.Status == model.OrderStatusRefunding ||
+			order.Status == model.OrderStatusRefunded {
+			return nil
+		}
+		if order.Gateway != g.Name() {
+			return errors.New("支付方式与订单不符")
+		}
+		if notify.Amount != order.Amount {
+			return fmt.Errorf("支付金额不符: 应付%d 实付%d", order.Amount, notify.Amount)
+		}
+
+		if order.Status == model.OrderStatusCreated {
+			now := time.Now()
+			order.Status = model.OrderStatusPaid
+			order.TradeNo = notify.TradeNo
+			order.PaidAt = &now
+			if err := tx.Model(order).Updates(map[string]interface{}{
+				"status":     order.Status,
+				"trade_no":   order.TradeNo,
+				"paid_at":    order.PaidAt,
+				"updated_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		user, err = s.fulfillTx(tx, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		s.vipService.AfterCommit(user)
+	}
+	return order, nil
+}
+
+// fulfillTx 为已支付的订单发放VIP并标记已履约；用户VIP冻结中时不发放，返回的 user 为 nil
+func (s *OrderService) fulfillTx(tx *gorm.DB, order *model.Order) (*model.User, error) {
+	locked, err := s.vipService.LockUserTx(tx, order.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if locked.VipFrozenAt != nil {
+		return nil, nil
+	}
+
+	user, _, err := s.vipService.GrantTx(tx, &model.VipChange{
+		UserID: order.UserID,
+		Level:  order.VipLevel,
+		Days:   order.Days,
+		Source: model.VipSourceOrder,
+		RefID:  order.OrderNo,
+		Remark: "购买套餐: " + order.PlanName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	order.Status = model.OrderStatusFulfilled
+	order.FulfilledAt = &now
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"status":       order.Status,
+		"fulfilled_at": order.FulfilledAt,
+		"updated_at":   now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// FulfillDeferredOrders 为支付时VIP冻结中、现已解冻的用户补发已支付订单的VIP，返回处理数量
+func (s *OrderService) FulfillDeferredOrders() int {
+	const batchSize = 200
+	processed := 0
+	lastID := 0
+	for {
+		orders, err := s.orderDAO.ListDeferred(lastID, batchSize)
+		if err != nil {
+			util.Warn(fmt.Sprintf("查询待补发订单失败: %v", err))
+			break
+		}
+		for _, pending := range orders {
+			lastID = pending.ID
+			var user *model.User
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				order, err := s.orderDAO.LockByOrderNoTx(tx, pending.OrderNo)
+				if err != nil || order.Status != model.OrderStatusPaid {
+					return err
+				}
+				user, err = s.fulfillTx(tx, order)
+				return err
+			})
+			if err != nil {
+				util.Warn(fmt.Sprintf("补发订单VIP失败 order=%s: %v", pending.OrderNo, err))
+				continue
+			}
+			if user != nil {
+				s.vipService.AfterCommit(user)
+				processed++
+			}
+		}
+		if len(orders) < batchSize {
+			break
+		}
+	}
+	return processed
+}
+
+// MockPay 使用本地模拟网关完成支付（仅限订单所有者）
+// 生成与真实平台相同形式的签名回调，再走正常的回调处理流程
+func (s *OrderService) MockPay(orderNo string, userID int) (*model.Order, error) {
+	order, err := s.GetOrder(orderNo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Gateway != payment.MockGatewayName {
+		return nil, errors.New("该订单不支持模拟支付")
+	}
+
+	g, ok := payment.Get(payment.MockGatewayName)
+	if !ok {
+		return nil, errors.New("模拟支付不可用")
+	}
+	mock, ok := g.(*payment.MockGateway)
+	if !ok {
+		return nil, errors.New("模拟支付不可用")
+	}
+
+	return s.HandleCallback(mock.Name(), mock.SignCallback(order.OrderNo, order.Amount))
+}
+
+// Refund 订单退款，回收该订单发放的VIP时长后向支付平台发起退款
+// 先在事务中完成全部本地校验并回收VIP，订单标记为退款中，提交后再调用支付平台退款，成功后标记为已退款；
+// 支付平台退款失败时订单停留在退款中，再次调用只重试支付平台退款，不会重复回收VIP
+func (s *OrderService) Refund(orderNo, reason string, operatorID int, operatorName, ip, ua string) (*model.Order, error) {
+	unlock, err := s.lockRefund(orderNo)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	order, user, daysRevoked, err := s.revokeForRefund(orderNo, reason, operatorID)
+	if err != nil {
+		Audit(&operatorID, operatorName, model.ActionRefundOrder, model.TargetOrder, orderNo,
+			map[string]interface{}{"reason": reason, "error": err.Error()}, ip, ua, "failed")
+		return nil, err
+	}
+	if user != nil {
+		s.vipService.AfterCommit(user)
+	}
+
+	if err := s.settleRefund(order); err != nil {
+		Audit(&operatorID, operatorName, model.ActionRefundOrder, model.TargetOrder, orderNo,
+			map[string]interface{}{"reason": reason, "status": order.Status, "error": err.Error()}, ip, ua, "failed")
+		return nil, fmt.Errorf("VIP已回收，但支付平台退款失败，请稍后重试: %w", err)
+	}
+
+	detail := map[string]interface{}{
+		"user_id": order.UserID,
+		"amount":  order.Amount,
+		"reason":  reason,
+	}
+	if user != nil {
+		detail["days_revoked"] = daysRevoked
+		detail["vip_level"] = user.VipLevel
+		detail["vip_expire_at"] = user.VipExpireAt
+	}
+	Audit(&operatorID, operatorName, model.ActionRefundOrder, model.TargetOrder, orderNo, detail, ip, ua, "success")
+	util.Info(fmt.Sprintf("订单已退款 order=%s user=%d", order.OrderNo, order.UserID))
+
+	return order, nil
+}
+
+// lockRefund 同一订单同时只允许一个退款流程，避免重复调用支付平台退款
+func (s *OrderService) lockRefund(orderNo string) (func(), error) {
+	key := "emby_ums:order:refund:" + orderNo
+	ok, err := redis.SetNX(key, 1, 2*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("获取退款锁失败: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("该订单正在退款中，请稍后再试")
+	}
+	return func() { redis.Del(key) }, nil
+}
+
+// revokeForRefund 在事务中回收订单发放的VIP并将订单标记为退款中
+// 履约后VIP未再变动时恢复到购买前的等级和到期时间，否则扣除该订单实际增加的时长；回收后已到期则降为免费等级。
+// 订单已处于退款中（上次支付平台退款失败）时直接返回，user 为 nil
+func (s *OrderService) revokeForRefund(orderNo, reason string, operatorID int) (*model.Order, *model.User, int, error) {
+	var order *model.Order
+	var user *model.User
+	daysRevoked := 0
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = s.orderDAO.LockByOrderNoTx(tx, orderNo); err != nil {
+			return errors.New("订单不存在")
+		}
+		if order.Status == model.OrderStatusRefunding {
+			return nil
+		}
+		if order.Status != model.OrderStatusFulfilled {
+			return errors.New("只有已完成的订单可以退款")
+		}
+		if _, err := s.gateway(order.Gateway); err != nil {
+			return err
+		}
+
+		if user, err = s.vipService.LockUserTx(tx, order.UserID); err != nil {
+			return err
+		}
+		if user.VipFrozenAt != nil {
+			return ErrVipFrozen
+		}
+
+		now := time.Now()
+		level := user.VipLevel
+		expireAt := user.VipExpireAt
+		granted := time.Duration(order.Days) * 24 * time.Hour
+		entry, _ := s.vipLedgerDAO.GetBySourceRefTx(tx, model.VipSourceOrder, order.OrderNo)
+		if entry != nil {
+			granted = GrantedDuration(entry, order.Days)
+		}
+		if entry != nil && user.VipLevel == entry.LevelAfter && user.VipExpireAt != nil &&
+			entry.ExpireAfter != nil && user.VipExpireAt.Equal(*entry.ExpireAfter) {
+			// 履约后VIP未再变动：整体恢复到购买前的等级和到期时间
+			level = entry.LevelBefore
+			expireAt = entry.ExpireBefore
+		} else if user.VipExpireAt != nil {
+			rolled := user.VipExpireAt.Add(-granted)
+			expireAt = &rolled
+		}
+		if expireAt == nil || !expireAt.After(now) {
+			level = model.FreeVipLevel
+			expireAt = &now
+		}
+
+		daysRevoked = int(math.Round(granted.Hours() / 24))
+		if _, err := s.vipService.ApplyTx(tx, user, level, expireAt, -daysRevoked, &model.VipChange{
+			Source:     model.VipSourceRefund,
+			OperatorID: &operatorID,
+			RefID:      order.OrderNo,
+			Remark:     "订单退款: " + reason,
+		}); err != nil {
+			return err
+		}
+
+		order.Status = model.OrderStatusRefunding
+		return tx.Model(order).Updates(map[string]interface{}{
+			"status":     order.Status,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return order, user, daysRevoked, nil
+}
+
+// settleRefund 调用支付平台退款，成功后将退款中的订单标记为已退款
+func (s *OrderService) settleRefund(order *model.Order) error {
+	g, err := s.gateway(order.Gateway)
+	if err != nil {
+		return err
+	}
+	if err := g.Refund(order.OrderNo, order.TradeNo, order.Amount); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.orderDAO.MarkRefunded(order.OrderNo, now); err != nil {
+		return fmt.Errorf("支付平台已退款，但更新订单状态失败: %w", err)
+	}
+	order.Status = model.OrderStatusRefunded
+	order.RefundedAt = &now
+	return nil
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+	"embyhub/pkg/payment"
+)
+
+const testGatewayName = "test"
+
+// testGateway 测试网关：HMAC签名回调，记录退款调用并可模拟退款失败
+type testGateway struct {
+	secret []byte
+
+	mu        sync.Mutex
+	seq       int
+	refunds   []string
+	refundErr error
+}
+
+func newTestGateway(secret string) *testGateway {
+	return &testGateway{secret: []byte(secret)}
+}
+
+func (g *testGateway) Name() string {
+	return testGatewayName
+}
+
+func (g *testGateway) CreatePayment(req *payment.CreateRequest) (*payment.CreateResult, error) {
+	return &payment.CreateResult{PaymentURL: "https://pay.test/" + req.OrderNo}, nil
+}
+
+func (g *testGateway) VerifyCallback(params url.Values) (*payment.Notification, error) {
+	sign := params.Get("sign")
+	if sign == "" || !hmac.Equal([]byte(sign), []byte(g.sign(params))) {
+		return nil, payment.ErrInvalidSignature
+	}
+	amount, err := strconv.ParseInt(params.Get("amount"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &payment.Notification{
+		OrderNo: params.Get("order_no"),
+		TradeNo: params.Get("trade_no"),
+		Amount:  amount,
+		Paid:    params.Get("status") == "paid",
+	}, nil
+}
+
+func (g *testGateway) Refund(orderNo, tradeNo string, amount int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.refundErr != nil {
+		return g.refundErr
+	}
+	g.refunds = append(g.refunds, tradeNo)
+	return nil
+}
+
+func (g *testGateway) setRefundErr(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refundErr = err
+}
+
+func (g *testGateway) refundCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.refunds)
+}
+
+// notify 生成已签名的回调参数，每次使用新的交易号
+func (g *testGateway) notify(orderNo string, amount int64, status string) url.Values {
+	g.mu.Lock()
+	g.seq++
+	tradeNo := fmt.Sprintf("T%d", g.seq)
+	g.mu.Unlock()
+
+	params := url.Values{}
+	params.Set("order_no", orderNo)
+	params.Set("trade_no", tradeNo)
+	params.Set("amount", strconv.FormatInt(amount, 10))
+	params.Set("status", status)
+	params.Set("sign", g.sign(params))
+	return params
+}
+
+func (g *testGateway) sign(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key != "sign" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha256.New, g.secret)
+	for _, key := range keys {
+		mac.Write([]byte(key + "=" + params.Get(key) + "&"))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// setupOrderTest 准备等级、套餐、用户和测试网关，并创建一笔待支付订单
+func setupOrderTest(t *testing.T) (*OrderService, *testGateway, *model.User, *model.Order) {
+	t.Helper()
+	setupTestEnv(t)
+
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	plan := &model.Plan{Name: "月卡", VipLevel: 1, Days: 30, Price: 1500, Status: 1}
+	if err := database.DB.Create(plan).Error; err != nil {
+		t.Fatalf("创建套餐失败: %v", err)
+	}
+	user := createTestUser(t, "buyer")
+
+	g := newTestGateway("test-secret")
+	payment.Register(g)
+	s := NewOrderService()
+	resp, err := s.CreateOrder(user.UserID, &model.OrderCreateRequest{PlanID: plan.ID, Gateway: testGatewayName})
+	if err != nil {
+		t.Fatalf("创建订单失败: %v", err)
+	}
+	if resp.Order.Status != model.OrderStatusCreated || resp.Order.Amount != plan.Price || resp.PaymentURL == "" {
+		t.Fatalf("订单创建结果不符: %+v %q", resp.Order, resp.PaymentURL)
+	}
+	return s, g, user, resp.Order
+}
+
+func countLedger(t *testing.T, userID int, source string) int64 {
+	t.Helper()
+	var count int64
+	if err := database.DB.Model(&model.VipLedger{}).
+		Where("user_id = ? AND source = ?", userID, source).Count(&count).Error; err != nil {
+		t.Fatalf("统计VIP流水失败: %v", err)
+	}
+	return count
+}
+
+func TestOrderPurchaseAndRefund(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	paid, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid"))
+	if err != nil {
+		t.Fatalf("处理支付回调失败: %v", err)
+	}
+	if paid.Status != model.OrderStatusFulfilled || paid.TradeNo == "" || paid.PaidAt == nil || paid.FulfilledAt == nil {
+		t.Fatalf("回调后订单状态不符: %+v", paid)
+	}
+
+	buyer := reloadUser(t, user.UserID)
+	if buyer.VipLevel != 1 || buyer.VipExpireAt == nil {
+		t.Fatalf("支付后未发放VIP: level=%d expire=%v", buyer.VipLevel, buyer.VipExpireAt)
+	}
+	if remaining := time.Until(*buyer.VipExpireAt); remaining < 29*24*time.Hour || remaining > 30*24*time.Hour {
+		t.Fatalf("发放时长不符: %v", remaining)
+	}
+
+	refunded, err := s.Refund(order.OrderNo, "测试退款", 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	if refunded.Status != model.OrderStatusRefunded || refunded.RefundedAt == nil {
+		t.Fatalf("退款后订单状态不符: %+v", refunded)
+	}
+	if g.refundCount() != 1 || g.refunds[0] != paid.TradeNo {
+		t.Fatalf("支付平台退款调用不符: %v", g.refunds)
+	}
+
+	buyer = reloadUser(t, user.UserID)
+	if buyer.VipLevel != model.FreeVipLevel {
+		t.Fatalf("退款后VIP未回收: level=%d", buyer.VipLevel)
+	}
+	if countLedger(t, user.UserID, model.VipSourceRefund) != 1 {
+		t.Fatal("退款应写入一条回收流水")
+	}
+
+	if _, err := s.Refund(order.OrderNo, "重复退款", 1, "admin", "", ""); err == nil {
+		t.Fatal("已退款的订单不应再次退款")
+	}
+	if g.refundCount() != 1 {
+		t.Fatalf("重复退款不应调用支付平台: %d", g.refundCount())
+	}
+}
+
+func TestOrderCallbackDuplicate(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	notify := g.notify(order.OrderNo, order.Amount, "paid")
+	if _, err := s.HandleCallback(testGatewayName, notify); err != nil {
+		t.Fatalf("处理支付回调失败: %v", err)
+	}
+	first := reloadUser(t, user.UserID)
+
+	// 支付平台重发同一通知，以及携带新交易号的重复通知
+	for _, params := range []url.Values{notify, g.notify(order.OrderNo, order.Amount, "paid")} {
+		again, err := s.HandleCallback(testGatewayName, params)
+		if err != nil {
+			t.Fatalf("重复回调应幂等返回: %v", err)
+		}
+		if again.Status != model.OrderStatusFulfilled || again.TradeNo != notify.Get("trade_no") {
+			t.Fatalf("重复回调改变了订单: %+v", again)
+		}
+	}
+
+	after := reloadUser(t, user.UserID)
+	if !after.VipExpireAt.Equal(*first.VipExpireAt) {
+		t.Fatalf("重复回调重复发放VIP: %v -> %v", first.VipExpireAt, after.VipExpireAt)
+	}
+	if countLedger(t, user.UserID, model.VipSourceOrder) != 1 {
+		t.Fatal("重复回调只应写入一条发放流水")
+	}
+}
+
+func TestOrderCallbackOutOfOrder(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	// 未支付通知先于支付成功通知到达
+	pending := g.notify(order.OrderNo, order.Amount, "pending")
+	if _, err := s.HandleCallback(testGatewayName, pending); err == nil {
+		t.Fatal("未支付通知不应履约")
+	}
+	if got, _ := s.GetOrder(order.OrderNo, 0); got.Status != model.OrderStatusCreated {
+		t.Fatalf("未支付通知改变了订单状态: %s", got.Status)
+	}
+
+	if _, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid")); err != nil {
+		t.Fatalf("处理支付回调失败: %v", err)
+	}
+	if _, err := s.Refund(order.OrderNo, "测试退款", 1, "admin", "", ""); err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+
+	// 退款后迟到的支付成功通知不应重新发放VIP
+	late, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid"))
+	if err != nil {
+		t.Fatalf("迟到回调应幂等返回: %v", err)
+	}
+	if late.Status != model.OrderStatusRefunded {
+		t.Fatalf("迟到回调改变了退款订单: %s", late.Status)
+	}
+	if buyer := reloadUser(t, user.UserID); buyer.VipLevel != model.FreeVipLevel {
+		t.Fatalf("迟到回调重新发放了VIP: level=%d", buyer.VipLevel)
+	}
+	if countLedger(t, user.UserID, model.VipSourceOrder) != 1 {
+		t.Fatal("迟到回调不应写入发放流水")
+	}
+}
+
+func TestOrderCallbackBadSignature(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	valid := g.notify(order.OrderNo, order.Amount, "paid")
+	cases := map[string]url.Values{}
+
+	missing := cloneValues(valid)
+	missing.Del("sign")
+	cases["缺少签名"] = missing
+
+	tampered := cloneValues(valid)
+	tampered.Set("amount", "1")
+	cases["篡改金额"] = tampered
+
+	forged := newTestGateway("other-secret").notify(order.OrderNo, order.Amount, "paid")
+	cases["错误密钥"] = forged
+
+	for name, params := range cases {
+		if _, err := s.HandleCallback(testGatewayName, params); !errors.Is(err, payment.ErrInvalidSignature) {
+			t.Fatalf("%s: 应返回签名无效，实际 %v", name, err)
+		}
+	}
+
+	// 签名有效但金额与订单不符
+	if _, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount-1, "paid")); err == nil {
+		t.Fatal("金额不符的回调不应履约")
+	}
+	// 未注册的网关
+	if _, err := s.HandleCallback("unknown", valid); err == nil {
+		t.Fatal("未注册网关的回调不应处理")
+	}
+
+	if got, _ := s.GetOrder(order.OrderNo, 0); got.Status != model.OrderStatusCreated {
+		t.Fatalf("无效回调改变了订单状态: %s", got.Status)
+	}
+	if buyer := reloadUser(t, user.UserID); buyer.VipLevel != model.FreeVipLevel {
+		t.Fatalf("无效回调发放了VIP: level=%d", buyer.VipLevel)
+	}
+}
+
+func TestOrderRefundGatewayFailure(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	if _, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid")); err != nil {
+		t.Fatalf("处理支付回调失败: %v", err)
+	}
+
+	g.setRefundErr(errors.New("余额不足"))
+	if _, err := s.Refund(order.OrderNo, "测试退款", 1, "admin", "", ""); err == nil {
+		t.Fatal("支付平台退款失败时应返回错误")
+	}
+	got, _ := s.GetOrder(order.OrderNo, 0)
+	if got.Status != model.OrderStatusRefunding {
+		t.Fatalf("支付平台退款失败后订单应为退款中: %s", got.Status)
+	}
+	if buyer := reloadUser(t, user.UserID); buyer.VipLevel != model.FreeVipLevel {
+		t.Fatalf("VIP应先于支付平台退款回收: level=%d", buyer.VipLevel)
+	}
+
+	// 退款中的订单不接受支付回调
+	if _, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid")); err != nil {
+		t.Fatalf("退款中订单的回调应幂等返回: %v", err)
+	}
+
+	g.setRefundErr(nil)
+	refunded, err := s.Refund(order.OrderNo, "重试退款", 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("重试退款失败: %v", err)
+	}
+	if refunded.Status != model.OrderStatusRefunded || g.refundCount() != 1 {
+		t.Fatalf("重试后订单状态或退款调用不符: %s %d", refunded.Status, g.refundCount())
+	}
+	if countLedger(t, user.UserID, model.VipSourceRefund) != 1 {
+		t.Fatal("重试退款不应重复回收VIP")
+	}
+}
+
+func TestOrderRefundFrozenUser(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	if _, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid")); err != nil {
+		t.Fatalf("处理支付回调失败: %v", err)
+	}
+	if err := database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).
+		Update("vip_frozen_at", time.Now()).Error; err != nil {
+		t.Fatalf("冻结用户失败: %v", err)
+	}
+
+	if _, err := s.Refund(order.OrderNo, "测试退款", 1, "admin", "", ""); !errors.Is(err, ErrVipFrozen) {
+		t.Fatalf("冻结用户退款应返回 ErrVipFrozen，实际 %v", err)
+	}
+	if g.refundCount() != 0 {
+		t.Fatal("本地校验失败时不应调用支付平台退款")
+	}
+	if got, _ := s.GetOrder(order.OrderNo, 0); got.Status != model.OrderStatusFulfilled {
+		t.Fatalf("本地校验失败时订单状态不应改变: %s", got.Status)
+	}
+}
+
+func cloneValues(v url.Values) url.Values {
+	c := url.Values{}
+	for key, values := range v {
+		c[key] = append([]string(nil), values...)
+	}
+	return c
+}
+
+func TestOrderCallbackWhileFrozen(t *testing.T) {
+	s, g, user, order := setupOrderTest(t)
+
+	// 下单后用户冻结VIP，支付回调照常确认收款，VIP推迟发放
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("vip_frozen_at", time.Now())
+	paid, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid"))
+	if err != nil {
+		t.Fatalf("冻结期间的支付回调应确认收款: %v", err)
+	}
+	if paid.Status != model.OrderStatusPaid || paid.PaidAt == nil || paid.TradeNo == "" {
+		t.Fatalf("冻结期间订单应标记为已支付: %+v", paid)
+	}
+	if reloadUser(t, user.UserID).VipExpireAt != nil {
+		t.Fatal("冻结期间不应发放VIP")
+	}
+
+	// 重复回调仍然成功且不发放
+	if _, err := s.HandleCallback(testGatewayName, g.notify(order.OrderNo, order.Amount, "paid")); err != nil {
+		t.Fatalf("重复回调应成功: %v", err)
+	}
+	if n := s.FulfillDeferredOrders(); n != 0 {
+		t.Fatalf("冻结期间不应补发: %d", n)
+	}
+
+	// 冻结中的用户不能再下单
+	if _, err := s.CreateOrder(user.UserID, &model.OrderCreateRequest{PlanID: order.PlanID, Gateway: testGatewayName}); !errors.Is(err, ErrVipFrozen) {
+		t.Fatalf("冻结中的用户下单应被拒绝: %v", err)
+	}
+
+	// 解冻后补发一次
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("vip_frozen_at", nil)
+	if n := s.FulfillDeferredOrders(); n != 1 {
+		t.Fatalf("解冻后应补发一笔订单: %d", n)
+	}
+	if n := s.FulfillDeferredOrders(); n != 0 {
+		t.Fatalf("已履约的订单不应重复补发: %d", n)
+	}
+	fulfilled, _ := s.GetOrder(order.OrderNo, 0)
+	if fulfilled.Status != model.OrderStatusFulfilled || fulfilled.FulfilledAt == nil {
+		t.Fatalf("补发后订单应已履约: %+v", fulfilled)
+	}
+	if got := reloadUser(t, user.UserID); got.VipLevel != 1 || got.VipExpireAt == nil {
+		t.Fatalf("补发后应获得VIP: %+v", got)
+	}
+	var entries int64
+	database.DB.Model(&model.VipLedger{}).Where("user_id = ? AND ref_id = ?", user.UserID, order.OrderNo).Count(&entries)
+	if entries != 1 {
+		t.Fatalf("应只有一条发放流水: %d", entries)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+)
+
+// 密码策略配置项
+var passwordPolicyConfigKeys = []string{
+	"password_min_length",
+	"password_require_letter",
+	"password_require_mixed_case",
+	"password_require_digit",
+	"password_require_symbol",
+	"password_blocklist_file",
+	"password_history_count",
+	"password_max_age_days",
+}
+
+// passwordSettings 密码策略配置
+type passwordSettings struct {
+	Policy       *util.PasswordPolicy
+	HistoryCount int // 禁止重复使用最近N次的密码，0=不限制
+	MaxAgeDays   int // 密码有效期（天），到期后登录须修改密码，0=永不过期
+}
+
+// passwordBlocklist 常见/泄露密码列表缓存，文件修改后自动重新加载
+var passwordBlocklist struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	list    map[string]struct{}
+}
+
+// PasswordPolicyService 密码策略服务
+// 密码规则、常见密码列表、历史密码和密码有效期均由 system_configs 配置，所有设置密码的入口都需经过校验
+type PasswordPolicyService struct {
+	configDAO  *dao.SystemConfigDAO
+	userDAO    *dao.UserDAO
+	historyDAO *dao.PasswordHistoryDAO
+}
+
+func NewPasswordPolicyService() *PasswordPolicyService {
+	return &PasswordPolicyService{
+		configDAO:  dao.NewSystemConfigDAO(),
+		userDAO:    dao.NewUserDAO(),
+		historyDAO: dao.NewPasswordHistoryDAO(),
+	}
+}
+
+// settings 读取密码策略配置，读取失败时使用默认策略
+func (s *PasswordPolicyService) settings() *passwordSettings {
+	values, err := s.configDAO.BatchGet(passwordPolicyConfigKeys)
+	if err != nil {
+		return &passwordSettings{Policy: util.DefaultPasswordPolicy()}
+	}
+
+	atoi := func(key string, def int) int {
+		if v, err := strconv.Atoi(strings.TrimSpace(values[key])); err == nil && v >= 0 {
+			return v
+		}
+		return def
+	}
+	flag := func(key string, def bool) bool {
+		switch strings.TrimSpace(values[key]) {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+		return def
+	}
+
+	defaults := util.DefaultPasswordPolicy()
+	return &passwordSettings{
+		Policy: &util.PasswordPolicy{
+			MinLength:        atoi("password_min_length", defaults.MinLength),
+			RequireLetter:    flag("password_require_letter", defaults.RequireLetter),
+			RequireMixedCase: flag("password_require_mixed_case", defaults.RequireMixedCase),
+			RequireDigit:     flag("password_require_digit", defaults.RequireDigit),
+			RequireSymbol:    flag("password_require_symbol", defaults.RequireSymbol),
+			Blocklist:        loadPasswordBlocklist(strings.TrimSpace(values["password_blocklist_file"])),
+		},
+		HistoryCount: atoi("password_history_count", 0),
+		MaxAgeDays:   atoi("password_max_age_days", 0),
+	}
+}
+
+// loadPasswordBlocklist 加载常见密码列表，文件不存在或读取失败时不启用
+func loadPasswordBlocklist(path string) map[string]struct{} {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		util.Warn(fmt.Sprintf("常见密码列表不可用: %v", err))
+		return nil
+	}
+
+	passwordBlocklist.Lock()
+	defer passwordBlocklist.Unlock()
+	if passwordBlocklist.path == path && passwordBlocklist.modTime.Equal(info.ModTime()) {
+		return passwordBlocklist.list
+	}
+
+	list, err := util.LoadPasswordList(path)
+	if err != nil {
+		util.Warn(fmt.Sprintf("读取常见密码列表失败: %v", err))
+		return nil
+	}
+	passwordBlocklist.path = path
+	passwordBlocklist.modTime = info.ModTime()
+	passwordBlocklist.list = list
+	util.Info(fmt.Sprintf("已加载常见密码列表: %s（%d条）", path, len(list)))
+	return list
+}
+
+// Check 校验新密码是否符合密码策略；user 不为空时同时检查是否与最近使用过的密码相同
+func (s *PasswordPolicyService) Check(user *model.User, password string) error {
+	settings := s.settings()
+	if err := settings.Policy.Validate(password); err != nil {
+		return err
+	}
+	if user == nil || settings.HistoryCount == 0 {
+		return nil
+	}
+
+	reused := fmt.Errorf("新密码不能与最近%d次使用过的密码相同", settings.HistoryCount)
+	if user.PasswordHash != "" && util.CheckPassword(password, user.PasswordHash) {
+		return reused
+	}
+	hashes, err := s.historyDAO.Recent(user.UserID, settings.HistoryCount)
+	if err != nil {
+		return fmt.Errorf("查询历史密码失败: %w", err)
+	}
+	for _, hash := range hashes {
+		if util.CheckPassword(password, hash) {
+			return reused
+		}
+	}
+	return nil
+}
+
+// Apply 为用户设置已通过校验的新密码并记录历史密码
+// mustChange 为 true 时用户下次登录须修改密码（管理员重置密码时使用）
+func (s *PasswordPolicyService) Apply(user *model.User, password string, mustChange bool) error {
+	hashedPassword, err := util.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("密码加密失败: %w", err)
+	}
+
+	now := time.Now()
+	user.PasswordHash = hashedPassword
+	user.MustChangePassword = mustChange
+	user.PasswordChangedAt = &now
+	user.UpdatedAt = now
+	if err := s.userDAO.Update(user); err != nil {
+		return fmt.Errorf("更新密码失败: %w", err)
+	}
+
+	s.Remember(user.UserID, hashedPassword)
+	return nil
+}
+
+// Remember 记录用户使用过的密码，只保留配置的历史条数
+func (s *PasswordPolicyService) Remember(userID int, passwordHash string) {
+	count := s.settings().HistoryCount
+	if count == 0 {
+		return
+	}
+	if err := s.historyDAO.Create(&model.PasswordHistory{UserID: userID, PasswordHash: passwordHash}); err != nil {
+		util.Warn(fmt.Sprintf("记录历史密码失败: user_id=%d, %v", userID, err))
+		return
+	}
+	if err := s.historyDAO.Prune(userID, count); err != nil {
+		util.Warn(fmt.Sprintf("清理历史密码失败: user_id=%d, %v", userID, err))
+	}
+}
+
+// MustChange 用户是否须先修改密码：管理员要求修改，或密码已超过有效期
+func (s *PasswordPolicyService) MustChange(user *model.User) bool {
+	if user.MustChangePassword {
+		return true
+	}
+	maxAge := s.settings().MaxAgeDays
+	if maxAge == 0 {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(maxAge)*24*time.Hour
+}
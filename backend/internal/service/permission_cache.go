@@ -0,0 +1,204 @@
+package service
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/redis"
+)
+
+// 权限缓存配置
+const (
+	permCacheCapacity = 256             // 进程内最多缓存的角色数
+	permCacheLocalTTL = 5 * time.Minute // 进程内缓存有效期，pub/sub 消息丢失时的兜底
+	permCacheChannel  = "emby_ums:perms:invalidate"
+)
+
+// permCacheEntry 进程内缓存的角色权限规则
+type permCacheEntry struct {
+	roleID   int
+	rules    *model.RolePermissionSet
+	expireAt time.Time
+}
+
+// PermissionCache 角色权限缓存
+// 进程内 LRU 在前，Redis 在后，均未命中时查询数据库；
+// 角色权限变更时递增 Redis 中的版本号，并通过 pub/sub 通知所有实例清除本地缓存
+type PermissionCache struct {
+	mu         sync.Mutex
+	ll         *list.List
+	items      map[int]*list.Element
+	generation uint64 // 每次失效递增，防止失效前发起的加载把旧数据写回本地缓存
+
+	permissionDAO *dao.PermissionDAO
+}
+
+func NewPermissionCache() *PermissionCache {
+	return &PermissionCache{
+		ll:            list.New(),
+		items:         make(map[int]*list.Element),
+		permissionDAO: dao.NewPermissionDAO(),
+	}
+}
+
+// 全局权限缓存实例
+var permissionCache = NewPermissionCache()
+
+// PermCache 获取全局权限缓存
+func PermCache() *PermissionCache {
+	return permissionCache
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func (c *PermissionCache) HasPermission(roleID int, permissionKey string) (bool, error) {
+	result, err := c.Check(roleID, permissionKey)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Check 检查角色对指定权限的判定结果，拒绝规则优先于允许规则
+func (c *PermissionCache) Check(roleID int, permissionKey string) (*model.PermissionCheckResult, error) {
+	rules, err := c.RoleRules(roleID)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluatePermission(roleID, rules, permissionKey), nil
+}
+
+// EvaluatePermission 按规则集判定权限
+func EvaluatePermission(roleID int, rules *model.RolePermissionSet, permissionKey string) *model.PermissionCheckResult {
+	result := &model.PermissionCheckResult{RoleID: roleID, Key: permissionKey}
+	for _, pattern := range rules.Deny {
+		if util.MatchPermission(pattern, permissionKey) {
+			result.Effect = model.PermissionEffectDeny
+			result.MatchedRule = pattern
+			return result
+		}
+	}
+	for _, pattern := range rules.Allow {
+		if util.MatchPermission(pattern, permissionKey) {
+			result.Allowed = true
+			result.Effect = model.PermissionEffectAllow
+			result.MatchedRule = pattern
+			return result
+		}
+	}
+	return result
+}
+
+// RoleRules 获取角色的权限规则，返回值只读
+func (c *PermissionCache) RoleRules(roleID int) (*model.RolePermissionSet, error) {
+	c.mu.Lock()
+	if elem, ok := c.items[roleID]; ok {
+		entry := elem.Value.(*permCacheEntry)
+		if time.Now().Before(entry.expireAt) {
+			c.ll.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.rules, nil
+		}
+		c.removeElement(elem)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	rules, err := c.load(roleID)
+	if err != nil {
+		return nil, err
+	}
+	c.store(roleID, rules, generation)
+	return rules, nil
+}
+
+// load 依次从 Redis 和数据库加载角色权限规则，Redis 不可用时直接查询数据库
+func (c *PermissionCache) load(roleID int) (*model.RolePermissionSet, error) {
+	version, err := Cache().RolePermissionsVersion(roleID)
+	if err != nil {
+		util.Warn(fmt.Sprintf("读取角色权限版本失败，回退到数据库: %v", err))
+		return c.permissionDAO.GetRuleSetByRoleID(roleID)
+	}
+	if rules, err := Cache().GetRolePermissions(roleID, version); err == nil {
+		return rules, nil
+	}
+
+	rules, err := c.permissionDAO.GetRuleSetByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Cache().SetRolePermissions(roleID, version, rules); err != nil {
+		util.Warn(fmt.Sprintf("写入角色权限缓存失败: %v", err))
+	}
+	return rules, nil
+}
+
+// store 写入本地缓存，加载期间发生过失效时丢弃结果
+func (c *PermissionCache) store(roleID int, rules *model.RolePermissionSet, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	entry := &permCacheEntry{roleID: roleID, rules: rules, expireAt: time.Now().Add(permCacheLocalTTL)}
+	if elem, ok := c.items[roleID]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[roleID] = c.ll.PushFront(entry)
+	if c.ll.Len() > permCacheCapacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// removeElement 移除本地缓存条目，调用方需持有锁
+func (c *PermissionCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*permCacheEntry).roleID)
+}
+
+// dropLocal 清除本地缓存，roleID 为0时清除全部
+func (c *PermissionCache) dropLocal(roleID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if roleID == 0 {
+		c.ll.Init()
+		c.items = make(map[int]*list.Element)
+		return
+	}
+	if elem, ok := c.items[roleID]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Invalidate 角色权限变更后调用：递增 Redis 版本号并广播给所有实例
+func (c *PermissionCache) Invalidate(roleID int) {
+	c.dropLocal(roleID)
+	if err := Cache().InvalidateRolePermissions(roleID); err != nil {
+		util.Warn(fmt.Sprintf("角色权限缓存失效失败: role_id=%d, %v", roleID, err))
+	}
+	if err := redis.Publish(permCacheChannel, roleID); err != nil {
+		util.Warn(fmt.Sprintf("广播角色权限失效失败: role_id=%d, %v", roleID, err))
+	}
+}
+
+// StartSync 订阅权限失效广播，收到后清除本地缓存
+// 订阅建立或重连成功时清空本地缓存，避免断线期间漏掉的消息导致脏数据
+func (c *PermissionCache) StartSync() {
+	go redis.SubscribeLoop(permCacheChannel, func() {
+		c.dropLocal(0)
+	}, func(payload string) {
+		if roleID, err := strconv.Atoi(payload); err == nil {
+			c.dropLocal(roleID)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"embyhub/internal/model"
+)
+
+// PermissionRegistry 代码中声明的权限及路由所需权限的登记表
+// 路由注册时登记，启动时同步到 permissions 表，并供前端按路由生成菜单
+type PermissionRegistry struct {
+	mu     sync.RWMutex
+	defs   map[string]model.PermissionDef
+	order  []string
+	routes []*model.RoutePermission
+	index  map[string]string // "METHOD 完整路径" -> 权限键
+}
+
+// 全局权限登记表
+var permissionRegistry = &PermissionRegistry{defs: make(map[string]model.PermissionDef)}
+
+// Permissions 获取全局权限登记表
+func Permissions() *PermissionRegistry {
+	return permissionRegistry
+}
+
+// Declare 声明权限，重复声明同一权限键视为编程错误
+func (r *PermissionRegistry) Declare(def model.PermissionDef) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.defs[def.Key]; ok {
+		panic(fmt.Sprintf("权限重复声明: %s", def.Key))
+	}
+	r.defs[def.Key] = def
+	r.order = append(r.order, def.Key)
+	return def.Key
+}
+
+// Get 获取已声明的权限
+func (r *PermissionRegistry) Get(key string) (model.PermissionDef, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, ok := r.defs[key]
+	return def, ok
+}
+
+// Definitions 按声明顺序返回所有权限
+func (r *PermissionRegistry) Definitions() []model.PermissionDef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]model.PermissionDef, 0, len(r.order))
+	for _, key := range r.order {
+		defs = append(defs, r.defs[key])
+	}
+	return defs
+}
+
+// SetRoutes 登记路由与所需权限的对应关系，按路径和方法排序
+func (r *PermissionRegistry) SetRoutes(routes []*model.RoutePermission) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	index := make(map[string]string, len(routes))
+	for _, route := range routes {
+		index[route.Method+" "+route.Path] = route.PermissionKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = routes
+	r.index = index
+}
+
+// Routes 返回路由与所需权限的对应关系
+func (r *PermissionRegistry) Routes() []*model.RoutePermission {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.routes
+}
+
+// RouteKey 获取路由所需的权限键，path 为路由模板（gin.Context.FullPath），不需要权限时返回空
+func (r *PermissionRegistry) RouteKey(method, path string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.index[method+" "+path]
+	return key, ok
+}
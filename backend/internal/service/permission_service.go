@@ -1,17 +1,30 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"embyhub/internal/dao"
 	"embyhub/internal/model"
+	"embyhub/internal/util"
+
+	"gorm.io/gorm"
 )
 
 type PermissionService struct {
-	permissionDAO *dao.PermissionDAO
+	permissionDAO      *dao.PermissionDAO
+	permissionGroupDAO *dao.PermissionGroupDAO
+	roleDAO            *dao.RoleDAO
+	userDAO            *dao.UserDAO
 }
 
 func NewPermissionService() *PermissionService {
 	return &PermissionService{
-		permissionDAO: dao.NewPermissionDAO(),
+		permissionDAO:      dao.NewPermissionDAO(),
+		permissionGroupDAO: dao.NewPermissionGroupDAO(),
+		roleDAO:            dao.NewRoleDAO(),
+		userDAO:            dao.NewUserDAO(),
 	}
 }
 
@@ -28,7 +41,210 @@ func (s *PermissionService) List() (*model.PermissionListResponse, error) {
 	}, nil
 }
 
+// SyncDeclared 将代码中声明的权限同步到 permissions 表
+// 新增缺失的权限，以代码为准更新名称、描述和分组，并标记代码中已不再声明的权限（通配模式除外）；
+// 被标记的权限不会删除，以免影响已授予它的角色
+func (s *PermissionService) SyncDeclared() (*model.PermissionSyncResult, error) {
+	existing, err := s.permissionDAO.List()
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*model.Permission, len(existing))
+	for _, permission := range existing {
+		byKey[permission.PermissionKey] = permission
+	}
+
+	result := &model.PermissionSyncResult{Added: []string{}, Updated: []string{}, Orphaned: []string{}}
+	declared := make(map[string]bool)
+	for _, def := range Permissions().Definitions() {
+		declared[def.Key] = true
+		permission, ok := byKey[def.Key]
+		if !ok {
+			permission = &model.Permission{
+				PermissionName: def.Name,
+				PermissionKey:  def.Key,
+				Description:    def.Description,
+				Category:       def.Category,
+			}
+			if err := s.permissionDAO.Create(permission); err != nil {
+				return nil, fmt.Errorf("新增权限 %s 失败: %w", def.Key, err)
+			}
+			result.Added = append(result.Added, def.Key)
+			continue
+		}
+		if permission.PermissionName == def.Name && permission.Description == def.Description &&
+			permission.Category == def.Category && !permission.Orphaned {
+			continue
+		}
+		permission.PermissionName = def.Name
+		permission.Description = def.Description
+		permission.Category = def.Category
+		permission.Orphaned = false
+		if err := s.permissionDAO.Update(permission); err != nil {
+			return nil, fmt.Errorf("更新权限 %s 失败: %w", def.Key, err)
+		}
+		result.Updated = append(result.Updated, def.Key)
+	}
+
+	for _, permission := range existing {
+		if declared[permission.PermissionKey] || util.IsPermissionPattern(permission.PermissionKey) {
+			continue
+		}
+		result.Orphaned = append(result.Orphaned, permission.PermissionKey)
+		if permission.Orphaned {
+			continue
+		}
+		permission.Orphaned = true
+		if err := s.permissionDAO.Update(permission); err != nil {
+			return nil, fmt.Errorf("标记权限 %s 失败: %w", permission.PermissionKey, err)
+		}
+	}
+
+	return result, nil
+}
+
+// Routes 获取路由及其所需权限
+func (s *PermissionService) Routes() []*model.RoutePermission {
+	return Permissions().Routes()
+}
+
 // GetByRoleID 根据角色ID获取权限列表
 func (s *PermissionService) GetByRoleID(roleID int) ([]*model.Permission, error) {
 	return s.permissionDAO.GetByRoleID(roleID)
 }
+
+// Check 检查角色（或用户所属角色）是否拥有指定权限，并返回命中的规则
+func (s *PermissionService) Check(req *model.PermissionCheckRequest) (*model.PermissionCheckResult, error) {
+	roleID := req.RoleID
+	if req.UserID > 0 {
+		user, err := s.userDAO.GetByID(req.UserID)
+		if err != nil {
+			return nil, errors.New("用户不存在")
+		}
+		roleID = user.RoleID
+	}
+	if roleID == 0 {
+		return nil, errors.New("请指定角色或用户")
+	}
+	if _, err := s.roleDAO.GetByID(roleID); err != nil {
+		return nil, errors.New("角色不存在")
+	}
+
+	return PermCache().Check(roleID, req.Key)
+}
+
+// Effective 展开角色的权限规则，返回实际拥有的具体权限
+func (s *PermissionService) Effective(roleID int) (*model.RoleEffectivePermissions, error) {
+	if _, err := s.roleDAO.GetByID(roleID); err != nil {
+		return nil, errors.New("角色不存在")
+	}
+
+	rules, err := s.permissionDAO.GetRuleSetByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.permissionDAO.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.RoleEffectivePermissions{
+		RoleID:    roleID,
+		Allow:     rules.Allow,
+		Deny:      rules.Deny,
+		Effective: []*model.Permission{},
+	}
+	for _, permission := range permissions {
+		// 通配权限本身不是具体权限，只用于匹配
+		if util.IsPermissionPattern(permission.PermissionKey) {
+			continue
+		}
+		if EvaluatePermission(roleID, rules, permission.PermissionKey).Allowed {
+			result.Effective = append(result.Effective, permission)
+		}
+	}
+	return result, nil
+}
+
+// ListGroups 获取权限组列表
+func (s *PermissionService) ListGroups() ([]*model.PermissionGroup, error) {
+	return s.permissionGroupDAO.List()
+}
+
+// CreateGroup 创建权限组
+func (s *PermissionService) CreateGroup(req *model.PermissionGroupRequest) (*model.PermissionGroup, error) {
+	group := &model.PermissionGroup{CreatedAt: time.Now()}
+	return s.saveGroup(group, req)
+}
+
+// UpdateGroup 更新权限组，使用该组的角色权限缓存随之失效
+func (s *PermissionService) UpdateGroup(groupID int, req *model.PermissionGroupRequest) (*model.PermissionGroup, error) {
+	group, err := s.permissionGroupDAO.GetByID(groupID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("权限组不存在")
+		}
+		return nil, err
+	}
+	return s.saveGroup(group, req)
+}
+
+// saveGroup 校验并保存权限组
+func (s *PermissionService) saveGroup(group *model.PermissionGroup, req *model.PermissionGroupRequest) (*model.PermissionGroup, error) {
+	exists, err := s.permissionGroupDAO.ExistsByName(req.GroupName, group.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("检查权限组名失败: %w", err)
+	}
+	if exists {
+		return nil, errors.New("权限组名已存在")
+	}
+	for _, permID := range req.PermissionIDs {
+		if _, err := s.permissionDAO.GetByID(permID); err != nil {
+			return nil, fmt.Errorf("权限ID %d 不存在", permID)
+		}
+	}
+
+	group.GroupName = req.GroupName
+	group.Description = req.Description
+	if err := s.permissionGroupDAO.Save(group, req.PermissionIDs); err != nil {
+		return nil, fmt.Errorf("保存权限组失败: %w", err)
+	}
+	s.invalidateGroupRoles(group.GroupID)
+
+	return s.permissionGroupDAO.GetByID(group.GroupID)
+}
+
+// DeleteGroup 删除权限组
+func (s *PermissionService) DeleteGroup(groupID int) error {
+	if _, err := s.permissionGroupDAO.GetByID(groupID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("权限组不存在")
+		}
+		return err
+	}
+
+	// 删除前记录受影响的角色，删除后关联已级联清除
+	roleIDs, err := s.permissionGroupDAO.RoleIDsByGroup(groupID)
+	if err != nil {
+		return err
+	}
+	if err := s.permissionGroupDAO.Delete(groupID); err != nil {
+		return err
+	}
+	for _, roleID := range roleIDs {
+		PermCache().Invalidate(roleID)
+	}
+	return nil
+}
+
+// invalidateGroupRoles 使授予了该权限组的角色权限缓存失效
+func (s *PermissionService) invalidateGroupRoles(groupID int) {
+	roleIDs, err := s.permissionGroupDAO.RoleIDsByGroup(groupID)
+	if err != nil {
+		util.Warn(fmt.Sprintf("获取权限组关联角色失败: group_id=%d, %v", groupID, err))
+		return
+	}
+	for _, roleID := range roleIDs {
+		PermCache().Invalidate(roleID)
+	}
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// 签到配置项
+var checkinConfigKeys = []string{
+	"checkin_base_points",
+	"checkin_streak_bonus",
+	"checkin_streak_max_bonus",
+}
+
+// errCheckedIn 今天已签到
+var errCheckedIn = errors.New("今天已经签到过了")
+
+// ErrPointsVipFrozen VIP冻结期间不能兑换VIP天数
+var ErrPointsVipFrozen = errors.New("VIP冻结期间不能兑换VIP天数，请先解冻后再兑换")
+
+// PointsService 签到与积分商城服务
+// 积分变动统一通过 PointsDAO.ApplyTx 写入只增流水；商品发放复用VIP发放和卡密生成逻辑
+type PointsService struct {
+	configDAO      *dao.SystemConfigDAO
+	userDAO        *dao.UserDAO
+	pointsDAO      *dao.PointsDAO
+	vipService     *VipService
+	vipTierService *VipTierService
+	cardKeyService *CardKeyService
+}
+
+func NewPointsService() *PointsService {
+	return &PointsService{
+		configDAO:      dao.NewSystemConfigDAO(),
+		userDAO:        dao.NewUserDAO(),
+		pointsDAO:      dao.NewPointsDAO(),
+		vipService:     NewVipService(),
+		vipTierService: NewVipTierService(),
+		cardKeyService: NewCardKeyService(),
+	}
+}
+
+// checkinPoints 计算连续签到第 streak 天可获得的积分
+func (s *PointsService) checkinPoints(streak int) int {
+	base, bonus, maxBonus := 10, 2, 20
+	values, err := s.configDAO.BatchGet(checkinConfigKeys)
+	if err == nil {
+		atoi := func(key string, def int) int {
+			if v, err := strconv.Atoi(strings.TrimSpace(values[key])); err == nil && v >= 0 {
+				return v
+			}
+			return def
+		}
+		base = atoi("checkin_base_points", base)
+		bonus = atoi("checkin_streak_bonus", bonus)
+		maxBonus = atoi("checkin_streak_max_bonus", maxBonus)
+	}
+
+	extra := (streak - 1) * bonus
+	if extra > maxBonus {
+		extra = maxBonus
+	}
+	return base + extra
+}
+
+// today 返回本地时区当天零点
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// Checkin 每日签到
+// Redis SETNX 拦截同一天的并发重复请求，数据库 (user_id, checkin_date) 唯一约束兜底
+func (s *PointsService) Checkin(userID int) (*model.CheckinResponse, error) {
+	date := today()
+	key := fmt.Sprintf("emby_ums:checkin:%d:%s", userID, date.Format("20060102"))
+	ok, err := redis.SetNX(key, 1, 48*time.Hour)
+	if err != nil {
+		util.Warn(fmt.Sprintf("签到防重复锁获取失败，回退到数据库约束: %v", err))
+	} else if !ok {
+		return nil, errCheckedIn
+	}
+
+	var resp *model.CheckinResponse
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		streak := 1
+		last, err := s.pointsDAO.GetCheckinTx(tx, userID, date.AddDate(0, 0, -1))
+		if err != nil {
+			return err
+		}
+		if last != nil {
+			streak = last.Streak + 1
+		}
+
+		checkin := &model.Checkin{
+			UserID:      userID,
+			CheckinDate: date,
+			Streak:      streak,
+			Points:      s.checkinPoints(streak),
+			CreatedAt:   time.Now(),
+		}
+		created, err := s.pointsDAO.CreateCheckinTx(tx, checkin)
+		if err != nil {
+			return err
+		}
+		if !created {
+			return errCheckedIn
+		}
+
+		entry := &model.PointsLedger{
+			UserID: userID,
+			Delta:  checkin.Points,
+			Reason: model.PointsReasonCheckin,
+			RefID:  date.Format("2006-01-02"),
+			Remark: fmt.Sprintf("连续签到第%d天", streak),
+		}
+		if err := s.pointsDAO.ApplyTx(tx, entry); err != nil {
+			return err
+		}
+		resp = &model.CheckinResponse{Streak: streak, Points: checkin.Points, Balance: entry.BalanceAfter}
+		return nil
+	})
+	if err != nil {
+		// 非重复签到导致的失败释放锁，允许用户重试
+		if err != errCheckedIn {
+			redis.Del(key)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Summary 获取用户积分和签到状态
+func (s *PointsService) Summary(userID int) (*model.PointsSummary, error) {
+	points, err := s.pointsDAO.GetBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+	summary := &model.PointsSummary{
+		Balance:     points.Balance,
+		TotalEarned: points.TotalEarned,
+		TotalSpent:  points.TotalSpent,
+	}
+
+	date := today()
+	checkin, err := s.pointsDAO.GetCheckin(userID, date)
+	if err != nil {
+		return nil, err
+	}
+	if checkin != nil {
+		summary.CheckedInToday = true
+		summary.Streak = checkin.Streak
+		return summary, nil
+	}
+	if last, err := s.pointsDAO.GetCheckin(userID, date.AddDate(0, 0, -1)); err == nil && last != nil {
+		summary.Streak = last.Streak
+	}
+	return summary, nil
+}
+
+// ListLedger 获取用户积分流水
+func (s *PointsService) ListLedger(userID int, req *model.PointsLedgerListRequest) (*model.PointsLedgerListResponse, error) {
+	entries, total, err := s.pointsDAO.ListLedger(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PointsLedgerListResponse{Total: int(total), List: entries}, nil
+}
+
+// Adjust 管理员调整用户积分
+func (s *PointsService) Adjust(userID int, req *model.PointsAdjustRequest, operatorID int, operatorName, ip, ua string) (*model.PointsLedger, error) {
+	if _, err := s.userDAO.GetByID(userID); err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	entry := &model.PointsLedger{
+		UserID:     userID,
+		Delta:      req.Delta,
+		Reason:     model.PointsReasonAdjust,
+		OperatorID: &operatorID,
+		Remark:     req.Remark,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return s.pointsDAO.ApplyTx(tx, entry)
+	})
+	if errors.Is(err, dao.ErrPointsInsufficient) {
+		err = errors.New("扣减后积分不能为负数")
+	}
+
+	detail := map[string]interface{}{"delta": req.Delta, "remark": req.Remark}
+	if err != nil {
+		detail["error"] = err.Error()
+		Audit(&operatorID, operatorName, model.ActionAdjustPoint, model.TargetUser, fmt.Sprint(userID),
+			detail, ip, ua, "failed")
+		return nil, err
+	}
+
+	detail["balance_after"] = entry.BalanceAfter
+	Audit(&operatorID, operatorName, model.ActionAdjustPoint, model.TargetUser, fmt.Sprint(userID),
+		detail, ip, ua, "success")
+	return entry, nil
+}
+
+// ListItems 获取积分商品列表
+func (s *PointsService) ListItems(onlyActive bool) ([]*model.PointsItem, error) {
+	return s.pointsDAO.ListItems(onlyActive)
+}
+
+// validateItem 校验商品配置
+func (s *PointsService) validateItem(req *model.PointsItemRequest) error {
+	if req.ItemType == model.PointsItemRequestQuota {
+		return nil
+	}
+	if req.VipLevel == 0 {
+		req.VipLevel = 1
+	}
+	_, err := s.vipTierService.GetGrantable(req.VipLevel)
+	return err
+}
+
+// CreateItem 创建积分商品
+func (s *PointsService) CreateItem(req *model.PointsItemRequest) (*model.PointsItem, error) {
+	if err := s.validateItem(req); err != nil {
+		return nil, err
+	}
+
+	item := &model.PointsItem{
+		Name:         req.Name,
+		Description:  req.Description,
+		ItemType:     req.ItemType,
+		Cost:         req.Cost,
+		VipLevel:     req.VipLevel,
+		Amount:       req.Amount,
+		Stock:        -1,
+		PerUserLimit: req.PerUserLimit,
+		Status:       1,
+		SortOrder:    req.SortOrder,
+	}
+	if req.Stock != nil {
+		item.Stock = *req.Stock
+	}
+	if req.Status != nil {
+		item.Status = *req.Status
+	}
+	if err := s.pointsDAO.CreateItem(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateItem 更新积分商品（已兑换记录保留兑换时的名称和价格）
+func (s *PointsService) UpdateItem(id int, req *model.PointsItemRequest) (*model.PointsItem, error) {
+	item, err := s.pointsDAO.GetItem(id)
+	if err != nil {
+		return nil, errors.New("商品不存在")
+	}
+	if err := s.validateItem(req); err != nil {
+		return nil, err
+	}
+
+	item.Name = req.Name
+	item.Description = req.Description
+	item.ItemType = req.ItemType
+	item.Cost = req.Cost
+	item.VipLevel = req.VipLevel
+	item.Amount = req.Amount
+	item.PerUserLimit = req.PerUserLimit
+	item.SortOrder = req.SortOrder
+	if req.Stock != nil {
+		item.Stock = *req.Stock
+	}
+	if req.Status != nil {
+		item.Status = *req.Status
+	}
+	item.UpdatedAt = time.Now()
+	if err := s.pointsDAO.UpdateItem(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteItem 删除积分商品（已有兑换记录的商品只能下架）
+func (s *PointsService) DeleteItem(id int) error {
+	if _, err := s.pointsDAO.GetItem(id); err != nil {
+		return errors.New("商品不存在")
+	}
+	count, err := s.pointsDAO.CountRedemptionsByItem(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该商品已有兑换记录，请改为下架")
+	}
+	return s.pointsDAO.DeleteItem(id)
+}
+
+// chargeTx 在事务中锁定商品、校验库存和限购、扣减积分并写入兑换记录
+func (s *PointsService) chargeTx(tx *gorm.DB, userID, itemID int, batchID *int) (*model.PointsRedemption, *model.PointsItem, int, error) {
+	item, err := s.pointsDAO.LockItemTx(tx, itemID)
+	if err != nil || item.Status != 1 {
+		return nil, nil, 0, errors.New("商品不存在或已下架")
+	}
+	if item.Stock == 0 {
+		return nil, nil, 0, errors.New("商品库存不足")
+	}
+	if item.PerUserLimit > 0 {
+		count, err := s.pointsDAO.CountRedemptionsTx(tx, userID, itemID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if count >= int64(item.PerUserLimit) {
+			return nil, nil, 0, fmt.Errorf("该商品每人限兑%d次", item.PerUserLimit)
+		}
+	}
+	if err := s.pointsDAO.DecrStockTx(tx, itemID); err != nil {
+		return nil, nil, 0, err
+	}
+
+	redemption := &model.PointsRedemption{
+		UserID:    userID,
+		ItemID:    item.ID,
+		ItemName:  item.Name,
+		ItemType:  item.ItemType,
+		Cost:      item.Cost,
+		BatchID:   batchID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.pointsDAO.CreateRedemptionTx(tx, redemption); err != nil {
+		return nil, nil, 0, err
+	}
+
+	entry := &model.PointsLedger{
+		UserID: userID,
+		Delta:  -item.Cost,
+		Reason: model.PointsReasonRedeem,
+		RefID:  fmt.Sprint(redemption.ID),
+		Remark: "兑换: " + item.Name,
+	}
+	if err := s.pointsDAO.ApplyTx(tx, entry); err != nil {
+		return nil, nil, 0, err
+	}
+	return redemption, item, entry.BalanceAfter, nil
+}
+
+// Redeem 使用积分兑换商品
+func (s *PointsService) Redeem(userID, itemID int) (*model.PointsRedeemResponse, error) {
+	item, err := s.pointsDAO.GetItem(itemID)
+	if err != nil || item.Status != 1 {
+		return nil, errors.New("商品不存在或已下架")
+	}
+
+	resp := &model.PointsRedeemResponse{}
+	switch item.ItemType {
+	case model.PointsItemVipDays:
+		// 冻结期间无法发放VIP，扣减积分前先拒绝
+		var user *model.User
+		if user, err = s.userDAO.GetByID(userID); err != nil {
+			return nil, errors.New("用户不存在")
+		}
+		if user.VipFrozenAt != nil {
+			return nil, ErrPointsVipFrozen
+		}
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			redemption, locked, balance, err := s.chargeTx(tx, userID, itemID, nil)
+			if err != nil {
+				return err
+			}
+			resp.Redemption, resp.Balance = redemption, balance
+			user, _, err = s.vipService.GrantTx(tx, &model.VipChange{
+				UserID:     userID,
+				Level:      locked.VipLevel,
+				Days:       locked.Amount,
+				Source:     model.VipSourcePoints,
+				OperatorID: &userID,
+				RefID:      fmt.Sprint(redemption.ID),
+				Remark:     "积分兑换: " + locked.Name,
+			})
+			if errors.Is(err, ErrVipFrozen) {
+				return ErrPointsVipFrozen
+			}
+			return err
+		})
+		if err == nil {
+			s.vipService.AfterCommit(user)
+		}
+
+	case model.PointsItemInviteCode:
+		// 复用卡密生成逻辑，积分扣减与批次写入在同一事务中
+		var cardKeys []*model.CardKey
+		cardKeys, err = s.cardKeyService.CreateWithHook(&model.CardKeyCreateRequest{
+			Count:    1,
+			CardType: 1,
+			Duration: item.Amount,
+			VipLevel: item.VipLevel,
+			Remark:   "积分兑换: " + item.Name,
+		}, userID, func(tx *gorm.DB, batch *model.CardBatch) error {
+			redemption, _, balance, err := s.chargeTx(tx, userID, itemID, &batch.ID)
+			if err != nil {
+				return err
+			}
+			resp.Redemption, resp.Balance = redemption, balance
+			return nil
+		})
+		if err == nil && len(cardKeys) > 0 {
+			resp.CardCode = cardKeys[0].CardCode
+		}
+
+	case model.PointsItemRequestQuota:
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			redemption, locked, balance, err := s.chargeTx(tx, userID, itemID, nil)
+			if err != nil {
+				return err
+			}
+			resp.Redemption, resp.Balance = redemption, balance
+			return s.pointsDAO.AddBonusRequestQuotaTx(tx, userID, locked.Amount)
+		})
+
+	default:
+		return nil, errors.New("不支持的商品类型")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
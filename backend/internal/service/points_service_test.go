@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+func TestRedeemVipDaysWhileFrozen(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	s := NewPointsService()
+
+	user := createTestUser(t, "alice")
+	setTestVip(t, user.UserID, 1, time.Now().Add(30*24*time.Hour))
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("vip_frozen_at", time.Now())
+	if _, err := s.Adjust(user.UserID, &model.PointsAdjustRequest{Delta: 100}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("调整积分失败: %v", err)
+	}
+	item := &model.PointsItem{Name: "7天VIP", ItemType: model.PointsItemVipDays, Cost: 50, VipLevel: 1, Amount: 7, Stock: 5, Status: 1}
+	database.DB.Create(item)
+
+	// 冻结期间拒绝兑换，积分、库存和兑换记录均不变
+	if _, err := s.Redeem(user.UserID, item.ID); !errors.Is(err, ErrPointsVipFrozen) {
+		t.Fatalf("冻结期间应拒绝兑换VIP天数: %v", err)
+	}
+	summary, err := s.Summary(user.UserID)
+	if err != nil || summary.Balance != 100 {
+		t.Fatalf("拒绝兑换时不应扣减积分: %+v %v", summary, err)
+	}
+	var stock int
+	var redemptions int64
+	database.DB.Model(&model.PointsItem{}).Where("id = ?", item.ID).Select("stock").Scan(&stock)
+	database.DB.Model(&model.PointsRedemption{}).Count(&redemptions)
+	if stock != 5 || redemptions != 0 {
+		t.Fatalf("拒绝兑换时不应扣减库存或写入兑换记录: 库存%d 记录%d", stock, redemptions)
+	}
+
+	// 解冻后可正常兑换
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Update("vip_frozen_at", nil)
+	resp, err := s.Redeem(user.UserID, item.ID)
+	if err != nil || resp.Balance != 50 {
+		t.Fatalf("解冻后应能兑换: %+v %v", resp, err)
+	}
+}
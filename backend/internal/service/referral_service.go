@@ -0,0 +1,388 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// referralCodeCharset 推荐码字符集（去除易混淆的 0/O/1/I/L）
+const referralCodeCharset = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// 推荐配置项
+var referralConfigKeys = []string{
+	"referral_enabled",
+	"referral_referrer_days",
+	"referral_referee_days",
+	"referral_vip_level",
+	"referral_reward_on_card",
+	"referral_active_days",
+	"referral_ip_window_days",
+	"referral_monthly_limit",
+}
+
+// ReferralService 推荐服务
+// 注册时建立推荐关系并做反作弊检查；被推荐人首次兑换卡密或持续活跃满N天后，
+// 经 VipService 为双方发放奖励天数（每个推荐关系只奖励一次）
+type ReferralService struct {
+	configDAO      *dao.SystemConfigDAO
+	referralDAO    *dao.ReferralDAO
+	userDAO        *dao.UserDAO
+	vipService     *VipService
+	vipTierService *VipTierService
+}
+
+func NewReferralService() *ReferralService {
+	return &ReferralService{
+		configDAO:      dao.NewSystemConfigDAO(),
+		referralDAO:    dao.NewReferralDAO(),
+		userDAO:        dao.NewUserDAO(),
+		vipService:     NewVipService(),
+		vipTierService: NewVipTierService(),
+	}
+}
+
+// Config 读取推荐配置
+func (s *ReferralService) Config() *model.ReferralConfig {
+	cfg := &model.ReferralConfig{VipLevel: 1, RewardOnCard: true, IPWindowDays: 30}
+	values, err := s.configDAO.BatchGet(referralConfigKeys)
+	if err != nil {
+		return &model.ReferralConfig{}
+	}
+
+	atoi := func(key string, def int) int {
+		if v, err := strconv.Atoi(strings.TrimSpace(values[key])); err == nil && v >= 0 {
+			return v
+		}
+		return def
+	}
+	cfg.Enabled = values["referral_enabled"] == "true"
+	cfg.ReferrerDays = atoi("referral_referrer_days", 0)
+	cfg.RefereeDays = atoi("referral_referee_days", 0)
+	cfg.VipLevel = atoi("referral_vip_level", cfg.VipLevel)
+	cfg.ActiveDays = atoi("referral_active_days", 0)
+	cfg.IPWindowDays = atoi("referral_ip_window_days", cfg.IPWindowDays)
+	cfg.MonthlyLimit = atoi("referral_monthly_limit", 0)
+	if v, ok := values["referral_reward_on_card"]; ok {
+		cfg.RewardOnCard = v != "false" && v != "0"
+	}
+	return cfg
+}
+
+// generateReferralCode 生成8位推荐码
+func generateReferralCode() string {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(referralCodeCharset)))
+	for i := 0; i < 8; i++ {
+		n, _ := rand.Int(rand.Reader, max)
+		sb.WriteByte(referralCodeCharset[n.Int64()])
+	}
+	return sb.String()
+}
+
+// GetOrCreateCode 获取用户推荐码，尚未生成时生成
+func (s *ReferralService) GetOrCreateCode(userID int) (string, error) {
+	for i := 0; i < 5; i++ {
+		user, err := s.userDAO.GetByID(userID)
+		if err != nil {
+			return "", errors.New("用户不存在")
+		}
+		if user.ReferralCode != nil {
+			return *user.ReferralCode, nil
+		}
+		// 推荐码冲突或并发生成时重新读取
+		s.referralDAO.SetCode(userID, generateReferralCode())
+	}
+	return "", errors.New("生成推荐码失败，请重试")
+}
+
+// Summary 获取我的推荐信息
+func (s *ReferralService) Summary(userID int) (*model.ReferralSummary, error) {
+	code, err := s.GetOrCreateCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.referralDAO.CountByStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.referralDAO.SumRewardDays(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := s.Config()
+	summary := &model.ReferralSummary{
+		ReferralCode: code,
+		Pending:      counts[model.ReferralStatusPending],
+		Rewarded:     counts[model.ReferralStatusRewarded],
+		Rejected:     counts[model.ReferralStatusRejected],
+		RewardDays:   days,
+	}
+	summary.Total = summary.Pending + summary.Rewarded + summary.Rejected
+	if cfg.Enabled {
+		summary.ReferrerDays = cfg.ReferrerDays
+		summary.RefereeDays = cfg.RefereeDays
+	}
+	return summary, nil
+}
+
+// ValidateCode 注册前校验推荐码（推荐功能关闭时忽略推荐码）
+func (s *ReferralService) ValidateCode(code string) (*model.User, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || !s.Config().Enabled {
+		return nil, nil
+	}
+	referrer, err := s.referralDAO.GetUserByCode(code)
+	if err != nil || referrer.Status != 1 {
+		return nil, errors.New("推荐码无效")
+	}
+	return referrer, nil
+}
+
+// checkFraud 反作弊检查，返回拒绝原因（为空表示通过）
+func (s *ReferralService) checkFraud(cfg *model.ReferralConfig, referrer, referee *model.User, ip, fingerprint string) string {
+	if referrer.UserID == referee.UserID {
+		return "不能推荐自己"
+	}
+	referrerKey, _ := normalizeTrialEmail(referrer.Email)
+	refereeKey, _ := normalizeTrialEmail(referee.Email)
+	if referrer.Email != "" && referrerKey == refereeKey {
+		return "推荐人与被推荐人邮箱相同"
+	}
+	if ip != "" && cfg.IPWindowDays > 0 {
+		since := time.Now().AddDate(0, 0, -cfg.IPWindowDays)
+		if used, err := s.referralDAO.ReferrerUsedIP(referrer.UserID, ip, since); err != nil || used {
+			return "与推荐人使用相同IP"
+		}
+	}
+	if fingerprint != "" {
+		if used, err := s.referralDAO.ReferrerUsedDevice(referrer.UserID, fingerprint); err != nil || used {
+			return "与推荐人使用相同设备"
+		}
+		if referred, err := s.referralDAO.DeviceReferred(fingerprint); err != nil || referred {
+			return "该设备已被推荐注册过"
+		}
+	}
+	return ""
+}
+
+// LinkOnRegister 注册成功后建立推荐关系；未通过反作弊检查的关系记为 rejected，不发放奖励
+func (s *ReferralService) LinkOnRegister(referrer, referee *model.User, ip, fingerprint string) {
+	if referrer == nil {
+		return
+	}
+	cfg := s.Config()
+	fingerprint = strings.TrimSpace(fingerprint)
+
+	referral := &model.Referral{
+		ReferrerID:        referrer.UserID,
+		RefereeID:         referee.UserID,
+		Status:            model.ReferralStatusPending,
+		IPAddress:         ip,
+		DeviceFingerprint: fingerprint,
+		CreatedAt:         time.Now(),
+	}
+	if reason := s.checkFraud(cfg, referrer, referee, ip, fingerprint); reason != "" {
+		referral.Status = model.ReferralStatusRejected
+		referral.RejectReason = reason
+	}
+
+	if err := s.referralDAO.Create(referral); err != nil {
+		util.Warn(fmt.Sprintf("建立推荐关系失败 referrer=%d referee=%d: %v", referrer.UserID, referee.UserID, err))
+	}
+}
+
+// ErrReferralDeferred 推荐双方有人VIP冻结中，奖励推迟到解冻后发放
+var ErrReferralDeferred = errors.New("推荐奖励已推迟：VIP冻结中")
+
+// Reward 被推荐人满足条件后为双方发放奖励（幂等：仅处理待奖励的推荐关系）
+// 应获奖励的一方VIP冻结中时不发放，记录触发方式后保持待奖励，解冻后由 RewardDeferredReferrals 补发
+func (s *ReferralService) Reward(refereeID int, trigger string) error {
+	cfg := s.Config()
+	if !cfg.Enabled {
+		return nil
+	}
+	if trigger == model.ReferralTriggerCard && !cfg.RewardOnCard {
+		return nil
+	}
+	if trigger == model.ReferralTriggerActive && cfg.ActiveDays <= 0 {
+		return nil
+	}
+	if _, err := s.vipTierService.GetGrantable(cfg.VipLevel); err != nil {
+		return err
+	}
+
+	var granted []*model.User
+	deferred := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		referral, err := s.referralDAO.LockPendingByRefereeTx(tx, refereeID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		referrerDays := cfg.ReferrerDays
+		if cfg.MonthlyLimit > 0 {
+			monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+			count, err := s.referralDAO.CountRewardedSinceTx(tx, referral.ReferrerID, monthStart)
+			if err != nil {
+				return err
+			}
+			if count >= int64(cfg.MonthlyLimit) {
+				referrerDays = 0 // 超过每月上限：推荐人本次不再获得奖励，被推荐人照常发放
+			}
+		}
+
+		// 按用户ID顺序加锁发放，避免并发奖励时死锁
+		grants := []struct {
+			userID int
+			days   int
+			remark string
+		}{
+			{referral.ReferrerID, referrerDays, "推荐奖励"},
+			{referral.RefereeID, cfg.RefereeDays, "受邀奖励"},
+		}
+		if grants[0].userID > grants[1].userID {
+			grants[0], grants[1] = grants[1], grants[0]
+		}
+		for _, g := range grants {
+			if g.days <= 0 {
+				continue
+			}
+			user, err := s.vipService.LockUserTx(tx, g.userID)
+			if err != nil {
+				return err
+			}
+			if user.VipFrozenAt != nil {
+				deferred = true
+			}
+		}
+		if deferred {
+			return tx.Model(referral).Update("reward_trigger", trigger).Error
+		}
+
+		for _, g := range grants {
+			if g.days <= 0 {
+				continue
+			}
+			user, _, err := s.vipService.GrantTx(tx, &model.VipChange{
+				UserID: g.userID,
+				Level:  cfg.VipLevel,
+				Days:   g.days,
+				Source: model.VipSourceReferral,
+				RefID:  fmt.Sprint(referral.ID),
+				Remark: g.remark,
+			})
+			if err != nil {
+				return err
+			}
+			granted = append(granted, user)
+		}
+
+		return tx.Model(referral).Updates(map[string]interface{}{
+			"status":               model.ReferralStatusRewarded,
+			"reward_trigger":       trigger,
+			"referrer_reward_days": referrerDays,
+			"referee_reward_days":  cfg.RefereeDays,
+			"rewarded_at":          now,
+		}).Error
+	})
+	if err != nil {
+		util.Warn(fmt.Sprintf("发放推荐奖励失败 referee=%d trigger=%s: %v", refereeID, trigger, err))
+		return err
+	}
+	if deferred {
+		return ErrReferralDeferred
+	}
+
+	for _, user := range granted {
+		s.vipService.AfterCommit(user)
+	}
+	return nil
+}
+
+// RewardActiveReferrals 为持续活跃满N天的被推荐人发放奖励，返回处理数量
+func (s *ReferralService) RewardActiveReferrals() int {
+	cfg := s.Config()
+	if !cfg.Enabled || cfg.ActiveDays <= 0 {
+		return 0
+	}
+
+	const batchSize = 200
+	processed := 0
+	lastID := 0
+	for {
+		ids, err := s.referralDAO.ListActivePendingRefereeIDs(cfg.ActiveDays, lastID, batchSize)
+		if err != nil {
+			util.Warn(fmt.Sprintf("查询活跃被推荐人失败: %v", err))
+			break
+		}
+		for _, id := range ids {
+			lastID = id
+			if err := s.Reward(id, model.ReferralTriggerActive); err == nil {
+				processed++
+			}
+		}
+		if len(ids) < batchSize {
+			break
+		}
+	}
+	return processed
+}
+
+// RewardDeferredReferrals 为因VIP冻结推迟、双方均已解冻的推荐关系补发奖励，返回处理数量
+func (s *ReferralService) RewardDeferredReferrals() int {
+	if !s.Config().Enabled {
+		return 0
+	}
+
+	const batchSize = 200
+	processed := 0
+	lastID := 0
+	for {
+		referrals, err := s.referralDAO.ListDeferred(lastID, batchSize)
+		if err != nil {
+			util.Warn(fmt.Sprintf("查询推迟的推荐奖励失败: %v", err))
+			break
+		}
+		for _, referral := range referrals {
+			lastID = referral.RefereeID
+			if err := s.Reward(referral.RefereeID, referral.Trigger); err == nil {
+				processed++
+			}
+		}
+		if len(referrals) < batchSize {
+			break
+		}
+	}
+	return processed
+}
+
+// ListMine 获取我的推荐记录
+func (s *ReferralService) ListMine(userID int, req *model.ReferralListRequest) (*model.ReferralListResponse, error) {
+	referrals, total, err := s.referralDAO.ListByReferrer(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReferralListResponse{Total: int(total), List: referrals}, nil
+}
+
+// Leaderboard 获取推荐排行榜
+func (s *ReferralService) Leaderboard(req *model.ReferralLeaderboardRequest) ([]*model.ReferralLeaderboardEntry, error) {
+	return s.referralDAO.Leaderboard(req)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+func TestReferralRewardDeferredWhileFrozen(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, 0, 1)
+	createTestTier(t, 1, 1)
+	for key, value := range map[string]string{
+		"referral_enabled":       "true",
+		"referral_referrer_days": "7",
+		"referral_referee_days":  "3",
+		"referral_vip_level":     "1",
+	} {
+		if err := database.DB.Create(&model.SystemConfig{ConfigKey: key, ConfigValue: value}).Error; err != nil {
+			t.Fatalf("写入配置失败: %v", err)
+		}
+	}
+
+	referrer := createTestUser(t, "referrer")
+	referee := createTestUser(t, "referee")
+	s := NewReferralService()
+	s.LinkOnRegister(referrer, referee, "", "")
+
+	// 推荐人冻结中：奖励推迟，不发放也不报错重试
+	frozenAt := time.Now()
+	database.DB.Model(&model.User{}).Where("user_id = ?", referrer.UserID).Update("vip_frozen_at", frozenAt)
+	if err := s.Reward(referee.UserID, model.ReferralTriggerCard); !errors.Is(err, ErrReferralDeferred) {
+		t.Fatalf("推荐人冻结时奖励应推迟: %v", err)
+	}
+	var referral model.Referral
+	database.DB.Where("referee_id = ?", referee.UserID).First(&referral)
+	if referral.Status != model.ReferralStatusPending || referral.Trigger != model.ReferralTriggerCard {
+		t.Fatalf("推迟的奖励应保持待奖励并记录触发方式: %+v", referral)
+	}
+	if reloadUser(t, referee.UserID).VipExpireAt != nil {
+		t.Fatal("推迟时被推荐人也不应获得奖励")
+	}
+	if n := s.RewardDeferredReferrals(); n != 0 {
+		t.Fatalf("冻结期间不应补发: %d", n)
+	}
+
+	// 解冻后补发
+	database.DB.Model(&model.User{}).Where("user_id = ?", referrer.UserID).Update("vip_frozen_at", nil)
+	if n := s.RewardDeferredReferrals(); n != 1 {
+		t.Fatalf("解冻后应补发一次: %d", n)
+	}
+	database.DB.Where("referee_id = ?", referee.UserID).First(&referral)
+	if referral.Status != model.ReferralStatusRewarded || referral.ReferrerRewardDays != 7 || referral.RefereeRewardDays != 3 {
+		t.Fatalf("补发后推荐关系不符: %+v", referral)
+	}
+	for _, id := range []int{referrer.UserID, referee.UserID} {
+		if user := reloadUser(t, id); user.VipLevel != 1 || user.VipExpireAt == nil {
+			t.Fatalf("补发后双方应获得VIP: %+v", user)
+		}
+	}
+	if n := s.RewardDeferredReferrals(); n != 0 {
+		t.Fatalf("已奖励的推荐关系不应重复发放: %d", n)
+	}
+}
@@ -7,27 +7,37 @@ import (
 	"embyhub/internal/util"
 	"embyhub/pkg/database"
 	"embyhub/pkg/emby"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 type RegisterService struct {
-	userDAO      *dao.UserDAO
-	emailService *EmailService
-	embyClient   *emby.Client
+	userDAO         *dao.UserDAO
+	emailService    *EmailService
+	trialService    *TrialService
+	referralService *ReferralService
+	embyClient      *emby.Client
+	passwordService *PasswordPolicyService
 }
 
 func NewRegisterService() *RegisterService {
 	return &RegisterService{
-		userDAO:      dao.NewUserDAO(),
-		emailService: NewEmailService(),
-		embyClient:   emby.NewClient(&config.GlobalConfig.Emby),
+		userDAO:         dao.NewUserDAO(),
+		emailService:    NewEmailService(),
+		trialService:    NewTrialService(),
+		referralService: NewReferralService(),
+		embyClient:      emby.NewClient(&config.GlobalConfig.Emby),
+		passwordService: NewPasswordPolicyService(),
 	}
 }
 
 // Register 用户注册（邮箱验证方式）
-func (s *RegisterService) Register(req *model.RegisterRequest) (*model.RegisterResponse, error) {
-	// 0. 验证密码强度
-	if err := util.ValidatePassword(req.Password); err != nil {
+// ip 和设备指纹用于试用防滥用检查
+func (s *RegisterService) Register(req *model.RegisterRequest, ip string) (*model.RegisterResponse, error) {
+	// 0. 验证密码是否符合密码策略
+	if err := s.passwordService.Check(nil, req.Password); err != nil {
 		return nil, err
 	}
 
@@ -48,78 +58,200 @@ func (s *RegisterService) Register(req *model.RegisterRequest) (*model.RegisterR
 		return nil, fmt.Errorf("邮箱已被使用")
 	}
 
-	// 3. 检查Emby是否已有同名用户
+	// 2.2 校验推荐码
+	referrer, err := s.referralService.ValidateCode(req.ReferralCode)
+	if err != nil {
+		return nil, err
+	}
+
+	user, trial, isExistingEmbyUser, err := s.createAccount(&newAccount{
+		Username:          req.Username,
+		Password:          req.Password,
+		Email:             req.Email,
+		EmailVerified:     true, // 邮箱已通过验证码验证
+		LinkEmbyUser:      true,
+		IP:                ip,
+		DeviceFingerprint: req.DeviceFingerprint,
+		Referrer:          referrer,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 清理已使用的验证码
+	database.DB.Model(&model.EmailCode{}).
+		Where("email = ? AND type = ?", req.Email, model.CodeTypeRegister).
+		Update("used", true)
+
+	// 构建返回消息
+	var msg string
+	if isExistingEmbyUser {
+		msg = "注册成功！已关联现有Emby账号，密码已同步更新"
+	} else {
+		msg = "注册成功！已创建Emby账号"
+	}
+	if trial != nil {
+		msg += fmt.Sprintf("，已赠送%d天VIP试用", trial.Days)
+	}
+
+	return &model.RegisterResponse{
+		UserID:     user.UserID,
+		Username:   user.Username,
+		Email:      req.Email,
+		EmbyUserID: user.EmbyUserID,
+		Message:    msg,
+		Trial:      trial,
+	}, nil
+}
+
+// newAccount 新建账号所需的信息
+type newAccount struct {
+	Username          string
+	Password          string
+	Email             string
+	EmailVerified     bool
+	LinkEmbyUser      bool // Emby 已有同名用户时关联并重置其密码，否则视为用户名冲突
+	IP                string
+	DeviceFingerprint string
+	Referrer          *model.User
+}
+
+// errEmbyUserExists Emby 已有同名用户且不允许关联
+var errEmbyUserExists = errors.New("Emby已存在同名用户")
+
+// createAccount 创建（或关联）Emby账号和本地用户，并发放注册试用、建立推荐关系、发送欢迎邮件
+// 邮箱注册和单点登录自动开通共用此流程
+func (s *RegisterService) createAccount(acc *newAccount) (*model.User, *model.TrialInfo, bool, error) {
+	// 检查Emby是否已有同名用户
 	var embyUserID string
 	var isExistingEmbyUser bool
-	existingEmbyUser, _ := s.embyClient.GetUserByName(req.Username)
+	existingEmbyUser, _ := s.embyClient.GetUserByName(acc.Username)
 	if existingEmbyUser != nil {
+		if !acc.LinkEmbyUser {
+			return nil, nil, false, errEmbyUserExists
+		}
 		// Emby已存在同名用户，关联现有用户并更新密码
 		embyUserID = existingEmbyUser.ID
 		isExistingEmbyUser = true
 		// 更新Emby用户密码
-		if err := s.embyClient.SetUserPassword(embyUserID, req.Password); err != nil {
-			return nil, fmt.Errorf("更新Emby密码失败: %w", err)
+		if err := s.embyClient.SetUserPassword(embyUserID, acc.Password); err != nil {
+			return nil, nil, false, fmt.Errorf("更新Emby密码失败: %w", err)
 		}
 	} else {
 		// 创建新的Emby用户
-		embyUser, err := s.embyClient.CreateUser(req.Username, req.Password)
+		embyUser, err := s.embyClient.CreateUser(acc.Username, acc.Password)
 		if err != nil {
-			return nil, fmt.Errorf("创建Emby用户失败: %w", err)
+			return nil, nil, false, fmt.Errorf("创建Emby用户失败: %w", err)
 		}
 		embyUserID = embyUser.ID
 		// 设置Emby用户密码
-		if err := s.embyClient.SetUserPassword(embyUserID, req.Password); err != nil {
-			return nil, fmt.Errorf("设置Emby密码失败: %w", err)
+		if err := s.embyClient.SetUserPassword(embyUserID, acc.Password); err != nil {
+			return nil, nil, false, fmt.Errorf("设置Emby密码失败: %w", err)
 		}
 		// 设置Emby用户权限（受限普通用户）
 		if err := s.embyClient.SetUserPolicy(embyUserID); err != nil {
-			return nil, fmt.Errorf("设置Emby权限失败: %w", err)
+			return nil, nil, false, fmt.Errorf("设置Emby权限失败: %w", err)
 		}
 	}
 
-	// 5. 加密密码
-	hashedPassword, err := util.HashPassword(req.Password)
+	// 加密密码
+	hashedPassword, err := util.HashPassword(acc.Password)
 	if err != nil {
-		return nil, fmt.Errorf("密码加密失败: %w", err)
+		return nil, nil, false, fmt.Errorf("密码加密失败: %w", err)
 	}
 
-	// 4. 创建本地用户（默认为普通用户角色ID=3）
+	// 创建本地用户（默认为普通用户角色ID=3）
 	user := &model.User{
-		Username:     req.Username,
+		Username:     acc.Username,
 		PasswordHash: hashedPassword,
-		Email:        req.Email,
+		Email:        acc.Email,
 		EmbyUserID:   embyUserID,
 		RoleID:       3, // 默认为普通用户角色
 		Status:       1, // 启用状态
 	}
+	if acc.EmailVerified && acc.Email != "" {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
 
 	if err := s.userDAO.Create(user); err != nil {
-		return nil, fmt.Errorf("创建用户失败: %w", err)
+		return nil, nil, false, fmt.Errorf("创建用户失败: %w", err)
 	}
+	s.passwordService.Remember(user.UserID, hashedPassword)
 
-	// 清理已使用的验证码
-	database.DB.Model(&model.EmailCode{}).
-		Where("email = ? AND type = ?", req.Email, model.CodeTypeRegister).
-		Update("used", true)
+	// 发放注册试用（不满足条件时不影响注册）
+	trial, _ := s.trialService.GrantOnRegister(user, acc.EmailVerified, acc.IP, acc.DeviceFingerprint)
+
+	// 建立推荐关系（奖励在被推荐人首次兑换卡密或持续活跃后发放）
+	s.referralService.LinkOnRegister(acc.Referrer, user, acc.IP, acc.DeviceFingerprint)
 
 	// 异步发送欢迎邮件
-	go s.sendWelcomeEmail(req.Email, req.Username)
+	if acc.Email != "" {
+		go s.sendWelcomeEmail(acc.Email, acc.Username)
+	}
 
-	// 构建返回消息
-	var msg string
-	if isExistingEmbyUser {
-		msg = "注册成功！已关联现有Emby账号，密码已同步更新"
-	} else {
-		msg = "注册成功！已创建Emby账号"
+	return user, trial, isExistingEmbyUser, nil
+}
+
+// ProvisionExternal 为通过单点登录首次登录的用户自动开通账号
+// 本地或Emby中用户名已被占用时追加随机后缀；随机生成的密码可在登录后修改
+func (s *RegisterService) ProvisionExternal(preferredUsername, email string, emailVerified bool, ip string) (*model.User, error) {
+	password, err := util.RandomToken(12)
+	if err != nil {
+		return nil, fmt.Errorf("生成初始密码失败: %w", err)
+	}
+	if !emailVerified {
+		email = ""
+	} else if existing, _ := s.userDAO.GetByEmail(email); existing != nil {
+		return nil, errors.New("邮箱已被其他账号使用")
 	}
 
-	return &model.RegisterResponse{
-		UserID:     user.UserID,
-		Username:   user.Username,
-		Email:      req.Email,
-		EmbyUserID: user.EmbyUserID,
-		Message:    msg,
-	}, nil
+	base := sanitizeUsername(preferredUsername)
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := util.RandomToken(2)
+			if err != nil {
+				return nil, err
+			}
+			username = fmt.Sprintf("%s_%s", base, suffix)
+		}
+		if existing, _ := s.userDAO.GetByUsername(username); existing != nil {
+			continue
+		}
+
+		user, _, _, err := s.createAccount(&newAccount{
+			Username:      username,
+			Password:      password,
+			Email:         email,
+			EmailVerified: emailVerified,
+			IP:            ip,
+		})
+		if errors.Is(err, errEmbyUserExists) {
+			continue
+		}
+		return user, err
+	}
+	return nil, errors.New("无法生成可用的用户名，请联系管理员")
+}
+
+// sanitizeUsername 将身份提供方的用户名转换为本地合法用户名（3-40位字母、数字、下划线、短横线）
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		}
+		if b.Len() >= 40 {
+			break
+		}
+	}
+	username := b.String()
+	if len(username) < 3 {
+		username = "user" + username
+	}
+	return username
 }
 
 // sendWelcomeEmail 发送欢迎邮件
@@ -11,19 +11,39 @@ import (
 )
 
 type RoleService struct {
-	roleDAO       *dao.RoleDAO
-	permissionDAO *dao.PermissionDAO
+	roleDAO            *dao.RoleDAO
+	permissionDAO      *dao.PermissionDAO
+	permissionGroupDAO *dao.PermissionGroupDAO
+	scopeService       *ScopeService
 }
 
 func NewRoleService() *RoleService {
 	return &RoleService{
-		roleDAO:       dao.NewRoleDAO(),
-		permissionDAO: dao.NewPermissionDAO(),
+		roleDAO:            dao.NewRoleDAO(),
+		permissionDAO:      dao.NewPermissionDAO(),
+		permissionGroupDAO: dao.NewPermissionGroupDAO(),
+		scopeService:       NewScopeService(),
 	}
 }
 
+// checkRank 角色等级不能高于操作者自身的等级
+func (s *RoleService) checkRank(operatorID int, rank int) error {
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return err
+	}
+	if rank > scope.Rank {
+		return errors.New("角色等级不能高于自己的等级")
+	}
+	return nil
+}
+
 // Create 创建角色
-func (s *RoleService) Create(req *model.RoleCreateRequest) (*model.Role, error) {
+func (s *RoleService) Create(req *model.RoleCreateRequest, operatorID int) (*model.Role, error) {
+	if err := s.checkRank(operatorID, req.Rank); err != nil {
+		return nil, err
+	}
+
 	// 检查角色名是否存在
 	exists, err := s.roleDAO.ExistsByName(req.RoleName)
 	if err != nil {
@@ -37,6 +57,8 @@ func (s *RoleService) Create(req *model.RoleCreateRequest) (*model.Role, error)
 	role := &model.Role{
 		RoleName:    req.RoleName,
 		Description: req.Description,
+		Rank:        req.Rank,
+		Require2FA:  req.Require2FA,
 	}
 
 	if err := s.roleDAO.Create(role); err != nil {
@@ -51,8 +73,8 @@ func (s *RoleService) GetByID(roleID int) (*model.Role, error) {
 	return s.roleDAO.GetByID(roleID)
 }
 
-// Update 更新角色
-func (s *RoleService) Update(roleID int, req *model.RoleUpdateRequest) (*model.Role, error) {
+// Update 更新角色，不能修改等级高于自己的角色
+func (s *RoleService) Update(roleID int, req *model.RoleUpdateRequest, operatorID int) (*model.Role, error) {
 	role, err := s.roleDAO.GetByID(roleID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -60,6 +82,9 @@ func (s *RoleService) Update(roleID int, req *model.RoleUpdateRequest) (*model.R
 		}
 		return nil, err
 	}
+	if err := s.checkRank(operatorID, role.Rank); err != nil {
+		return nil, err
+	}
 
 	// 更新字段
 	if req.RoleName != "" && req.RoleName != role.RoleName {
@@ -77,9 +102,21 @@ func (s *RoleService) Update(roleID int, req *model.RoleUpdateRequest) (*model.R
 		role.Description = req.Description
 	}
 
+	if req.Rank != nil && *req.Rank != role.Rank {
+		if err := s.checkRank(operatorID, *req.Rank); err != nil {
+			return nil, err
+		}
+		role.Rank = *req.Rank
+	}
+
+	if req.Require2FA != nil {
+		role.Require2FA = *req.Require2FA
+	}
+
 	if err := s.roleDAO.Update(role); err != nil {
 		return nil, fmt.Errorf("更新角色失败: %w", err)
 	}
+	PermCache().Invalidate(roleID)
 
 	return s.roleDAO.GetByID(roleID)
 }
@@ -94,7 +131,11 @@ func (s *RoleService) Delete(roleID int) error {
 		return err
 	}
 
-	return s.roleDAO.Delete(roleID)
+	if err := s.roleDAO.Delete(roleID); err != nil {
+		return err
+	}
+	PermCache().Invalidate(roleID)
+	return nil
 }
 
 // List 获取角色列表
@@ -110,8 +151,8 @@ func (s *RoleService) List() (*model.RoleListResponse, error) {
 	}, nil
 }
 
-// AssignPermissions 为角色分配权限
-func (s *RoleService) AssignPermissions(roleID int, permissionIDs []int) error {
+// AssignPermissions 为角色分配权限、拒绝规则和权限组
+func (s *RoleService) AssignPermissions(roleID int, req *model.RolePermissionRequest) error {
 	// 检查角色是否存在
 	_, err := s.roleDAO.GetByID(roleID)
 	if err != nil {
@@ -122,12 +163,21 @@ func (s *RoleService) AssignPermissions(roleID int, permissionIDs []int) error {
 	}
 
 	// 验证所有权限ID是否有效
-	for _, permID := range permissionIDs {
+	for _, permID := range append(append([]int{}, req.PermissionIDs...), req.DenyPermissionIDs...) {
 		_, err := s.permissionDAO.GetByID(permID)
 		if err != nil {
 			return fmt.Errorf("权限ID %d 不存在", permID)
 		}
 	}
+	for _, groupID := range req.GroupIDs {
+		if _, err := s.permissionGroupDAO.GetByID(groupID); err != nil {
+			return fmt.Errorf("权限组ID %d 不存在", groupID)
+		}
+	}
 
-	return s.roleDAO.AssignPermissions(roleID, permissionIDs)
+	if err := s.roleDAO.AssignPermissions(roleID, req.PermissionIDs, req.DenyPermissionIDs, req.GroupIDs); err != nil {
+		return err
+	}
+	PermCache().Invalidate(roleID)
+	return nil
 }
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 管理范围相关错误
+var (
+	ErrScopeGroupDenied = errors.New("无权管理该用户组")
+	ErrScopeUserDenied  = errors.New("无权管理该用户组的用户")
+	ErrScopeRankDenied  = errors.New("不能操作角色等级高于自己的用户")
+	ErrScopeRoleDenied  = errors.New("不能分配等级高于自己的角色")
+)
+
+// ScopeService 管理范围服务
+// 全局角色拥有的权限作用于所有用户；用户组内授予的角色仅作用于该组的用户和卡密
+type ScopeService struct {
+	userDAO      *dao.UserDAO
+	userGroupDAO *dao.UserGroupDAO
+}
+
+func NewScopeService() *ScopeService {
+	return &ScopeService{
+		userDAO:      dao.NewUserDAO(),
+		userGroupDAO: dao.NewUserGroupDAO(),
+	}
+}
+
+// HasPermission 判断用户是否在任一范围内拥有指定权限（全局角色或任一用户组内授予的角色）
+// 全局角色显式拒绝的权限不会被组内授权放开
+func (s *ScopeService) HasPermission(userID, roleID int, permissionKey string) (bool, error) {
+	result, err := PermCache().Check(roleID, permissionKey)
+	if err != nil {
+		return false, err
+	}
+	if result.Allowed || result.Effect == model.PermissionEffectDeny {
+		return result.Allowed, nil
+	}
+
+	roleIDs, err := s.userGroupDAO.GrantRoleIDs(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, grantRoleID := range roleIDs {
+		allowed, err := PermCache().HasPermission(grantRoleID, permissionKey)
+		if err != nil {
+			return false, err
+		}
+		if allowed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Load 加载操作者的管理范围
+func (s *ScopeService) Load(operatorID int) (*OperatorScope, error) {
+	operator, err := s.userDAO.GetByID(operatorID)
+	if err != nil {
+		return nil, errors.New("操作者不存在")
+	}
+	grants, err := s.userGroupDAO.ListGrantsByUser(operatorID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户组授权失败: %w", err)
+	}
+
+	scope := &OperatorScope{
+		UserID: operator.UserID,
+		RoleID: operator.RoleID,
+		grants: grants,
+	}
+	if operator.Role != nil {
+		scope.Rank = operator.Role.Rank
+	}
+	return scope, nil
+}
+
+// AuthorizeUser 加载操作者管理范围并校验其能否以指定权限操作目标用户
+func (s *ScopeService) AuthorizeUser(operatorID int, permissionKey string, userID int) (*OperatorScope, *model.User, error) {
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("用户不存在")
+		}
+		return nil, nil, err
+	}
+	scope, err := s.Load(operatorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := scope.CheckUser(permissionKey, user); err != nil {
+		return nil, nil, err
+	}
+	return scope, user, nil
+}
+
+// ListScope 返回操作者拥有指定权限的用户组ID，用于列表筛选，nil 表示不限
+func (s *ScopeService) ListScope(operatorID int, permissionKey string) ([]int, error) {
+	scope, err := s.Load(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	return scope.ListScope(permissionKey)
+}
+
+// OperatorScope 操作者的管理范围
+type OperatorScope struct {
+	UserID int
+	RoleID int
+	Rank   int // 全局角色等级
+
+	grants []*model.UserGroupGrant
+}
+
+// Groups 返回操作者拥有指定权限的范围：global 为 true 表示不限用户组，否则返回可管理的用户组ID
+func (s *OperatorScope) Groups(permissionKey string) (bool, []int, error) {
+	result, err := PermCache().Check(s.RoleID, permissionKey)
+	if err != nil {
+		return false, nil, err
+	}
+	if result.Allowed {
+		return true, nil, nil
+	}
+	groupIDs := []int{}
+	if result.Effect == model.PermissionEffectDeny {
+		return false, groupIDs, nil
+	}
+	for _, grant := range s.grants {
+		allowed, err := PermCache().HasPermission(grant.RoleID, permissionKey)
+		if err != nil {
+			return false, nil, err
+		}
+		if allowed {
+			groupIDs = append(groupIDs, grant.GroupID)
+		}
+	}
+	return false, groupIDs, nil
+}
+
+// ListScope 返回用于列表筛选的用户组ID，nil 表示不限
+func (s *OperatorScope) ListScope(permissionKey string) ([]int, error) {
+	global, groupIDs, err := s.Groups(permissionKey)
+	if err != nil || global {
+		return nil, err
+	}
+	return groupIDs, nil
+}
+
+// CheckGroup 校验操作者能否以指定权限管理某用户组（groupID 为空表示未分组，仅全局权限可管理）
+func (s *OperatorScope) CheckGroup(permissionKey string, groupID *int) error {
+	global, groupIDs, err := s.Groups(permissionKey)
+	if err != nil {
+		return err
+	}
+	if global {
+		return nil
+	}
+	if groupID != nil {
+		for _, id := range groupIDs {
+			if id == *groupID {
+				return nil
+			}
+		}
+	}
+	return ErrScopeGroupDenied
+}
+
+// CheckUser 校验操作者能否以指定权限操作目标用户：目标须在管理范围内，且角色等级不高于操作者
+func (s *OperatorScope) CheckUser(permissionKey string, target *model.User) error {
+	if err := s.CheckGroup(permissionKey, target.GroupID); err != nil {
+		if errors.Is(err, ErrScopeGroupDenied) {
+			return ErrScopeUserDenied
+		}
+		return err
+	}
+	if target.Role != nil && target.Role.Rank > s.RankIn(target.GroupID) {
+		return ErrScopeRankDenied
+	}
+	return nil
+}
+
+// CheckAssignRole 校验操作者能否在指定用户组内分配某角色
+func (s *OperatorScope) CheckAssignRole(role *model.Role, groupID *int) error {
+	if role.Rank > s.RankIn(groupID) {
+		return ErrScopeRoleDenied
+	}
+	return nil
+}
+
+// RankIn 返回操作者在指定用户组内的等级（全局角色与组内授予角色取高者）
+func (s *OperatorScope) RankIn(groupID *int) int {
+	rank := s.Rank
+	if groupID == nil {
+		return rank
+	}
+	for _, grant := range s.grants {
+		if grant.GroupID == *groupID && grant.Role != nil && grant.Role.Rank > rank {
+			rank = grant.Role.Rank
+		}
+	}
+	return rank
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// 会话相关错误
+var (
+	ErrSessionNotFound     = errors.New("会话不存在或已失效")
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，登录会话已撤销")
+)
+
+// 会话相关配置
+const (
+	defaultMaxSessions = 5           // 每个用户默认最多同时保留的会话数
+	defaultAccessTTL   = 15          // 访问令牌默认有效期（分钟）
+	sessionCacheTTL    = time.Hour   // 会话有效性缓存时长
+	sessionTouchEvery  = time.Minute // 最近活跃时间的最小更新间隔
+)
+
+// SessionService 登录会话服务
+// 每次登录创建一个会话，访问令牌携带会话ID，刷新令牌归属于会话；撤销会话后对应令牌立即失效
+type SessionService struct {
+	sessionDAO      *dao.SessionDAO
+	refreshTokenDAO *dao.RefreshTokenDAO
+	configDAO       *dao.SystemConfigDAO
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{
+		sessionDAO:      dao.NewSessionDAO(),
+		refreshTokenDAO: dao.NewRefreshTokenDAO(),
+		configDAO:       dao.NewSystemConfigDAO(),
+	}
+}
+
+// sessionCacheKey 会话有效性缓存键，值为会话所属用户ID
+func sessionCacheKey(sessionID string) string {
+	return fmt.Sprintf("emby_ums:session:%s", sessionID)
+}
+
+// Create 为用户创建会话，超出会话数上限时撤销最久未活跃的会话
+func (s *SessionService) Create(userID int, meta *model.SessionMeta) (*model.UserSession, error) {
+	id, err := util.RandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("生成会话ID失败: %w", err)
+	}
+
+	device := strings.TrimSpace(meta.Device)
+	if device == "" {
+		device = util.DeviceFromUserAgent(meta.UserAgent)
+	}
+	userAgent := meta.UserAgent
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+
+	now := time.Now()
+	session := &model.UserSession{
+		ID:         id,
+		UserID:     userID,
+		Device:     device,
+		IP:         meta.IP,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(util.SessionTTL()),
+	}
+	if err := s.sessionDAO.Create(session); err != nil {
+		return nil, fmt.Errorf("创建会话失败: %w", err)
+	}
+
+	if err := s.enforceLimit(userID); err != nil {
+		util.Warn(fmt.Sprintf("清理超出上限的会话失败: user_id=%d, %v", userID, err))
+	}
+	return session, nil
+}
+
+// configInt 读取正整数配置，未配置或无效时返回默认值
+func (s *SessionService) configInt(key string, def int) int {
+	if cfg, err := s.configDAO.Get(key); err == nil {
+		if v, err := strconv.Atoi(strings.TrimSpace(cfg.ConfigValue)); err == nil && v > 0 {
+			return v
+		}
+	}
+	return def
+}
+
+// maxSessions 读取每个用户的会话数上限
+func (s *SessionService) maxSessions() int {
+	return s.configInt("max_sessions_per_user", defaultMaxSessions)
+}
+
+// AccessTokenTTL 访问令牌有效期
+func (s *SessionService) AccessTokenTTL() time.Duration {
+	return time.Duration(s.configInt("access_token_minutes", defaultAccessTTL)) * time.Minute
+}
+
+// enforceLimit 撤销超出上限的会话（保留最近活跃的）
+func (s *SessionService) enforceLimit(userID int) error {
+	sessions, err := s.sessionDAO.ListActiveByUser(userID)
+	if err != nil {
+		return err
+	}
+	limit := s.maxSessions()
+	if len(sessions) <= limit {
+		return nil
+	}
+
+	ids := make([]string, 0, len(sessions)-limit)
+	for _, session := range sessions[limit:] {
+		ids = append(ids, session.ID)
+	}
+	return s.Revoke(userID, ids...)
+}
+
+// Validate 校验会话是否有效且属于该用户，优先读取Redis缓存
+func (s *SessionService) Validate(userID int, sessionID string) error {
+	if sessionID == "" {
+		return errors.New("会话不存在")
+	}
+
+	key := sessionCacheKey(sessionID)
+	if data, err := redis.Get(key); err == nil {
+		if data != strconv.Itoa(userID) {
+			return errors.New("会话不存在")
+		}
+		return nil
+	}
+
+	session, err := s.sessionDAO.GetActive(sessionID)
+	if err != nil || session.UserID != userID {
+		return errors.New("会话已失效")
+	}
+	ttl := time.Until(session.ExpiresAt)
+	if ttl > sessionCacheTTL {
+		ttl = sessionCacheTTL
+	}
+	redis.Set(key, userID, ttl)
+	return nil
+}
+
+// Touch 更新会话最近活跃时间，同一会话每分钟最多写一次数据库
+func (s *SessionService) Touch(sessionID, ip string) {
+	if sessionID == "" {
+		return
+	}
+	if ok, err := redis.SetNX(fmt.Sprintf("emby_ums:session:seen:%s", sessionID), 1, sessionTouchEvery); err != nil || !ok {
+		return
+	}
+	if err := s.sessionDAO.Touch(sessionID, ip); err != nil {
+		util.Warn(fmt.Sprintf("更新会话活跃时间失败: %v", err))
+	}
+}
+
+// IssueRefreshToken 为会话签发刷新令牌（新令牌族的第一个），返回令牌原文
+func (s *SessionService) IssueRefreshToken(session *model.UserSession) (string, error) {
+	raw, err := util.RandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+	if err := s.refreshTokenDAO.Create(&model.RefreshToken{
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		TokenHash: util.HashToken(raw),
+		ExpiresAt: session.ExpiresAt,
+	}); err != nil {
+		return "", fmt.Errorf("保存刷新令牌失败: %w", err)
+	}
+	return raw, nil
+}
+
+// Rotate 使用刷新令牌换取新的刷新令牌：旧令牌标记为已使用，会话有效期顺延
+// 已使用过的令牌再次出现视为泄露，撤销其所属会话（整个令牌族）并返回 ErrRefreshTokenReused
+func (s *SessionService) Rotate(raw string) (*model.RefreshToken, string, error) {
+	newRaw, err := util.RandomToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+
+	var current *model.RefreshToken
+	reused := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := s.refreshTokenDAO.LockByHashTx(tx, util.HashToken(raw))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+		current = token
+		if token.UsedAt != nil {
+			reused = true
+			return nil
+		}
+		if time.Now().After(token.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+		if _, err := s.sessionDAO.LockActiveTx(tx, token.SessionID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+
+		if err := s.refreshTokenDAO.MarkUsedTx(tx, token.ID); err != nil {
+			return err
+		}
+		expiresAt := time.Now().Add(util.SessionTTL())
+		if err := s.refreshTokenDAO.CreateTx(tx, &model.RefreshToken{
+			SessionID: token.SessionID,
+			UserID:    token.UserID,
+			TokenHash: util.HashToken(newRaw),
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			return err
+		}
+		return s.sessionDAO.UpdateExpiryTx(tx, token.SessionID, expiresAt)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if reused {
+		if err := s.Revoke(current.UserID, current.SessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			util.Warn(fmt.Sprintf("撤销刷新令牌被重用的会话失败: session_id=%s, %v", current.SessionID, err))
+		}
+		return current, "", ErrRefreshTokenReused
+	}
+	return current, newRaw, nil
+}
+
+// List 获取用户的有效会话，currentID 对应的会话标记为当前会话
+func (s *SessionService) List(userID int, currentID string) ([]*model.UserSession, error) {
+	sessions, err := s.sessionDAO.ListActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+	return sessions, nil
+}
+
+// Revoke 撤销用户的指定会话
+func (s *SessionService) Revoke(userID int, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	ids, err := s.sessionDAO.Revoke(userID, sessionIDs...)
+	if err != nil {
+		return fmt.Errorf("撤销会话失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return ErrSessionNotFound
+	}
+	dropSessionCache(ids)
+	return nil
+}
+
+// RevokeAll 撤销用户的全部会话，exceptID 非空时保留该会话，返回撤销数量
+func (s *SessionService) RevokeAll(userIDs []int, exceptID string) (int, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+	ids, err := s.sessionDAO.RevokeByUsers(userIDs, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("撤销会话失败: %w", err)
+	}
+	dropSessionCache(ids)
+	return len(ids), nil
+}
+
+// dropSessionCache 清除会话有效性缓存
+func dropSessionCache(sessionIDs []string) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		keys = append(keys, sessionCacheKey(id))
+	}
+	if err := redis.Del(keys...); err != nil {
+		util.Warn(fmt.Sprintf("清除会话缓存失败: %v", err))
+	}
+}
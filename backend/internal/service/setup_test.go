@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"embyhub/internal/model"
+	"embyhub/internal/testutil"
+	"embyhub/pkg/database"
+)
+
+// setupTestEnv 为单个测试准备独立的内存数据库和Redis，替换全局连接
+func setupTestEnv(t *testing.T) {
+	t.Helper()
+	testutil.Setup(t)
+}
+
+// createTestTier 创建启用的VIP等级
+func createTestTier(t *testing.T, level int, dayValue int) *model.VipTier {
+	t.Helper()
+	tier := &model.VipTier{
+		Level:         level,
+		Name:          fmt.Sprintf("VIP%d", level),
+		AllowPlayback: true,
+		DayValue:      dayValue,
+		Status:        1,
+	}
+	// 免费等级的主键为0，按零值主键会被当作自增，需显式写入
+	if err := database.DB.Exec(
+		"INSERT INTO vip_tiers (level, name, allow_playback, day_value, status) VALUES (?, ?, ?, ?, ?)",
+		tier.Level, tier.Name, tier.AllowPlayback, tier.DayValue, tier.Status,
+	).Error; err != nil {
+		t.Fatalf("创建VIP等级失败: %v", err)
+	}
+	return tier
+}
+
+// createTestUser 创建普通用户
+func createTestUser(t *testing.T, username string) *model.User {
+	t.Helper()
+	user := &model.User{
+		Username:     username,
+		PasswordHash: "x",
+		Email:        username + "@example.com",
+		RoleID:       2,
+		Status:       1,
+	}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+// reloadUser 从数据库重新读取用户
+func reloadUser(t *testing.T, userID int) *model.User {
+	t.Helper()
+	var user model.User
+	if err := database.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		t.Fatalf("读取用户失败: %v", err)
+	}
+	return &user
+}
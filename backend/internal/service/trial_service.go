@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// trialAdvisoryLock 试用发放的事务级咨询锁，串行化防滥用检查与写入
+const trialAdvisoryLock = 0x7472_6961_6c
+
+// 试用配置项
+var trialConfigKeys = []string{
+	"trial_days",
+	"trial_vip_level",
+	"trial_require_verified_email",
+	"trial_ip_window_days",
+	"trial_blocked_email_domains",
+	"trial_reminder_hours",
+}
+
+// errTrialDenied 不满足试用条件（不影响注册）
+var errTrialDenied = errors.New("不满足试用条件")
+
+// TrialService 注册试用服务
+// 每个规范化邮箱、设备指纹只能领取一次，同一IP在时间窗口内只能领取一次
+type TrialService struct {
+	configDAO      *dao.SystemConfigDAO
+	trialDAO       *dao.TrialDAO
+	vipService     *VipService
+	vipTierService *VipTierService
+}
+
+func NewTrialService() *TrialService {
+	return &TrialService{
+		configDAO:      dao.NewSystemConfigDAO(),
+		trialDAO:       dao.NewTrialDAO(),
+		vipService:     NewVipService(),
+		vipTierService: NewVipTierService(),
+	}
+}
+
+// Config 读取试用配置，未配置时试用关闭
+func (s *TrialService) Config() *model.TrialConfig {
+	cfg := &model.TrialConfig{VipLevel: 1, RequireVerifiedEmail: true, IPWindowDays: 30, ReminderHours: 24}
+	values, err := s.configDAO.BatchGet(trialConfigKeys)
+	if err != nil {
+		return &model.TrialConfig{}
+	}
+
+	atoi := func(key string, def int) int {
+		if v, err := strconv.Atoi(strings.TrimSpace(values[key])); err == nil && v >= 0 {
+			return v
+		}
+		return def
+	}
+	cfg.Days = atoi("trial_days", 0)
+	cfg.VipLevel = atoi("trial_vip_level", cfg.VipLevel)
+	cfg.IPWindowDays = atoi("trial_ip_window_days", cfg.IPWindowDays)
+	cfg.ReminderHours = atoi("trial_reminder_hours", cfg.ReminderHours)
+	if v, ok := values["trial_require_verified_email"]; ok {
+		cfg.RequireVerifiedEmail = v != "false" && v != "0"
+	}
+	for _, pattern := range strings.Split(values["trial_blocked_email_domains"], ",") {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			cfg.BlockedEmailDomains = append(cfg.BlockedEmailDomains, pattern)
+		}
+	}
+	return cfg
+}
+
+// normalizeTrialEmail 规范化邮箱，使同一邮箱的变体得到相同的键
+// 忽略大小写和 +标签；Gmail 额外忽略本地部分的点号
+func normalizeTrialEmail(email string) (key, domain string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email, ""
+	}
+	local, domain := email[:at], email[at+1:]
+	if i := strings.Index(local, "+"); i >= 0 {
+		local = local[:i]
+	}
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain, domain
+}
+
+// emailDomainBlocked 邮箱域名是否命中禁止模式
+// 模式 *.example.com 同时匹配 example.com 本身
+func emailDomainBlocked(domain string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, domain); matched {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && domain == pattern[2:] {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantOnRegister 为新注册用户发放试用VIP
+// 不满足条件时返回 nil 和原因，不影响注册流程
+func (s *TrialService) GrantOnRegister(user *model.User, emailVerified bool, ip, fingerprint string) (*model.TrialInfo, error) {
+	cfg := s.Config()
+	if cfg.Days <= 0 {
+		return nil, errTrialDenied
+	}
+	if cfg.RequireVerifiedEmail && !emailVerified {
+		return nil, fmt.Errorf("%w: 邮箱未验证", errTrialDenied)
+	}
+	if _, err := s.vipTierService.GetGrantable(cfg.VipLevel); err != nil {
+		return nil, fmt.Errorf("%w: %v", errTrialDenied, err)
+	}
+
+	emailKey, domain := normalizeTrialEmail(user.Email)
+	if emailDomainBlocked(domain, cfg.BlockedEmailDomains) {
+		return nil, fmt.Errorf("%w: 邮箱域名不支持试用", errTrialDenied)
+	}
+	fingerprint = strings.TrimSpace(fingerprint)
+
+	var granted *model.User
+	var grant *model.TrialGrant
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", trialAdvisoryLock).Error; err != nil {
+			return err
+		}
+
+		if exists, err := s.trialDAO.ExistsByEmailKeyTx(tx, emailKey); err != nil || exists {
+			return firstErr(err, fmt.Errorf("%w: 该邮箱已领取过试用", errTrialDenied))
+		}
+		if fingerprint != "" {
+			if exists, err := s.trialDAO.ExistsByFingerprintTx(tx, fingerprint); err != nil || exists {
+				return firstErr(err, fmt.Errorf("%w: 该设备已领取过试用", errTrialDenied))
+			}
+		}
+		if ip != "" && cfg.IPWindowDays > 0 {
+			since := time.Now().AddDate(0, 0, -cfg.IPWindowDays)
+			if exists, err := s.trialDAO.ExistsByIPTx(tx, ip, since); err != nil || exists {
+				return firstErr(err, fmt.Errorf("%w: 该IP近期已领取过试用", errTrialDenied))
+			}
+		}
+
+		var err error
+		granted, _, err = s.vipService.GrantTx(tx, &model.VipChange{
+			UserID: user.UserID,
+			Level:  cfg.VipLevel,
+			Days:   cfg.Days,
+			Source: model.VipSourceTrial,
+			Remark: "注册试用",
+		})
+		if err != nil {
+			return err
+		}
+
+		grant = &model.TrialGrant{
+			UserID:            user.UserID,
+			EmailKey:          emailKey,
+			IPAddress:         ip,
+			DeviceFingerprint: fingerprint,
+			VipLevel:          cfg.VipLevel,
+			Days:              cfg.Days,
+			ExpireAt:          *granted.VipExpireAt,
+			CreatedAt:         time.Now(),
+		}
+		return s.trialDAO.CreateTx(tx, grant)
+	})
+	if err != nil {
+		if !errors.Is(err, errTrialDenied) {
+			util.Warn(fmt.Sprintf("发放注册试用失败 user=%d: %v", user.UserID, err))
+		}
+		return nil, err
+	}
+
+	s.vipService.AfterCommit(granted)
+	return &model.TrialInfo{VipLevel: grant.VipLevel, Days: grant.Days, ExpireAt: grant.ExpireAt}, nil
+}
+
+// firstErr 返回第一个非空错误
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
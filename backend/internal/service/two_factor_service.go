@@ -0,0 +1,351 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+)
+
+// 两步验证相关错误
+var (
+	ErrTwoFactorInvalid   = errors.New("验证码错误或已使用")
+	ErrTwoFactorChallenge = errors.New("登录验证已过期，请重新登录")
+	ErrTwoFactorLocked    = errors.New("两步验证失败次数过多")
+)
+
+// 两步验证配置
+const (
+	totpIssuer             = "Emby Hub"
+	recoveryCodeCount      = 10
+	challengeTTL           = 5 * time.Minute  // 登录第二步的有效期
+	challengeMaxAttempts   = 5                // 登录第二步最多尝试次数
+	stepUpTTL              = 10 * time.Minute // 二次验证通过后敏感操作的免验证时长
+	challengeKeyPrefix     = "emby_ums:2fa:challenge:"
+	stepUpKeyPrefix        = "emby_ums:2fa:stepup:"
+	challengeAttemptSuffix = ":attempts"
+	verifyMaxFailures      = 5                // 验证码连续错误次数上限，超过后锁定
+	verifyFailureWindow    = 15 * time.Minute // 错误次数的统计窗口
+	verifyLockDuration     = 15 * time.Minute // 锁定时长
+	verifyFailureKeyPrefix = "emby_ums:2fa:failures:"
+	verifyLockKeyPrefix    = "emby_ums:2fa:lock:"
+)
+
+// loginChallenge 密码验证通过、等待两步验证的登录
+type loginChallenge struct {
+	UserID            int    `json:"user_id"`
+	Device            string `json:"device"`
+	DeviceFingerprint string `json:"device_fingerprint"`
+	IP                string `json:"ip"`
+	UserAgent         string `json:"user_agent"`
+	Method            string `json:"method"`
+}
+
+// TwoFactorService TOTP两步验证服务
+type TwoFactorService struct {
+	twoFactorDAO *dao.TwoFactorDAO
+	userDAO      *dao.UserDAO
+}
+
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorDAO: dao.NewTwoFactorDAO(),
+		userDAO:      dao.NewUserDAO(),
+	}
+}
+
+// Enabled 判断用户是否已开启两步验证
+func (s *TwoFactorService) Enabled(userID int) (bool, error) {
+	return s.twoFactorDAO.IsEnabled(userID)
+}
+
+// Required 判断用户所属角色是否要求两步验证
+func (s *TwoFactorService) Required(user *model.User) bool {
+	return user.Role != nil && user.Role.Require2FA
+}
+
+// Status 获取用户的两步验证状态
+func (s *TwoFactorService) Status(userID int) (*model.TwoFactorStatus, error) {
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &model.TwoFactorStatus{Enabled: enabled, Required: s.Required(user)}
+	if enabled {
+		left, err := s.twoFactorDAO.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesLeft = int(left)
+	}
+	return status, nil
+}
+
+// Setup 生成新的TOTP密钥（待确认），返回密钥和扫码地址
+func (s *TwoFactorService) Setup(userID int) (*model.TwoFactorSetupResponse, error) {
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("已开启两步验证，如需更换请先关闭")
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	if err := s.twoFactorDAO.SavePending(userID, secret); err != nil {
+		return nil, fmt.Errorf("保存密钥失败: %w", err)
+	}
+	return &model.TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    util.TOTPProvisioningURI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// Enable 使用验证器中的验证码确认绑定，开启两步验证并返回恢复码
+func (s *TwoFactorService) Enable(userID int, code string) ([]string, error) {
+	totp, err := s.twoFactorDAO.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("请先获取绑定二维码")
+		}
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, errors.New("已开启两步验证")
+	}
+	if err := s.verifyTOTP(totp, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorDAO.Enable(userID, hashes); err != nil {
+		return nil, fmt.Errorf("开启两步验证失败: %w", err)
+	}
+	return codes, nil
+}
+
+// Disable 关闭两步验证，需提供验证码或恢复码；角色要求两步验证时不能关闭
+func (s *TwoFactorService) Disable(userID int, code string) error {
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	if s.Required(user) {
+		return errors.New("所属角色要求开启两步验证，不能关闭")
+	}
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	return s.twoFactorDAO.Delete(userID)
+}
+
+// Reset 管理员重置用户的两步验证（用户丢失验证器时使用），同时解除验证失败锁定
+func (s *TwoFactorService) Reset(userID int) error {
+	if err := s.twoFactorDAO.Delete(userID); err != nil {
+		return err
+	}
+	redis.Del(fmt.Sprintf("%s%d", verifyFailureKeyPrefix, userID), fmt.Sprintf("%s%d", verifyLockKeyPrefix, userID))
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，需提供验证码或恢复码
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorDAO.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("生成恢复码失败: %w", err)
+	}
+	return codes, nil
+}
+
+// Verify 校验已开启两步验证的用户提交的TOTP验证码或恢复码
+// 登录第二步、二次验证、关闭两步验证和重新生成恢复码共用同一失败计数，
+// 连续错误达到上限后锁定该用户的两步验证，防止跨入口穷举验证码
+func (s *TwoFactorService) Verify(userID int, code string) error {
+	lockKey := fmt.Sprintf("%s%d", verifyLockKeyPrefix, userID)
+	if locked, _ := redis.ExistsKey(lockKey); locked {
+		ttl, _ := redis.TTL(lockKey)
+		return fmt.Errorf("%w，请%d分钟后重试", ErrTwoFactorLocked, int(ttl.Minutes())+1)
+	}
+
+	totp, err := s.twoFactorDAO.GetTOTP(userID)
+	if err != nil || !totp.Enabled {
+		return errors.New("未开启两步验证")
+	}
+	if err := s.verifyTOTP(totp, code); err == nil {
+		s.clearVerifyFailure(userID)
+		return nil
+	}
+
+	used, err := s.twoFactorDAO.UseRecoveryCode(userID, util.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		s.recordVerifyFailure(userID)
+		return ErrTwoFactorInvalid
+	}
+	s.clearVerifyFailure(userID)
+	return nil
+}
+
+// recordVerifyFailure 记录验证码错误，达到上限时锁定
+func (s *TwoFactorService) recordVerifyFailure(userID int) {
+	failureKey := fmt.Sprintf("%s%d", verifyFailureKeyPrefix, userID)
+	failures, _ := redis.Incr(failureKey)
+	if failures == 1 {
+		redis.Expire(failureKey, verifyFailureWindow)
+	}
+	if failures >= verifyMaxFailures {
+		redis.Set(fmt.Sprintf("%s%d", verifyLockKeyPrefix, userID), "1", verifyLockDuration)
+		redis.Del(failureKey)
+		util.Warn(fmt.Sprintf("用户 %d 两步验证失败次数过多，已锁定%d分钟", userID, int(verifyLockDuration.Minutes())))
+	}
+}
+
+// clearVerifyFailure 验证通过后清除错误次数
+func (s *TwoFactorService) clearVerifyFailure(userID int) {
+	redis.Del(fmt.Sprintf("%s%d", verifyFailureKeyPrefix, userID))
+}
+
+// verifyTOTP 校验TOTP验证码，同一时间步的验证码只能使用一次
+func (s *TwoFactorService) verifyTOTP(totp *model.UserTOTP, code string) error {
+	step, ok := util.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return ErrTwoFactorInvalid
+	}
+	fresh, err := s.twoFactorDAO.UseStep(totp.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrTwoFactorInvalid
+	}
+	return nil
+}
+
+// generateRecoveryCodes 生成恢复码，返回原文（展示给用户）和摘要（入库）
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := util.RandomToken(5)
+		if err != nil {
+			return nil, nil, fmt.Errorf("生成恢复码失败: %w", err)
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, util.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 忽略恢复码中的分隔符、空格和大小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// CreateChallenge 密码验证通过后创建登录挑战，返回挑战令牌
+func (s *TwoFactorService) CreateChallenge(userID int, meta *model.SessionMeta) (string, error) {
+	token, err := util.RandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("生成登录挑战失败: %w", err)
+	}
+	data, err := json.Marshal(&loginChallenge{
+		UserID:            userID,
+		Device:            meta.Device,
+		DeviceFingerprint: meta.DeviceFingerprint,
+		IP:                meta.IP,
+		UserAgent:         meta.UserAgent,
+		Method:            meta.Method,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := redis.Set(challengeKeyPrefix+util.HashToken(token), string(data), challengeTTL); err != nil {
+		return "", fmt.Errorf("保存登录挑战失败: %w", err)
+	}
+	return token, nil
+}
+
+// LoadChallenge 读取登录挑战，超过最大尝试次数后挑战失效
+func (s *TwoFactorService) LoadChallenge(token string) (int, *model.SessionMeta, error) {
+	key := challengeKeyPrefix + util.HashToken(token)
+	data, err := redis.Get(key)
+	if err != nil {
+		return 0, nil, ErrTwoFactorChallenge
+	}
+
+	attempts, _ := redis.Incr(key + challengeAttemptSuffix)
+	redis.Expire(key+challengeAttemptSuffix, challengeTTL)
+	if attempts > challengeMaxAttempts {
+		s.ConsumeChallenge(token)
+		return 0, nil, errors.New("验证失败次数过多，请重新登录")
+	}
+
+	var challenge loginChallenge
+	if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+		return 0, nil, ErrTwoFactorChallenge
+	}
+	return challenge.UserID, &model.SessionMeta{
+		Device:            challenge.Device,
+		DeviceFingerprint: challenge.DeviceFingerprint,
+		IP:                challenge.IP,
+		UserAgent:         challenge.UserAgent,
+		Method:            challenge.Method,
+	}, nil
+}
+
+// ConsumeChallenge 登录完成后删除挑战
+func (s *TwoFactorService) ConsumeChallenge(token string) {
+	key := challengeKeyPrefix + util.HashToken(token)
+	redis.Del(key, key+challengeAttemptSuffix)
+}
+
+// MarkStepUp 记录会话已通过二次验证
+func (s *TwoFactorService) MarkStepUp(sessionID string) error {
+	return redis.Set(stepUpKeyPrefix+sessionID, 1, stepUpTTL)
+}
+
+// CheckStepUp 判断会话能否执行敏感操作：未开启两步验证的用户直接放行，否则需在有效期内通过二次验证
+func (s *TwoFactorService) CheckStepUp(userID int, sessionID string) (bool, error) {
+	// 个人访问令牌没有会话，无法完成二次验证
+	if sessionID != "" {
+		if ok, _ := redis.ExistsKey(stepUpKeyPrefix + sessionID); ok {
+			return true, nil
+		}
+	}
+	enabled, err := s.Enabled(userID)
+	if err != nil {
+		return false, err
+	}
+	return !enabled, nil
+}
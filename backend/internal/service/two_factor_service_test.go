@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"embyhub/internal/util"
+)
+
+// enableTestTwoFactor 为用户开启两步验证，返回恢复码
+func enableTestTwoFactor(t *testing.T, s *TwoFactorService, userID int) []string {
+	t.Helper()
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("生成恢复码失败: %v", err)
+	}
+	if err := s.twoFactorDAO.SavePending(userID, secret); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+	if err := s.twoFactorDAO.Enable(userID, hashes); err != nil {
+		t.Fatalf("开启两步验证失败: %v", err)
+	}
+	return codes
+}
+
+func TestTwoFactorVerifyLockout(t *testing.T) {
+	setupTestEnv(t)
+	s := NewTwoFactorService()
+	user := createTestUser(t, "alice")
+	codes := enableTestTwoFactor(t, s, user.UserID)
+
+	// 成功验证会清零错误次数
+	for i := 0; i < verifyMaxFailures-1; i++ {
+		if err := s.Verify(user.UserID, "000000"); !errors.Is(err, ErrTwoFactorInvalid) {
+			t.Fatalf("错误验证码应校验失败: %v", err)
+		}
+	}
+	if err := s.Verify(user.UserID, codes[0]); err != nil {
+		t.Fatalf("恢复码应校验通过: %v", err)
+	}
+
+	// 各入口共用失败计数：关闭两步验证、重新生成恢复码的错误同样计入
+	for i := 0; i < verifyMaxFailures-2; i++ {
+		s.Verify(user.UserID, "000000")
+	}
+	if err := s.Disable(user.UserID, "000000"); !errors.Is(err, ErrTwoFactorInvalid) {
+		t.Fatalf("错误验证码不应关闭两步验证: %v", err)
+	}
+	if _, err := s.RegenerateRecoveryCodes(user.UserID, "000000"); !errors.Is(err, ErrTwoFactorInvalid) {
+		t.Fatalf("错误验证码不应重新生成恢复码: %v", err)
+	}
+
+	// 锁定后正确的恢复码也被拒绝，且不会被消耗
+	if err := s.Verify(user.UserID, codes[1]); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Fatalf("锁定期间应拒绝验证: %v", err)
+	}
+	if left, _ := s.twoFactorDAO.CountRecoveryCodes(user.UserID); left != recoveryCodeCount-1 {
+		t.Fatalf("锁定期间不应消耗恢复码: %d", left)
+	}
+
+	// 管理员重置两步验证后解除锁定
+	if err := s.Reset(user.UserID); err != nil {
+		t.Fatalf("重置两步验证失败: %v", err)
+	}
+	codes = enableTestTwoFactor(t, s, user.UserID)
+	if err := s.Verify(user.UserID, codes[0]); err != nil {
+		t.Fatalf("重置后应可验证: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type UserGroupService struct {
+	userGroupDAO *dao.UserGroupDAO
+	userDAO      *dao.UserDAO
+	roleDAO      *dao.RoleDAO
+	scopeService *ScopeService
+}
+
+func NewUserGroupService() *UserGroupService {
+	return &UserGroupService{
+		userGroupDAO: dao.NewUserGroupDAO(),
+		userDAO:      dao.NewUserDAO(),
+		roleDAO:      dao.NewRoleDAO(),
+		scopeService: NewScopeService(),
+	}
+}
+
+// List 获取操作者可查看的用户组
+func (s *UserGroupService) List(operatorID int) ([]*model.UserGroup, error) {
+	groupIDs, err := s.scopeService.ListScope(operatorID, "user:view")
+	if err != nil {
+		return nil, err
+	}
+	return s.userGroupDAO.List(groupIDs)
+}
+
+// Create 创建用户组（仅全局 group:manage 权限）
+func (s *UserGroupService) Create(req *model.UserGroupRequest, operatorID int) (*model.UserGroup, error) {
+	if err := s.checkManage(operatorID, nil); err != nil {
+		return nil, err
+	}
+	group := &model.UserGroup{CreatedAt: time.Now()}
+	return s.save(group, req)
+}
+
+// Update 更新用户组
+func (s *UserGroupService) Update(groupID int, req *model.UserGroupRequest, operatorID int) (*model.UserGroup, error) {
+	group, err := s.get(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkManage(operatorID, &groupID); err != nil {
+		return nil, err
+	}
+	return s.save(group, req)
+}
+
+// Delete 删除用户组（仅全局 group:manage 权限），组内用户移出该组，组内授权失效
+func (s *UserGroupService) Delete(groupID int, operatorID int) error {
+	if _, err := s.get(groupID); err != nil {
+		return err
+	}
+	if err := s.checkManage(operatorID, nil); err != nil {
+		return err
+	}
+	return s.userGroupDAO.Delete(groupID)
+}
+
+// ListGrants 获取用户组内的授权
+func (s *UserGroupService) ListGrants(groupID int, operatorID int) ([]*model.UserGroupGrant, error) {
+	if _, err := s.get(groupID); err != nil {
+		return nil, err
+	}
+	if err := s.checkManage(operatorID, &groupID); err != nil {
+		return nil, err
+	}
+	return s.userGroupDAO.ListGrants(groupID)
+}
+
+// Grant 授予用户在组内的角色，授予的角色等级不能高于操作者在该组内的等级
+func (s *UserGroupService) Grant(groupID int, req *model.UserGroupGrantRequest, operatorID int) (*model.UserGroupGrant, error) {
+	if _, err := s.get(groupID); err != nil {
+		return nil, err
+	}
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := scope.CheckGroup("group:manage", &groupID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userDAO.GetByID(req.UserID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Role != nil && user.Role.Rank > scope.RankIn(&groupID) {
+		return nil, ErrScopeRankDenied
+	}
+	role, err := s.roleDAO.GetByID(req.RoleID)
+	if err != nil {
+		return nil, errors.New("角色不存在")
+	}
+	if err := scope.CheckAssignRole(role, &groupID); err != nil {
+		return nil, err
+	}
+
+	grant := &model.UserGroupGrant{
+		GroupID:   groupID,
+		UserID:    req.UserID,
+		RoleID:    req.RoleID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.userGroupDAO.UpsertGrant(grant); err != nil {
+		return nil, fmt.Errorf("授权失败: %w", err)
+	}
+	return grant, nil
+}
+
+// Revoke 撤销用户在组内的授权，不能撤销等级高于自己的授权
+func (s *UserGroupService) Revoke(groupID, userID int, operatorID int) error {
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return err
+	}
+	if err := scope.CheckGroup("group:manage", &groupID); err != nil {
+		return err
+	}
+
+	grants, err := s.userGroupDAO.ListGrantsByUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if grant.GroupID == groupID && grant.Role != nil {
+			if err := scope.CheckAssignRole(grant.Role, &groupID); err != nil {
+				return err
+			}
+		}
+	}
+
+	deleted, err := s.userGroupDAO.DeleteGrant(groupID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("授权不存在")
+	}
+	return nil
+}
+
+// get 获取用户组
+func (s *UserGroupService) get(groupID int) (*model.UserGroup, error) {
+	group, err := s.userGroupDAO.GetByID(groupID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户组不存在")
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+// checkManage 校验操作者能否管理用户组（groupID 为空时要求全局权限）
+func (s *UserGroupService) checkManage(operatorID int, groupID *int) error {
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return err
+	}
+	return scope.CheckGroup("group:manage", groupID)
+}
+
+// save 校验并保存用户组
+func (s *UserGroupService) save(group *model.UserGroup, req *model.UserGroupRequest) (*model.UserGroup, error) {
+	exists, err := s.userGroupDAO.ExistsByName(req.Name, group.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("检查用户组名失败: %w", err)
+	}
+	if exists {
+		return nil, errors.New("用户组名已存在")
+	}
+
+	group.Name = req.Name
+	group.Description = req.Description
+	if err := s.userGroupDAO.Save(group); err != nil {
+		return nil, fmt.Errorf("保存用户组失败: %w", err)
+	}
+	return group, nil
+}
@@ -11,26 +11,46 @@ import (
 	"embyhub/internal/util"
 	"embyhub/pkg/emby"
 	"embyhub/pkg/redis"
-
-	"gorm.io/gorm"
 )
 
 type UserService struct {
-	userDAO    *dao.UserDAO
-	roleDAO    *dao.RoleDAO
-	embyClient *emby.Client
+	userDAO          *dao.UserDAO
+	sessionService   *SessionService
+	twoFactorService *TwoFactorService
+	roleDAO          *dao.RoleDAO
+	userGroupDAO     *dao.UserGroupDAO
+	embyClient       *emby.Client
+	vipTierService   *VipTierService
+	vipService       *VipService
+	scopeService     *ScopeService
+	passwordService  *PasswordPolicyService
 }
 
 func NewUserService() *UserService {
 	return &UserService{
-		userDAO:    dao.NewUserDAO(),
-		roleDAO:    dao.NewRoleDAO(),
-		embyClient: emby.NewClient(&config.GlobalConfig.Emby),
+		userDAO:          dao.NewUserDAO(),
+		sessionService:   NewSessionService(),
+		twoFactorService: NewTwoFactorService(),
+		roleDAO:          dao.NewRoleDAO(),
+		userGroupDAO:     dao.NewUserGroupDAO(),
+		embyClient:       emby.NewClient(&config.GlobalConfig.Emby),
+		vipTierService:   NewVipTierService(),
+		vipService:       NewVipService(),
+		scopeService:     NewScopeService(),
+		passwordService:  NewPasswordPolicyService(),
 	}
 }
 
-// Create 创建用户
-func (s *UserService) Create(req *model.UserCreateRequest) (*model.User, error) {
+// Create 创建用户，组管理员只能在自己管理的用户组内创建
+func (s *UserService) Create(req *model.UserCreateRequest, operatorID int) (*model.User, error) {
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := scope.CheckGroup("user:create", req.GroupID); err != nil {
+		return nil, err
+	}
+
 	// 检查用户名是否存在
 	exists, err := s.userDAO.ExistsByUsername(req.Username)
 	if err != nil {
@@ -52,10 +72,17 @@ func (s *UserService) Create(req *model.UserCreateRequest) (*model.User, error)
 	}
 
 	// 检查角色是否存在
-	_, err = s.roleDAO.GetByID(req.RoleID)
+	role, err := s.roleDAO.GetByID(req.RoleID)
 	if err != nil {
 		return nil, errors.New("角色不存在")
 	}
+	if err := scope.CheckAssignRole(role, req.GroupID); err != nil {
+		return nil, err
+	}
+
+	if err := s.passwordService.Check(nil, req.Password); err != nil {
+		return nil, err
+	}
 
 	// 加密密码
 	passwordHash, err := util.HashPassword(req.Password)
@@ -91,6 +118,7 @@ func (s *UserService) Create(req *model.UserCreateRequest) (*model.User, error)
 		Email:        req.Email,
 		EmbyUserID:   embyUserID,
 		RoleID:       req.RoleID,
+		GroupID:      req.GroupID,
 		Status:       1,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
@@ -99,11 +127,21 @@ func (s *UserService) Create(req *model.UserCreateRequest) (*model.User, error)
 	if err := s.userDAO.Create(user); err != nil {
 		return nil, fmt.Errorf("创建用户失败: %w", err)
 	}
+	s.passwordService.Remember(user.UserID, passwordHash)
 
 	// 查询完整用户信息（包含角色）
 	return s.userDAO.GetByID(user.UserID)
 }
 
+// View 查看用户详情：本人可直接查看，否则需在管理范围内
+func (s *UserService) View(userID int, operatorID int) (*model.User, error) {
+	if userID == operatorID {
+		return s.GetByID(userID)
+	}
+	_, user, err := s.scopeService.AuthorizeUser(operatorID, "user:view", userID)
+	return user, err
+}
+
 // GetByID 根据ID获取用户
 func (s *UserService) GetByID(userID int) (*model.User, error) {
 	// 先从缓存获取
@@ -121,16 +159,28 @@ func (s *UserService) GetByID(userID int) (*model.User, error) {
 }
 
 // Update 更新用户
-func (s *UserService) Update(userID int, req *model.UserUpdateRequest) (*model.User, error) {
-	// 获取用户
-	user, err := s.userDAO.GetByID(userID)
+func (s *UserService) Update(userID int, req *model.UserUpdateRequest, operatorID int) (*model.User, error) {
+	scope, user, err := s.scopeService.AuthorizeUser(operatorID, "user:edit", userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户不存在")
-		}
 		return nil, err
 	}
 
+	// 调整用户组时，目标组同样需在管理范围内（0 表示移出用户组）
+	if req.GroupID != nil {
+		var groupID *int
+		if *req.GroupID > 0 {
+			if _, err := s.userGroupDAO.GetByID(*req.GroupID); err != nil {
+				return nil, errors.New("用户组不存在")
+			}
+			groupID = req.GroupID
+		}
+		if err := scope.CheckGroup("user:edit", groupID); err != nil {
+			return nil, err
+		}
+		user.GroupID = groupID
+		user.Group = nil
+	}
+
 	// 更新字段
 	if req.Email != "" && req.Email != user.Email {
 		exists, err := s.userDAO.ExistsByEmail(req.Email)
@@ -141,22 +191,31 @@ func (s *UserService) Update(userID int, req *model.UserUpdateRequest) (*model.U
 			return nil, errors.New("邮箱已存在")
 		}
 		user.Email = req.Email
+		user.EmailVerifiedAt = nil // 新邮箱尚未验证
 	}
 
 	if req.EmbyUserID != "" {
 		user.EmbyUserID = req.EmbyUserID
 	}
 
-	if req.RoleID > 0 {
-		_, err := s.roleDAO.GetByID(req.RoleID)
+	// 角色或状态变化时需要使旧Token失效
+	securityChanged := false
+	if req.RoleID > 0 && req.RoleID != user.RoleID {
+		role, err := s.roleDAO.GetByID(req.RoleID)
 		if err != nil {
 			return nil, errors.New("角色不存在")
 		}
+		if err := scope.CheckAssignRole(role, user.GroupID); err != nil {
+			return nil, err
+		}
 		user.RoleID = req.RoleID
+		user.Role = nil
+		securityChanged = true
 	}
 
-	if req.Status != nil {
+	if req.Status != nil && *req.Status != user.Status {
 		user.Status = *req.Status
+		securityChanged = true
 	}
 
 	user.UpdatedAt = time.Now()
@@ -164,6 +223,11 @@ func (s *UserService) Update(userID int, req *model.UserUpdateRequest) (*model.U
 	if err := s.userDAO.Update(user); err != nil {
 		return nil, fmt.Errorf("更新用户失败: %w", err)
 	}
+	if securityChanged {
+		if err := BumpSecurityVersion(userID); err != nil {
+			return nil, fmt.Errorf("使旧登录状态失效失败: %w", err)
+		}
+	}
 
 	// 清除缓存
 	cacheKey := fmt.Sprintf("emby_ums:user:info:%d", userID)
@@ -173,13 +237,9 @@ func (s *UserService) Update(userID int, req *model.UserUpdateRequest) (*model.U
 }
 
 // Delete 删除用户
-func (s *UserService) Delete(userID int) error {
-	// 检查用户是否存在
-	user, err := s.userDAO.GetByID(userID)
+func (s *UserService) Delete(userID int, operatorID int) error {
+	_, user, err := s.scopeService.AuthorizeUser(operatorID, "user:delete", userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("用户不存在")
-		}
 		return err
 	}
 
@@ -191,6 +251,11 @@ func (s *UserService) Delete(userID int) error {
 		}
 	}
 
+	// 先使登录状态失效，避免缓存的安全版本号让旧Token继续可用
+	if err := BumpSecurityVersion(userID); err != nil {
+		return fmt.Errorf("使旧登录状态失效失败: %w", err)
+	}
+
 	// 删除用户
 	if err := s.userDAO.Delete(userID); err != nil {
 		return fmt.Errorf("删除用户失败: %w", err)
@@ -203,8 +268,16 @@ func (s *UserService) Delete(userID int) error {
 	return nil
 }
 
-// List 获取用户列表
-func (s *UserService) List(req *model.UserListRequest) (*model.UserListResponse, error) {
+// List 获取用户列表，仅返回操作者管理范围内的用户
+func (s *UserService) List(req *model.UserListRequest, operatorID int) (*model.UserListResponse, error) {
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ScopeGroupIDs, err = scope.ListScope("user:view"); err != nil {
+		return nil, err
+	}
+
 	users, total, err := s.userDAO.List(req)
 	if err != nil {
 		return nil, err
@@ -217,12 +290,13 @@ func (s *UserService) List(req *model.UserListRequest) (*model.UserListResponse,
 }
 
 // ResetPassword 重置用户密码
-func (s *UserService) ResetPassword(userID int, newPassword string) error {
-	user, err := s.userDAO.GetByID(userID)
+// mustChange 为 true 时用户下次登录须先修改密码
+func (s *UserService) ResetPassword(userID int, newPassword string, mustChange bool, operatorID int) error {
+	_, user, err := s.scopeService.AuthorizeUser(operatorID, "user:edit", userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("用户不存在")
-		}
+		return err
+	}
+	if err := s.passwordService.Check(user, newPassword); err != nil {
 		return err
 	}
 
@@ -233,48 +307,108 @@ func (s *UserService) ResetPassword(userID int, newPassword string) error {
 		}
 	}
 
-	// 加密新密码
-	passwordHash, err := util.HashPassword(newPassword)
+	if err := s.passwordService.Apply(user, newPassword, mustChange); err != nil {
+		return err
+	}
+	return BumpSecurityVersion(userID)
+}
+
+// BatchUpdateStatus 批量更新用户状态，并使这些用户的旧Token失效
+// 任一用户不在操作者管理范围内时整体拒绝
+func (s *UserService) BatchUpdateStatus(userIDs []int, status int, operatorID int) error {
+	scope, err := s.scopeService.Load(operatorID)
+	if err != nil {
+		return err
+	}
+	users, err := s.userDAO.GetByIDs(userIDs)
 	if err != nil {
-		return fmt.Errorf("密码加密失败: %w", err)
+		return err
+	}
+	found := make(map[int]bool, len(users))
+	for _, user := range users {
+		found[user.UserID] = true
+	}
+	for _, userID := range userIDs {
+		if !found[userID] {
+			return fmt.Errorf("用户不存在: %d", userID)
+		}
+	}
+	for _, user := range users {
+		if err := scope.CheckUser("user:edit", user); err != nil {
+			return fmt.Errorf("用户 %s: %w", user.Username, err)
+		}
 	}
 
-	user.PasswordHash = passwordHash
-	user.UpdatedAt = time.Now()
+	if err := s.userDAO.BatchUpdateStatus(userIDs, status); err != nil {
+		return err
+	}
+	return BumpSecurityVersion(userIDs...)
+}
+
+// SetVip 管理员为用户发放指定等级的VIP天数（level 为0时发放用户当前有效等级，无有效VIP时为1级）
+func (s *UserService) SetVip(userID int, level int, days int, operatorID int) (*model.User, error) {
+	_, user, err := s.scopeService.AuthorizeUser(operatorID, "user:edit", userID)
+	if err != nil {
+		return nil, err
+	}
+	if level == 0 {
+		level = EffectiveVipLevel(user, time.Now())
+		if level == model.FreeVipLevel {
+			level = 1
+		}
+	}
+	if _, err := s.vipTierService.GetGrantable(level); err != nil {
+		return nil, err
+	}
 
-	return s.userDAO.Update(user)
+	return s.vipService.Grant(&model.VipChange{
+		UserID:     userID,
+		Level:      level,
+		Days:       days,
+		Source:     model.VipSourceAdmin,
+		OperatorID: &operatorID,
+		Remark:     "管理员设置",
+	})
 }
 
-// BatchUpdateStatus 批量更新用户状态
-func (s *UserService) BatchUpdateStatus(userIDs []int, status int) error {
-	return s.userDAO.BatchUpdateStatus(userIDs, status)
+// ListSessions 查看用户的有效登录会话
+func (s *UserService) ListSessions(userID int, operatorID int) ([]*model.UserSession, error) {
+	if _, _, err := s.scopeService.AuthorizeUser(operatorID, "user:view", userID); err != nil {
+		return nil, err
+	}
+	return s.sessionService.List(userID, "")
 }
 
-// SetVip 设置用户VIP
-func (s *UserService) SetVip(userID int, days int) (*model.User, error) {
-	user, err := s.userDAO.GetByID(userID)
-	if err != nil {
-		return nil, errors.New("用户不存在")
+// RevokeSessions 强制用户下线：sessionID 为空时撤销其全部会话，返回撤销数量
+func (s *UserService) RevokeSessions(userID int, sessionID string, operatorID int, operatorName, ip, ua string) (int, error) {
+	if _, _, err := s.scopeService.AuthorizeUser(operatorID, "user:edit", userID); err != nil {
+		return 0, err
 	}
 
-	// 计算VIP到期时间
-	now := time.Now()
-	var vipExpireAt time.Time
-	if user.VipExpireAt != nil && user.VipExpireAt.After(now) {
-		// 如果当前VIP未过期，在原基础上增加
-		vipExpireAt = user.VipExpireAt.AddDate(0, 0, days)
+	count := 1
+	var err error
+	if sessionID == "" {
+		count, err = s.sessionService.RevokeAll([]int{userID}, "")
 	} else {
-		// 从现在开始计算
-		vipExpireAt = now.AddDate(0, 0, days)
+		err = s.sessionService.Revoke(userID, sessionID)
+	}
+	if err != nil {
+		return 0, err
 	}
 
-	user.VipLevel = 1
-	user.VipExpireAt = &vipExpireAt
-	user.UpdatedAt = now
+	Audit(&operatorID, operatorName, model.ActionRevokeSess, model.TargetUser, fmt.Sprint(userID),
+		map[string]interface{}{"session_id": sessionID, "count": count}, ip, ua, "success")
+	return count, nil
+}
 
-	if err := s.userDAO.Update(user); err != nil {
-		return nil, errors.New("设置VIP失败")
+// ResetTwoFactor 重置用户的两步验证（用户丢失验证器时由管理员操作），角色要求时用户下次登录需重新绑定
+func (s *UserService) ResetTwoFactor(userID int, operatorID int, operatorName, ip, ua string) error {
+	if _, _, err := s.scopeService.AuthorizeUser(operatorID, "user:edit", userID); err != nil {
+		return err
 	}
-
-	return user, nil
+	if err := s.twoFactorService.Reset(userID); err != nil {
+		return fmt.Errorf("重置两步验证失败: %w", err)
+	}
+	Audit(&operatorID, operatorName, model.ActionReset2FA, model.TargetUser, fmt.Sprint(userID), nil, ip, ua, "success")
+	return nil
 }
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+	"embyhub/pkg/redis"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VipService VIP时长变更服务
+// 所有VIP等级和到期时间的修改都经由该服务完成，并在同一事务中写入 vip_ledger
+type VipService struct {
+	vipTierDAO     *dao.VipTierDAO
+	vipLedgerDAO   *dao.VipLedgerDAO
+	vipFreezeDAO   *dao.VipFreezeDAO
+	vipTierService *VipTierService
+}
+
+// ErrVipFrozen 用户VIP冻结中，需先解冻才能变更
+var ErrVipFrozen = errors.New("VIP已冻结，请先解冻")
+
+func NewVipService() *VipService {
+	return &VipService{
+		vipTierDAO:     dao.NewVipTierDAO(),
+		vipLedgerDAO:   dao.NewVipLedgerDAO(),
+		vipFreezeDAO:   dao.NewVipFreezeDAO(),
+		vipTierService: NewVipTierService(),
+	}
+}
+
+// LockUserTx 在事务中锁定并读取用户，避免并发变更互相覆盖到期时间
+func (s *VipService) LockUserTx(tx *gorm.DB, userID int) (*model.User, error) {
+	var user model.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return &user, nil
+}
+
+// Grant 发放VIP（独立事务），提交后同步Emby策略和用户缓存
+func (s *VipService) Grant(change *model.VipChange) (*model.User, error) {
+	var user *model.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, _, err = s.GrantTx(tx, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.AfterCommit(user)
+	return user, nil
+}
+
+// GrantTx 在事务中为用户发放指定等级的VIP天数
+// 跨等级时按 DayValue 折算剩余时长，规则见 convertVipGrant
+func (s *VipService) GrantTx(tx *gorm.DB, change *model.VipChange) (*model.User, *model.VipLedger, error) {
+	if change.Days <= 0 {
+		return nil, nil, errors.New("发放天数必须大于0")
+	}
+
+	user, err := s.LockUserTx(tx, change.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.VipFrozenAt != nil {
+		return nil, nil, ErrVipFrozen
+	}
+
+	target, err := s.vipTierDAO.GetByLevelTx(tx, change.Level)
+	if err != nil {
+		return nil, nil, errors.New("VIP等级不存在")
+	}
+
+	now := time.Now()
+	var current *model.VipTier
+	if EffectiveVipLevel(user, now) != model.FreeVipLevel {
+		// 当前等级已被删除时按同价值处理
+		current, _ = s.vipTierDAO.GetByLevelTx(tx, user.VipLevel)
+		if current == nil {
+			current = &model.VipTier{Level: user.VipLevel, DayValue: target.DayValue}
+		}
+	}
+
+	level, expireAt := convertVipGrant(now, user.VipExpireAt, current, target, change.Days)
+	entry, err := s.ApplyTx(tx, user, level, &expireAt, change.Days, change)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, entry, nil
+}
+
+// GrantedDuration 发放流水实际增加的时长（按发放后等级计）
+// 等级未变（同级续期，或低等级时长折算后并入当前等级）时为前后到期时间之差；
+// 等级变化时原剩余时长已折算进新等级，新增部分即发放天数
+func GrantedDuration(entry *model.VipLedger, days int) time.Duration {
+	before := EffectiveVipLevel(&model.User{VipLevel: entry.LevelBefore, VipExpireAt: entry.ExpireBefore}, entry.CreatedAt)
+	if before != model.FreeVipLevel && before == entry.LevelAfter && entry.ExpireAfter != nil {
+		return entry.ExpireAfter.Sub(*entry.ExpireBefore)
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ApplyTx 在事务中将用户VIP设置为指定等级和到期时间，并写入流水
+// user 需已通过 LockUserTx 加锁；用于撤销回收、到期降级等非发放类变更
+func (s *VipService) ApplyTx(tx *gorm.DB, user *model.User, level int, expireAt *time.Time, deltaDays int, change *model.VipChange) (*model.VipLedger, error) {
+	now := time.Now()
+	entry := &model.VipLedger{
+		UserID:       user.UserID,
+		Source:       change.Source,
+		DeltaDays:    deltaDays,
+		LevelBefore:  user.VipLevel,
+		LevelAfter:   level,
+		ExpireBefore: user.VipExpireAt,
+		ExpireAfter:  expireAt,
+		OperatorID:   change.OperatorID,
+		RefID:        change.RefID,
+		Remark:       change.Remark,
+		CreatedAt:    now,
+	}
+
+	if err := tx.Model(&model.User{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+		"vip_level":     level,
+		"vip_expire_at": expireAt,
+		"updated_at":    now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("更新VIP失败: %w", err)
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("写入VIP流水失败: %w", err)
+	}
+
+	user.VipLevel = level
+	user.VipExpireAt = expireAt
+	user.UpdatedAt = now
+	return entry, nil
+}
+
+// ExpireUsers 将到期用户降为免费等级（保留到期时间），每个用户写入一条到期流水
+// 返回实际降级的用户
+func (s *VipService) ExpireUsers(userIDs []int) ([]*model.User, error) {
+	var expired []*model.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var users []*model.User
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id IN ? AND vip_level > 0 AND vip_expire_at < ? AND vip_frozen_at IS NULL", userIDs, now).
+			Find(&users).Error; err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if _, err := s.ApplyTx(tx, user, model.FreeVipLevel, user.VipExpireAt, 0, &model.VipChange{
+				Source: model.VipSourceExpiry,
+				Remark: "VIP到期降为免费等级",
+			}); err != nil {
+				return err
+			}
+		}
+		expired = users
+		return nil
+	})
+	return expired, err
+}
+
+// Freeze 冻结VIP：保存剩余时长并将Emby策略降为免费等级
+// 单次冻结天数不超过等级的 MaxFreezeDays，每个自然年的冻结次数不超过 FreezesPerYear，到期自动解冻
+func (s *VipService) Freeze(userID int, req *model.VipFreezeRequest) (*model.VipFreeze, error) {
+	var user *model.User
+	var freeze *model.VipFreeze
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = s.LockUserTx(tx, userID); err != nil {
+			return err
+		}
+		if user.VipFrozenAt != nil {
+			return errors.New("VIP已处于冻结状态")
+		}
+
+		now := time.Now()
+		if EffectiveVipLevel(user, now) == model.FreeVipLevel {
+			return errors.New("当前没有有效的VIP，无法冻结")
+		}
+		tier, err := s.vipTierDAO.GetByLevelTx(tx, user.VipLevel)
+		if err != nil || tier.MaxFreezeDays <= 0 || tier.FreezesPerYear <= 0 {
+			return errors.New("当前VIP等级不支持冻结")
+		}
+		if req.Days > tier.MaxFreezeDays {
+			return fmt.Errorf("当前VIP等级单次最多冻结%d天", tier.MaxFreezeDays)
+		}
+
+		yearStart := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+		count, err := s.vipFreezeDAO.CountSinceTx(tx, userID, yearStart)
+		if err != nil {
+			return err
+		}
+		if count >= int64(tier.FreezesPerYear) {
+			return fmt.Errorf("当前VIP等级每年最多冻结%d次", tier.FreezesPerYear)
+		}
+
+		freeze = &model.VipFreeze{
+			UserID:           userID,
+			VipLevel:         user.VipLevel,
+			RemainingSeconds: int64(user.VipExpireAt.Sub(now) / time.Second),
+			FrozenAt:         now,
+			FreezeUntil:      now.AddDate(0, 0, req.Days),
+			Reason:           req.Reason,
+			CreatedAt:        now,
+		}
+		if err := s.vipFreezeDAO.CreateTx(tx, freeze); err != nil {
+			return err
+		}
+
+		if _, err := s.ApplyTx(tx, user, user.VipLevel, user.VipExpireAt, 0, &model.VipChange{
+			Source:     model.VipSourceFreeze,
+			OperatorID: &userID,
+			RefID:      fmt.Sprint(freeze.ID),
+			Remark:     fmt.Sprintf("冻结VIP %d天", req.Days),
+		}); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.User{}).Where("user_id = ?", userID).Update("vip_frozen_at", now).Error; err != nil {
+			return err
+		}
+		user.VipFrozenAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.AfterCommit(user)
+	return freeze, nil
+}
+
+// Unfreeze 解冻VIP：从解冻时刻起恢复冻结时的剩余时长
+// by 为解冻方式（user/admin/auto），operatorID 在自动解冻时为空
+func (s *VipService) Unfreeze(userID int, by string, operatorID *int) (*model.User, error) {
+	var user *model.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = s.LockUserTx(tx, userID); err != nil {
+			return err
+		}
+		if user.VipFrozenAt == nil {
+			return errors.New("VIP未处于冻结状态")
+		}
+		freeze, err := s.vipFreezeDAO.GetActiveTx(tx, userID)
+		if err != nil {
+			return errors.New("冻结记录不存在")
+		}
+
+		now := time.Now()
+		expireAt := now.Add(time.Duration(freeze.RemainingSeconds) * time.Second)
+		if err := tx.Model(&model.User{}).Where("user_id = ?", userID).Update("vip_frozen_at", nil).Error; err != nil {
+			return err
+		}
+		user.VipFrozenAt = nil
+
+		if _, err := s.ApplyTx(tx, user, freeze.VipLevel, &expireAt, 0, &model.VipChange{
+			Source:     model.VipSourceUnfreeze,
+			OperatorID: operatorID,
+			RefID:      fmt.Sprint(freeze.ID),
+			Remark:     fmt.Sprintf("解冻VIP（冻结%d天）", int(now.Sub(freeze.FrozenAt).Hours()/24)),
+		}); err != nil {
+			return err
+		}
+
+		return tx.Model(freeze).Updates(map[string]interface{}{
+			"unfrozen_at": now,
+			"unfreeze_by": by,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.AfterCommit(user)
+	return user, nil
+}
+
+// UnfreezeDue 自动解冻已到最晚解冻时间的用户，返回解冻数量
+// 按用户ID向后分批处理，个别用户解冻失败时记录并跳过，不阻塞其后的用户
+func (s *VipService) UnfreezeDue() int {
+	const batchSize = 200
+	now := time.Now()
+	count := 0
+	lastID := 0
+	var failed []int
+	for {
+		ids, err := s.vipFreezeDAO.ListDueUserIDs(now, lastID, batchSize)
+		if err != nil {
+			util.Warn(fmt.Sprintf("查询到期冻结失败: %v", err))
+			break
+		}
+		for _, id := range ids {
+			lastID = id
+			if _, err := s.Unfreeze(id, model.UnfreezeByAuto, nil); err != nil {
+				util.Warn(fmt.Sprintf("自动解冻失败 user=%d: %v", id, err))
+				failed = append(failed, id)
+				continue
+			}
+			count++
+		}
+		if len(ids) < batchSize {
+			break
+		}
+	}
+	if len(failed) > 0 {
+		util.Warn(fmt.Sprintf("本次自动解冻有%d个用户失败，已跳过: %v", len(failed), failed))
+	}
+	return count
+}
+
+// ListFreezes 获取用户的冻结记录
+func (s *VipService) ListFreezes(userID int) ([]*model.VipFreeze, error) {
+	return s.vipFreezeDAO.ListByUser(userID)
+}
+
+// AfterCommit VIP变更提交后按当前等级同步Emby策略并清除用户缓存
+func (s *VipService) AfterCommit(user *model.User) {
+	if err := s.vipTierService.ApplyEmbyPolicy(user); err != nil {
+		util.Warn(fmt.Sprintf("同步Emby等级策略失败 user=%d: %v", user.UserID, err))
+	}
+	redis.Del(fmt.Sprintf("emby_ums:user:info:%d", user.UserID))
+}
+
+// History 获取用户VIP变动历史
+func (s *VipService) History(userID int, req *model.VipHistoryRequest) (*model.VipHistoryResponse, error) {
+	entries, total, err := s.vipLedgerDAO.ListByUser(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.VipHistoryResponse{
+		Total: int(total),
+		List:  entries,
+	}, nil
+}
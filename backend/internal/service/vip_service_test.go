@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+func TestUnfreezeDueSkipsFailures(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+
+	now := time.Now()
+	freeze := func(user *model.User, frozen bool) {
+		if frozen {
+			database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).
+				Updates(map[string]interface{}{"vip_level": 1, "vip_frozen_at": now.AddDate(0, 0, -10)})
+		}
+		if err := database.DB.Create(&model.VipFreeze{
+			UserID:           user.UserID,
+			VipLevel:         1,
+			RemainingSeconds: 86400,
+			FrozenAt:         now.AddDate(0, 0, -10),
+			FreezeUntil:      now.Add(-time.Minute),
+		}).Error; err != nil {
+			t.Fatalf("创建冻结记录失败: %v", err)
+		}
+	}
+
+	// 排在前面的用户数据异常（冻结记录未关闭但用户未冻结），解冻失败
+	broken := createTestUser(t, "broken")
+	freeze(broken, false)
+	ok := createTestUser(t, "ok")
+	freeze(ok, true)
+
+	s := NewVipService()
+	if n := s.UnfreezeDue(); n != 1 {
+		t.Fatalf("异常用户不应阻塞其后的用户: %d", n)
+	}
+	if got := reloadUser(t, ok.UserID); got.VipFrozenAt != nil || got.VipExpireAt == nil || got.VipLevel != 1 {
+		t.Fatalf("到期用户应已解冻并恢复剩余时长: %+v", got)
+	}
+
+	// 下次运行仍会跳过异常用户，不影响新到期的用户
+	later := createTestUser(t, "later")
+	freeze(later, true)
+	if n := s.UnfreezeDue(); n != 1 {
+		t.Fatalf("再次运行应解冻新到期的用户: %d", n)
+	}
+	if reloadUser(t, later.UserID).VipFrozenAt != nil {
+		t.Fatal("新到期的用户应已解冻")
+	}
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"embyhub/config"
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/emby"
+)
+
+// VipTierService VIP等级服务
+type VipTierService struct {
+	vipTierDAO *dao.VipTierDAO
+	configDAO  *dao.SystemConfigDAO
+	embyClient *emby.Client
+}
+
+func NewVipTierService() *VipTierService {
+	return &VipTierService{
+		vipTierDAO: dao.NewVipTierDAO(),
+		configDAO:  dao.NewSystemConfigDAO(),
+		embyClient: emby.NewClient(&config.GlobalConfig.Emby),
+	}
+}
+
+// List 获取所有VIP等级
+func (s *VipTierService) List() ([]*model.VipTier, error) {
+	return s.vipTierDAO.List()
+}
+
+// Get 获取VIP等级
+func (s *VipTierService) Get(level int) (*model.VipTier, error) {
+	tier, err := s.vipTierDAO.GetByLevel(level)
+	if err != nil {
+		return nil, errors.New("VIP等级不存在")
+	}
+	return tier, nil
+}
+
+// GetGrantable 获取可发放的VIP等级（非免费且已启用）
+func (s *VipTierService) GetGrantable(level int) (*model.VipTier, error) {
+	if level == model.FreeVipLevel {
+		return nil, errors.New("不能发放免费等级")
+	}
+	tier, err := s.Get(level)
+	if err != nil {
+		return nil, err
+	}
+	if tier.Status != 1 {
+		return nil, fmt.Errorf("VIP等级「%s」已停用", tier.Name)
+	}
+	return tier, nil
+}
+
+// Create 创建VIP等级
+func (s *VipTierService) Create(req *model.VipTierCreateRequest) (*model.VipTier, error) {
+	if _, err := s.vipTierDAO.GetByLevel(req.Level); err == nil {
+		return nil, errors.New("该等级已存在")
+	}
+
+	now := time.Now()
+	tier := &model.VipTier{
+		Level:          req.Level,
+		Name:           req.Name,
+		Description:    req.Description,
+		AllowPlayback:  true,
+		StreamLimit:    req.StreamLimit,
+		BitrateLimit:   req.BitrateLimit,
+		LibraryIDs:     joinLibraryIDs(req.LibraryIDs),
+		DeviceQuota:    req.DeviceQuota,
+		RequestQuota:   req.RequestQuota,
+		DayValue:       req.DayValue,
+		MaxFreezeDays:  req.MaxFreezeDays,
+		FreezesPerYear: req.FreezesPerYear,
+		Status:         1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if req.AllowPlayback != nil {
+		tier.AllowPlayback = *req.AllowPlayback
+	}
+
+	if err := s.vipTierDAO.Create(tier); err != nil {
+		return nil, fmt.Errorf("创建VIP等级失败: %w", err)
+	}
+	return tier, nil
+}
+
+// Update 更新VIP等级，权益变化后异步同步该等级用户的Emby策略
+func (s *VipTierService) Update(level int, req *model.VipTierUpdateRequest) (*model.VipTier, error) {
+	tier, err := s.Get(level)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		tier.Name = req.Name
+	}
+	if req.Description != nil {
+		tier.Description = *req.Description
+	}
+	if req.AllowPlayback != nil {
+		tier.AllowPlayback = *req.AllowPlayback
+	}
+	if req.StreamLimit != nil {
+		tier.StreamLimit = *req.StreamLimit
+	}
+	if req.BitrateLimit != nil {
+		tier.BitrateLimit = *req.BitrateLimit
+	}
+	if req.LibraryIDs != nil {
+		tier.LibraryIDs = joinLibraryIDs(req.LibraryIDs)
+	}
+	if req.DeviceQuota != nil {
+		tier.DeviceQuota = *req.DeviceQuota
+	}
+	if req.RequestQuota != nil {
+		tier.RequestQuota = *req.RequestQuota
+	}
+	if req.DayValue != nil {
+		tier.DayValue = *req.DayValue
+	}
+	if req.MaxFreezeDays != nil {
+		tier.MaxFreezeDays = *req.MaxFreezeDays
+	}
+	if req.FreezesPerYear != nil {
+		tier.FreezesPerYear = *req.FreezesPerYear
+	}
+	if req.Status != nil {
+		if level == model.FreeVipLevel && *req.Status == 0 {
+			return nil, errors.New("免费等级不能停用")
+		}
+		tier.Status = *req.Status
+	}
+	tier.UpdatedAt = time.Now()
+
+	if err := s.vipTierDAO.Update(tier); err != nil {
+		return nil, fmt.Errorf("更新VIP等级失败: %w", err)
+	}
+
+	go s.SyncTierPolicy(tier)
+	return tier, nil
+}
+
+// Delete 删除VIP等级（免费等级、仍有用户或未使用卡密的等级不可删除）
+func (s *VipTierService) Delete(level int) error {
+	if level == model.FreeVipLevel {
+		return errors.New("免费等级不能删除")
+	}
+	if _, err := s.Get(level); err != nil {
+		return err
+	}
+
+	if count, err := s.vipTierDAO.CountUsers(level); err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("仍有%d个用户处于该等级，无法删除", count)
+	}
+	if count, err := s.vipTierDAO.CountUnusedCards(level); err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("仍有%d张该等级的未使用卡密，无法删除", count)
+	}
+
+	return s.vipTierDAO.Delete(level)
+}
+
+// ApplyEmbyPolicy 按用户当前有效等级设置Emby权限策略
+func (s *VipTierService) ApplyEmbyPolicy(user *model.User) error {
+	if user.EmbyUserID == "" {
+		return nil
+	}
+	tier, err := s.Get(EffectiveVipLevel(user, time.Now()))
+	if err != nil {
+		return err
+	}
+	if err := s.embyClient.SetUserTierPolicy(user.EmbyUserID, tier); err != nil {
+		return err
+	}
+	return s.enforceDeviceQuota(user.EmbyUserID, tier, nil)
+}
+
+// deviceQuotaEnforced 是否按等级设备数上限移除Emby设备（默认关闭，开启后会删除Emby中的真实设备）
+func (s *VipTierService) deviceQuotaEnforced() bool {
+	cfg, err := s.configDAO.Get("emby_device_quota_enforce")
+	return err == nil && strings.TrimSpace(cfg.ConfigValue) == "true"
+}
+
+// enforceDeviceQuota 按等级设备数上限移除用户最久未活跃的Emby设备（被移除设备上的登录随之失效）
+// devices 为空时从Emby查询该用户的设备；未开启 emby_device_quota_enforce 时不做任何操作
+func (s *VipTierService) enforceDeviceQuota(embyUserID string, tier *model.VipTier, devices []emby.Device) error {
+	if tier.DeviceQuota <= 0 || !s.deviceQuotaEnforced() {
+		return nil
+	}
+	if devices == nil {
+		all, err := s.embyClient.GetDevices(embyUserID)
+		if err != nil {
+			return err
+		}
+		for _, device := range all {
+			if device.LastUserId == embyUserID {
+				devices = append(devices, device)
+			}
+		}
+	}
+	if len(devices) <= tier.DeviceQuota {
+		return nil
+	}
+
+	// Emby返回的活跃时间为ISO 8601格式，可直接按字符串倒序排列
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].DateLastActivity > devices[j].DateLastActivity
+	})
+	for _, device := range devices[tier.DeviceQuota:] {
+		if err := s.embyClient.DeleteDevice(device.Id); err != nil {
+			return err
+		}
+		util.Info(fmt.Sprintf("超出等级设备数上限，已移除Emby设备 user=%s device=%s(%s)", embyUserID, device.Name, device.AppName))
+	}
+	return nil
+}
+
+// EnforceDeviceQuotas 检查所有已绑定Emby的用户，移除超出当前等级设备数上限的设备
+// 返回移除的设备数；未开启 emby_device_quota_enforce 时不做任何操作
+func (s *VipTierService) EnforceDeviceQuotas() (int, error) {
+	if !s.deviceQuotaEnforced() {
+		return 0, nil
+	}
+	devices, err := s.embyClient.GetDevices("")
+	if err != nil {
+		return 0, err
+	}
+	byUser := make(map[string][]emby.Device)
+	for _, device := range devices {
+		if device.LastUserId != "" {
+			byUser[device.LastUserId] = append(byUser[device.LastUserId], device)
+		}
+	}
+
+	tiers, err := s.vipTierDAO.List()
+	if err != nil {
+		return 0, err
+	}
+	minQuota := 0
+	tierByLevel := make(map[int]*model.VipTier, len(tiers))
+	for _, tier := range tiers {
+		tierByLevel[tier.Level] = tier
+		if tier.DeviceQuota > 0 && (minQuota == 0 || tier.DeviceQuota < minQuota) {
+			minQuota = tier.DeviceQuota
+		}
+	}
+	if minQuota == 0 {
+		return 0, nil
+	}
+
+	// 只需检查设备数超过最小上限的用户
+	var embyUserIDs []string
+	for embyUserID, list := range byUser {
+		if len(list) > minQuota {
+			embyUserIDs = append(embyUserIDs, embyUserID)
+		}
+	}
+	if len(embyUserIDs) == 0 {
+		return 0, nil
+	}
+	users, err := s.vipTierDAO.ListByEmbyUserIDs(embyUserIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, user := range users {
+		tier := tierByLevel[EffectiveVipLevel(user, now)]
+		list := byUser[user.EmbyUserID]
+		if tier == nil || tier.DeviceQuota <= 0 || len(list) <= tier.DeviceQuota {
+			continue
+		}
+		if err := s.enforceDeviceQuota(user.EmbyUserID, tier, list); err != nil {
+			util.Warn(fmt.Sprintf("移除超出上限的Emby设备失败 user=%d: %v", user.UserID, err))
+			continue
+		}
+		removed += len(list) - tier.DeviceQuota
+	}
+	return removed, nil
+}
+
+// SyncTierPolicy 将等级权益同步到当前有效等级为该等级的所有已绑定Emby的用户
+func (s *VipTierService) SyncTierPolicy(tier *model.VipTier) {
+	const batchSize = 200
+	lastID := 0
+	for {
+		users, err := s.vipTierDAO.ListEmbyUserIDs(tier.Level, batchSize, lastID, time.Now())
+		if err != nil {
+			util.Warn(fmt.Sprintf("同步VIP等级策略失败 level=%d: %v", tier.Level, err))
+			return
+		}
+		for _, user := range users {
+			if err := s.embyClient.SetUserTierPolicy(user.EmbyUserID, tier); err != nil {
+				util.Warn(fmt.Sprintf("同步Emby策略失败 user=%d level=%d: %v", user.UserID, tier.Level, err))
+			} else if err := s.enforceDeviceQuota(user.EmbyUserID, tier, nil); err != nil {
+				util.Warn(fmt.Sprintf("移除超出上限的Emby设备失败 user=%d level=%d: %v", user.UserID, tier.Level, err))
+			}
+			lastID = user.UserID
+		}
+		if len(users) < batchSize {
+			return
+		}
+	}
+}
+
+// EffectiveVipLevel 用户当前有效等级（已过期或已冻结视为免费等级）
+func EffectiveVipLevel(user *model.User, now time.Time) int {
+	if user.VipFrozenAt != nil {
+		return model.FreeVipLevel
+	}
+	if user.VipLevel == model.FreeVipLevel || user.VipExpireAt == nil || !user.VipExpireAt.After(now) {
+		return model.FreeVipLevel
+	}
+	return user.VipLevel
+}
+
+// convertVipGrant 计算发放VIP后的等级和到期时间
+// current 为当前有效等级（nil 表示当前无有效VIP）：
+//  1. 无有效VIP：从现在起按目标等级计算
+//  2. 同等级：在原到期时间上顺延
+//  3. 升级：剩余时长按 DayValue 比例折算为目标等级时长，再加上本次天数
+//  4. 降级：保留当前较高等级，本次天数按 DayValue 比例折算为当前等级时长后顺延
+func convertVipGrant(now time.Time, expireAt *time.Time, current, target *model.VipTier, days int) (int, time.Time) {
+	granted := time.Duration(days) * 24 * time.Hour
+
+	if current == nil {
+		return target.Level, now.Add(granted)
+	}
+
+	remaining := expireAt.Sub(now)
+	switch {
+	case current.Level == target.Level:
+		return target.Level, expireAt.Add(granted)
+	case target.Level > current.Level:
+		converted := scaleDuration(remaining, current.DayValue, target.DayValue)
+		return target.Level, now.Add(converted + granted)
+	default:
+		converted := scaleDuration(granted, target.DayValue, current.DayValue)
+		return current.Level, expireAt.Add(converted)
+	}
+}
+
+// scaleDuration 按价值比例折算时长（from等级的时长折算为to等级的时长）
+func scaleDuration(d time.Duration, fromValue, toValue int) time.Duration {
+	if fromValue <= 0 || toValue <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * float64(fromValue) / float64(toValue))
+}
+
+// joinLibraryIDs 拼接媒体库ID
+func joinLibraryIDs(ids []string) string {
+	var cleaned []string
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			cleaned = append(cleaned, id)
+		}
+	}
+	return strings.Join(cleaned, ",")
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"embyhub/config"
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+	"embyhub/pkg/emby"
+
+	"gorm.io/gorm"
+)
+
+func TestListEmbyUserIDsEffectiveLevel(t *testing.T) {
+	setupTestEnv(t)
+	now := time.Now()
+	future, past := now.Add(24*time.Hour), now.Add(-time.Hour)
+
+	users := map[string]map[string]interface{}{
+		"active":  {"vip_level": 1, "vip_expire_at": future},
+		"expired": {"vip_level": 1, "vip_expire_at": past},
+		"frozen":  {"vip_level": 1, "vip_expire_at": future, "vip_frozen_at": now},
+		"free":    {"vip_level": 0},
+		"unbound": {"vip_level": 1, "vip_expire_at": future, "emby_user_id": ""},
+	}
+	ids := make(map[int]string)
+	for name, fields := range users {
+		user := createTestUser(t, name)
+		if _, ok := fields["emby_user_id"]; !ok {
+			fields["emby_user_id"] = "emby-" + name
+		}
+		if err := database.DB.Table("users").Where("user_id = ?", user.UserID).Updates(fields).Error; err != nil {
+			t.Fatalf("更新用户失败: %v", err)
+		}
+		ids[user.UserID] = name
+	}
+
+	list := func(level int) map[string]bool {
+		result, err := dao.NewVipTierDAO().ListEmbyUserIDs(level, 100, 0, now)
+		if err != nil {
+			t.Fatalf("查询等级用户失败: %v", err)
+		}
+		names := make(map[string]bool)
+		for _, user := range result {
+			names[ids[user.UserID]] = true
+		}
+		return names
+	}
+
+	// 已过期、已冻结的用户按免费等级同步策略
+	if got := list(1); len(got) != 1 || !got["active"] {
+		t.Fatalf("VIP1 应只包含有效用户: %v", got)
+	}
+	if got := list(0); len(got) != 3 || !got["expired"] || !got["frozen"] || !got["free"] {
+		t.Fatalf("免费等级应包含过期、冻结和免费用户: %v", got)
+	}
+}
+
+// fakeEmbyDevices 模拟Emby设备接口，记录被删除的设备
+type fakeEmbyDevices struct {
+	mu      sync.Mutex
+	devices []emby.Device
+	deleted []string
+}
+
+func newFakeEmbyDevices(t *testing.T, devices []emby.Device) *fakeEmbyDevices {
+	t.Helper()
+	f := &fakeEmbyDevices{devices: devices}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch {
+		case r.URL.Path == "/Devices" && r.Method == http.MethodGet:
+			var items []emby.Device
+			for _, device := range f.devices {
+				if userID := r.URL.Query().Get("UserId"); userID == "" || device.LastUserId == userID {
+					items = append(items, device)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Items": items})
+		case r.URL.Path == "/Devices" && r.Method == http.MethodDelete:
+			f.deleted = append(f.deleted, r.URL.Query().Get("Id"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	config.GlobalConfig.Emby.ServerURL = server.URL
+	config.GlobalConfig.Emby.Timeout = 5
+	return f
+}
+
+func (f *fakeEmbyDevices) deletedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleted...)
+}
+
+func TestEnforceDeviceQuotas(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	database.DB.Model(&model.VipTier{}).Where("level = ?", 1).Update("device_quota", 2)
+
+	user := createTestUser(t, "alice")
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+		"emby_user_id": "emby-alice", "vip_level": 1, "vip_expire_at": time.Now().Add(24 * time.Hour),
+	})
+	fake := newFakeEmbyDevices(t, []emby.Device{
+		{Id: "d1", LastUserId: "emby-alice", DateLastActivity: "2026-01-04T00:00:00Z"},
+		{Id: "d2", LastUserId: "emby-alice", DateLastActivity: "2026-01-01T00:00:00Z"},
+		{Id: "d3", LastUserId: "emby-alice", DateLastActivity: "2026-01-03T00:00:00Z"},
+		{Id: "d4", LastUserId: "emby-alice", DateLastActivity: "2026-01-02T00:00:00Z"},
+		{Id: "other", LastUserId: "emby-bob", DateLastActivity: "2026-01-01T00:00:00Z"},
+	})
+	s := NewVipTierService()
+
+	// 默认关闭：同步和定时检查都不删除设备
+	if removed, err := s.EnforceDeviceQuotas(); err != nil || removed != 0 {
+		t.Fatalf("未开启时不应移除设备: %d %v", removed, err)
+	}
+	if err := s.ApplyEmbyPolicy(reloadUser(t, user.UserID)); err != nil {
+		t.Fatalf("同步Emby策略失败: %v", err)
+	}
+	if deleted := fake.deletedIDs(); len(deleted) != 0 {
+		t.Fatalf("未开启时不应删除Emby设备: %v", deleted)
+	}
+
+	// 开启后移除最久未活跃的设备，只保留上限数量
+	database.DB.Create(&model.SystemConfig{ConfigKey: "emby_device_quota_enforce", ConfigValue: "true"})
+	removed, err := s.EnforceDeviceQuotas()
+	if err != nil || removed != 2 {
+		t.Fatalf("应移除超出上限的2个设备: %d %v", removed, err)
+	}
+	deleted := fake.deletedIDs()
+	sort.Strings(deleted)
+	if len(deleted) != 2 || deleted[0] != "d2" || deleted[1] != "d4" {
+		t.Fatalf("应移除最久未活跃的设备: %v", deleted)
+	}
+}
+
+func TestConvertVipGrant(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	at := func(d time.Duration) *time.Time { v := now.Add(d); return &v }
+	vip1 := &model.VipTier{Level: 1, DayValue: 100}
+	vip2 := &model.VipTier{Level: 2, DayValue: 300}
+	unpriced := &model.VipTier{Level: 3}
+
+	cases := []struct {
+		name            string
+		expireAt        *time.Time
+		current, target *model.VipTier
+		days            int
+		level           int
+		want            time.Time
+	}{
+		{"无有效VIP从现在起算", at(-5 * day), nil, vip2, 30, 2, now.Add(30 * day)},
+		{"同等级顺延", at(10 * day), vip1, vip1, 30, 1, now.Add(40 * day)},
+		// 剩余10天1级折算为 10×100/300 天2级，不取整
+		{"升级折算剩余时长", at(10 * day), vip1, vip2, 30, 2, now.Add(30*day + 10*day/3)},
+		// 7天1级折算为 7×100/300 天并入2级，不取整
+		{"降级折算本次天数", at(10 * day), vip2, vip1, 7, 2, now.Add(10*day + 7*day/3)},
+		{"剩余不足一天按比例折算", at(time.Hour), vip1, vip2, 1, 2, now.Add(day + 20*time.Minute)},
+		{"未定价等级不折算", at(10 * day), vip1, unpriced, 30, 3, now.Add(40 * day)},
+	}
+	for _, c := range cases {
+		level, expireAt := convertVipGrant(now, c.expireAt, c.current, c.target, c.days)
+		if level != c.level || expireAt.Sub(c.want).Abs() > time.Millisecond {
+			t.Errorf("%s: 应为等级%d、%s到期，实际为等级%d、%s", c.name, c.level, c.want, level, expireAt)
+		}
+	}
+}
+
+func TestGrantTxRecordsConvertedDuration(t *testing.T) {
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+	createTestTier(t, 2, 300)
+	createTestTier(t, 3, 600)
+	user := createTestUser(t, "alice")
+	database.DB.Model(&model.User{}).Where("user_id = ?", user.UserID).
+		Updates(map[string]interface{}{"vip_level": 2, "vip_expire_at": time.Now().Add(10 * 24 * time.Hour)})
+
+	grant := func(level, days int) (*model.User, *model.VipLedger) {
+		var granted *model.User
+		var entry *model.VipLedger
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			granted, entry, err = NewVipService().GrantTx(tx, &model.VipChange{UserID: user.UserID, Level: level, Days: days, Source: model.VipSourceAdmin})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("发放VIP失败: %v", err)
+		}
+		return granted, entry
+	}
+
+	// 2级用户获得1级7天：保留2级，实际增加 7×100/300 天
+	granted, entry := grant(1, 7)
+	if granted.VipLevel != 2 || entry.DeltaDays != 7 {
+		t.Fatalf("应保留较高等级并记录发放天数: 等级%d 天数%d", granted.VipLevel, entry.DeltaDays)
+	}
+	if got := GrantedDuration(entry, 7); (got - 7*24*time.Hour/3).Abs() > time.Second {
+		t.Fatalf("实际增加时长应按价值折算: %s", got)
+	}
+
+	// 升级时原剩余时长已折算进新等级，新增部分即发放天数
+	_, entry = grant(3, 5)
+	if got := GrantedDuration(entry, 5); got != 5*24*time.Hour {
+		t.Fatalf("升级后新增时长应为发放天数: %s", got)
+	}
+}
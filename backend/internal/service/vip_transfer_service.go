@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"embyhub/internal/dao"
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+// 转赠配置项
+var vipTransferConfigKeys = []string{
+	"vip_transfer_enabled",
+	"vip_transfer_min_remaining_days",
+	"vip_transfer_cooldown_hours",
+	"vip_transfer_yearly_days",
+}
+
+// VipTransferService VIP转赠服务
+// 转出方需通过邮箱验证码确认；双方VIP在同一事务中变更并各写一条流水
+type VipTransferService struct {
+	configDAO      *dao.SystemConfigDAO
+	userDAO        *dao.UserDAO
+	vipTransferDAO *dao.VipTransferDAO
+	emailService   *EmailService
+	vipService     *VipService
+}
+
+func NewVipTransferService() *VipTransferService {
+	return &VipTransferService{
+		configDAO:      dao.NewSystemConfigDAO(),
+		userDAO:        dao.NewUserDAO(),
+		vipTransferDAO: dao.NewVipTransferDAO(),
+		emailService:   NewEmailService(),
+		vipService:     NewVipService(),
+	}
+}
+
+// Config 读取转赠配置
+func (s *VipTransferService) Config() *model.VipTransferConfig {
+	cfg := &model.VipTransferConfig{MinRemainingDays: 7, CooldownHours: 24, YearlyDays: 90}
+	values, err := s.configDAO.BatchGet(vipTransferConfigKeys)
+	if err != nil {
+		return &model.VipTransferConfig{}
+	}
+
+	atoi := func(key string, def int) int {
+		if v, err := strconv.Atoi(strings.TrimSpace(values[key])); err == nil && v >= 0 {
+			return v
+		}
+		return def
+	}
+	cfg.Enabled = values["vip_transfer_enabled"] == "true"
+	cfg.MinRemainingDays = atoi("vip_transfer_min_remaining_days", cfg.MinRemainingDays)
+	cfg.CooldownHours = atoi("vip_transfer_cooldown_hours", cfg.CooldownHours)
+	cfg.YearlyDays = atoi("vip_transfer_yearly_days", cfg.YearlyDays)
+	return cfg
+}
+
+// SendCode 向转出方邮箱发送转赠确认验证码
+func (s *VipTransferService) SendCode(userID int) error {
+	if !s.Config().Enabled {
+		return errors.New("VIP转赠功能未开启")
+	}
+	user, err := s.userDAO.GetByID(userID)
+	if err != nil {
+		return errors.New("用户不存在")
+	}
+	if user.Email == "" {
+		return errors.New("请先绑定邮箱")
+	}
+	return s.emailService.SendVerificationCode(&model.SendCodeRequest{
+		Email: user.Email,
+		Type:  model.CodeTypeVipTransfer,
+	})
+}
+
+// Transfer 将转出方的N天VIP转赠给接收方
+// 转出方到期时间减少N天且需保留最低天数；接收方按转出方等级获得N天（跨等级按 DayValue 折算）
+func (s *VipTransferService) Transfer(fromID int, req *model.VipTransferRequest, ip, ua string) (*model.VipTransfer, error) {
+	cfg := s.Config()
+	if !cfg.Enabled {
+		return nil, errors.New("VIP转赠功能未开启")
+	}
+
+	sender, err := s.userDAO.GetByID(fromID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	receiver, err := s.userDAO.GetByUsername(req.ToUsername)
+	if err != nil || receiver == nil || receiver.Status != 1 {
+		return nil, errors.New("接收用户不存在或已禁用")
+	}
+	if receiver.UserID == fromID {
+		return nil, errors.New("不能转赠给自己")
+	}
+	if sender.Email == "" {
+		return nil, errors.New("请先绑定邮箱")
+	}
+	if err := s.emailService.VerifyCode(sender.Email, req.Code, model.CodeTypeVipTransfer); err != nil {
+		return nil, err
+	}
+
+	var from, to *model.User
+	var transfer *model.VipTransfer
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 按用户ID顺序加锁，避免互相转赠时死锁
+		first, second := fromID, receiver.UserID
+		if first > second {
+			first, second = second, first
+		}
+		locked := make(map[int]*model.User, 2)
+		for _, id := range []int{first, second} {
+			user, err := s.vipService.LockUserTx(tx, id)
+			if err != nil {
+				return err
+			}
+			locked[id] = user
+		}
+		from = locked[fromID]
+		if from.VipFrozenAt != nil || locked[receiver.UserID].VipFrozenAt != nil {
+			return ErrVipFrozen
+		}
+
+		now := time.Now()
+		if EffectiveVipLevel(from, now) == model.FreeVipLevel {
+			return errors.New("当前没有有效的VIP，无法转赠")
+		}
+		remaining := from.VipExpireAt.AddDate(0, 0, -req.Days)
+		if remaining.Before(now.AddDate(0, 0, cfg.MinRemainingDays)) {
+			return fmt.Errorf("转赠后至少需要保留%d天VIP", cfg.MinRemainingDays)
+		}
+
+		if cfg.CooldownHours > 0 {
+			last, err := s.vipTransferDAO.LastSentAtTx(tx, fromID)
+			if err != nil {
+				return err
+			}
+			if last != nil && now.Before(last.Add(time.Duration(cfg.CooldownHours)*time.Hour)) {
+				return fmt.Errorf("转赠过于频繁，每%d小时只能转赠一次", cfg.CooldownHours)
+			}
+		}
+		if cfg.YearlyDays > 0 {
+			yearStart := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+			sent, err := s.vipTransferDAO.SumSentSinceTx(tx, fromID, yearStart)
+			if err != nil {
+				return err
+			}
+			if sent+int64(req.Days) > int64(cfg.YearlyDays) {
+				return fmt.Errorf("每年最多转赠%d天，今年还可转赠%d天", cfg.YearlyDays, int64(cfg.YearlyDays)-sent)
+			}
+		}
+
+		transfer = &model.VipTransfer{
+			FromUserID: fromID,
+			ToUserID:   receiver.UserID,
+			Days:       req.Days,
+			VipLevel:   from.VipLevel,
+			Remark:     req.Remark,
+			CreatedAt:  now,
+		}
+		if err := s.vipTransferDAO.CreateTx(tx, transfer); err != nil {
+			return err
+		}
+
+		refID := fmt.Sprint(transfer.ID)
+		if _, err := s.vipService.ApplyTx(tx, from, from.VipLevel, &remaining, -req.Days, &model.VipChange{
+			Source:     model.VipSourceTransfer,
+			OperatorID: &fromID,
+			RefID:      refID,
+			Remark:     "转赠给 " + receiver.Username,
+		}); err != nil {
+			return err
+		}
+
+		to, _, err = s.vipService.GrantTx(tx, &model.VipChange{
+			UserID:     receiver.UserID,
+			Level:      from.VipLevel,
+			Days:       req.Days,
+			Source:     model.VipSourceTransfer,
+			OperatorID: &fromID,
+			RefID:      refID,
+			Remark:     "来自 " + sender.Username + " 的转赠",
+		})
+		return err
+	})
+	if err != nil {
+		Audit(&fromID, sender.Username, model.ActionVipTransfer, model.TargetUser, fmt.Sprint(receiver.UserID),
+			map[string]interface{}{"days": req.Days, "error": err.Error()}, ip, ua, "failed")
+		return nil, err
+	}
+
+	s.vipService.AfterCommit(from)
+	s.vipService.AfterCommit(to)
+	Audit(&fromID, sender.Username, model.ActionVipTransfer, model.TargetUser, fmt.Sprint(receiver.UserID),
+		map[string]interface{}{
+			"transfer_id":        transfer.ID,
+			"days":               req.Days,
+			"vip_level":          transfer.VipLevel,
+			"from_expire_after":  from.VipExpireAt,
+			"to_expire_after":    to.VipExpireAt,
+			"to_vip_level_after": to.VipLevel,
+		}, ip, ua, "success")
+
+	return transfer, nil
+}
+
+// List 获取用户的转赠记录（转出和转入）
+func (s *VipTransferService) List(userID int, req *model.VipTransferListRequest) (*model.VipTransferListResponse, error) {
+	transfers, total, err := s.vipTransferDAO.ListByUser(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &model.VipTransferListResponse{Total: int(total), List: transfers}, nil
+}
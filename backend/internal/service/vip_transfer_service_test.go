@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"embyhub/internal/model"
+	"embyhub/pkg/database"
+)
+
+// setupTransferTest 准备转赠配置和一个持有40天VIP1的转出方
+func setupTransferTest(t *testing.T, configs map[string]string) (*VipTransferService, *model.User) {
+	t.Helper()
+	setupTestEnv(t)
+	createTestTier(t, model.FreeVipLevel, 0)
+	createTestTier(t, 1, 100)
+
+	values := map[string]string{
+		"vip_transfer_enabled":            "true",
+		"vip_transfer_min_remaining_days": "7",
+		"vip_transfer_cooldown_hours":     "24",
+		"vip_transfer_yearly_days":        "90",
+	}
+	for key, value := range configs {
+		values[key] = value
+	}
+	for key, value := range values {
+		database.DB.Create(&model.SystemConfig{ConfigKey: key, ConfigValue: value})
+	}
+
+	sender := createTestUser(t, "alice")
+	setTestVip(t, sender.UserID, 1, time.Now().Add(40*24*time.Hour))
+	return NewVipTransferService(), reloadUser(t, sender.UserID)
+}
+
+// transfer 写入转出方的邮箱验证码后发起转赠
+func transfer(s *VipTransferService, sender *model.User, to string, days int) error {
+	database.DB.Create(&model.EmailCode{Email: sender.Email, Code: "123456", Type: model.CodeTypeVipTransfer, ExpiresAt: time.Now().Add(10 * time.Minute)})
+	_, err := s.Transfer(sender.UserID, &model.VipTransferRequest{ToUsername: to, Days: days, Code: "123456"}, "", "")
+	return err
+}
+
+// assertUnchanged 校验转赠失败后双方VIP和转赠记录均未变更
+func assertUnchanged(t *testing.T, users ...*model.User) {
+	t.Helper()
+	for _, user := range users {
+		got := reloadUser(t, user.UserID)
+		if got.VipLevel != user.VipLevel || (got.VipExpireAt == nil) != (user.VipExpireAt == nil) ||
+			(got.VipExpireAt != nil && !got.VipExpireAt.Equal(*user.VipExpireAt)) {
+			t.Fatalf("转赠失败后 %s 的VIP不应变化: %+v", user.Username, got)
+		}
+	}
+	var count int64
+	database.DB.Model(&model.VipTransfer{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("转赠失败后不应写入转赠记录: %d", count)
+	}
+}
+
+func TestVipTransferRejects(t *testing.T) {
+	s, sender := setupTransferTest(t, nil)
+	receiver := createTestUser(t, "bob")
+	disabled := createTestUser(t, "carol")
+	database.DB.Model(&model.User{}).Where("user_id = ?", disabled.UserID).Update("status", 0)
+
+	cases := []struct {
+		name string
+		to   string
+		days int
+		want string
+	}{
+		{"转赠给自己", "alice", 1, "不能转赠给自己"},
+		{"接收方不存在", "nobody", 1, "接收用户不存在"},
+		{"接收方已禁用", "carol", 1, "接收用户不存在"},
+		{"剩余天数不足", "bob", 34, "至少需要保留7天"},
+	}
+	for _, c := range cases {
+		if err := transfer(s, sender, c.to, c.days); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%s: 应返回 %q，实际为 %v", c.name, c.want, err)
+		}
+	}
+
+	// 验证码错误
+	if _, err := s.Transfer(sender.UserID, &model.VipTransferRequest{ToUsername: "bob", Days: 1, Code: "000000"}, "", ""); err == nil {
+		t.Fatal("验证码错误时应拒绝转赠")
+	}
+
+	// 转出方没有有效VIP
+	if err := transfer(s, receiver, "alice", 1); err == nil || !strings.Contains(err.Error(), "没有有效的VIP") {
+		t.Fatalf("没有有效VIP时应拒绝转赠: %v", err)
+	}
+	assertUnchanged(t, sender, receiver)
+}
+
+func TestVipTransferWhileFrozen(t *testing.T) {
+	s, sender := setupTransferTest(t, nil)
+	receiver := createTestUser(t, "bob")
+
+	// 任一方VIP冻结时都不能转赠
+	for _, frozen := range []*model.User{sender, receiver} {
+		database.DB.Model(&model.User{}).Where("user_id = ?", frozen.UserID).Update("vip_frozen_at", time.Now())
+		if err := transfer(s, sender, "bob", 1); !errors.Is(err, ErrVipFrozen) {
+			t.Fatalf("%s 冻结时应拒绝转赠: %v", frozen.Username, err)
+		}
+		database.DB.Model(&model.User{}).Where("user_id = ?", frozen.UserID).Update("vip_frozen_at", nil)
+	}
+	assertUnchanged(t, sender, receiver)
+}
+
+func TestVipTransferLimits(t *testing.T) {
+	s, sender := setupTransferTest(t, nil)
+	receiver := createTestUser(t, "bob")
+
+	if err := transfer(s, sender, "bob", 10); err != nil {
+		t.Fatalf("转赠失败: %v", err)
+	}
+	assertExpireAt(t, reloadUser(t, sender.UserID), 1, sender.VipExpireAt.AddDate(0, 0, -10))
+	if got := reloadUser(t, receiver.UserID); got.VipLevel != 1 || got.VipExpireAt == nil ||
+		got.VipExpireAt.Sub(time.Now().Add(10*24*time.Hour)).Abs() > time.Minute {
+		t.Fatalf("接收方应获得10天VIP1: %+v", got)
+	}
+
+	// 冷却期内不能再次转赠
+	if err := transfer(s, sender, "bob", 1); err == nil || !strings.Contains(err.Error(), "转赠过于频繁") {
+		t.Fatalf("冷却期内应拒绝转赠: %v", err)
+	}
+
+	// 年度额度：已转出10天，额度15天时最多再转5天
+	database.DB.Model(&model.SystemConfig{}).Where("config_key = ?", "vip_transfer_cooldown_hours").Update("config_value", "0")
+	database.DB.Model(&model.SystemConfig{}).Where("config_key = ?", "vip_transfer_yearly_days").Update("config_value", "15")
+	if err := transfer(s, sender, "bob", 6); err == nil || !strings.Contains(err.Error(), "今年还可转赠5天") {
+		t.Fatalf("超出年度额度时应拒绝转赠: %v", err)
+	}
+	if err := transfer(s, sender, "bob", 5); err != nil {
+		t.Fatalf("年度额度内应能转赠: %v", err)
+	}
+}
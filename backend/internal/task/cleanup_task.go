@@ -62,7 +62,16 @@ func (t *CleanupTask) run() {
 	accessCleaned := t.cleanAccessRecords(90)
 	totalCleaned += accessCleaned
 
-	// 2. 清理30天前的审计日志（可选，根据需求调整）
+	// 2. 清理过期或撤销超过7天的登录会话
+	totalCleaned += t.cleanSessions(7)
+
+	// 3. 清理180天前的登录历史
+	totalCleaned += t.cleanLoginHistory(180)
+
+	// 4. 清理过期或撤销超过30天的访问令牌
+	totalCleaned += t.cleanAPITokens(30)
+
+	// 5. 清理30天前的审计日志（可选，根据需求调整）
 	// auditCleaned := t.cleanAuditLogs(30)
 	// totalCleaned += auditCleaned
 
@@ -91,6 +100,48 @@ func (t *CleanupTask) cleanAccessRecords(days int) int64 {
 	return result.RowsAffected
 }
 
+// cleanSessions 清理已过期或已撤销的登录会话
+func (t *CleanupTask) cleanSessions(days int) int64 {
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result := database.DB.Exec(`
+		DELETE FROM user_sessions 
+		WHERE expires_at < ? OR revoked_at < ?
+	`, cutoff, cutoff)
+
+	if result.Error != nil {
+		log.Printf("[CleanupTask] 清理登录会话失败: %v", result.Error)
+		return 0
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("[CleanupTask] 清理登录会话: %d 条（%d天前）", result.RowsAffected, days)
+	}
+
+	return result.RowsAffected
+}
+
+// cleanLoginHistory 清理登录历史
+func (t *CleanupTask) cleanLoginHistory(days int) int64 {
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result := database.DB.Exec(`
+		DELETE FROM login_history 
+		WHERE created_at < ?
+	`, cutoff)
+
+	if result.Error != nil {
+		log.Printf("[CleanupTask] 清理登录历史失败: %v", result.Error)
+		return 0
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("[CleanupTask] 清理登录历史: %d 条（%d天前）", result.RowsAffected, days)
+	}
+
+	return result.RowsAffected
+}
+
 // cleanAuditLogs 清理审计日志
 func (t *CleanupTask) cleanAuditLogs(days int) int64 {
 	cutoff := time.Now().AddDate(0, 0, -days)
@@ -132,3 +183,24 @@ func (t *CleanupTask) RunManualCleanup(accessDays, auditDays int) *CleanupStats
 
 	return stats
 }
+
+// cleanAPITokens 清理已过期或已撤销的个人访问令牌
+func (t *CleanupTask) cleanAPITokens(days int) int64 {
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	result := database.DB.Exec(`
+		DELETE FROM api_tokens 
+		WHERE expires_at < ? OR revoked_at < ?
+	`, cutoff, cutoff)
+
+	if result.Error != nil {
+		log.Printf("[CleanupTask] 清理访问令牌失败: %v", result.Error)
+		return 0
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("[CleanupTask] 清理访问令牌: %d 条（%d天前）", result.RowsAffected, days)
+	}
+
+	return result.RowsAffected
+}
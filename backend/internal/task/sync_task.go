@@ -67,4 +67,12 @@ func (t *SyncTask) runSync() {
 	} else {
 		fmt.Println("[SyncTask] Emby用户同步完成，无新用户")
 	}
+
+	// 移除超出VIP等级设备数上限的设备
+	removed, err := service.NewVipTierService().EnforceDeviceQuotas()
+	if err != nil {
+		log.Printf("[SyncTask] 检查设备数上限失败: %v", err)
+	} else if removed > 0 {
+		log.Printf("[SyncTask] 已移除 %d 个超出等级设备数上限的设备", removed)
+	}
 }
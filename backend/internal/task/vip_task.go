@@ -4,10 +4,9 @@ import (
 	"log"
 	"time"
 
-	"embyhub/internal/dao"
 	"embyhub/internal/model"
+	"embyhub/internal/service"
 	"embyhub/pkg/database"
-	"embyhub/pkg/email"
 )
 
 // VipTask VIP到期处理任务
@@ -64,41 +63,64 @@ func (t *VipTask) run() {
 	startTime := time.Now()
 	log.Println("[VipTask] 开始检查VIP到期状态...")
 
-	// 1. 批量更新过期VIP用户（高效：一条SQL搞定）
+	// 1. 自动解冻到期的冻结用户，再批量更新过期VIP用户（冻结中的用户不参与到期处理）
+	unfrozenCount := service.NewVipService().UnfreezeDue()
 	expiredCount := t.processExpiredVip()
 
 	// 2. 获取即将到期的VIP用户数量（用于统计/通知）
 	expiringCount := t.countExpiringVip(3) // 3天内到期
 
-	// 3. 发送VIP即将到期邮件提醒
+	// 3. 发送VIP到期提醒（按阶段去重）和试用结束转化提醒
 	sentCount := t.sendExpiringReminders()
 
+	// 4. 为持续活跃满N天的被推荐人发放推荐奖励，并补发因VIP冻结推迟的奖励和订单
+	referralService := service.NewReferralService()
+	referralCount := referralService.RewardActiveReferrals() + referralService.RewardDeferredReferrals()
+	orderCount := service.NewOrderService().FulfillDeferredOrders()
+
 	duration := time.Since(startTime)
-	log.Printf("[VipTask] VIP检查完成，耗时: %v，过期处理: %d，即将到期: %d，发送提醒: %d",
-		duration, expiredCount, expiringCount, sentCount)
+	log.Printf("[VipTask] VIP检查完成，耗时: %v，自动解冻: %d，过期处理: %d，即将到期: %d，发送提醒: %d，推荐奖励: %d，补发订单: %d",
+		duration, unfrozenCount, expiredCount, expiringCount, sentCount, referralCount, orderCount)
 }
 
 // processExpiredVip 批量处理过期VIP
-// 使用单条SQL批量更新，高效处理大量数据
+// 过期用户经 VipService 降为免费等级（保留到期时间用于统计并写入到期流水），
+// 再按免费等级权益同步Emby策略；分批处理控制内存和单次锁定范围
 func (t *VipTask) processExpiredVip() int64 {
 	now := time.Now()
+	vipService := service.NewVipService()
 
-	// 批量更新所有过期的VIP用户
-	// 使用数据库原生批量更新，无需加载到内存
-	result := database.DB.Exec(`
-		UPDATE users 
-		SET vip_level = 0, updated_at = ? 
-		WHERE vip_level = 1 
-		AND vip_expire_at IS NOT NULL 
-		AND vip_expire_at < ?
-	`, now, now)
+	var total int64
+	for {
+		var userIDs []int
+		err := database.DB.Model(&model.User{}).
+			Where("vip_level > 0 AND vip_expire_at IS NOT NULL AND vip_expire_at < ? AND vip_frozen_at IS NULL", now).
+			Order("user_id").Limit(t.batchSize).Pluck("user_id", &userIDs).Error
+		if err != nil {
+			log.Printf("[VipTask] 查询过期VIP失败: %v", err)
+			break
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+
+		users, err := vipService.ExpireUsers(userIDs)
+		if err != nil {
+			log.Printf("[VipTask] 更新过期VIP失败: %v", err)
+			break
+		}
+		total += int64(len(users))
+
+		for _, user := range users {
+			vipService.AfterCommit(user)
+		}
 
-	if result.Error != nil {
-		log.Printf("[VipTask] 更新过期VIP失败: %v", result.Error)
-		return 0
+		if len(userIDs) < t.batchSize {
+			break
+		}
 	}
 
-	return result.RowsAffected
+	return total
 }
 
 // countExpiringVip 统计即将到期的VIP数量
@@ -109,7 +131,7 @@ func (t *VipTask) countExpiringVip(days int) int64 {
 	var count int64
 	database.DB.Raw(`
 		SELECT COUNT(*) FROM users 
-		WHERE vip_level = 1 
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL 
 		AND vip_expire_at IS NOT NULL 
 		AND vip_expire_at > ? 
 		AND vip_expire_at <= ?
@@ -126,7 +148,7 @@ func (t *VipTask) GetExpiredVipUserIDs(limit int, offset int) ([]int, error) {
 
 	err := database.DB.Raw(`
 		SELECT user_id FROM users 
-		WHERE vip_level = 1 
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL 
 		AND vip_expire_at IS NOT NULL 
 		AND vip_expire_at < ?
 		ORDER BY user_id
@@ -158,7 +180,7 @@ func (t *VipTask) GetExpiringVipUsers(days int, limit int, offset int) ([]Expiri
 			vip_expire_at,
 			EXTRACT(DAY FROM (vip_expire_at - ?))::int as days_left
 		FROM users 
-		WHERE vip_level = 1 
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL 
 		AND vip_expire_at IS NOT NULL 
 		AND vip_expire_at > ? 
 		AND vip_expire_at <= ?
@@ -218,90 +240,36 @@ func (t *VipTask) GetVipStatistics() (*VipStatistics, error) {
 	// 有效VIP总数
 	database.DB.Raw(`
 		SELECT COUNT(*) FROM users 
-		WHERE vip_level = 1 AND vip_expire_at > ?
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL AND vip_expire_at > ?
 	`, now).Scan(&stats.TotalVip)
 
 	// 今日到期
 	database.DB.Raw(`
 		SELECT COUNT(*) FROM users 
-		WHERE vip_level = 1 
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL 
 		AND vip_expire_at > ? AND vip_expire_at <= ?
 	`, now, today).Scan(&stats.ExpiredToday)
 
 	// 3天内到期
 	database.DB.Raw(`
 		SELECT COUNT(*) FROM users 
-		WHERE vip_level = 1 
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL 
 		AND vip_expire_at > ? AND vip_expire_at <= ?
 	`, now, now.AddDate(0, 0, 3)).Scan(&stats.Expiring3Day)
 
 	// 7天内到期
 	database.DB.Raw(`
 		SELECT COUNT(*) FROM users 
-		WHERE vip_level = 1 
+		WHERE vip_level > 0 AND vip_frozen_at IS NULL 
 		AND vip_expire_at > ? AND vip_expire_at <= ?
 	`, now, now.AddDate(0, 0, 7)).Scan(&stats.Expiring7Day)
 
 	return stats, nil
 }
 
-// sendExpiringReminders 发送VIP即将到期提醒邮件
-// 每天只发送一次（3天内到期的用户）
+// sendExpiringReminders 按配置的阶段发送VIP到期提醒，并向未转化的试用用户发送试用结束提醒
+// 每个阶段每个到期日期最多发送一次，重复运行不会重复发送
 func (t *VipTask) sendExpiringReminders() int {
-	// 获取邮件配置
-	configDAO := dao.NewSystemConfigDAO()
-	configMap, err := configDAO.BatchGet(email.GetConfigKeys())
-	if err != nil {
-		return 0
-	}
-	// 根据provider检查配置
-	provider := configMap["email_provider"]
-	switch provider {
-	case "aliyun":
-		if configMap["aliyun_access_key_id"] == "" {
-			return 0
-		}
-	case "resend":
-		if configMap["resend_api_key"] == "" {
-			return 0
-		}
-	case "aliyun_smtp", "smtp":
-		if configMap["smtp_host"] == "" {
-			return 0
-		}
-	default:
-		if configMap["smtp_host"] == "" {
-			return 0
-		}
-	}
-
-	emailClient := email.NewClient(email.GetConfigFromDB(configMap))
-
-	// 获取3天内到期且有邮箱的用户
-	now := time.Now()
-	expireTime := now.AddDate(0, 0, 3)
-
-	var users []model.User
-	database.DB.Where("vip_level = 1 AND email != '' AND vip_expire_at > ? AND vip_expire_at <= ?",
-		now, expireTime).Find(&users)
-
-	sentCount := 0
-	for _, user := range users {
-		// 计算剩余天数
-		daysLeft := int(user.VipExpireAt.Sub(now).Hours() / 24)
-		if daysLeft < 1 {
-			daysLeft = 1
-		}
-
-		// 发送VIP到期提醒邮件
-		expireDate := user.VipExpireAt.Format("2006年01月02日 15:04")
-		if err := emailClient.SendVipExpiringEmail(user.Email, user.Username, expireDate, daysLeft); err != nil {
-			log.Printf("[VipTask] 发送VIP到期提醒失败 user=%s email=%s: %v", user.Username, user.Email, err)
-		} else {
-			sentCount++
-			log.Printf("[VipTask] 发送VIP到期提醒成功 user=%s email=%s days_left=%d", user.Username, user.Email, daysLeft)
-		}
-	}
-
-	return sentCount
+	notificationService := service.NewNotificationService()
+	return notificationService.SendVipReminders() + notificationService.SendTrialReminders()
 }
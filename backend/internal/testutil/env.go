@@ -0,0 +1,105 @@
+// Package testutil 提供测试使用的内存数据库和Redis环境
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"embyhub/config"
+	"embyhub/internal/model"
+	"embyhub/internal/util"
+	"embyhub/pkg/database"
+	"embyhub/pkg/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var dbSeq int64
+
+// models 测试库中自动创建的表
+var models = []interface{}{
+	&model.VipTier{},
+	&model.CardRedemption{},
+	&model.NotificationLog{},
+	&model.EmailCode{},
+	&model.SystemConfig{},
+	&model.User{},
+	&model.VipLedger{},
+	&model.AuditLog{},
+	&model.UserTOTP{},
+	&model.RecoveryCode{},
+	&model.UserSession{},
+	&model.Role{},
+	&model.Referral{},
+	&model.CardKey{},
+	&model.UserIdentity{},
+	&model.APIToken{},
+	&model.PasswordHistory{},
+	&model.VipFreeze{},
+	&model.TrialGrant{},
+	&model.RefreshToken{},
+	&model.UserPoints{},
+	&model.PointsLedger{},
+	&model.Checkin{},
+	&model.PointsItem{},
+	&model.PointsRedemption{},
+	&model.Permission{},
+	&model.PermissionGroup{},
+	&model.LoginHistory{},
+	&model.VipTransfer{},
+	&model.UserGroup{},
+	&model.UserGroupGrant{},
+	&model.AgentQuota{},
+	&model.AgentQuotaLedger{},
+	&model.Plan{},
+	&model.Order{},
+	&model.CardBatch{},
+	&model.AccessRecord{},
+}
+
+// Setup 为单个测试准备独立的内存数据库和Redis，替换全局连接，测试结束后恢复
+// 数据库表由模型自动创建（SQLite 不支持行锁，锁定语句会被忽略）
+func Setup(t testing.TB) {
+	t.Helper()
+
+	config.GlobalConfig = &config.Config{}
+	config.GlobalConfig.JWT.Secret = "test-jwt-secret"
+	config.GlobalConfig.JWT.ExpireHours = 24
+	util.Logger = zap.NewNop()
+
+	dsn := fmt.Sprintf("file:embyhub_test_%d?mode=memory&cache=shared", atomic.AddInt64(&dbSeq, 1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取数据库实例失败: %v", err)
+	}
+	// 单连接串行执行，避免共享缓存下的表锁冲突
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("创建测试表失败: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+
+	prevDB, prevRedis := database.DB, redis.Client
+	database.DB, redis.Client = db, client
+	t.Cleanup(func() {
+		// 审计日志异步写入，等待写完再关闭连接
+		time.Sleep(20 * time.Millisecond)
+		database.DB, redis.Client = prevDB, prevRedis
+		client.Close()
+		sqlDB.Close()
+	})
+}
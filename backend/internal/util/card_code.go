@@ -0,0 +1,206 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// CardCodeCharset 默认卡密字符集（去除易混淆字符 0/O、1/I）
+const CardCodeCharset = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// 校验位算法
+const (
+	CardChecksumNone = "none"  // 无校验位
+	CardChecksumLuhn = "luhn"  // Luhn mod N
+	CardChecksumCRC  = "crc32" // CRC32 取模
+)
+
+// CardPrefixMaxLength 卡密前缀最大长度
+const CardPrefixMaxLength = 8
+
+var cardPrefixRegexp = regexp.MustCompile(fmt.Sprintf(`^[A-Z0-9]{0,%d}$`, CardPrefixMaxLength))
+
+// CardCodeTemplate 卡密码模板
+// 生成格式：PREFIX-XXXX-XXXX-XXXX（最后一位为校验位）
+type CardCodeTemplate struct {
+	Prefix    string `json:"prefix"`     // 前缀（0-8位字母数字）
+	GroupSize int    `json:"group_size"` // 分组长度（0=不分组）
+	Length    int    `json:"length"`     // 随机字符长度（不含校验位）
+	Charset   string `json:"charset"`    // 字符集（为空使用默认字符集）
+	Checksum  string `json:"checksum"`   // 校验位算法：none / luhn / crc32
+}
+
+// Normalize 规范化模板并填充默认值
+func (t *CardCodeTemplate) Normalize() error {
+	t.Prefix = strings.ToUpper(strings.TrimSpace(t.Prefix))
+	if !cardPrefixRegexp.MatchString(t.Prefix) {
+		return errors.New("卡密前缀只能包含0-8位字母或数字")
+	}
+
+	if t.Charset == "" {
+		t.Charset = CardCodeCharset
+	}
+	t.Charset = strings.ToUpper(t.Charset)
+	seen := make(map[rune]bool)
+	for _, r := range t.Charset {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return errors.New("卡密字符集只能包含字母和数字")
+		}
+		if seen[r] {
+			return errors.New("卡密字符集包含重复字符")
+		}
+		seen[r] = true
+	}
+	if len(t.Charset) < 10 {
+		return errors.New("卡密字符集至少需要10个字符")
+	}
+
+	if t.Length == 0 {
+		t.Length = 12
+	}
+	if t.Length < 6 || t.Length > 32 {
+		return errors.New("卡密长度需在6-32位之间")
+	}
+	if t.GroupSize < 0 || t.GroupSize > t.Length {
+		return errors.New("卡密分组长度无效")
+	}
+
+	switch t.Checksum {
+	case "":
+		t.Checksum = CardChecksumLuhn
+	case CardChecksumNone, CardChecksumLuhn, CardChecksumCRC:
+	default:
+		return fmt.Errorf("不支持的校验算法: %s", t.Checksum)
+	}
+
+	if len(t.Format(strings.Repeat("A", t.bodyLength()))) > 64 {
+		return errors.New("卡密总长度不能超过64位")
+	}
+	return nil
+}
+
+// Capacity 模板可生成的组合数量（以2为底的对数）
+func (t *CardCodeTemplate) Capacity() float64 {
+	return float64(t.Length) * math.Log2(float64(len(t.Charset)))
+}
+
+// Generate 生成一个格式化后的卡密码
+func (t *CardCodeTemplate) Generate() (string, error) {
+	body := make([]byte, t.Length)
+	max := big.NewInt(int64(len(t.Charset)))
+	for i := range body {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		body[i] = t.Charset[n.Int64()]
+	}
+
+	code := string(body)
+	if check, ok := t.checkChar(code); ok {
+		code += string(check)
+	}
+	return t.Format(code), nil
+}
+
+// Format 将随机部分（含校验位）按模板格式化
+func (t *CardCodeTemplate) Format(body string) string {
+	var parts []string
+	if t.Prefix != "" {
+		parts = append(parts, t.Prefix)
+	}
+	if t.GroupSize > 0 {
+		for i := 0; i < len(body); i += t.GroupSize {
+			end := i + t.GroupSize
+			if end > len(body) {
+				end = len(body)
+			}
+			parts = append(parts, body[i:end])
+		}
+	} else {
+		parts = append(parts, body)
+	}
+	return strings.Join(parts, "-")
+}
+
+// Verify 校验规范化后的卡密码是否符合模板（前缀、长度、字符集、校验位）
+func (t *CardCodeTemplate) Verify(normalized string) bool {
+	if !strings.HasPrefix(normalized, t.Prefix) {
+		return false
+	}
+	body := normalized[len(t.Prefix):]
+	if len(body) != t.bodyLength() {
+		return false
+	}
+	for _, r := range body {
+		if !strings.ContainsRune(t.Charset, r) {
+			return false
+		}
+	}
+	check, ok := t.checkChar(body[:t.Length])
+	if !ok {
+		return true
+	}
+	return body[t.Length] == check
+}
+
+// bodyLength 随机部分加校验位的长度
+func (t *CardCodeTemplate) bodyLength() int {
+	if t.Checksum == CardChecksumNone {
+		return t.Length
+	}
+	return t.Length + 1
+}
+
+// checkChar 计算校验字符
+func (t *CardCodeTemplate) checkChar(body string) (byte, bool) {
+	n := len(t.Charset)
+	switch t.Checksum {
+	case CardChecksumLuhn:
+		// Luhn mod N：从右往左，偶数位加倍后按N进制拆分求和
+		factor := 2
+		sum := 0
+		for i := len(body) - 1; i >= 0; i-- {
+			addend := factor * strings.IndexByte(t.Charset, body[i])
+			addend = addend/n + addend%n
+			sum += addend
+			if factor == 2 {
+				factor = 1
+			} else {
+				factor = 2
+			}
+		}
+		return t.Charset[(n-sum%n)%n], true
+	case CardChecksumCRC:
+		return t.Charset[crc32.ChecksumIEEE([]byte(t.Prefix+body))%uint32(n)], true
+	default:
+		return 0, false
+	}
+}
+
+// CardCodePrefixes 规范化卡密码可能使用的模板前缀（取开头1至8位）
+func CardCodePrefixes(normalized string) []string {
+	var prefixes []string
+	for n := 1; n <= CardPrefixMaxLength && n < len(normalized); n++ {
+		prefixes = append(prefixes, normalized[:n])
+	}
+	return prefixes
+}
+
+// NormalizeCardCode 规范化用户输入的卡密码（统一大写，去除横线和空白）
+func NormalizeCardCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, code)
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCardCodeTemplateNormalize(t *testing.T) {
+	tpl := &CardCodeTemplate{Prefix: " vip ", Charset: "abcdefghjk"}
+	if err := tpl.Normalize(); err != nil {
+		t.Fatalf("规范化失败: %v", err)
+	}
+	if tpl.Prefix != "VIP" || tpl.Charset != "ABCDEFGHJK" || tpl.Length != 12 || tpl.Checksum != CardChecksumLuhn {
+		t.Fatalf("默认值不符: %+v", tpl)
+	}
+
+	invalid := map[string]CardCodeTemplate{
+		"前缀过长":   {Prefix: "ABCDEFGHI"},
+		"前缀含符号":  {Prefix: "VIP|"},
+		"字符集重复":  {Charset: "AABCDEFGHJK"},
+		"字符集含符号": {Charset: "ABCDEFGHJ-"},
+		"字符集过少":  {Charset: "ABCDEFGHJ"},
+		"长度过短":   {Length: 5},
+		"长度过长":   {Length: 33},
+		"分组过长":   {Length: 8, GroupSize: 9},
+		"未知校验算法": {Checksum: "md5"},
+		"总长度超限":  {Prefix: "ABCDEFGH", Length: 32, GroupSize: 1},
+	}
+	for name, tpl := range invalid {
+		if err := tpl.Normalize(); err == nil {
+			t.Errorf("%s: 应拒绝模板 %+v", name, tpl)
+		}
+	}
+}
+
+func TestCardCodeGenerateVerify(t *testing.T) {
+	for _, checksum := range []string{CardChecksumNone, CardChecksumLuhn, CardChecksumCRC} {
+		tpl := &CardCodeTemplate{Prefix: "VIP", GroupSize: 4, Length: 12, Checksum: checksum}
+		if err := tpl.Normalize(); err != nil {
+			t.Fatalf("规范化失败: %v", err)
+		}
+		for i := 0; i < 50; i++ {
+			code, err := tpl.Generate()
+			if err != nil {
+				t.Fatalf("生成卡密失败: %v", err)
+			}
+			groups := strings.Split(code, "-")
+			if groups[0] != "VIP" || len(groups[1]) != 4 {
+				t.Fatalf("%s: 卡密格式不符: %s", checksum, code)
+			}
+			if !tpl.Verify(NormalizeCardCode(strings.ToLower(code))) {
+				t.Fatalf("%s: 生成的卡密应通过校验: %s", checksum, code)
+			}
+		}
+	}
+
+	tpl := &CardCodeTemplate{Prefix: "VIP", Length: 8}
+	tpl.Normalize()
+	code := NormalizeCardCode(mustGenerate(t, tpl))
+	for name, input := range map[string]string{
+		"缺少前缀":   "GIFT" + code[3:],
+		"缺少校验位":  code[:len(code)-1],
+		"多出字符":   code + "A",
+		"字符集外字符": code[:4] + "0" + code[5:],
+	} {
+		if tpl.Verify(input) {
+			t.Errorf("%s: 不应通过校验 %s", name, input)
+		}
+	}
+}
+
+func TestCardCodeLuhn(t *testing.T) {
+	// 十进制字符集下与标准 Luhn 算法一致
+	decimal := &CardCodeTemplate{Length: 10, Charset: "0123456789", Checksum: CardChecksumLuhn}
+	if err := decimal.Normalize(); err != nil {
+		t.Fatalf("规范化失败: %v", err)
+	}
+	if !decimal.Verify("79927398713") || decimal.Verify("79927398710") {
+		t.Fatal("Luhn 校验位应为 3")
+	}
+
+	// 任意单个字符输错都能被发现
+	tpl := &CardCodeTemplate{Length: 12, Checksum: CardChecksumLuhn}
+	tpl.Normalize()
+	code := NormalizeCardCode(mustGenerate(t, tpl))
+	for i := 0; i < len(code); i++ {
+		for _, r := range tpl.Charset {
+			if byte(r) == code[i] {
+				continue
+			}
+			if typo := code[:i] + string(r) + code[i+1:]; tpl.Verify(typo) {
+				t.Fatalf("第%d位输错应校验失败: %s -> %s", i+1, code, typo)
+			}
+		}
+	}
+}
+
+func TestNormalizeCardCode(t *testing.T) {
+	if got := NormalizeCardCode(" vip-ab2c d3e4\t\r\n"); got != "VIPAB2CD3E4" {
+		t.Fatalf("规范化结果不符: %s", got)
+	}
+	if got := CardCodePrefixes("VIPABCDEFGH"); len(got) != 8 || got[0] != "V" || got[7] != "VIPABCDE" {
+		t.Fatalf("前缀候选不符: %v", got)
+	}
+	if got := CardCodePrefixes("VIP"); len(got) != 2 || got[1] != "VI" {
+		t.Fatalf("前缀候选不应包含整个卡密: %v", got)
+	}
+}
+
+func mustGenerate(t *testing.T, tpl *CardCodeTemplate) string {
+	t.Helper()
+	code, err := tpl.Generate()
+	if err != nil {
+		t.Fatalf("生成卡密失败: %v", err)
+	}
+	return code
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"net"
+	"strings"
+)
+
+// DeviceFromUserAgent 根据 User-Agent 推断设备名称（如 "Chrome / Windows"），无法识别时返回 "未知设备"
+func DeviceFromUserAgent(ua string) string {
+	lower := strings.ToLower(ua)
+
+	platform := ""
+	switch {
+	case strings.Contains(lower, "iphone"):
+		platform = "iPhone"
+	case strings.Contains(lower, "ipad"):
+		platform = "iPad"
+	case strings.Contains(lower, "android"):
+		platform = "Android"
+	case strings.Contains(lower, "windows"):
+		platform = "Windows"
+	case strings.Contains(lower, "mac os"):
+		platform = "macOS"
+	case strings.Contains(lower, "linux"):
+		platform = "Linux"
+	}
+
+	client := ""
+	switch {
+	case strings.Contains(lower, "edg/"):
+		client = "Edge"
+	case strings.Contains(lower, "chrome/"):
+		client = "Chrome"
+	case strings.Contains(lower, "firefox/"):
+		client = "Firefox"
+	case strings.Contains(lower, "safari/"):
+		client = "Safari"
+	case strings.Contains(lower, "emby"):
+		client = "Emby"
+	}
+
+	switch {
+	case client != "" && platform != "":
+		return client + " / " + platform
+	case client != "":
+		return client
+	case platform != "":
+		return platform
+	}
+	return "未知设备"
+}
+
+// DeviceHash 计算设备指纹：优先使用客户端持久化的设备标识，否则退化为按 UA 推断的设备类型
+func DeviceHash(clientID, ua string) string {
+	clientID = strings.TrimSpace(clientID)
+	if clientID != "" {
+		return HashToken("client:" + clientID)
+	}
+	return HashToken("ua:" + DeviceFromUserAgent(ua))
+}
+
+// NetworkOf 返回IP所在网段（IPv4 取 /24，IPv6 取 /48），用于判断是否来自新的网络
+func NetworkOf(ip string) string {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return ip
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return addr.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
@@ -11,22 +11,38 @@ import (
 
 // Claims JWT声明
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	RoleID   int    `json:"role_id"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	RoleID    int    `json:"role_id"`
+	Version   int    `json:"sv"`  // 签发时用户的安全版本号
+	SessionID string `json:"sid"` // 所属登录会话
+
+	MustChangePassword bool `json:"mcp,omitempty"` // 须先修改密码，修改前只能访问修改密码等少数接口
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID int, username string, roleID int) (string, error) {
+// ErrTokenExpired 访问令牌已过期
+var ErrTokenExpired = errors.New("Token已过期")
+
+// SessionTTL 登录会话（刷新令牌）有效期
+func SessionTTL() time.Duration {
+	return time.Hour * time.Duration(config.GlobalConfig.JWT.ExpireHours)
+}
+
+// GenerateToken 生成JWT访问令牌
+func GenerateToken(userID int, username string, roleID int, securityVersion int, sessionID string, mustChangePassword bool, ttl time.Duration) (string, error) {
 	cfg := config.GlobalConfig.JWT
 
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		RoleID:   roleID,
+		UserID:    userID,
+		Username:  username,
+		RoleID:    roleID,
+		Version:   securityVersion,
+		SessionID: sessionID,
+
+		MustChangePassword: mustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(cfg.ExpireHours))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    cfg.Issuer,
 		},
@@ -45,6 +61,9 @@ func ParseToken(tokenString string) (*Claims, error) {
 	})
 
 	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
 		return nil, err
 	}
 
@@ -55,34 +74,43 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("无效的Token")
 }
 
-// RefreshToken 刷新Token
-// 如果Token在刷新窗口期内（过期前30分钟），则生成新Token
-func RefreshToken(tokenString string) (string, error) {
-	claims, err := ParseToken(tokenString)
-	if err != nil {
-		return "", err
-	}
+// 一次性操作令牌的受众，与访问令牌区分，不同用途的令牌不能互用
+const (
+	AudienceMagicLink  = "magic_link"  // 邮件登录链接
+	AudienceLoginAlert = "login_alert" // 登录提醒邮件中的"不是我本人"链接
+)
 
-	// 检查是否在刷新窗口期内（过期前30分钟可刷新）
-	refreshWindow := 30 * time.Minute
-	if time.Until(claims.ExpiresAt.Time) > refreshWindow {
-		// 还没到刷新窗口，返回原Token
-		return tokenString, nil
+// GenerateActionToken 生成邮件链接中使用的一次性操作令牌，subject 为操作对象，jti 用于保证令牌只能使用一次
+func GenerateActionToken(audience, subject, jti string, ttl time.Duration) (string, error) {
+	cfg := config.GlobalConfig.JWT
+
+	claims := jwt.RegisteredClaims{
+		Subject:   subject,
+		ID:        jti,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    cfg.Issuer,
 	}
 
-	// 生成新Token
-	return GenerateToken(claims.UserID, claims.Username, claims.RoleID)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.Secret))
 }
 
-// GetTokenRemainingTime 获取Token剩余有效时间（秒）
-func GetTokenRemainingTime(tokenString string) int64 {
-	claims, err := ParseToken(tokenString)
-	if err != nil {
-		return 0
-	}
-	remaining := time.Until(claims.ExpiresAt.Time)
-	if remaining < 0 {
-		return 0
+// ParseActionToken 校验一次性操作令牌的签名、受众和有效期
+func ParseActionToken(tokenString, audience string) (*jwt.RegisteredClaims, error) {
+	cfg := config.GlobalConfig.JWT
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.Secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.ID == "" || claims.Subject == "" {
+		return nil, errors.New("链接无效或已过期")
 	}
-	return int64(remaining.Seconds())
+	return &claims, nil
 }
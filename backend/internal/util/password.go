@@ -1,8 +1,12 @@
 package util
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
+	"os"
 	"regexp"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -22,53 +26,95 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// ValidatePassword 验证密码强度
-// 要求：至少6位，包含字母和数字
-func ValidatePassword(password string) error {
-	if len(password) < 6 {
-		return errors.New("密码至少6个字符")
+// 密码长度上限（bcrypt 只使用前72字节）
+const passwordMaxLength = 50
+
+// 密码中的字符类别
+var (
+	passwordLetter = regexp.MustCompile(`[a-zA-Z]`)
+	passwordLower  = regexp.MustCompile(`[a-z]`)
+	passwordUpper  = regexp.MustCompile(`[A-Z]`)
+	passwordDigit  = regexp.MustCompile(`[0-9]`)
+	passwordSymbol = regexp.MustCompile(`[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?~` + "`" + `]`)
+)
+
+// PasswordPolicy 密码策略
+type PasswordPolicy struct {
+	MinLength        int
+	RequireLetter    bool
+	RequireMixedCase bool // 同时包含大写和小写字母
+	RequireDigit     bool
+	RequireSymbol    bool
+	Blocklist        map[string]struct{} // 常见或已泄露的密码（小写）
+}
+
+// Validate 按策略校验密码
+func (p *PasswordPolicy) Validate(password string) error {
+	if len(password) < p.MinLength {
+		return fmt.Errorf("密码至少%d个字符", p.MinLength)
 	}
-	if len(password) > 50 {
-		return errors.New("密码最多50个字符")
+	if len(password) > passwordMaxLength {
+		return fmt.Errorf("密码最多%d个字符", passwordMaxLength)
 	}
 
-	// 检查是否包含字母
-	hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(password)
-	if !hasLetter {
+	if p.RequireLetter && !passwordLetter.MatchString(password) {
 		return errors.New("密码需包含字母")
 	}
-
-	// 检查是否包含数字
-	hasNumber := regexp.MustCompile(`[0-9]`).MatchString(password)
-	if !hasNumber {
+	if p.RequireMixedCase {
+		if !passwordLower.MatchString(password) {
+			return errors.New("密码需包含小写字母")
+		}
+		if !passwordUpper.MatchString(password) {
+			return errors.New("密码需包含大写字母")
+		}
+	}
+	if p.RequireDigit && !passwordDigit.MatchString(password) {
 		return errors.New("密码需包含数字")
 	}
+	if p.RequireSymbol && !passwordSymbol.MatchString(password) {
+		return errors.New("密码需包含特殊字符")
+	}
 
+	if _, ok := p.Blocklist[strings.ToLower(password)]; ok {
+		return errors.New("该密码过于常见或已出现在泄露密码库中，请更换")
+	}
 	return nil
 }
 
+// DefaultPasswordPolicy 默认密码策略：至少6位，包含字母和数字
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: 6, RequireLetter: true, RequireDigit: true}
+}
+
+// ValidatePassword 验证密码强度
+// 要求：至少6位，包含字母和数字
+func ValidatePassword(password string) error {
+	return DefaultPasswordPolicy().Validate(password)
+}
+
 // ValidateStrongPassword 验证强密码
 // 要求：至少8位，包含大小写字母、数字和特殊字符
 func ValidateStrongPassword(password string) error {
-	if len(password) < 8 {
-		return errors.New("密码至少8个字符")
-	}
-	if len(password) > 50 {
-		return errors.New("密码最多50个字符")
-	}
+	policy := &PasswordPolicy{MinLength: 8, RequireMixedCase: true, RequireDigit: true, RequireSymbol: true}
+	return policy.Validate(password)
+}
 
-	if !regexp.MustCompile(`[a-z]`).MatchString(password) {
-		return errors.New("密码需包含小写字母")
-	}
-	if !regexp.MustCompile(`[A-Z]`).MatchString(password) {
-		return errors.New("密码需包含大写字母")
-	}
-	if !regexp.MustCompile(`[0-9]`).MatchString(password) {
-		return errors.New("密码需包含数字")
-	}
-	if !regexp.MustCompile(`[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?]`).MatchString(password) {
-		return errors.New("密码需包含特殊字符")
+// LoadPasswordList 读取密码列表文件（每行一个密码，忽略空行和 # 开头的注释），统一转为小写
+func LoadPasswordList(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	return nil
+	list := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+	return list, scanner.Err()
 }
@@ -0,0 +1,42 @@
+package util
+
+import "strings"
+
+// PermissionWildcard 权限通配符
+const PermissionWildcard = "*"
+
+// IsPermissionPattern 判断权限Key是否为通配模式
+func IsPermissionPattern(key string) bool {
+	return strings.Contains(key, PermissionWildcard)
+}
+
+// MatchPermission 判断权限模式是否匹配指定权限Key
+// 权限Key按冒号分层：
+//   - "*" 匹配所有权限
+//   - 末段为 "*" 时匹配该前缀下任意层级，如 "cardkey:*" 匹配 "cardkey:create"
+//   - 中间段为 "*" 时匹配恰好一段，如 "*:view" 匹配 "user:view"
+//   - 其余情况要求完全相等
+func MatchPermission(pattern, key string) bool {
+	if pattern == key || pattern == PermissionWildcard {
+		return true
+	}
+	if !IsPermissionPattern(pattern) {
+		return false
+	}
+
+	patternParts := strings.Split(pattern, ":")
+	keyParts := strings.Split(key, ":")
+	for i, part := range patternParts {
+		last := i == len(patternParts)-1
+		if part == PermissionWildcard && last {
+			return len(keyParts) > i
+		}
+		if i >= len(keyParts) {
+			return false
+		}
+		if part != PermissionWildcard && part != keyParts[i] {
+			return false
+		}
+	}
+	return len(keyParts) == len(patternParts)
+}
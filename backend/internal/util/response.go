@@ -57,6 +57,21 @@ func UnauthorizedResponse(c *gin.Context, message string) {
 	ErrorResponse(c, 401, message)
 }
 
+// TokenExpiredResponse 访问令牌已过期，客户端应刷新令牌后重试
+func TokenExpiredResponse(c *gin.Context, message string) {
+	ErrorResponse(c, model.CodeTokenExpired, message)
+}
+
+// StepUpRequiredResponse 敏感操作需要二次验证，客户端应完成验证后重试
+func StepUpRequiredResponse(c *gin.Context, message string) {
+	ErrorResponse(c, model.CodeStepUpRequired, message)
+}
+
+// PasswordChangeRequiredResponse 须先修改密码，客户端应修改密码后重试
+func PasswordChangeRequiredResponse(c *gin.Context, message string) {
+	ErrorResponse(c, model.CodePasswordChangeRequired, message)
+}
+
 // ForbiddenResponse 禁止访问
 func ForbiddenResponse(c *gin.Context, message string) {
 	ErrorResponse(c, 403, message)
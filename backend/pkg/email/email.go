@@ -173,9 +173,27 @@ func (c *Client) SendVipExpiringEmail(to, username, expireDate string, daysLeft
 	return c.Send(to, subject, body)
 }
 
+// SendVipExpiredEmail 发送VIP已到期通知
+func (c *Client) SendVipExpiredEmail(to, username, expireDate string) error {
+	subject, body := VipExpiredEmail(username, expireDate)
+	return c.Send(to, subject, body)
+}
+
+// SendTrialEndingEmail 发送试用即将结束提醒
+func (c *Client) SendTrialEndingEmail(to, username, expireDate string, hoursLeft int) error {
+	subject, body := TrialEndingEmail(username, expireDate, hoursLeft)
+	return c.Send(to, subject, body)
+}
+
+// SendMagicLinkEmail 发送邮件登录链接
+func (c *Client) SendMagicLinkEmail(to, link string, minutes int) error {
+	subject, body := MagicLinkEmail(link, minutes)
+	return c.Send(to, subject, body)
+}
+
 // SendLoginAlertEmail 发送登录提醒
-func (c *Client) SendLoginAlertEmail(to, username, ip, device, loginTime string) error {
-	subject, body := LoginAlertEmail(username, ip, device, loginTime)
+func (c *Client) SendLoginAlertEmail(to, username, ip, device, loginTime, revokeLink string) error {
+	subject, body := LoginAlertEmail(username, ip, device, loginTime, revokeLink)
 	return c.Send(to, subject, body)
 }
 
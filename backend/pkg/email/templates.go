@@ -133,12 +133,71 @@ func VipExpiringEmail(username string, expireDate string, daysLeft int) (subject
 	return
 }
 
-// LoginAlertEmail 异常登录提醒
-func LoginAlertEmail(username, ip, device, loginTime string) (subject, body string) {
+// VipExpiredEmail VIP已到期通知
+func VipExpiredEmail(username string, expireDate string) (subject, body string) {
+	subject = "📅 VIP会员已到期"
+	content := fmt.Sprintf(`
+        <h2 style="margin: 0 0 20px; color: #1a1a2e; font-size: 20px;">亲爱的 %s</h2>
+        <p style="color: #555; line-height: 1.6; margin: 0 0 25px;">您的VIP会员已到期，账号已恢复为免费权益：</p>
+        <div style="background: linear-gradient(135deg, #eceff1 0%%, #cfd8dc 100%%); border-radius: 12px; padding: 25px; margin: 25px 0; text-align: center;">
+            <p style="margin: 0 0 10px; color: #607d8b; font-size: 14px;">到期时间</p>
+            <p style="margin: 0; color: #37474f; font-size: 24px; font-weight: bold;">%s</p>
+        </div>
+        <p style="color: #888; font-size: 13px; margin: 0;">使用VIP升级码即可立即恢复会员权益 🎬</p>
+    `, username, expireDate)
+	body = fmt.Sprintf(baseTemplate, "#607d8b", "#90a4ae", "👑", "VIP提醒", content)
+	return
+}
+
+// TrialEndingEmail 试用即将结束提醒
+func TrialEndingEmail(username string, expireDate string, hoursLeft int) (subject, body string) {
+	subject = "🎁 VIP试用即将结束"
+	content := fmt.Sprintf(`
+        <h2 style="margin: 0 0 20px; color: #1a1a2e; font-size: 20px;">亲爱的 %s</h2>
+        <p style="color: #555; line-height: 1.6; margin: 0 0 25px;">您的VIP试用即将结束，结束后将恢复为免费权益：</p>
+        <div style="background: linear-gradient(135deg, #e8f5e9 0%%, #c8e6c9 100%%); border-radius: 12px; padding: 25px; margin: 25px 0; text-align: center;">
+            <p style="margin: 0 0 10px; color: #388e3c; font-size: 14px;">试用结束时间</p>
+            <p style="margin: 0 0 15px; color: #1b5e20; font-size: 24px; font-weight: bold;">%s</p>
+            <div style="display: inline-block; background: #43a047; color: #fff; padding: 8px 20px; border-radius: 20px; font-weight: bold;">
+                剩余 %d 小时
+            </div>
+        </div>
+        <p style="color: #888; font-size: 13px; margin: 0;">购买套餐或使用VIP升级码即可继续畅享会员权益 🎬</p>
+    `, username, expireDate, hoursLeft)
+	body = fmt.Sprintf(baseTemplate, "#43a047", "#66bb6a", "🎁", "试用提醒", content)
+	return
+}
+
+// MagicLinkEmail 邮件登录链接
+func MagicLinkEmail(link string, minutes int) (subject, body string) {
+	subject = "🔗 登录链接"
+	content := fmt.Sprintf(`
+        <p style="color: #555; line-height: 1.6; margin: 0 0 25px;">您正在通过邮件登录账号，点击下方按钮即可登录：</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="%s" style="display: inline-block; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%); color: #fff; text-decoration: none; font-weight: bold; padding: 14px 40px; border-radius: 8px;">立即登录</a>
+        </div>
+        <p style="color: #888; font-size: 12px; word-break: break-all;">按钮无法点击时，请复制以下链接到浏览器打开：<br>%s</p>
+        <div style="background: #fff8e1; border-radius: 8px; padding: 15px; margin: 20px 0;">
+            <p style="margin: 0; color: #f57c00; font-size: 13px;">
+                ⏰ 链接%d分钟内有效，且只能使用一次<br>
+                ⚠️ 如非本人操作，请忽略此邮件，切勿转发给他人
+            </p>
+        </div>
+    `, link, link, minutes)
+	body = fmt.Sprintf(baseTemplate, "#667eea", "#764ba2", "🔗", "邮件登录", content)
+	return
+}
+
+// LoginAlertEmail 新设备或新网络登录提醒，revokeLink 非空时附带"不是我本人"链接
+func LoginAlertEmail(username, ip, device, loginTime, revokeLink string) (subject, body string) {
 	subject = "🚨 账号登录提醒"
+	action := `⚠️ 如非本人操作，请立即修改密码！`
+	if revokeLink != "" {
+		action = fmt.Sprintf(`⚠️ 如非本人操作，请点击 <a href="%s" style="color: #c62828; font-weight: bold;">这不是我本人</a>，将立即退出所有设备并要求重置密码`, revokeLink)
+	}
 	content := fmt.Sprintf(`
         <h2 style="margin: 0 0 20px; color: #1a1a2e; font-size: 20px;">安全提醒</h2>
-        <p style="color: #555; line-height: 1.6; margin: 0 0 25px;">您的账号 <strong>%s</strong> 刚刚进行了登录：</p>
+        <p style="color: #555; line-height: 1.6; margin: 0 0 25px;">您的账号 <strong>%s</strong> 刚刚在新的设备或网络上登录：</p>
         <div style="background: #f5f5f5; border-radius: 12px; padding: 20px; margin: 25px 0;">
             <table style="width: 100%%; border-collapse: collapse;">
                 <tr><td style="padding: 8px 0; color: #888; width: 80px;">🕐 时间</td><td style="color: #333;">%s</td></tr>
@@ -148,10 +207,10 @@ func LoginAlertEmail(username, ip, device, loginTime string) (subject, body stri
         </div>
         <div style="background: #ffebee; border-radius: 8px; padding: 15px; margin: 20px 0;">
             <p style="margin: 0; color: #c62828; font-size: 13px;">
-                ⚠️ 如非本人操作，请立即修改密码！
+                %s
             </p>
         </div>
-    `, username, loginTime, ip, device)
+    `, username, loginTime, ip, device, action)
 	body = fmt.Sprintf(baseTemplate, "#f44336", "#e53935", "🛡️", "安全提醒", content)
 	return
 }
@@ -236,6 +236,77 @@ func (c *Client) DeleteUser(userID string) error {
 	return nil
 }
 
+// Device Emby设备信息
+type Device struct {
+	Id               string `json:"Id"`
+	Name             string `json:"Name"`
+	AppName          string `json:"AppName"`
+	LastUserId       string `json:"LastUserId"`
+	LastUserName     string `json:"LastUserName"`
+	DateLastActivity string `json:"DateLastActivity"`
+}
+
+// GetDevices 获取设备列表，userID 不为空时只返回该用户最近使用的设备
+func (c *Client) GetDevices(userID string) ([]Device, error) {
+	apiUrl := fmt.Sprintf("%s/Devices", c.ServerURL)
+	if userID != "" {
+		apiUrl += "?UserId=" + url.QueryEscape(userID)
+	}
+
+	req, err := http.NewRequest("GET", apiUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求Emby服务器失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Emby服务器返回错误: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Items []Device `json:"Items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	return result.Items, nil
+}
+
+// DeleteDevice 删除设备（该设备上的登录随之失效）
+func (c *Client) DeleteDevice(deviceID string) error {
+	apiUrl := fmt.Sprintf("%s/Devices?Id=%s", c.ServerURL, url.QueryEscape(deviceID))
+
+	req, err := http.NewRequest("DELETE", apiUrl, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求Emby服务器失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("删除设备失败: %d - %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
 // ========== 媒体库相关API ==========
 
 // MediaLibrary 媒体库信息
@@ -499,8 +570,27 @@ func (c *Client) GetImageURL(itemId string, imageType string, tag string) string
 	return fmt.Sprintf("%s/Items/%s/Images/%s?tag=%s", c.ServerURL, itemId, imageType, tag)
 }
 
+// RestrictedPolicyOverrides VIP到期/撤销后的受限策略（禁止播放和下载）
+var RestrictedPolicyOverrides = map[string]interface{}{
+	"EnableMediaPlayback":            false,
+	"EnableAudioPlaybackTranscoding": false,
+	"EnableVideoPlaybackTranscoding": false,
+	"EnablePlaybackRemuxing":         false,
+	"EnableLiveTvAccess":             false,
+}
+
 // SetUserPolicy 设置Emby用户权限策略（受限普通用户）
 func (c *Client) SetUserPolicy(userID string) error {
+	return c.SetUserPolicyWithOverrides(userID, nil)
+}
+
+// SetUserRestrictedPolicy 设置Emby用户为受限策略（VIP失效）
+func (c *Client) SetUserRestrictedPolicy(userID string) error {
+	return c.SetUserPolicyWithOverrides(userID, RestrictedPolicyOverrides)
+}
+
+// SetUserPolicyWithOverrides 在默认策略基础上覆盖部分字段后设置Emby用户权限策略
+func (c *Client) SetUserPolicyWithOverrides(userID string, overrides map[string]interface{}) error {
 	url := fmt.Sprintf("%s/Users/%s/Policy", c.ServerURL, userID)
 
 	// 受限普通用户权限配置（与test用户相同）
@@ -535,6 +625,9 @@ func (c *Client) SetUserPolicy(userID string) error {
 		"SimultaneousStreamLimit":         0, // 无并发限制
 		"RemoteClientBitrateLimit":        0, // 无码率限制
 	}
+	for key, value := range overrides {
+		policy[key] = value
+	}
 
 	bodyBytes, err := json.Marshal(policy)
 	if err != nil {
@@ -562,3 +655,21 @@ func (c *Client) SetUserPolicy(userID string) error {
 
 	return nil
 }
+
+// SetUserTierPolicy 按VIP等级权益设置Emby用户权限策略
+func (c *Client) SetUserTierPolicy(userID string, tier *model.VipTier) error {
+	overrides := map[string]interface{}{
+		"SimultaneousStreamLimit":  tier.StreamLimit,
+		"RemoteClientBitrateLimit": tier.BitrateLimit * 1000, // kbps -> bps
+	}
+	if !tier.AllowPlayback {
+		for key, value := range RestrictedPolicyOverrides {
+			overrides[key] = value
+		}
+	}
+	if libraries := tier.Libraries(); len(libraries) > 0 {
+		overrides["EnableAllFolders"] = false
+		overrides["EnabledFolders"] = libraries
+	}
+	return c.SetUserPolicyWithOverrides(userID, overrides)
+}
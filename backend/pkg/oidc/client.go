@@ -0,0 +1,369 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config 依赖方（本系统）在身份提供方注册的客户端信息
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// IDToken 已校验的ID令牌中本系统使用的声明
+type IDToken struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+// 缓存配置
+const (
+	discoveryTTL     = time.Hour        // 发现文档缓存时长
+	keysTTL          = time.Hour        // 签名公钥缓存时长
+	keysMinRefresh   = time.Minute      // 遇到未知 kid 时两次刷新公钥的最小间隔
+	requestTimeout   = 10 * time.Second // 请求身份提供方的超时时间
+	maxResponseBytes = 1 << 20
+)
+
+// 支持的ID令牌签名算法（不接受 none 和对称算法）
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// metadata 发现文档中本系统使用的字段
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// providerCache 同一身份提供方的发现文档和签名公钥（多个 Client 共享）
+type providerCache struct {
+	mu          sync.Mutex
+	meta        *metadata
+	metaFetched time.Time
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+var (
+	cachesMu sync.Mutex
+	caches   = make(map[string]*providerCache)
+)
+
+func cacheFor(issuer string) *providerCache {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	c, ok := caches[issuer]
+	if !ok {
+		c = &providerCache{}
+		caches[issuer] = c
+	}
+	return c
+}
+
+// Client OIDC 依赖方客户端：授权码模式 + PKCE
+type Client struct {
+	cfg    Config
+	cache  *providerCache
+	client *http.Client
+}
+
+// NewClient 创建OIDC客户端，发现文档和签名公钥在首次使用时获取
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:    cfg,
+		cache:  cacheFor(cfg.Issuer),
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// CodeChallenge 计算 PKCE S256 code_challenge
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址
+func (c *Client) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	meta, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+	scopes := c.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.cfg.ClientID)
+	params.Set("redirect_uri", c.cfg.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange 使用授权码换取令牌，校验ID令牌（签名、签发方、受众、有效期和 nonce）后返回其声明
+func (c *Client) Exchange(code, codeVerifier, nonce string) (*IDToken, error) {
+	meta, err := c.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	// 身份提供方声明支持 client_secret_basic（或未声明，默认即为该方式）时优先使用
+	useBasic := len(meta.TokenAuthMethods) == 0 || contains(meta.TokenAuthMethods, "client_secret_basic")
+	if !useBasic {
+		form.Set("client_id", c.cfg.ClientID)
+		if c.cfg.ClientSecret != "" {
+			form.Set("client_secret", c.cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequest("POST", meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := c.doJSON(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("换取令牌失败: %d %s %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("身份提供方未返回ID令牌")
+	}
+	return c.verifyIDToken(token.IDToken, meta.Issuer, nonce)
+}
+
+// idTokenClaims ID令牌声明
+type idTokenClaims struct {
+	Nonce             string    `json:"nonce"`
+	AuthorizedParty   string    `json:"azp"`
+	Email             string    `json:"email"`
+	EmailVerified     boolClaim `json:"email_verified"`
+	PreferredUsername string    `json:"preferred_username"`
+	Name              string    `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// boolClaim 兼容部分身份提供方以字符串形式返回的布尔声明
+type boolClaim bool
+
+func (b *boolClaim) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = boolClaim(s == "true")
+	return nil
+}
+
+// verifyIDToken 校验ID令牌
+func (c *Client) verifyIDToken(raw, issuer, nonce string) (*IDToken, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, c.keyFunc,
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌校验失败: %w", err)
+	}
+	// 存在多个受众时必须携带 azp，且 azp 必须是本客户端
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != c.cfg.ClientID {
+		return nil, errors.New("ID令牌校验失败: azp 不匹配")
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("ID令牌校验失败: nonce 不匹配")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("ID令牌校验失败: 缺少 sub")
+	}
+
+	return &IDToken{
+		Subject:           claims.Subject,
+		Email:             strings.TrimSpace(claims.Email),
+		EmailVerified:     bool(claims.EmailVerified),
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+	}, nil
+}
+
+// keyFunc 按 kid 查找签名公钥，遇到未知 kid 时刷新公钥（身份提供方轮换密钥）
+func (c *Client) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := c.lookupKey(kid, false); ok {
+		return key, nil
+	}
+	if key, ok := c.lookupKey(kid, true); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未找到签名公钥: kid=%s", kid)
+}
+
+// lookupKey 查找签名公钥，refresh 为 true 时在允许的频率内重新获取公钥
+// 令牌未携带 kid 时，身份提供方只有一个公钥才能使用
+func (c *Client) lookupKey(kid string, refresh bool) (interface{}, bool) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	stale := time.Since(c.cache.keysFetched) > keysTTL
+	if c.cache.keys == nil || stale || (refresh && time.Since(c.cache.keysFetched) > keysMinRefresh) {
+		if err := c.fetchKeysLocked(); err != nil && c.cache.keys == nil {
+			return nil, false
+		}
+	}
+	if kid == "" {
+		if len(c.cache.keys) == 1 {
+			for _, key := range c.cache.keys {
+				return key, true
+			}
+		}
+		return nil, false
+	}
+	key, ok := c.cache.keys[kid]
+	return key, ok
+}
+
+// fetchKeysLocked 获取身份提供方的签名公钥（调用方需持有锁）
+func (c *Client) fetchKeysLocked() error {
+	meta, err := c.discoverLocked()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("GET", meta.JWKSURI, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var set jwkSet
+	status, err := c.doJSON(req, &set)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("获取签名公钥失败: %d", status)
+	}
+	c.cache.keys = set.publicKeys()
+	c.cache.keysFetched = time.Now()
+	return nil
+}
+
+// discover 获取发现文档（带缓存）
+func (c *Client) discover() (*metadata, error) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	return c.discoverLocked()
+}
+
+func (c *Client) discoverLocked() (*metadata, error) {
+	if c.cache.meta != nil && time.Since(c.cache.metaFetched) < discoveryTTL {
+		return c.cache.meta, nil
+	}
+
+	wellKnown := strings.TrimSuffix(c.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest("GET", wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var meta metadata
+	status, err := c.doJSON(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("获取发现文档失败: %d", status)
+	}
+	if meta.Issuer != c.cfg.Issuer {
+		return nil, fmt.Errorf("发现文档中的 issuer 不匹配: %s", meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("发现文档缺少必要的端点")
+	}
+
+	c.cache.meta = &meta
+	c.cache.metaFetched = time.Now()
+	return &meta, nil
+}
+
+// doJSON 发送请求并解析JSON响应，返回HTTP状态码
+func (c *Client) doJSON(req *http.Request, out interface{}) (int, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("请求身份提供方失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("解析响应失败: %d - %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	return resp.StatusCode, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"embyhub/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "embyhub"
+	testSecret      = "client-secret"
+	testRedirectURL = "https://site.example.com/oidc/callback"
+)
+
+var testIdentity = oidctest.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true, PreferredUsername: "alice"}
+
+func newTestClient(p *oidctest.Provider) *Client {
+	return NewClient(Config{
+		Issuer:       p.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testSecret,
+		RedirectURL:  testRedirectURL,
+	})
+}
+
+// login 走一遍授权流程：生成授权地址，在身份提供方登录后用授权码换取ID令牌
+func login(t *testing.T, p *oidctest.Provider, c *Client, identity oidctest.Identity) (*IDToken, error) {
+	t.Helper()
+	authURL, err := c.AuthCodeURL("state", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+	if err != nil {
+		t.Fatalf("生成授权地址失败: %v", err)
+	}
+	code, err := p.Authorize(authURL, identity)
+	if err != nil {
+		t.Fatalf("身份提供方授权失败: %v", err)
+	}
+	return c.Exchange(code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+}
+
+func TestDiscoveryAndAuthCodeURL(t *testing.T) {
+	p := oidctest.NewProvider(t, testClientID, testSecret)
+	c := newTestClient(p)
+
+	authURL, err := c.AuthCodeURL("state-1", "nonce-1", "verifier")
+	if err != nil {
+		t.Fatalf("生成授权地址失败: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	if u.Scheme+"://"+u.Host+u.Path != p.Issuer()+"/authorize" {
+		t.Fatalf("授权端点应来自发现文档: %s", authURL)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid profile email",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if q.Get(key) != value {
+			t.Fatalf("授权参数 %s = %q，应为 %q", key, q.Get(key), value)
+		}
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"issuer":"https://evil.example.com","authorization_endpoint":"https://evil.example.com/a","token_endpoint":"https://evil.example.com/t","jwks_uri":"https://evil.example.com/k"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{Issuer: server.URL, ClientID: testClientID, RedirectURL: testRedirectURL})
+	if _, err := c.AuthCodeURL("state", "nonce", "verifier"); err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Fatalf("发现文档 issuer 不匹配时应失败: %v", err)
+	}
+}
+
+func TestExchangePKCE(t *testing.T) {
+	p := oidctest.NewProvider(t, testClientID, testSecret)
+	c := newTestClient(p)
+
+	token, err := login(t, p, c, testIdentity)
+	if err != nil {
+		t.Fatalf("换取令牌失败: %v", err)
+	}
+	if token.Subject != "sub-1" || token.Email != "alice@example.com" || !token.EmailVerified || token.PreferredUsername != "alice" {
+		t.Fatalf("ID令牌声明不符: %+v", token)
+	}
+
+	// code_verifier 与授权请求中的 code_challenge 不符
+	authURL, _ := c.AuthCodeURL("state", "nonce-1", "verifier-a")
+	code, _ := p.Authorize(authURL, testIdentity)
+	if _, err := c.Exchange(code, "verifier-b", "nonce-1"); err == nil {
+		t.Fatal("PKCE 校验失败时不应换取到令牌")
+	}
+
+	// 授权码只能使用一次
+	authURL, _ = c.AuthCodeURL("state", "nonce-1", "verifier-a")
+	code, _ = p.Authorize(authURL, testIdentity)
+	if _, err := c.Exchange(code, "verifier-a", "nonce-1"); err != nil {
+		t.Fatalf("换取令牌失败: %v", err)
+	}
+	if _, err := c.Exchange(code, "verifier-a", "nonce-1"); err == nil {
+		t.Fatal("重复使用的授权码不应换取到令牌")
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	p := oidctest.NewProvider(t, testClientID, testSecret)
+	c := newTestClient(p)
+
+	if _, err := login(t, p, c, testIdentity); err != nil {
+		t.Fatalf("换取令牌失败: %v", err)
+	}
+	if p.JWKSHits() != 1 {
+		t.Fatalf("首次校验应获取一次公钥: %d", p.JWKSHits())
+	}
+
+	// 身份提供方轮换密钥：超过最小刷新间隔后遇到新 kid 会重新获取公钥
+	if err := p.RotateKey("key-2", false); err != nil {
+		t.Fatalf("轮换密钥失败: %v", err)
+	}
+	c.cache.mu.Lock()
+	c.cache.keysFetched = time.Now().Add(-2 * keysMinRefresh)
+	c.cache.mu.Unlock()
+
+	if _, err := login(t, p, c, testIdentity); err != nil {
+		t.Fatalf("密钥轮换后校验失败: %v", err)
+	}
+	if p.JWKSHits() != 2 {
+		t.Fatalf("遇到新 kid 应重新获取公钥: %d", p.JWKSHits())
+	}
+}
+
+func TestUnknownKid(t *testing.T) {
+	p := oidctest.NewProvider(t, testClientID, testSecret)
+	c := newTestClient(p)
+
+	if _, err := login(t, p, c, testIdentity); err != nil {
+		t.Fatalf("换取令牌失败: %v", err)
+	}
+	if err := p.SignWithUnpublishedKey("rogue"); err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+
+	// 刚获取过公钥，最小刷新间隔内遇到未知 kid 不会再次请求 JWKS
+	for i := 0; i < 3; i++ {
+		if _, err := login(t, p, c, testIdentity); err == nil || !strings.Contains(err.Error(), "kid=rogue") {
+			t.Fatalf("未知 kid 应校验失败: %v", err)
+		}
+	}
+	if p.JWKSHits() != 1 {
+		t.Fatalf("未知 kid 不应频繁刷新公钥: %d", p.JWKSHits())
+	}
+
+	// 超过最小刷新间隔后会刷新一次，刷新后仍未找到则失败
+	c.cache.mu.Lock()
+	c.cache.keysFetched = time.Now().Add(-2 * keysMinRefresh)
+	c.cache.mu.Unlock()
+	if _, err := login(t, p, c, testIdentity); err == nil {
+		t.Fatal("未发布的 kid 应校验失败")
+	}
+	if p.JWKSHits() != 2 {
+		t.Fatalf("超过最小刷新间隔后应刷新一次公钥: %d", p.JWKSHits())
+	}
+}
+
+func TestIDTokenClaimValidation(t *testing.T) {
+	cases := map[string]func(claims jwt.MapClaims){
+		"签发方不符": func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+		"受众不符":  func(claims jwt.MapClaims) { claims["aud"] = "other-client" },
+		"多受众缺少azp": func(claims jwt.MapClaims) {
+			claims["aud"] = []string{testClientID, "other-client"}
+		},
+		"azp不符":   func(claims jwt.MapClaims) { claims["azp"] = "other-client" },
+		"nonce不符": func(claims jwt.MapClaims) { claims["nonce"] = "nonce-2" },
+		"缺少nonce": func(claims jwt.MapClaims) { delete(claims, "nonce") },
+		"已过期": func(claims jwt.MapClaims) {
+			claims["iat"] = time.Now().Add(-time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		},
+		"缺少exp":   func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"签发时间在未来": func(claims jwt.MapClaims) { claims["iat"] = time.Now().Add(time.Hour).Unix() },
+		"缺少sub":   func(claims jwt.MapClaims) { delete(claims, "sub") },
+	}
+
+	p := oidctest.NewProvider(t, testClientID, testSecret)
+	c := newTestClient(p)
+	for name, mutate := range cases {
+		p.Mutate(mutate)
+		if _, err := login(t, p, c, testIdentity); err == nil {
+			t.Fatalf("%s: ID令牌应校验失败", name)
+		}
+	}
+
+	// 在允许的时钟偏差内过期的令牌仍然有效
+	p.Mutate(func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-30 * time.Second).Unix() })
+	if _, err := login(t, p, c, testIdentity); err != nil {
+		t.Fatalf("时钟偏差内的令牌应有效: %v", err)
+	}
+
+	// 多受众且 azp 为本客户端时有效
+	p.Mutate(func(claims jwt.MapClaims) {
+		claims["aud"] = []string{testClientID, "other-client"}
+		claims["azp"] = testClientID
+	})
+	if _, err := login(t, p, c, testIdentity); err != nil {
+		t.Fatalf("azp 为本客户端的多受众令牌应有效: %v", err)
+	}
+}
+
+func TestExchangeNonceRequired(t *testing.T) {
+	p := oidctest.NewProvider(t, testClientID, testSecret)
+	c := newTestClient(p)
+
+	authURL, _ := c.AuthCodeURL("state", "", "verifier")
+	code, _ := p.Authorize(authURL, testIdentity)
+	if _, err := c.Exchange(code, "verifier", ""); err == nil {
+		t.Fatal("未使用 nonce 的登录应校验失败")
+	}
+}
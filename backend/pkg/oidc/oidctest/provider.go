@@ -0,0 +1,249 @@
+// Package oidctest 提供用于测试的本地OIDC身份提供方
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Identity 登录身份提供方的用户
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+// authRequest 已授权、等待换取令牌的授权码
+type authRequest struct {
+	identity    Identity
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// Provider 本地OIDC身份提供方：发现文档、JWKS、授权码 + PKCE 换取RS256签名的ID令牌
+type Provider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey // 已发布的签名密钥
+	signKid  string                     // 当前签名使用的 kid
+	signKey  *rsa.PrivateKey            // 当前签名使用的密钥（可能未发布）
+	codes    map[string]*authRequest
+	jwksHits int
+	mutate   func(claims jwt.MapClaims) // 签发前修改声明，用于构造无效令牌
+}
+
+// NewProvider 启动身份提供方，测试结束时自动关闭
+func NewProvider(t testing.TB, clientID, clientSecret string) *Provider {
+	t.Helper()
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		keys:         make(map[string]*rsa.PrivateKey),
+		codes:        make(map[string]*authRequest),
+	}
+	if err := p.RotateKey("key-1", false); err != nil {
+		t.Fatalf("生成签名密钥失败: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Issuer 签发方
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// RotateKey 生成新的签名密钥并开始用它签发令牌，keepOld 为 false 时不再发布旧密钥
+func (p *Provider) RotateKey(kid string, keepOld bool) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !keepOld {
+		p.keys = make(map[string]*rsa.PrivateKey)
+	}
+	p.keys[kid] = key
+	p.signKid, p.signKey = kid, key
+	return nil
+}
+
+// SignWithUnpublishedKey 改用不在 JWKS 中发布的密钥签名，用于模拟未知 kid；再次 RotateKey 后恢复
+func (p *Provider) SignWithUnpublishedKey(kid string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signKid, p.signKey = kid, key
+	return nil
+}
+
+// Mutate 设置签发前对ID令牌声明的修改，传入 nil 恢复正常签发
+func (p *Provider) Mutate(fn func(claims jwt.MapClaims)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mutate = fn
+}
+
+// JWKSHits JWKS 被请求的次数
+func (p *Provider) JWKSHits() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.jwksHits
+}
+
+// Authorize 模拟用户在身份提供方完成登录：校验授权地址参数后返回授权码
+func (p *Provider) Authorize(authURL string, identity Identity) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != p.ClientID {
+		return "", fmt.Errorf("授权请求无效: %s", authURL)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		return "", fmt.Errorf("授权请求缺少 PKCE: %s", authURL)
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = &authRequest{
+		identity:    identity,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+	return code, nil
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jwksHits++
+
+	keys := make([]map[string]string, 0, len(p.keys))
+	for kid, key := range p.keys {
+		keys = append(keys, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != p.ClientID || secret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	r.ParseForm()
+
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code")) // 授权码只能使用一次
+	p.mu.Unlock()
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != req.redirectURI {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer(),
+		"sub":            req.identity.Subject,
+		"aud":            p.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          req.nonce,
+		"email":          req.identity.Email,
+		"email_verified": req.identity.EmailVerified,
+	}
+	if req.identity.PreferredUsername != "" {
+		claims["preferred_username"] = req.identity.PreferredUsername
+	}
+
+	p.mu.Lock()
+	if p.mutate != nil {
+		p.mutate(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.signKid
+	key := p.signKey
+	p.mu.Unlock()
+
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,168 @@
+package payment
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EPayGatewayName 易支付网关名称
+const EPayGatewayName = "epay"
+
+// EPayGateway 易支付（彩虹易支付等兼容平台）网关
+// 下单跳转 submit.php，异步通知按 MD5 签名校验，退款调用 api.php?act=refund
+type EPayGateway struct {
+	apiURL string
+	pid    string
+	key    string
+	client *http.Client
+}
+
+// NewEPayGateway 创建易支付网关，apiURL 为平台地址（如 https://pay.example.com）
+func NewEPayGateway(apiURL, pid, key string) *EPayGateway {
+	return &EPayGateway{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		pid:    pid,
+		key:    key,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name 网关名称
+func (g *EPayGateway) Name() string {
+	return EPayGatewayName
+}
+
+// CreatePayment 生成支付跳转地址，回调地址必须是支付平台可访问的完整URL
+func (g *EPayGateway) CreatePayment(req *CreateRequest) (*CreateResult, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("支付金额无效: %d", req.Amount)
+	}
+	if !strings.HasPrefix(req.NotifyURL, "http://") && !strings.HasPrefix(req.NotifyURL, "https://") {
+		return nil, errors.New("回调地址必须是完整URL，请先配置站点访问地址")
+	}
+
+	params := url.Values{}
+	params.Set("pid", g.pid)
+	params.Set("out_trade_no", req.OrderNo)
+	params.Set("notify_url", req.NotifyURL)
+	params.Set("name", req.Subject)
+	params.Set("money", formatYuan(req.Amount))
+	if req.ReturnURL != "" {
+		params.Set("return_url", req.ReturnURL)
+	}
+	params.Set("sign", g.sign(params))
+	params.Set("sign_type", "MD5")
+
+	return &CreateResult{PaymentURL: g.apiURL + "/submit.php?" + params.Encode()}, nil
+}
+
+// VerifyCallback 校验异步通知签名，trade_status 为 TRADE_SUCCESS 时视为已支付
+func (g *EPayGateway) VerifyCallback(params url.Values) (*Notification, error) {
+	sign := params.Get("sign")
+	if sign == "" || subtle.ConstantTimeCompare([]byte(strings.ToLower(sign)), []byte(g.sign(params))) != 1 {
+		return nil, ErrInvalidSignature
+	}
+	if params.Get("pid") != g.pid {
+		return nil, ErrInvalidSignature
+	}
+
+	amount, err := parseYuan(params.Get("money"))
+	if err != nil {
+		return nil, fmt.Errorf("回调金额无效: %w", err)
+	}
+	return &Notification{
+		OrderNo: params.Get("out_trade_no"),
+		TradeNo: params.Get("trade_no"),
+		Amount:  amount,
+		Paid:    params.Get("trade_status") == "TRADE_SUCCESS",
+	}, nil
+}
+
+// Refund 原路退款
+func (g *EPayGateway) Refund(orderNo, tradeNo string, amount int64) error {
+	form := url.Values{}
+	form.Set("pid", g.pid)
+	form.Set("key", g.key)
+	form.Set("money", formatYuan(amount))
+	if tradeNo != "" {
+		form.Set("trade_no", tradeNo)
+	} else {
+		form.Set("out_trade_no", orderNo)
+	}
+
+	resp, err := g.client.PostForm(g.apiURL+"/api.php?act=refund", form)
+	if err != nil {
+		return fmt.Errorf("请求支付平台失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析退款结果失败: %w", err)
+	}
+	if result.Code != 1 {
+		return fmt.Errorf("支付平台拒绝退款: %s", result.Msg)
+	}
+	return nil
+}
+
+// sign 对除 sign、sign_type 和空值外的参数按键名排序拼接后追加密钥计算 MD5
+func (g *EPayGateway) sign(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key != "sign" && key != "sign_type" && params.Get(key) != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(params.Get(key))
+	}
+	sb.WriteString(g.key)
+
+	sum := md5.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// formatYuan 分转元（保留两位小数）
+func formatYuan(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// parseYuan 元转分，最多两位小数
+func parseYuan(s string) (int64, error) {
+	yuan, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("金额格式错误: %s", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	y, err := strconv.ParseInt(yuan, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("金额格式错误: %s", s)
+	}
+	f, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || y < 0 || f < 0 {
+		return 0, fmt.Errorf("金额格式错误: %s", s)
+	}
+	return y*100 + f, nil
+}
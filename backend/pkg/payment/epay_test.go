@@ -0,0 +1,165 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// signedEPayCallback 生成易支付异步通知参数
+func signedEPayCallback(g *EPayGateway, money, status string) url.Values {
+	params := url.Values{}
+	params.Set("pid", g.pid)
+	params.Set("trade_no", "2024010112345")
+	params.Set("out_trade_no", "20240101000000123456")
+	params.Set("type", "alipay")
+	params.Set("name", "月卡")
+	params.Set("money", money)
+	params.Set("trade_status", status)
+	params.Set("sign", g.sign(params))
+	params.Set("sign_type", "MD5")
+	return params
+}
+
+func TestEPayVerifyCallback(t *testing.T) {
+	g := NewEPayGateway("https://pay.example.com", "1001", "secret")
+
+	notify, err := g.VerifyCallback(signedEPayCallback(g, "15.00", "TRADE_SUCCESS"))
+	if err != nil {
+		t.Fatalf("有效回调验签失败: %v", err)
+	}
+	if notify.OrderNo != "20240101000000123456" || notify.TradeNo != "2024010112345" || notify.Amount != 1500 || !notify.Paid {
+		t.Fatalf("回调解析不符: %+v", notify)
+	}
+
+	unpaid, err := g.VerifyCallback(signedEPayCallback(g, "15.00", "WAIT_BUYER_PAY"))
+	if err != nil || unpaid.Paid {
+		t.Fatalf("未支付回调应验签通过且未支付: %+v %v", unpaid, err)
+	}
+
+	// 大写签名同样有效
+	upper := signedEPayCallback(g, "15.00", "TRADE_SUCCESS")
+	upper.Set("sign", strings.ToUpper(upper.Get("sign")))
+	if _, err := g.VerifyCallback(upper); err != nil {
+		t.Fatalf("大写签名验签失败: %v", err)
+	}
+}
+
+func TestEPayVerifyCallbackInvalid(t *testing.T) {
+	g := NewEPayGateway("https://pay.example.com", "1001", "secret")
+
+	tampered := signedEPayCallback(g, "15.00", "TRADE_SUCCESS")
+	tampered.Set("money", "0.01")
+
+	missing := signedEPayCallback(g, "15.00", "TRADE_SUCCESS")
+	missing.Del("sign")
+
+	other := NewEPayGateway("https://pay.example.com", "1001", "other")
+	forged := signedEPayCallback(other, "15.00", "TRADE_SUCCESS")
+
+	otherPid := NewEPayGateway("https://pay.example.com", "1002", "secret")
+	wrongPid := signedEPayCallback(otherPid, "15.00", "TRADE_SUCCESS")
+
+	cases := map[string]url.Values{
+		"篡改金额": tampered,
+		"缺少签名": missing,
+		"错误密钥": forged,
+		"商户不符": wrongPid,
+	}
+	for name, params := range cases {
+		if _, err := g.VerifyCallback(params); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%s: 应返回签名无效，实际 %v", name, err)
+		}
+	}
+
+	if _, err := g.VerifyCallback(signedEPayCallback(g, "15.001", "TRADE_SUCCESS")); err == nil {
+		t.Fatal("超过两位小数的金额应校验失败")
+	}
+}
+
+func TestEPayCreatePayment(t *testing.T) {
+	g := NewEPayGateway("https://pay.example.com/", "1001", "secret")
+
+	if _, err := g.CreatePayment(&CreateRequest{OrderNo: "1", Amount: 100, NotifyURL: "/api/payments/callback/epay"}); err == nil {
+		t.Fatal("相对回调地址应被拒绝")
+	}
+
+	result, err := g.CreatePayment(&CreateRequest{
+		OrderNo:   "20240101000000123456",
+		Amount:    1505,
+		Subject:   "月卡",
+		NotifyURL: "https://site.example.com/api/payments/callback/epay",
+		ReturnURL: "https://site.example.com/",
+	})
+	if err != nil {
+		t.Fatalf("创建支付失败: %v", err)
+	}
+	u, err := url.Parse(result.PaymentURL)
+	if err != nil || u.Host != "pay.example.com" || u.Path != "/submit.php" {
+		t.Fatalf("支付地址不符: %s", result.PaymentURL)
+	}
+	params := u.Query()
+	if params.Get("money") != "15.05" || params.Get("pid") != "1001" {
+		t.Fatalf("支付参数不符: %v", params)
+	}
+	if params.Get("sign") != g.sign(params) {
+		t.Fatal("支付参数签名不符")
+	}
+}
+
+func TestEPayRefund(t *testing.T) {
+	var got url.Values
+	code, msg := 1, "退款成功"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api.php" || r.URL.Query().Get("act") != "refund" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		r.ParseForm()
+		got = r.PostForm
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": msg})
+	}))
+	defer server.Close()
+
+	g := NewEPayGateway(server.URL, "1001", "secret")
+	if err := g.Refund("20240101000000123456", "2024010112345", 1500); err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	if got.Get("pid") != "1001" || got.Get("key") != "secret" || got.Get("trade_no") != "2024010112345" || got.Get("money") != "15.00" {
+		t.Fatalf("退款参数不符: %v", got)
+	}
+
+	// 没有平台交易号时按商户订单号退款
+	if err := g.Refund("20240101000000123456", "", 1500); err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	if got.Get("out_trade_no") != "20240101000000123456" || got.Get("trade_no") != "" {
+		t.Fatalf("退款参数不符: %v", got)
+	}
+
+	code, msg = -1, "余额不足"
+	if err := g.Refund("20240101000000123456", "2024010112345", 1500); err == nil || !strings.Contains(err.Error(), "余额不足") {
+		t.Fatalf("平台拒绝退款时应返回错误: %v", err)
+	}
+}
+
+func TestParseYuan(t *testing.T) {
+	cases := map[string]int64{"15": 1500, "15.5": 1550, "15.05": 1505, "0.01": 1}
+	for s, want := range cases {
+		if got, err := parseYuan(s); err != nil || got != want {
+			t.Fatalf("parseYuan(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "abc", "1.234", "-1.00", "1.-5"} {
+		if _, err := parseYuan(s); err == nil {
+			t.Fatalf("parseYuan(%q) 应返回错误", s)
+		}
+	}
+	if formatYuan(1505) != "15.05" || formatYuan(7) != "0.07" {
+		t.Fatal("formatYuan 结果不符")
+	}
+}
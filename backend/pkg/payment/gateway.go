@@ -0,0 +1,77 @@
+package payment
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+)
+
+// CreateRequest 创建支付请求
+type CreateRequest struct {
+	OrderNo   string // 商户订单号
+	Amount    int64  // 金额（分）
+	Subject   string // 商品描述
+	NotifyURL string // 异步回调地址
+	ReturnURL string // 支付完成后跳转地址
+}
+
+// CreateResult 创建支付结果
+type CreateResult struct {
+	PaymentURL string // 支付跳转地址
+	TradeNo    string // 支付平台交易号（部分平台在回调时才返回）
+}
+
+// Notification 已验签的支付回调
+type Notification struct {
+	OrderNo string
+	TradeNo string
+	Amount  int64
+	Paid    bool
+}
+
+// Gateway 支付网关
+// 实现需保证 VerifyCallback 只在签名有效时返回通知
+type Gateway interface {
+	// Name 网关名称（用于回调路由和订单记录）
+	Name() string
+	// CreatePayment 创建支付
+	CreatePayment(req *CreateRequest) (*CreateResult, error)
+	// VerifyCallback 校验回调签名并解析回调参数
+	VerifyCallback(params url.Values) (*Notification, error)
+	// Refund 退款
+	Refund(orderNo, tradeNo string, amount int64) error
+}
+
+// ErrInvalidSignature 回调签名无效
+var ErrInvalidSignature = errors.New("支付回调签名无效")
+
+var (
+	mu       sync.RWMutex
+	gateways = make(map[string]Gateway)
+)
+
+// Register 注册支付网关（同名覆盖）
+func Register(g Gateway) {
+	mu.Lock()
+	defer mu.Unlock()
+	gateways[g.Name()] = g
+}
+
+// Get 获取支付网关
+func Get(name string) (Gateway, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	g, ok := gateways[name]
+	return g, ok
+}
+
+// Names 已注册的网关名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(gateways))
+	for name := range gateways {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MockGatewayName 本地模拟网关名称
+const MockGatewayName = "mock"
+
+// MockGateway 本地模拟支付网关
+// 不访问任何外部服务：CreatePayment 返回本地模拟支付地址，
+// SignCallback 生成与真实平台相同形式的签名回调，用于在本地走通完整支付流程
+type MockGateway struct {
+	secret []byte
+}
+
+// NewMockGateway 创建模拟网关
+func NewMockGateway(secret string) *MockGateway {
+	return &MockGateway{secret: []byte(secret)}
+}
+
+// Name 网关名称
+func (g *MockGateway) Name() string {
+	return MockGatewayName
+}
+
+// CreatePayment 创建模拟支付
+func (g *MockGateway) CreatePayment(req *CreateRequest) (*CreateResult, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("支付金额无效: %d", req.Amount)
+	}
+	return &CreateResult{
+		PaymentURL: fmt.Sprintf("/api/orders/%s/mock-pay", url.PathEscape(req.OrderNo)),
+	}, nil
+}
+
+// SignCallback 生成已签名的支付成功回调参数
+func (g *MockGateway) SignCallback(orderNo string, amount int64) url.Values {
+	params := url.Values{}
+	params.Set("order_no", orderNo)
+	params.Set("trade_no", fmt.Sprintf("MOCK%d", time.Now().UnixNano()))
+	params.Set("amount", strconv.FormatInt(amount, 10))
+	params.Set("status", "paid")
+	params.Set("sign", g.sign(params))
+	return params
+}
+
+// VerifyCallback 校验回调签名
+func (g *MockGateway) VerifyCallback(params url.Values) (*Notification, error) {
+	sign := params.Get("sign")
+	if sign == "" || !hmac.Equal([]byte(sign), []byte(g.sign(params))) {
+		return nil, ErrInvalidSignature
+	}
+
+	amount, err := strconv.ParseInt(params.Get("amount"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("回调金额无效: %w", err)
+	}
+	return &Notification{
+		OrderNo: params.Get("order_no"),
+		TradeNo: params.Get("trade_no"),
+		Amount:  amount,
+		Paid:    params.Get("status") == "paid",
+	}, nil
+}
+
+// Refund 模拟退款（总是成功）
+func (g *MockGateway) Refund(orderNo, tradeNo string, amount int64) error {
+	return nil
+}
+
+// sign 对除 sign 外的参数按键名排序后计算 HMAC-SHA256
+func (g *MockGateway) sign(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key != "sign" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(params.Get(key))
+	}
+
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(sb.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
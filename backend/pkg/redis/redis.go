@@ -94,3 +94,46 @@ func ExistsKey(key string) (bool, error) {
 	result, err := Client.Exists(ctx, key).Result()
 	return result > 0, err
 }
+
+// SetNX 键不存在时设置值，返回是否设置成功
+func SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	return Client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// Publish 向频道发布消息
+func Publish(channel string, message interface{}) error {
+	return Client.Publish(ctx, channel, message).Err()
+}
+
+// SubscribeLoop 持续订阅频道，直到Redis连接关闭才返回
+// 订阅建立（含断线重连后）时调用 onSubscribe，收到消息时调用 onMessage
+func SubscribeLoop(channel string, onSubscribe func(), onMessage func(payload string)) {
+	pubsub := Client.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if err == redis.ErrClosed {
+				return
+			}
+			// Receive 出错后会在下次调用时自动重连
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if onSubscribe != nil {
+				onSubscribe()
+			}
+		case *redis.Message:
+			onMessage(m.Payload)
+		}
+	}
+}
+
+// IsNil 判断是否为键不存在错误
+func IsNil(err error) bool {
+	return err == redis.Nil
+}
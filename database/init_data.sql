@@ -2,12 +2,14 @@
 -- 默认角色、权限和管理员账号
 
 -- 插入默认角色
-INSERT INTO roles (role_name, description) VALUES
-('超级管理员', '拥有系统所有权限'),
-('普通管理员', '拥有用户管理和访问统计权限'),
-('访客管理员', '仅拥有查看权限');
+-- role_rank 为角色等级，管理员不能操作等级高于自己的用户
+INSERT INTO roles (role_name, description, role_rank) VALUES
+('超级管理员', '拥有系统所有权限', 100),
+('普通管理员', '拥有用户管理和访问统计权限', 50),
+('访客管理员', '仅拥有查看权限', 10),
+('代理商', '使用额度生成和管理自己的卡密', 5);
 
--- 插入默认权限
+-- 插入默认权限（服务启动时会按代码中的权限声明同步名称、描述和分组，并补齐缺失的权限）
 INSERT INTO permissions (permission_name, permission_key, description) VALUES
 -- 用户管理权限
 ('查看用户', 'user:view', '查看用户列表和详情'),
@@ -16,6 +18,7 @@ INSERT INTO permissions (permission_name, permission_key, description) VALUES
 ('删除用户', 'user:delete', '删除用户'),
 ('导入用户', 'user:import', '批量导入用户'),
 ('导出用户', 'user:export', '批量导出用户'),
+('管理用户组', 'group:manage', '管理用户组及组内授权'),
 
 -- 角色管理权限
 ('查看角色', 'role:view', '查看角色列表和详情'),
@@ -43,8 +46,36 @@ INSERT INTO permissions (permission_name, permission_key, description) VALUES
 -- 卡密管理权限
 ('查看卡密', 'cardkey:view', '查看卡密列表'),
 ('生成卡密', 'cardkey:create', '生成新卡密'),
+('编辑卡密', 'cardkey:edit', '禁用、启用卡密及撤销兑换'),
 ('删除卡密', 'cardkey:delete', '删除卡密'),
-('导出卡密', 'cardkey:export', '导出卡密列表');
+('导出卡密', 'cardkey:export', '导出卡密列表'),
+
+-- 代理商权限
+('代理商卡密', 'agent:card', '使用额度生成并管理自己的卡密'),
+('管理代理商', 'agent:manage', '为代理商充值额度并查看结算'),
+-- 订单权限
+('查看订单', 'order:view', '查看所有用户的订单'),
+('管理订单', 'order:manage', '管理套餐并处理订单退款'),
+-- 积分权限
+('管理积分', 'points:manage', '管理积分商城商品并调整用户积分'),
+-- 通配权限（* 匹配所有权限，模块:* 匹配该模块下所有权限）
+('全部权限', '*', '匹配所有权限'),
+('用户管理全部权限', 'user:*', '匹配所有用户管理权限'),
+('角色管理全部权限', 'role:*', '匹配所有角色管理权限'),
+('卡密管理全部权限', 'cardkey:*', '匹配所有卡密管理权限'),
+('订单管理全部权限', 'order:*', '匹配所有订单权限'),
+('全部查看权限', '*:view', '匹配所有模块的查看权限');
+
+-- 插入默认权限组
+INSERT INTO permission_groups (group_name, description) VALUES
+('卡密管理员', '卡密的查看、生成、删除和导出'),
+('只读', '所有模块的查看权限');
+
+INSERT INTO permission_group_items (group_id, permission_id)
+SELECT 1, permission_id FROM permissions WHERE permission_key = 'cardkey:*';
+
+INSERT INTO permission_group_items (group_id, permission_id)
+SELECT 2, permission_id FROM permissions WHERE permission_key = '*:view';
 
 -- 为超级管理员分配所有权限
 INSERT INTO role_permissions (role_id, permission_id)
@@ -67,22 +98,97 @@ WHERE permission_key IN (
     'system:view', 'stats:view', 'emby:view'
 );
 
+-- 为代理商分配代理商卡密权限
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT 4, permission_id FROM permissions
+WHERE permission_key IN ('agent:card');
+
 -- 插入默认超级管理员账号
 -- 用户名：admin
 -- 密码：Liubei00（bcrypt加密后的哈希值）
 INSERT INTO users (username, password_hash, email, role_id, status) VALUES
 ('admin', '$2a$10$NIDdLWXZi/0cv3yuQcoyjulmEOqynzjUQgsjtLxrWypD33wGClaX6', 'admin@embyhub.com', 1, 1);
 
+-- 插入默认VIP等级
+INSERT INTO vip_tiers (level, name, description, allow_playback, stream_limit, bitrate_limit, device_quota, request_quota, day_value, max_freeze_days, freezes_per_year) VALUES
+(0, '免费用户', 'VIP到期后的默认等级', FALSE, 1, 0, 1, 0, 0, 0, 0),
+(1, 'VIP会员', '标准VIP权益', TRUE, 2, 0, 3, 5, 100, 30, 1),
+(2, '高级VIP', '更多并发和求片额度', TRUE, 4, 0, 6, 20, 200, 90, 2);
+
+-- 插入默认套餐（价格单位：分）
+INSERT INTO plans (name, vip_level, days, price, sort_order) VALUES
+('VIP月卡', 1, 30, 1500, 1),
+('VIP年卡', 1, 365, 15000, 2),
+('高级VIP月卡', 2, 30, 2800, 3);
+
+-- 插入默认积分商品
+INSERT INTO points_items (name, description, item_type, cost, vip_level, amount, per_user_limit, sort_order) VALUES
+('VIP 1天', '积分直接兑换1天VIP', 'vip_days', 100, 1, 1, 0, 1),
+('VIP 7天邀请码', '生成一张7天VIP卡密，可赠送好友', 'invite_code', 600, 1, 7, 2, 2),
+('求片额度 +1', '增加1次求片额度', 'request_quota', 50, 1, 1, 0, 3);
+
 -- 插入默认系统配置
 INSERT INTO system_configs (config_key, config_value, description) VALUES
 ('emby_server_url', 'http://localhost:8096', 'Emby服务器地址'),
 ('emby_api_key', '', 'Emby API密钥'),
 ('emby_sync_interval', '3600', 'Emby数据同步周期（秒）'),
+('emby_device_quota_enforce', 'false', '是否按VIP等级设备数上限移除Emby中最久未活跃的设备（会删除真实设备并使其登录失效）'),
 ('jwt_secret', 'emby-ums-secret-key-change-in-production', 'JWT签名密钥'),
 ('jwt_expire_hours', '24', 'JWT Token过期时间（小时）'),
 ('password_min_length', '6', '密码最小长度'),
 ('log_retention_days', '30', '日志保留天数'),
-('session_timeout_minutes', '120', '管理员会话超时时间（分钟）');
+('session_timeout_minutes', '120', '管理员会话超时时间（分钟）'),
+('vip_reminder_stages', '7,3,1,0', 'VIP到期提醒阶段（到期前天数，逗号分隔，0=到期当天，留空关闭）'),
+('payment_gateway', 'epay', '默认支付网关（epay=易支付）'),
+('payment_epay_url', '', '易支付平台地址（如 https://pay.example.com），与商户ID、密钥均配置后启用，修改后重启生效'),
+('payment_epay_pid', '', '易支付商户ID'),
+('payment_epay_key', '', '易支付商户密钥'),
+('payment_mock_enabled', 'false', '是否启用本地模拟支付（任何用户都可免费完成支付，仅限开发和测试，修改后重启生效）'),
+('payment_mock_secret', '', '模拟支付回调签名密钥（留空则每次启动随机生成）'),
+('trial_days', '3', '注册试用VIP天数（0=关闭试用）'),
+('trial_vip_level', '1', '注册试用VIP等级'),
+('trial_require_verified_email', 'true', '注册试用是否要求邮箱已验证'),
+('trial_ip_window_days', '30', '同一IP领取试用的间隔天数（0=不限制）'),
+('trial_blocked_email_domains', '', '禁止试用的邮箱域名（逗号分隔，支持通配符如 *.temp-mail.org）'),
+('trial_reminder_hours', '24', '试用结束前多少小时发送续费提醒（0=不提醒）'),
+('referral_enabled', 'true', '是否开启推荐奖励'),
+('referral_referrer_days', '7', '每次成功推荐奖励推荐人的VIP天数'),
+('referral_referee_days', '3', '被推荐人获得的VIP天数'),
+('referral_vip_level', '1', '推荐奖励的VIP等级'),
+('referral_reward_on_card', 'true', '被推荐人首次兑换卡密时发放奖励'),
+('referral_active_days', '7', '被推荐人注册满N天且有访问记录时发放奖励（0=关闭）'),
+('referral_ip_window_days', '30', '推荐人近N天使用过相同IP时视为自我推荐'),
+('referral_monthly_limit', '0', '推荐人每月最多获得奖励次数（0=不限制）'),
+('vip_transfer_enabled', 'true', '是否允许用户之间转赠VIP天数'),
+('vip_transfer_min_remaining_days', '7', '转赠后转出方至少保留的VIP天数'),
+('vip_transfer_cooldown_hours', '24', '两次转赠之间的间隔（小时，0=不限制）'),
+('vip_transfer_yearly_days', '90', '每个自然年最多转出的天数（0=不限制）'),
+('checkin_base_points', '10', '每日签到基础积分'),
+('checkin_streak_bonus', '2', '连续签到每多一天额外增加的积分'),
+('checkin_streak_max_bonus', '20', '连续签到额外积分上限'),
+('max_sessions_per_user', '5', '每个用户最多同时登录的设备数（超出时最久未活跃的会话被下线）'),
+('access_token_minutes', '15', '访问令牌有效期（分钟），过期后客户端使用刷新令牌换取新令牌'),
+('oidc_enabled', 'false', '是否启用单点登录（OIDC）'),
+('oidc_provider_name', '', '登录按钮上显示的身份提供方名称'),
+('oidc_issuer', '', '身份提供方地址（issuer），用于获取 /.well-known/openid-configuration'),
+('oidc_client_id', '', '在身份提供方注册的客户端ID'),
+('oidc_client_secret', '', '在身份提供方注册的客户端密钥'),
+('oidc_redirect_url', '', '回调地址，需与身份提供方登记的一致，如 https://hub.example.com/oidc/callback'),
+('oidc_scopes', 'openid profile email', '请求的授权范围（空格分隔）'),
+('oidc_auto_provision', 'false', '首次单点登录且无法按已验证邮箱关联现有账号时，是否自动开通账号'),
+('site_url', '', '站点访问地址（如 https://hub.example.com），用于生成邮件中的链接'),
+('magic_link_enabled', 'false', '是否启用邮件登录链接（需同时配置站点访问地址）'),
+('magic_link_ttl_minutes', '15', '邮件登录链接有效期（分钟），链接只能使用一次'),
+('magic_link_hourly_limit', '5', '同一邮箱每小时最多请求登录链接的次数'),
+('login_alert_enabled', 'true', '新设备或新网络登录时是否发送提醒邮件（配置站点访问地址后邮件中附带"不是我本人"链接）'),
+('api_token_max_per_user', '10', '每个用户最多保留的有效个人访问令牌数'),
+('password_require_letter', 'true', '密码是否必须包含字母'),
+('password_require_mixed_case', 'false', '密码是否必须同时包含大写和小写字母'),
+('password_require_digit', 'true', '密码是否必须包含数字'),
+('password_require_symbol', 'false', '密码是否必须包含特殊字符'),
+('password_blocklist_file', 'database/common_passwords.txt', '常见或已泄露密码列表文件（每行一个，相对于程序运行目录），命中的密码不能使用，留空不检查'),
+('password_history_count', '5', '禁止重复使用最近N次使用过的密码，0=不限制'),
+('password_max_age_days', '0', '密码有效期（天），到期后登录须修改密码，0=永不过期');
 
 -- 插入测试访问记录（可选）
 INSERT INTO access_records (user_id, resource, ip_address, device_info) VALUES
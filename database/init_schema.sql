@@ -4,16 +4,50 @@
 -- 删除已存在的表（按依赖关系逆序删除）
 DROP TABLE IF EXISTS access_records CASCADE;
 DROP TABLE IF EXISTS role_permissions CASCADE;
+DROP TABLE IF EXISTS role_permission_denies CASCADE;
+DROP TABLE IF EXISTS role_permission_groups CASCADE;
+DROP TABLE IF EXISTS permission_group_items CASCADE;
+DROP TABLE IF EXISTS permission_groups CASCADE;
+DROP TABLE IF EXISTS password_history CASCADE;
+DROP TABLE IF EXISTS api_tokens CASCADE;
+DROP TABLE IF EXISTS login_history CASCADE;
+DROP TABLE IF EXISTS user_identities CASCADE;
+DROP TABLE IF EXISTS user_recovery_codes CASCADE;
+DROP TABLE IF EXISTS user_totp CASCADE;
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+DROP TABLE IF EXISTS user_sessions CASCADE;
+DROP TABLE IF EXISTS user_group_grants CASCADE;
+DROP TABLE IF EXISTS user_groups CASCADE;
 DROP TABLE IF EXISTS users CASCADE;
 DROP TABLE IF EXISTS roles CASCADE;
 DROP TABLE IF EXISTS permissions CASCADE;
 DROP TABLE IF EXISTS system_configs CASCADE;
+DROP TABLE IF EXISTS card_batches CASCADE;
+DROP TABLE IF EXISTS vip_tiers CASCADE;
+DROP TABLE IF EXISTS vip_ledger CASCADE;
+DROP TABLE IF EXISTS notification_log CASCADE;
+DROP TABLE IF EXISTS card_redemptions CASCADE;
+DROP TABLE IF EXISTS agent_quotas CASCADE;
+DROP TABLE IF EXISTS agent_quota_ledger CASCADE;
+DROP TABLE IF EXISTS orders CASCADE;
+DROP TABLE IF EXISTS plans CASCADE;
+DROP TABLE IF EXISTS trial_grants CASCADE;
+DROP TABLE IF EXISTS referrals CASCADE;
+DROP TABLE IF EXISTS vip_freezes CASCADE;
+DROP TABLE IF EXISTS vip_transfers CASCADE;
+DROP TABLE IF EXISTS points_redemptions CASCADE;
+DROP TABLE IF EXISTS points_items CASCADE;
+DROP TABLE IF EXISTS checkins CASCADE;
+DROP TABLE IF EXISTS points_ledger CASCADE;
+DROP TABLE IF EXISTS user_points CASCADE;
 
 -- 角色表
 CREATE TABLE roles (
     role_id SERIAL PRIMARY KEY,
     role_name VARCHAR(50) NOT NULL UNIQUE,
     description VARCHAR(200),
+    role_rank INT NOT NULL DEFAULT 0, -- 角色等级，不能操作等级高于自己的用户
+    require_2fa BOOLEAN NOT NULL DEFAULT FALSE, -- 是否要求该角色的用户开启两步验证
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -22,7 +56,9 @@ CREATE TABLE permissions (
     permission_id SERIAL PRIMARY KEY,
     permission_name VARCHAR(50) NOT NULL UNIQUE,
     permission_key VARCHAR(50) NOT NULL UNIQUE,
-    description VARCHAR(200)
+    description VARCHAR(200),
+    category VARCHAR(50), -- 权限分组，由代码声明同步
+    orphaned BOOLEAN NOT NULL DEFAULT FALSE -- 代码中已不再声明的权限
 );
 
 -- 角色-权限关联表（多对多）
@@ -34,22 +70,193 @@ CREATE TABLE role_permissions (
     FOREIGN KEY (permission_id) REFERENCES permissions(permission_id) ON DELETE CASCADE
 );
 
+-- 角色拒绝规则表（优先于允许规则）
+CREATE TABLE role_permission_denies (
+    role_id INT NOT NULL REFERENCES roles(role_id) ON DELETE CASCADE,
+    permission_id INT NOT NULL REFERENCES permissions(permission_id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+-- 权限组表
+CREATE TABLE permission_groups (
+    group_id SERIAL PRIMARY KEY,
+    group_name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 权限组成员表
+CREATE TABLE permission_group_items (
+    group_id INT NOT NULL REFERENCES permission_groups(group_id) ON DELETE CASCADE,
+    permission_id INT NOT NULL REFERENCES permissions(permission_id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, permission_id)
+);
+
+-- 角色-权限组关联表
+CREATE TABLE role_permission_groups (
+    role_id INT NOT NULL REFERENCES roles(role_id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES permission_groups(group_id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, group_id)
+);
+
+-- 用户组表（子社区）
+CREATE TABLE user_groups (
+    group_id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 -- 用户表
 CREATE TABLE users (
     user_id SERIAL PRIMARY KEY,
     username VARCHAR(50) NOT NULL UNIQUE,
     password_hash VARCHAR(100) NOT NULL,
     email VARCHAR(100) UNIQUE,
+    email_verified_at TIMESTAMP, -- 邮箱验证时间（注册验证码、找回密码、登录链接或身份提供方确认），空表示未验证
     emby_user_id VARCHAR(50),
     role_id INT NOT NULL,
+    group_id INT REFERENCES user_groups(group_id) ON DELETE SET NULL, -- 所属用户组
     status SMALLINT NOT NULL DEFAULT 1, -- 1-启用，0-禁用
-    vip_level SMALLINT NOT NULL DEFAULT 0, -- 0-普通用户，1-VIP
+    vip_level SMALLINT NOT NULL DEFAULT 0, -- 对应 vip_tiers.level，0-免费用户
     vip_expire_at TIMESTAMP, -- VIP过期时间
+    vip_reminder BOOLEAN NOT NULL DEFAULT TRUE, -- 是否接收VIP到期提醒
+    vip_frozen_at TIMESTAMP, -- VIP冻结时间，非空表示冻结中
+    referral_code VARCHAR(16) UNIQUE, -- 推荐码（首次查看时生成）
+    referred_by INT REFERENCES users(user_id) ON DELETE SET NULL, -- 推荐人
+    bonus_request_quota INT NOT NULL DEFAULT 0, -- 额外求片额度（积分兑换等）
+    security_version INT NOT NULL DEFAULT 0, -- 安全版本号，角色/状态/密码变更时递增使旧Token失效
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE, -- 下次登录须修改密码（如管理员重置密码后）
+    password_changed_at TIMESTAMP, -- 最近一次修改密码的时间，用于密码有效期
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (role_id) REFERENCES roles(role_id)
 );
 
+CREATE INDEX idx_users_group_id ON users(group_id);
+
+-- 用户组内授权表：用户在指定用户组内拥有某角色的权限
+CREATE TABLE user_group_grants (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES user_groups(group_id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    role_id INT NOT NULL REFERENCES roles(role_id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (group_id, user_id)
+);
+
+CREATE INDEX idx_user_group_grants_user_id ON user_group_grants(user_id);
+
+-- 登录会话表（每次登录一条，Token 携带会话ID）
+CREATE TABLE user_sessions (
+    id VARCHAR(32) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    device VARCHAR(100),
+    ip VARCHAR(50),
+    user_agent VARCHAR(500),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+
+-- 刷新令牌表（只保存SHA-256摘要，一次性使用，同一会话内轮换的令牌为一族）
+CREATE TABLE refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    session_id VARCHAR(32) NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    user_id INT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+
+-- TOTP两步验证表（enabled=false 表示已生成密钥但尚未确认绑定）
+CREATE TABLE user_totp (
+    user_id INT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0, -- 最近一次使用的时间步，防止验证码重放
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 两步验证恢复码表（只保存SHA-256摘要）
+CREATE TABLE user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
+
+-- 外部身份绑定表（单点登录）
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    provider VARCHAR(255) NOT NULL, -- 身份提供方（issuer）
+    subject VARCHAR(255) NOT NULL,  -- 身份提供方中的用户标识（sub）
+    email VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+    CONSTRAINT uk_identity_subject UNIQUE (provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- 登录历史表（新设备、新网络登录时发送提醒）
+CREATE TABLE login_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    session_id VARCHAR(32),
+    method VARCHAR(20) NOT NULL,    -- 登录方式：password/oidc/magic_link
+    ip VARCHAR(50),
+    network VARCHAR(64),            -- IP所在网段（IPv4 /24，IPv6 /48）
+    user_agent VARCHAR(500),
+    device VARCHAR(100),
+    device_hash VARCHAR(64),        -- 设备指纹
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    new_network BOOLEAN NOT NULL DEFAULT FALSE,
+    alerted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_history_user_id ON login_history(user_id, id DESC);
+
+-- 个人访问令牌表（只保存SHA-256摘要，供机器人和脚本调用接口）
+CREATE TABLE api_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL, -- 令牌开头几位，便于识别
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,              -- 权限键（逗号分隔），self 表示无需权限的个人接口
+    allowed_ips TEXT,                  -- 允许的IP或网段（逗号分隔），空=不限
+    expires_at TIMESTAMP,              -- 空=永不过期
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(50),
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
+
+-- 历史密码表（只保存bcrypt摘要，禁止重复使用最近的密码）
+CREATE TABLE password_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    password_hash VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_password_history_user_id ON password_history(user_id, id DESC);
+
 -- 访问记录表
 CREATE TABLE access_records (
     record_id BIGSERIAL PRIMARY KEY,
@@ -76,24 +283,323 @@ CREATE INDEX idx_access_records_user_id ON access_records(user_id);
 CREATE INDEX idx_access_records_access_time ON access_records(access_time);
 CREATE INDEX idx_access_records_user_time ON access_records(user_id, access_time);
 
+-- 卡密批次表（记录生成模板）
+-- VIP等级表（level 0 为免费等级，VIP到期后降级到该等级）
+CREATE TABLE vip_tiers (
+    level SMALLINT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    description VARCHAR(200),
+    allow_playback BOOLEAN NOT NULL DEFAULT TRUE, -- 是否允许播放
+    stream_limit INT NOT NULL DEFAULT 0, -- 同时播放数，0=不限
+    bitrate_limit INT NOT NULL DEFAULT 0, -- 远程码率上限（kbps），0=不限
+    library_ids TEXT, -- 可访问媒体库ID（逗号分隔），空=全部
+    device_quota INT NOT NULL DEFAULT 0, -- 设备数上限，0=不限
+    request_quota INT NOT NULL DEFAULT 0, -- 每月求片数量
+    day_value INT NOT NULL DEFAULT 100, -- 每天价值，跨等级折算剩余时长
+    max_freeze_days INT NOT NULL DEFAULT 0, -- 单次冻结最长天数，0=不可冻结
+    freezes_per_year INT NOT NULL DEFAULT 0, -- 每个自然年可冻结次数
+    status SMALLINT NOT NULL DEFAULT 1, -- 0=停用 1=启用
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE card_batches (
+    id SERIAL PRIMARY KEY,
+    card_type SMALLINT NOT NULL,
+    duration INT NOT NULL,
+    vip_level SMALLINT NOT NULL DEFAULT 1 REFERENCES vip_tiers(level),
+    count INT NOT NULL,
+    prefix VARCHAR(8),
+    group_size INT NOT NULL DEFAULT 0, -- 分组长度，0=不分组
+    code_length INT NOT NULL, -- 随机字符长度（不含校验位）
+    charset VARCHAR(64) NOT NULL,
+    checksum VARCHAR(10) NOT NULL DEFAULT 'none', -- none/luhn/crc32
+    remark VARCHAR(200),
+    created_by INT NOT NULL REFERENCES users(user_id),
+    group_id INT REFERENCES user_groups(group_id) ON DELETE SET NULL, -- 所属用户组，空表示全局批次
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 兑换未命中时按输入前缀查找带校验位的批次模板
+CREATE INDEX idx_card_batches_checksum_prefix ON card_batches(prefix) WHERE checksum <> 'none';
+
 -- 卡密表
 CREATE TABLE card_keys (
     id SERIAL PRIMARY KEY,
-    card_code VARCHAR(32) NOT NULL UNIQUE,
+    card_code VARCHAR(64) NOT NULL UNIQUE,
+    code_key VARCHAR(64) NOT NULL UNIQUE, -- 规范化卡密码（大写、去除分隔符）
+    batch_id INT REFERENCES card_batches(id),
     card_type SMALLINT NOT NULL DEFAULT 1, -- 1=注册码 2=VIP升级码
     duration INT NOT NULL DEFAULT 30, -- 有效期（天）
-    status SMALLINT NOT NULL DEFAULT 1, -- 0=已禁用 1=未使用 2=已使用
+    vip_level SMALLINT NOT NULL DEFAULT 1 REFERENCES vip_tiers(level), -- 兑换的VIP等级
+    status SMALLINT NOT NULL DEFAULT 1, -- 0=已禁用 1=未使用 2=已使用 3=已撤销
     used_by INT REFERENCES users(user_id),
     used_at TIMESTAMP,
     expire_at TIMESTAMP,
     remark VARCHAR(200),
     created_by INT NOT NULL REFERENCES users(user_id),
+    group_id INT REFERENCES user_groups(group_id) ON DELETE SET NULL, -- 所属用户组，空表示全局卡密
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_card_keys_card_code ON card_keys(card_code);
 CREATE INDEX idx_card_keys_status ON card_keys(status);
 CREATE INDEX idx_card_keys_card_type ON card_keys(card_type);
+CREATE INDEX idx_card_keys_batch_id ON card_keys(batch_id);
+CREATE INDEX idx_card_keys_group_id ON card_keys(group_id);
+
+-- 卡密兑换记录表（每次兑换发放的VIP天数，用于撤销回滚）
+CREATE TABLE card_redemptions (
+    id SERIAL PRIMARY KEY,
+    card_key_id INT NOT NULL REFERENCES card_keys(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    days_granted INT NOT NULL, -- 实际增加的天数（跨等级折算后，四舍五入）
+    granted_seconds BIGINT NOT NULL DEFAULT 0, -- 实际增加的时长（秒），撤销时按此回滚
+    expire_before TIMESTAMP, -- 兑换前VIP到期时间
+    expire_after TIMESTAMP NOT NULL, -- 兑换后VIP到期时间
+    level_before SMALLINT NOT NULL DEFAULT 0, -- 兑换前VIP等级
+    level_after SMALLINT NOT NULL DEFAULT 1, -- 兑换后VIP等级
+    revoked_at TIMESTAMP,
+    revoked_by INT REFERENCES users(user_id),
+    revoke_reason VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_card_redemptions_card_key_id ON card_redemptions(card_key_id);
+CREATE INDEX idx_card_redemptions_user_id ON card_redemptions(user_id);
+CREATE INDEX idx_card_keys_created_by ON card_keys(created_by);
+
+-- VIP时长变动流水表（只增不改）
+CREATE TABLE vip_ledger (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL, -- card/admin/trial/referral/refund/expiry
+    delta_days INT NOT NULL, -- 发放为正，回收为负，到期为0
+    level_before SMALLINT NOT NULL,
+    level_after SMALLINT NOT NULL,
+    expire_before TIMESTAMP,
+    expire_after TIMESTAMP,
+    operator_id INT REFERENCES users(user_id) ON DELETE SET NULL,
+    ref_id VARCHAR(50), -- 关联对象ID（如卡密ID）
+    remark VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_vip_ledger_user_id ON vip_ledger(user_id, id);
+
+-- 通知发送记录表（唯一键保证同一阶段最多发送一次）
+CREATE TABLE notification_log (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL, -- vip_expiry
+    stage INT NOT NULL, -- 到期前天数，0=到期当天
+    ref_key VARCHAR(50) NOT NULL, -- 去重键（VIP到期日期）
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(100),
+    status VARCHAR(20) NOT NULL, -- pending/sent/failed
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, type, stage, ref_key)
+);
+
+CREATE INDEX idx_notification_log_created_at ON notification_log(created_at);
+
+-- 代理商额度表（单位：VIP天数）
+CREATE TABLE agent_quotas (
+    user_id INT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    balance INT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    total_top_up INT NOT NULL DEFAULT 0,
+    total_consumed INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 代理商额度流水表
+CREATE TABLE agent_quota_ledger (
+    id SERIAL PRIMARY KEY,
+    agent_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    delta INT NOT NULL,
+    balance_after INT NOT NULL,
+    reason VARCHAR(20) NOT NULL, -- topup / adjust / generate / refund
+    batch_id INT REFERENCES card_batches(id),
+    card_key_id INT, -- 退还时对应的卡密（卡密已删除，不加外键）
+    operator_id INT REFERENCES users(user_id),
+    remark VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_agent_quota_ledger_agent_id ON agent_quota_ledger(agent_id, created_at);
+
+-- 套餐表
+CREATE TABLE plans (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    vip_level INT NOT NULL REFERENCES vip_tiers(level),
+    days INT NOT NULL CHECK (days > 0),
+    price BIGINT NOT NULL CHECK (price > 0), -- 单位：分
+    status SMALLINT NOT NULL DEFAULT 1, -- 0=下架 1=上架
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 订单表
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    order_no VARCHAR(32) NOT NULL UNIQUE,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    plan_id INT NOT NULL REFERENCES plans(id),
+    plan_name VARCHAR(50) NOT NULL,
+    vip_level INT NOT NULL,
+    days INT NOT NULL,
+    amount BIGINT NOT NULL, -- 单位：分
+    status VARCHAR(20) NOT NULL DEFAULT 'created', -- created / paid / fulfilled / refunding / refunded
+    gateway VARCHAR(20) NOT NULL,
+    trade_no VARCHAR(64),
+    paid_at TIMESTAMP,
+    fulfilled_at TIMESTAMP,
+    refunded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_orders_user_id ON orders(user_id, created_at);
+CREATE INDEX idx_orders_status ON orders(status);
+
+-- 注册试用发放记录（防止同一邮箱/IP/设备重复领取）
+CREATE TABLE trial_grants (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+    email_key VARCHAR(100) NOT NULL UNIQUE, -- 规范化后的邮箱（忽略大小写、+标签、Gmail点号）
+    ip_address VARCHAR(45),
+    device_fingerprint VARCHAR(128),
+    vip_level INT NOT NULL,
+    days INT NOT NULL,
+    expire_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_trial_grants_ip ON trial_grants(ip_address, created_at);
+CREATE INDEX idx_trial_grants_fingerprint ON trial_grants(device_fingerprint);
+CREATE INDEX idx_trial_grants_expire_at ON trial_grants(expire_at);
+
+-- 推荐关系表（每个被推荐人只有一个推荐人，奖励只发放一次）
+CREATE TABLE referrals (
+    id SERIAL PRIMARY KEY,
+    referrer_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    referee_id INT NOT NULL UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending / rewarded / rejected
+    reject_reason VARCHAR(100),
+    ip_address VARCHAR(45), -- 被推荐人注册IP
+    device_fingerprint VARCHAR(128),
+    reward_trigger VARCHAR(20), -- card / active（待奖励时表示已触发、因VIP冻结推迟发放）
+    referrer_reward_days INT NOT NULL DEFAULT 0,
+    referee_reward_days INT NOT NULL DEFAULT 0,
+    rewarded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_referrals_referrer_id ON referrals(referrer_id, status);
+CREATE INDEX idx_referrals_device ON referrals(device_fingerprint);
+CREATE INDEX idx_referrals_rewarded_at ON referrals(rewarded_at);
+
+-- VIP冻结记录表
+CREATE TABLE vip_freezes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    vip_level INT NOT NULL,
+    remaining_seconds BIGINT NOT NULL, -- 冻结时剩余的VIP时长
+    frozen_at TIMESTAMP NOT NULL,
+    freeze_until TIMESTAMP NOT NULL, -- 最晚解冻时间（到期自动解冻）
+    unfrozen_at TIMESTAMP,
+    unfreeze_by VARCHAR(20), -- user / admin / auto
+    reason VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_vip_freezes_user_id ON vip_freezes(user_id, frozen_at);
+CREATE INDEX idx_vip_freezes_active ON vip_freezes(freeze_until) WHERE unfrozen_at IS NULL;
+
+-- VIP转赠记录表
+CREATE TABLE vip_transfers (
+    id SERIAL PRIMARY KEY,
+    from_user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    to_user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    days INT NOT NULL CHECK (days > 0),
+    vip_level INT NOT NULL, -- 转出方等级
+    remark VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_vip_transfers_from ON vip_transfers(from_user_id, created_at);
+CREATE INDEX idx_vip_transfers_to ON vip_transfers(to_user_id);
+
+-- 用户积分表
+CREATE TABLE user_points (
+    user_id INT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    balance INT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    total_earned INT NOT NULL DEFAULT 0,
+    total_spent INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 积分流水表（只增不改）
+CREATE TABLE points_ledger (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    delta INT NOT NULL,
+    balance_after INT NOT NULL,
+    reason VARCHAR(20) NOT NULL, -- checkin / redeem / adjust
+    ref_id VARCHAR(50),
+    operator_id INT REFERENCES users(user_id) ON DELETE SET NULL,
+    remark VARCHAR(200),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_points_ledger_user_id ON points_ledger(user_id, id);
+
+-- 签到记录表
+CREATE TABLE checkins (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    checkin_date DATE NOT NULL,
+    streak INT NOT NULL, -- 连续签到天数
+    points INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, checkin_date)
+);
+
+-- 积分商城商品表
+CREATE TABLE points_items (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    description VARCHAR(200),
+    item_type VARCHAR(20) NOT NULL, -- vip_days / invite_code / request_quota
+    cost INT NOT NULL CHECK (cost > 0),
+    vip_level INT NOT NULL DEFAULT 1,
+    amount INT NOT NULL CHECK (amount > 0), -- VIP天数或求片额度
+    stock INT NOT NULL DEFAULT -1, -- 库存，-1=不限
+    per_user_limit INT NOT NULL DEFAULT 0, -- 每人限兑次数，0=不限
+    status SMALLINT NOT NULL DEFAULT 1, -- 1-上架，0-下架
+    sort_order INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 积分兑换记录表
+CREATE TABLE points_redemptions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    item_id INT NOT NULL REFERENCES points_items(id),
+    item_name VARCHAR(50) NOT NULL,
+    item_type VARCHAR(20) NOT NULL,
+    cost INT NOT NULL,
+    batch_id INT REFERENCES card_batches(id) ON DELETE SET NULL, -- invite_code 类商品生成的卡密批次
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_points_redemptions_user_item ON points_redemptions(user_id, item_id);
+
 
 -- 添加注释
 COMMENT ON TABLE users IS 'Emby用户信息表';
@@ -54,8 +54,11 @@ function App() {
         <Routes>
           <Route path="/setup" element={<Setup />} />
           <Route path="/login" element={<Login />} />
+          <Route path="/oidc/callback" element={<Login />} />
+          <Route path="/magic-login" element={<Login />} />
           <Route path="/register" element={<Register />} />
           <Route path="/forgot-password" element={<ForgotPassword />} />
+          <Route path="/not-me" element={<ForgotPassword />} />
           <Route
             path="/"
             element={
@@ -0,0 +1,130 @@
+import request from '@/utils/request';
+import type { CardKeyCreateRequest } from './cardKey';
+
+// 代理商额度（单位：VIP天数）
+export interface AgentQuota {
+  user_id: number;
+  balance: number;
+  total_top_up: number;
+  total_consumed: number;
+  updated_at: string;
+  user?: { username: string; email?: string };
+}
+
+// 额度流水
+export interface AgentQuotaLedger {
+  id: number;
+  agent_id: number;
+  delta: number;
+  balance_after: number;
+  reason: 'topup' | 'adjust' | 'generate' | 'refund';
+  batch_id?: number;
+  card_key_id?: number;
+  operator_id?: number;
+  remark?: string;
+  created_at: string;
+}
+
+// 结算报表
+export interface AgentSettlement {
+  agent_id: number;
+  period_start: string;
+  period_end: string;
+  cards_generated: number;
+  days_generated: number;
+  cards_redeemed: number;
+  days_redeemed: number;
+  cards_revoked: number;
+  days_revoked: number;
+  quota_top_up: number;
+  quota_consumed: number;
+  quota_refunded: number;
+  balance_end: number;
+}
+
+// 结算周期（日期格式 YYYY-MM-DD，结束日期包含当天）
+export interface SettlementPeriod {
+  start_time: string;
+  end_time: string;
+}
+
+// 获取我的额度
+export function getAgentQuota() {
+  return request.get('/agent/quota');
+}
+
+// 获取我的额度流水
+export function getAgentLedger(params?: { page?: number; page_size?: number; reason?: string }) {
+  return request.get('/agent/quota/ledger', { params });
+}
+
+// 使用额度生成卡密
+export function generateAgentCardKeys(data: CardKeyCreateRequest) {
+  return request.post('/agent/card-keys', data);
+}
+
+// 获取我的卡密列表
+export function getAgentCardKeys(params?: {
+  page?: number;
+  page_size?: number;
+  status?: number;
+  batch_id?: number;
+  keyword?: string;
+}) {
+  return request.get('/agent/card-keys', { params });
+}
+
+// 获取我的卡密统计
+export function getAgentCardKeyStatistics() {
+  return request.get('/agent/card-keys/statistics');
+}
+
+// 禁用我的卡密
+export function disableAgentCardKey(id: number) {
+  return request.put(`/agent/card-keys/${id}/disable`);
+}
+
+// 启用我的卡密
+export function enableAgentCardKey(id: number) {
+  return request.put(`/agent/card-keys/${id}/enable`);
+}
+
+// 删除我的未使用卡密（退还额度）
+export function deleteAgentCardKey(id: number) {
+  return request.delete(`/agent/card-keys/${id}`);
+}
+
+// 获取我的批次列表
+export function getAgentBatches(params?: { page?: number; page_size?: number }) {
+  return request.get('/agent/batches', { params });
+}
+
+// 获取我的卡密兑换记录
+export function getAgentRedemptions(params?: { page?: number; page_size?: number }) {
+  return request.get('/agent/redemptions', { params });
+}
+
+// 获取我的结算报表
+export function getAgentSettlement(params: SettlementPeriod) {
+  return request.get('/agent/settlement', { params });
+}
+
+// 获取代理商列表（管理员）
+export function getAgents(params?: { page?: number; page_size?: number }) {
+  return request.get('/agents', { params });
+}
+
+// 调整代理商额度（管理员，正数充值、负数扣减）
+export function adjustAgentQuota(id: number, delta: number, remark?: string) {
+  return request.post(`/agents/${id}/quota`, { delta, remark });
+}
+
+// 获取代理商额度流水（管理员）
+export function getAgentLedgerById(id: number, params?: { page?: number; page_size?: number; reason?: string }) {
+  return request.get(`/agents/${id}/ledger`, { params });
+}
+
+// 获取代理商结算报表（管理员）
+export function getAgentSettlementById(id: number, params: SettlementPeriod) {
+  return request.get(`/agents/${id}/settlement`, { params });
+}
@@ -1,25 +1,66 @@
 // 认证相关API
-import { post, get } from '@/utils/request'
-import type { LoginRequest, LoginResponse, User } from '@/types'
+import { post, get, del } from '@/utils/request'
+import type { APIToken, LoginHistory, LoginRequest, LoginResponse, OIDCConfig, User, UserSession, TwoFactorSetup, TwoFactorStatus } from '@/types'
 
 // 登录
 export const login = (data: LoginRequest) => {
   return post<LoginResponse>('/auth/login', data)
 }
 
+// 登录第二步：提交两步验证码或恢复码
+export const loginTwoFactor = (data: { challenge_token: string; code: string }) => {
+  return post<LoginResponse>('/auth/login/2fa', data)
+}
+
+// 登录过程中绑定验证器（角色要求两步验证但尚未开启时）
+export const setupTwoFactorChallenge = (challengeToken: string) => {
+  return post<TwoFactorSetup>('/auth/login/2fa/setup', { challenge_token: challengeToken })
+}
+
+// 获取单点登录配置
+export const getOIDCConfig = () => {
+  return get<OIDCConfig>('/auth/oidc/config')
+}
+
+// 获取跳转到身份提供方的授权地址
+export const getOIDCAuthorizeURL = () => {
+  return get<{ url: string }>('/auth/oidc/authorize')
+}
+
+// 身份提供方回调后完成登录（开启两步验证时同样返回登录挑战）
+export const oidcCallback = (data: { code: string; state: string; device_fingerprint?: string }) => {
+  return post<LoginResponse>('/auth/oidc/callback', data)
+}
+
+// 获取邮件登录是否启用
+export const getMagicLinkConfig = () => {
+  return get<{ enabled: boolean }>('/auth/magic-link/config')
+}
+
+// 发送邮件登录链接
+export const sendMagicLink = (email: string) => {
+  return post('/auth/magic-link', { email })
+}
+
+// 使用邮件中的登录链接登录（开启两步验证时同样返回登录挑战）
+export const magicLinkLogin = (token: string, deviceFingerprint?: string) => {
+  return post<LoginResponse>('/auth/magic-link/login', { token, device_fingerprint: deviceFingerprint })
+}
+
 // 发送邮箱验证码
 export const sendEmailCode = (data: { email: string; type?: string }) => {
   return post<{ message: string }>('/email/send-code', data)
 }
 
-// 注册（邮箱验证方式）
-export const register = (data: { email: string; code: string; username: string; password: string }) => {
+// 注册（邮箱验证方式，device_fingerprint 用于试用防滥用）
+export const register = (data: { email: string; code: string; username: string; password: string; device_fingerprint?: string; referral_code?: string }) => {
   return post<{
     user_id: number;
     username: string;
     email: string;
     emby_user_id?: string;
     message: string;
+    trial?: { vip_level: number; days: number; expire_at: string };
   }>('/auth/register', data)
 }
 
@@ -32,3 +73,73 @@ export const logout = () => {
 export const getCurrentUser = () => {
   return get<User>('/auth/current')
 }
+
+// 获取我的登录会话
+export const getMySessions = () => {
+  return get<UserSession[]>('/auth/sessions')
+}
+
+// 撤销指定会话（远程下线某台设备）
+export const revokeMySession = (id: string) => {
+  return del(`/auth/sessions/${id}`)
+}
+
+// 退出所有设备（keepCurrent 为 true 时保留当前设备）
+export const revokeAllMySessions = (keepCurrent = false) => {
+  return del<{ count: number }>('/auth/sessions', { params: { keep_current: keepCurrent } })
+}
+
+// 获取我的登录历史
+export const getLoginHistory = (params?: { page?: number; page_size?: number }) => {
+  return get<{ total: number; list: LoginHistory[] }>('/auth/login-history', { params })
+}
+
+// 登录提醒邮件中的"不是我本人"：退出所有设备并要求重置密码
+export const revokeFromLoginAlert = (token: string) => {
+  return post('/auth/login-alert/revoke', { token })
+}
+
+// 获取我的个人访问令牌
+export const getAPITokens = () => {
+  return get<APIToken[]>('/auth/tokens')
+}
+
+// 创建个人访问令牌，令牌原文只返回这一次
+export const createAPIToken = (data: { name: string; scopes: string[]; allowed_ips?: string[]; expires_in_days?: number }) => {
+  return post<{ token: string; api_token: APIToken }>('/auth/tokens', data)
+}
+
+// 撤销个人访问令牌
+export const revokeAPIToken = (id: number) => {
+  return del(`/auth/tokens/${id}`)
+}
+
+// 获取两步验证状态
+export const getTwoFactorStatus = () => {
+  return get<TwoFactorStatus>('/auth/2fa')
+}
+
+// 生成验证器绑定信息
+export const setupTwoFactor = () => {
+  return post<TwoFactorSetup>('/auth/2fa/setup')
+}
+
+// 确认绑定并开启两步验证，返回恢复码
+export const enableTwoFactor = (code: string) => {
+  return post<{ recovery_codes: string[] }>('/auth/2fa/enable', { code })
+}
+
+// 关闭两步验证
+export const disableTwoFactor = (code: string) => {
+  return post('/auth/2fa/disable', { code })
+}
+
+// 重新生成恢复码
+export const regenerateRecoveryCodes = (code: string) => {
+  return post<{ recovery_codes: string[] }>('/auth/2fa/recovery-codes', { code })
+}
+
+// 敏感操作二次验证
+export const verifyTwoFactor = (code: string) => {
+  return post('/auth/2fa/verify', { code })
+}
@@ -6,12 +6,15 @@ export interface CardKey {
   card_code: string;
   card_type: number;
   duration: number;
+  vip_level: number;
   status: number;
+  batch_id?: number;
   used_by?: number;
   used_at?: string;
   expire_at?: string;
   remark?: string;
   created_by: number;
+  group_id?: number;
   created_at: string;
   used_by_user?: { username: string };
   created_by_user?: { username: string };
@@ -22,7 +25,15 @@ export interface CardKeyCreateRequest {
   count: number;
   card_type: number;
   duration: number;
+  vip_level?: number; // 兑换的VIP等级，默认1
   remark?: string;
+  group_id?: number; // 所属用户组，组管理员必填
+  // 卡密码模板（均不填时使用默认格式）
+  prefix?: string;
+  group_size?: number;
+  code_length?: number;
+  charset?: string;
+  checksum?: 'none' | 'luhn' | 'crc32';
 }
 
 // 获取卡密列表
@@ -31,6 +42,7 @@ export function getCardKeys(params?: {
   page_size?: number;
   status?: number;
   card_type?: number;
+  batch_id?: number;
   keyword?: string;
 }) {
   return request.get('/card-keys', { params });
@@ -75,3 +87,13 @@ export function validateCardKey(cardCode: string) {
 export function useVipCard(cardCode: string) {
   return request.post('/card-keys/use-vip', { card_code: cardCode });
 }
+
+// 撤销卡密兑换（回滚已发放的VIP时长）
+export function revokeCardKey(id: number, reason?: string) {
+  return request.post(`/card-keys/${id}/revoke`, { reason });
+}
+
+// 获取卡密兑换记录
+export function getCardKeyRedemptions(id: number) {
+  return request.get(`/card-keys/${id}/redemptions`);
+}
@@ -0,0 +1,107 @@
+import request from '@/utils/request';
+
+// 套餐（价格单位：分）
+export interface Plan {
+  id: number;
+  name: string;
+  vip_level: number;
+  days: number;
+  price: number;
+  status: number;
+  sort_order: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// 订单
+export interface Order {
+  id: number;
+  order_no: string;
+  user_id: number;
+  plan_id: number;
+  plan_name: string;
+  vip_level: number;
+  days: number;
+  amount: number;
+  status: 'created' | 'paid' | 'fulfilled' | 'refunding' | 'refunded';
+  gateway: string;
+  trade_no?: string;
+  paid_at?: string;
+  fulfilled_at?: string;
+  refunded_at?: string;
+  created_at: string;
+  user?: { username: string; email?: string };
+}
+
+// 创建/更新套餐请求
+export interface PlanRequest {
+  name: string;
+  vip_level: number;
+  days: number;
+  price: number;
+  status?: number;
+  sort_order?: number;
+}
+
+export interface OrderListParams {
+  page?: number;
+  page_size?: number;
+  status?: string;
+  user_id?: number;
+  keyword?: string;
+}
+
+// 获取上架套餐
+export function getPlans() {
+  return request.get('/plans');
+}
+
+// 获取全部套餐（含下架）
+export function getAllPlans() {
+  return request.get('/plans/all');
+}
+
+// 创建套餐
+export function createPlan(data: PlanRequest) {
+  return request.post('/plans', data);
+}
+
+// 更新套餐
+export function updatePlan(id: number, data: PlanRequest) {
+  return request.put(`/plans/${id}`, data);
+}
+
+// 删除套餐
+export function deletePlan(id: number) {
+  return request.delete(`/plans/${id}`);
+}
+
+// 创建订单（gateway 不填时使用默认支付方式）
+export function createOrder(planId: number, gateway?: string) {
+  return request.post('/orders', { plan_id: planId, gateway });
+}
+
+// 获取我的订单
+export function getMyOrders(params?: OrderListParams) {
+  return request.get('/orders/my', { params });
+}
+
+// 获取订单详情
+export function getOrder(orderNo: string) {
+  return request.get(`/orders/${orderNo}`);
+}
+
+// 模拟支付（仅本地模拟网关，需开启 payment_mock_enabled）
+export function mockPayOrder(orderNo: string) {
+  return request.post(`/orders/${orderNo}/mock-pay`);
+}
+
+// 获取订单列表（管理员）
+export function getOrders(params?: OrderListParams) {
+  return request.get('/orders', { params });
+}
+
+// 订单退款
+export function refundOrder(orderNo: string, reason?: string) {
+  return request.post(`/orders/${orderNo}/refund`, { reason });
+}
@@ -1,6 +1,6 @@
 // 权限管理API
-import { get } from '@/utils/request'
-import type { Permission } from '@/types'
+import { get, post, put, del } from '@/utils/request'
+import type { Permission, PermissionGroup, PermissionCheckResult, RoutePermission } from '@/types'
 
 // 获取权限列表
 export const getPermissionList = () => {
@@ -9,3 +9,33 @@ export const getPermissionList = () => {
 
 // 别名导出，保持向后兼容
 export const getPermissions = getPermissionList
+
+// 检查角色或用户是否拥有指定权限
+export const checkPermission = (params: { key: string; role_id?: number; user_id?: number }) => {
+  return get<PermissionCheckResult>('/permissions/check', params)
+}
+
+// 获取各路由所需的权限（用于生成菜单）
+export const getPermissionRoutes = () => {
+  return get<RoutePermission[]>('/permissions/routes')
+}
+
+// 获取权限组列表
+export const getPermissionGroups = () => {
+  return get<PermissionGroup[]>('/permission-groups')
+}
+
+// 创建权限组
+export const createPermissionGroup = (data: { group_name: string; description?: string; permission_ids: number[] }) => {
+  return post<PermissionGroup>('/permission-groups', data)
+}
+
+// 更新权限组
+export const updatePermissionGroup = (id: number, data: { group_name: string; description?: string; permission_ids: number[] }) => {
+  return put<PermissionGroup>(`/permission-groups/${id}`, data)
+}
+
+// 删除权限组
+export const deletePermissionGroup = (id: number) => {
+  return del(`/permission-groups/${id}`)
+}
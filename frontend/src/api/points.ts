@@ -0,0 +1,110 @@
+import request from '@/utils/request';
+
+// 积分商品
+export interface PointsItem {
+  id: number;
+  name: string;
+  description: string;
+  item_type: 'vip_days' | 'invite_code' | 'request_quota';
+  cost: number;
+  vip_level: number;
+  amount: number;
+  stock: number; // -1=不限
+  per_user_limit: number; // 0=不限
+  status: number;
+  sort_order: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// 积分流水
+export interface PointsLedger {
+  id: number;
+  user_id: number;
+  delta: number;
+  balance_after: number;
+  reason: 'checkin' | 'redeem' | 'adjust';
+  ref_id?: string;
+  operator_id?: number;
+  remark: string;
+  created_at: string;
+}
+
+// 我的积分
+export interface PointsSummary {
+  balance: number;
+  total_earned: number;
+  total_spent: number;
+  checked_in_today: boolean;
+  streak: number;
+}
+
+// 创建/更新商品请求
+export interface PointsItemRequest {
+  name: string;
+  description?: string;
+  item_type: PointsItem['item_type'];
+  cost: number;
+  vip_level?: number;
+  amount: number;
+  stock?: number;
+  per_user_limit?: number;
+  status?: number;
+  sort_order?: number;
+}
+
+export interface PointsLedgerParams {
+  page?: number;
+  page_size?: number;
+  reason?: string;
+}
+
+// 获取我的积分
+export function getPoints() {
+  return request.get('/points');
+}
+
+// 每日签到
+export function checkin() {
+  return request.post('/points/checkin');
+}
+
+// 获取我的积分流水
+export function getPointsLedger(params?: PointsLedgerParams) {
+  return request.get('/points/ledger', { params });
+}
+
+// 获取上架商品
+export function getPointsItems() {
+  return request.get('/points/items');
+}
+
+// 兑换商品
+export function redeemPointsItem(id: number) {
+  return request.post(`/points/items/${id}/redeem`);
+}
+
+// 获取全部商品（含下架）
+export function getAllPointsItems() {
+  return request.get('/points/items/all');
+}
+
+// 创建商品
+export function createPointsItem(data: PointsItemRequest) {
+  return request.post('/points/items', data);
+}
+
+// 更新商品
+export function updatePointsItem(id: number, data: PointsItemRequest) {
+  return request.put(`/points/items/${id}`, data);
+}
+
+// 删除商品
+export function deletePointsItem(id: number) {
+  return request.delete(`/points/items/${id}`);
+}
+
+// 调整用户积分（管理员）
+export function adjustUserPoints(userId: number, delta: number, remark?: string) {
+  return request.post(`/users/${userId}/points`, { delta, remark });
+}
@@ -1,6 +1,6 @@
 // 角色管理API
 import { get, post, put, del } from '@/utils/request'
-import type { Role, RoleCreateRequest } from '@/types'
+import type { Role, RoleCreateRequest, RoleEffectivePermissions } from '@/types'
 
 // 获取角色列表
 export const getRoleList = () => {
@@ -30,7 +30,16 @@ export const deleteRole = (id: number) => {
   return del(`/roles/${id}`)
 }
 
-// 为角色分配权限
-export const assignPermissions = (id: number, permissionIds: number[]) => {
-  return post(`/roles/${id}/permissions`, { permission_ids: permissionIds })
+// 为角色分配权限（denyIds、groupIds 不传时保持不变）
+export const assignPermissions = (id: number, permissionIds: number[], denyIds?: number[], groupIds?: number[]) => {
+  return post(`/roles/${id}/permissions`, {
+    permission_ids: permissionIds,
+    deny_permission_ids: denyIds,
+    group_ids: groupIds,
+  })
+}
+
+// 获取角色展开后实际拥有的权限
+export const getRoleEffectivePermissions = (id: number) => {
+  return get<RoleEffectivePermissions>(`/roles/${id}/effective-permissions`)
 }
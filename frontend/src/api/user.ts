@@ -1,6 +1,6 @@
 // 用户管理API
 import { get, post, put, del } from '@/utils/request'
-import type { User, UserCreateRequest, UserUpdateRequest, PaginationResponse } from '@/types'
+import type { User, UserCreateRequest, UserUpdateRequest, UserSession, PaginationResponse } from '@/types'
 
 // 获取用户列表
 export const getUserList = (params: any) => {
@@ -31,8 +31,23 @@ export const deleteUser = (id: number) => {
 }
 
 // 重置密码
-export const resetPassword = (id: number, password: string) => {
-  return put(`/users/${id}/password`, { password })
+export const resetPassword = (id: number, password: string, mustChange = true) => {
+  return put(`/users/${id}/password`, { password, must_change: mustChange })
+}
+
+// 获取用户的登录会话
+export const getUserSessions = (id: number) => {
+  return get<UserSession[]>(`/users/${id}/sessions`)
+}
+
+// 强制用户下线（不传 sessionId 时下线全部设备）
+export const revokeUserSessions = (id: number, sessionId?: string) => {
+  return del<{ count: number }>(sessionId ? `/users/${id}/sessions/${sessionId}` : `/users/${id}/sessions`)
+}
+
+// 重置用户的两步验证
+export const resetUserTwoFactor = (id: number) => {
+  return del(`/users/${id}/2fa`)
 }
 
 // 批量更新用户状态
@@ -40,7 +55,86 @@ export const batchUpdateStatus = (userIds: number[], status: number) => {
   return put('/users/batch/status', { user_ids: userIds, status })
 }
 
-// 设置用户VIP
-export const setUserVip = (userId: number, days: number) => {
-  return put(`/users/${userId}/vip`, { days })
+// 设置用户VIP（vipLevel 不填时沿用当前等级）
+export const setUserVip = (userId: number, days: number, vipLevel?: number) => {
+  return put(`/users/${userId}/vip`, { days, vip_level: vipLevel })
+}
+
+// 获取用户VIP变动历史
+export const getUserVipHistory = (userId: number, params?: { page?: number; page_size?: number; source?: string }) => {
+  return get(`/users/${userId}/vip-history`, params)
+}
+
+// 获取我的VIP变动历史
+export const getMyVipHistory = (params?: { page?: number; page_size?: number; source?: string }) => {
+  return get('/vip/history', params)
+}
+
+// 设置是否接收VIP到期提醒
+export const updateNotificationPreferences = (vipReminder: boolean) => {
+  return put('/notifications/preferences', { vip_reminder: vipReminder })
+}
+
+// 获取通知发送记录（管理员）
+export const getNotificationLogs = (params?: {
+  page?: number
+  page_size?: number
+  type?: string
+  status?: string
+  stage?: number
+  user_id?: number
+  start_time?: string
+  end_time?: string
+}) => {
+  return get('/notifications/logs', params)
+}
+
+// 获取我的推荐码和推荐统计
+export const getMyReferral = () => {
+  return get('/referral')
+}
+
+// 获取我的推荐记录
+export const getMyReferralInvitees = (params?: { page?: number; page_size?: number; status?: string }) => {
+  return get('/referral/invitees', params)
+}
+
+// 获取推荐排行榜
+export const getReferralLeaderboard = (params?: { limit?: number; start_time?: string; end_time?: string }) => {
+  return get('/referral/leaderboard', params)
+}
+
+// 冻结我的VIP（days 为计划冻结天数，到期自动解冻）
+export const freezeMyVip = (days: number, reason?: string) => {
+  return post('/vip/freeze', { days, reason })
+}
+
+// 解冻我的VIP
+export const unfreezeMyVip = () => {
+  return post('/vip/unfreeze')
+}
+
+// 获取我的VIP冻结记录
+export const getMyVipFreezes = () => {
+  return get('/vip/freezes')
+}
+
+// 解冻用户VIP（管理员）
+export const unfreezeUserVip = (userId: number) => {
+  return post(`/users/${userId}/vip/unfreeze`)
+}
+
+// 发送VIP转赠确认验证码
+export const sendVipTransferCode = () => {
+  return post('/vip/transfer/code')
+}
+
+// 转赠VIP天数
+export const transferVip = (data: { to_username: string; days: number; code: string; remark?: string }) => {
+  return post('/vip/transfer', data)
+}
+
+// 获取我的转赠记录
+export const getMyVipTransfers = (params?: { page?: number; page_size?: number }) => {
+  return get('/vip/transfers', params)
 }
@@ -0,0 +1,38 @@
+// 用户组API
+import { get, post, put, del } from '@/utils/request'
+import type { UserGroup, UserGroupGrant } from '@/types'
+
+// 获取用户组列表（仅返回可管理的用户组）
+export const getUserGroups = () => {
+  return get<UserGroup[]>('/user-groups')
+}
+
+// 创建用户组
+export const createUserGroup = (data: { name: string; description?: string }) => {
+  return post<UserGroup>('/user-groups', data)
+}
+
+// 更新用户组
+export const updateUserGroup = (id: number, data: { name: string; description?: string }) => {
+  return put<UserGroup>(`/user-groups/${id}`, data)
+}
+
+// 删除用户组
+export const deleteUserGroup = (id: number) => {
+  return del(`/user-groups/${id}`)
+}
+
+// 获取组内授权
+export const getUserGroupGrants = (id: number) => {
+  return get<UserGroupGrant[]>(`/user-groups/${id}/grants`)
+}
+
+// 授予用户在组内的角色
+export const grantUserGroupRole = (id: number, data: { user_id: number; role_id: number }) => {
+  return post<UserGroupGrant>(`/user-groups/${id}/grants`, data)
+}
+
+// 撤销组内授权
+export const revokeUserGroupRole = (id: number, userId: number) => {
+  return del(`/user-groups/${id}/grants/${userId}`)
+}
@@ -0,0 +1,57 @@
+import request from '@/utils/request';
+
+// VIP等级及权益
+export interface VipTier {
+  level: number; // 0=免费用户
+  name: string;
+  description?: string;
+  allow_playback: boolean;
+  stream_limit: number; // 同时播放数，0=不限
+  bitrate_limit: number; // 远程码率上限（kbps），0=不限
+  library_ids: string; // 可访问媒体库ID（逗号分隔），空=全部
+  device_quota: number;
+  request_quota: number;
+  day_value: number; // 每天价值，跨等级折算剩余时长
+  max_freeze_days: number; // 单次冻结最长天数，0=不可冻结
+  freezes_per_year: number; // 每年可冻结次数
+  status: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// 创建/更新VIP等级请求
+export interface VipTierRequest {
+  level?: number;
+  name?: string;
+  description?: string;
+  allow_playback?: boolean;
+  stream_limit?: number;
+  bitrate_limit?: number;
+  library_ids?: string[];
+  device_quota?: number;
+  request_quota?: number;
+  day_value?: number;
+  max_freeze_days?: number;
+  freezes_per_year?: number;
+  status?: number;
+}
+
+// 获取VIP等级列表
+export function getVipTiers() {
+  return request.get('/vip-tiers');
+}
+
+// 创建VIP等级
+export function createVipTier(data: VipTierRequest) {
+  return request.post('/vip-tiers', data);
+}
+
+// 更新VIP等级
+export function updateVipTier(level: number, data: VipTierRequest) {
+  return request.put(`/vip-tiers/${level}`, data);
+}
+
+// 删除VIP等级
+export function deleteVipTier(level: number) {
+  return request.delete(`/vip-tiers/${level}`);
+}
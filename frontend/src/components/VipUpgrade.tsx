@@ -94,12 +94,12 @@ const VipUpgrade: React.FC<VipUpgradeProps> = ({ visible, onClose, onSuccess })
               label="VIP升级码"
               rules={[
                 { required: true, message: '请输入VIP升级码' },
-                { pattern: /^TL\|[A-Z0-9]{24}$/, message: '卡密格式不正确' },
+                { pattern: /^[A-Za-z0-9|\-\s]{6,80}$/, message: '卡密格式不正确' },
               ]}
             >
               <Input
                 prefix={<KeyOutlined />}
-                placeholder="TL|XXXXXXXXXXXXXXXXXXXXXXXX"
+                placeholder="请输入卡密，横线和空格可省略"
                 style={{ textTransform: 'uppercase' }}
                 size="large"
               />
@@ -559,7 +559,7 @@ const CardKeyList: React.FC = () => {
           </Form.Item>
         </Form>
         <div style={{ marginTop: 8, color: '#666', fontSize: 12 }}>
-          💡 卡密格式：TL|XXXXXXXXXXXXXXXXXXXXXXXX（26位）
+          💡 默认卡密格式：TL|XXXXXXXXXXXXXXXXXXXXXXXX（26位），也可通过接口指定前缀、分组和校验位模板
         </div>
       </Modal>
 
@@ -1,9 +1,12 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import { Card, message, Button } from 'antd';
 import { CheckCircleOutlined } from '@ant-design/icons';
 import LogoIcon from '@/components/LogoIcon';
-import { Link, useNavigate } from 'react-router-dom';
+import { Link, useNavigate, useSearchParams } from 'react-router-dom';
+import { useDispatch } from 'react-redux';
 import { post } from '@/utils/request';
+import { revokeFromLoginAlert } from '@/api/auth';
+import { clearAuthInfo } from '@/store/slices/authSlice';
 import ColorDots from '@/components/ColorDots';
 import './Login.css';
 
@@ -18,6 +21,25 @@ const ForgotPassword: React.FC = () => {
   const [confirmPassword, setConfirmPassword] = useState('');
   const [countdown, setCountdown] = useState(0);
   const [focused, setFocused] = useState<string | null>(null);
+  // 登录提醒邮件中的"不是我本人"链接
+  const [searchParams] = useSearchParams();
+  const dispatch = useDispatch();
+  const [revoked, setRevoked] = useState(false);
+  const revokeHandled = useRef(false);
+
+  useEffect(() => {
+    const token = searchParams.get('token');
+    if (!token || revokeHandled.current) return;
+    revokeHandled.current = true;
+    revokeFromLoginAlert(token)
+      .then((res) => {
+        if (res.code === 200) {
+          dispatch(clearAuthInfo());
+          setRevoked(true);
+        }
+      })
+      .catch(() => {});
+  }, [searchParams]);
 
   // 倒计时
   useEffect(() => {
@@ -106,6 +128,12 @@ const ForgotPassword: React.FC = () => {
             <h1>找回密码</h1>
           </div>
           
+          {revoked && (
+            <p style={{ textAlign: 'center', color: '#c62828', marginBottom: 16 }}>
+              已退出所有设备，原密码已失效，请通过邮箱验证码设置新密码
+            </p>
+          )}
+
           {/* 输入框 */}
           <div className="login-input-box">
             {/* 邮箱 */}
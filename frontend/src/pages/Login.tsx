@@ -1,12 +1,14 @@
 import React, { useState, useRef, useEffect } from 'react';
-import { Card, message, Checkbox } from 'antd';
+import { Card, message, Checkbox, Modal, QRCode, Input } from 'antd';
 import { ArrowRightOutlined } from '@ant-design/icons';
 import LogoIcon from '@/components/LogoIcon';
-import { useNavigate, Link } from 'react-router-dom';
+import { useNavigate, Link, useSearchParams } from 'react-router-dom';
 import { useDispatch } from 'react-redux';
-import { login } from '@/api/auth';
+import { login, loginTwoFactor, setupTwoFactorChallenge, getOIDCConfig, getOIDCAuthorizeURL, oidcCallback, getMagicLinkConfig, sendMagicLink, magicLinkLogin } from '@/api/auth';
 import { setAuthInfo } from '@/store/slices/authSlice';
+import { getDeviceId } from '@/utils/auth';
 import ColorDots from '@/components/ColorDots';
+import type { LoginResponse, OIDCConfig, TwoFactorSetup } from '@/types';
 import './Login.css';
 
 const Login: React.FC = () => {
@@ -18,6 +20,150 @@ const Login: React.FC = () => {
   const [showPassword, setShowPassword] = useState(false);
   const [focused, setFocused] = useState<'username' | 'password' | null>(null);
   const passwordRef = useRef<HTMLInputElement>(null);
+  // 两步验证：密码通过后需提交验证码
+  const [challenge, setChallenge] = useState<string | null>(null);
+  const [twoFactorSetup, setTwoFactorSetup] = useState<TwoFactorSetup | null>(null);
+  const [code, setCode] = useState('');
+  // 单点登录（callbackHandled 防止回调参数被重复兑换）
+  const [searchParams] = useSearchParams();
+  const [oidcConfig, setOidcConfig] = useState<OIDCConfig | null>(null);
+  const callbackHandled = useRef(false);
+  // 邮件登录链接
+  const [magicLinkEnabled, setMagicLinkEnabled] = useState(false);
+  const [magicLinkOpen, setMagicLinkOpen] = useState(false);
+  const [magicLinkEmail, setMagicLinkEmail] = useState('');
+
+  // 登录成功：保存令牌，首次绑定两步验证时展示恢复码
+  const completeLogin = (data: LoginResponse) => {
+    dispatch(setAuthInfo({
+      token: data.token!,
+      refreshToken: data.refresh_token!,
+      userInfo: data.user_info!
+    }));
+    message.success('登录成功');
+    if (data.must_change_password) {
+      message.warning('管理员要求您修改密码或密码已过期，请先修改密码');
+    }
+    if (data.recovery_codes?.length) {
+      Modal.info({
+        title: '请妥善保存恢复码',
+        content: (
+          <div>
+            <p>验证器不可用时可使用恢复码登录，每个恢复码只能使用一次，此处仅显示一次。</p>
+            <pre>{data.recovery_codes.join('\n')}</pre>
+          </div>
+        ),
+        onOk: () => navigate('/'),
+      });
+      return;
+    }
+    navigate('/');
+  };
+
+  // 提交两步验证码
+  const handleTwoFactor = async () => {
+    if (!challenge || !code) {
+      message.error('请输入验证码');
+      return;
+    }
+    setLoading(true);
+    try {
+      const response = await loginTwoFactor({ challenge_token: challenge, code });
+      if (response.code === 200 && response.data) {
+        completeLogin(response.data);
+      }
+    } catch {
+      setCode('');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 第一步（密码或单点登录）通过后：需要两步验证时进入验证码步骤，否则直接完成登录
+  const handleFirstStep = async (status: number, data?: LoginResponse, msg?: string) => {
+    if (status === 200 && data?.two_factor_required) {
+      setChallenge(data.challenge_token!);
+      if (data.two_factor_setup) {
+        const setup = await setupTwoFactorChallenge(data.challenge_token!);
+        setTwoFactorSetup(setup.data ?? null);
+      }
+    } else if (status === 200 && data) {
+      completeLogin(data);
+    } else {
+      message.error(msg || '登录失败');
+    }
+  };
+
+  // 跳转到身份提供方
+  const handleSSO = async () => {
+    setLoading(true);
+    try {
+      const response = await getOIDCAuthorizeURL();
+      if (response.code === 200 && response.data) {
+        window.location.href = response.data.url;
+        return;
+      }
+    } catch {
+      // 错误提示由请求拦截器处理
+    }
+    setLoading(false);
+  };
+
+  // 发送邮件登录链接
+  const handleSendMagicLink = async () => {
+    if (!magicLinkEmail) {
+      message.error('请输入邮箱');
+      return;
+    }
+    setLoading(true);
+    try {
+      const response = await sendMagicLink(magicLinkEmail);
+      if (response.code === 200) {
+        message.success(response.message || '登录链接已发送，请查收邮件');
+        setMagicLinkOpen(false);
+      }
+    } catch {
+      // 错误提示由请求拦截器处理
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // 加载单点登录配置；从身份提供方回调或打开邮件登录链接时直接完成登录
+  useEffect(() => {
+    const magicToken = searchParams.get('token');
+    if (magicToken) {
+      if (callbackHandled.current) return;
+      callbackHandled.current = true;
+      setLoading(true);
+      magicLinkLogin(magicToken, getDeviceId())
+        .then((response) => handleFirstStep(response.code, response.data, response.message))
+        .catch(() => navigate('/login', { replace: true }))
+        .finally(() => setLoading(false));
+      return;
+    }
+    const authCode = searchParams.get('code');
+    const state = searchParams.get('state');
+    if (authCode && state) {
+      if (callbackHandled.current) return;
+      callbackHandled.current = true;
+      setLoading(true);
+      oidcCallback({ code: authCode, state, device_fingerprint: getDeviceId() })
+        .then((response) => handleFirstStep(response.code, response.data, response.message))
+        .catch(() => navigate('/login', { replace: true }))
+        .finally(() => setLoading(false));
+      return;
+    }
+    if (searchParams.get('error')) {
+      message.error(searchParams.get('error_description') || '单点登录已取消');
+    }
+    getOIDCConfig()
+      .then((response) => setOidcConfig(response.data ?? null))
+      .catch(() => setOidcConfig(null));
+    getMagicLinkConfig()
+      .then((response) => setMagicLinkEnabled(!!response.data?.enabled))
+      .catch(() => setMagicLinkEnabled(false));
+  }, [searchParams]);
 
   // 处理登录
   const handleLogin = async () => {
@@ -38,18 +184,8 @@ const Login: React.FC = () => {
 
     setLoading(true);
     try {
-      const response = await login({ username, password });
-      
-      if (response.code === 200 && response.data) {
-        dispatch(setAuthInfo({
-          token: response.data.token,
-          userInfo: response.data.user_info
-        }));
-        message.success('登录成功');
-        navigate('/');
-      } else {
-        message.error(response.message || '登录失败');
-      }
+      const response = await login({ username, password, device_fingerprint: getDeviceId() });
+      await handleFirstStep(response.code, response.data, response.message);
     } catch (error: any) {
       message.error(error.message || '登录失败');
     } finally {
@@ -67,7 +203,11 @@ const Login: React.FC = () => {
   // 回车提交
   const handleKeyDown = (e: React.KeyboardEvent) => {
     if (e.key === 'Enter') {
-      handleLogin();
+      if (challenge) {
+        handleTwoFactor();
+      } else {
+        handleLogin();
+      }
     }
   };
 
@@ -90,59 +230,106 @@ const Login: React.FC = () => {
             <h1>登录 Emby Hub</h1>
           </div>
           
-          {/* 输入框容器 */}
-          <div className={`login-input-box ${showPassword ? 'has-password' : ''}`}>
-            {/* 用户名输入 */}
-            <div className="login-input-item">
-              <span className={`login-input-label ${focused === 'username' || username ? '' : 'login-input-placeholder'}`}>
-                用户名或邮箱
-              </span>
-              <input
-                type="text"
-                placeholder={focused === 'username' || username ? '' : '用户名或邮箱'}
-                value={username}
-                onChange={(e) => setUsername(e.target.value)}
-                onFocus={() => setFocused('username')}
-                onBlur={() => setFocused(null)}
-                onKeyDown={handleKeyDown}
-              />
-              {!showPassword && (
-                <button 
-                  className={`login-submit-btn ${username ? 'active' : ''}`}
-                  onClick={handleLogin}
-                  disabled={loading}
-                >
-                  <ArrowRightOutlined />
-                </button>
+          {/* 两步验证 */}
+          {challenge && (
+            <div className="login-input-box">
+              {twoFactorSetup && (
+                <div className="login-two-factor-setup">
+                  <p>账号所属角色要求开启两步验证，请使用验证器应用扫码绑定</p>
+                  <QRCode value={twoFactorSetup.uri} />
+                  <p>无法扫码时手动输入密钥：<code>{twoFactorSetup.secret}</code></p>
+                </div>
               )}
-            </div>
-            
-            {/* 密码输入 */}
-            {showPassword && (
               <div className="login-input-item">
-                <span className={`login-input-label ${focused === 'password' || password ? '' : 'login-input-placeholder'}`}>
-                  密码
+                <span className={`login-input-label ${focused === 'password' || code ? '' : 'login-input-placeholder'}`}>
+                  {twoFactorSetup ? '验证器中的6位验证码' : '验证码或恢复码'}
                 </span>
                 <input
-                  ref={passwordRef}
-                  type="password"
-                  placeholder={focused === 'password' || password ? '' : '密码'}
-                  value={password}
-                  onChange={(e) => setPassword(e.target.value)}
+                  type="text"
+                  autoFocus
+                  autoComplete="one-time-code"
+                  placeholder={focused === 'password' || code ? '' : '验证码或恢复码'}
+                  value={code}
+                  onChange={(e) => setCode(e.target.value.trim())}
                   onFocus={() => setFocused('password')}
                   onBlur={() => setFocused(null)}
                   onKeyDown={handleKeyDown}
                 />
-                <button 
-                  className={`login-submit-btn ${password ? 'active' : ''}`}
-                  onClick={handleLogin}
+                <button
+                  className={`login-submit-btn ${code ? 'active' : ''}`}
+                  onClick={handleTwoFactor}
                   disabled={loading}
                 >
                   <ArrowRightOutlined />
                 </button>
               </div>
-            )}
-          </div>
+            </div>
+          )}
+
+          {/* 输入框容器 */}
+          {!challenge && (
+            <div className={`login-input-box ${showPassword ? 'has-password' : ''}`}>
+              {/* 用户名输入 */}
+              <div className="login-input-item">
+                <span className={`login-input-label ${focused === 'username' || username ? '' : 'login-input-placeholder'}`}>
+                  用户名或邮箱
+                </span>
+                <input
+                  type="text"
+                  placeholder={focused === 'username' || username ? '' : '用户名或邮箱'}
+                  value={username}
+                  onChange={(e) => setUsername(e.target.value)}
+                  onFocus={() => setFocused('username')}
+                  onBlur={() => setFocused(null)}
+                  onKeyDown={handleKeyDown}
+                />
+                {!showPassword && (
+                  <button 
+                    className={`login-submit-btn ${username ? 'active' : ''}`}
+                    onClick={handleLogin}
+                    disabled={loading}
+                  >
+                    <ArrowRightOutlined />
+                  </button>
+                )}
+              </div>
+            
+              {/* 密码输入 */}
+              {showPassword && (
+                <div className="login-input-item">
+                  <span className={`login-input-label ${focused === 'password' || password ? '' : 'login-input-placeholder'}`}>
+                    密码
+                  </span>
+                  <input
+                    ref={passwordRef}
+                    type="password"
+                    placeholder={focused === 'password' || password ? '' : '密码'}
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    onFocus={() => setFocused('password')}
+                    onBlur={() => setFocused(null)}
+                    onKeyDown={handleKeyDown}
+                  />
+                  <button 
+                    className={`login-submit-btn ${password ? 'active' : ''}`}
+                    onClick={handleLogin}
+                    disabled={loading}
+                  >
+                    <ArrowRightOutlined />
+                  </button>
+                </div>
+              )}
+            </div>
+          )}
+
+          {/* 单点登录 */}
+          {!challenge && oidcConfig?.enabled && (
+            <div className="login-sso">
+              <button onClick={handleSSO} disabled={loading}>
+                使用 {oidcConfig.name} 登录
+              </button>
+            </div>
+          )}
 
           {/* 记住登录 */}
           <div className="login-remember">
@@ -156,12 +343,35 @@ const Login: React.FC = () => {
                 忘记密码？<span className="link-arrow">↗</span>
               </Link>
             </div>
+            {magicLinkEnabled && (
+              <div className="login-links-row">
+                <a onClick={() => setMagicLinkOpen(true)}>通过邮件链接登录</a>
+              </div>
+            )}
             <div className="login-links-row">
               <Link to="/register">创建账户</Link>
             </div>
           </div>
         </Card>
       </div>
+
+      <Modal
+        title="通过邮件链接登录"
+        open={magicLinkOpen}
+        onOk={handleSendMagicLink}
+        onCancel={() => setMagicLinkOpen(false)}
+        okText="发送登录链接"
+        confirmLoading={loading}
+      >
+        <p>输入账号绑定的邮箱，我们会发送一个一次性登录链接。</p>
+        <Input
+          type="email"
+          placeholder="邮箱"
+          value={magicLinkEmail}
+          onChange={(e) => setMagicLinkEmail(e.target.value.trim())}
+          onPressEnter={handleSendMagicLink}
+        />
+      </Modal>
     </div>
   );
 };
@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { Table, Button, Space, Modal, Form, Input, Select, message, Tag, Tooltip, Popconfirm, Descriptions, Badge, InputNumber, Card } from 'antd';
+import { Table, Button, Space, Modal, Form, Input, Select, message, Tag, Tooltip, Popconfirm, Descriptions, Badge, InputNumber, Card, Checkbox } from 'antd';
 import { PlusOutlined, EditOutlined, DeleteOutlined, ReloadOutlined, EyeOutlined, SyncOutlined, CheckCircleOutlined, CloseCircleOutlined, CrownOutlined } from '@ant-design/icons';
 import { getUsers, createUser, updateUser, deleteUser, resetPassword, setUserVip } from '@/api/user';
 import { getRoles } from '@/api/role';
@@ -138,10 +138,10 @@ const UserList: React.FC = () => {
   };
 
   // 重置密码
-  const handleResetPassword = async (values: { password: string }) => {
+  const handleResetPassword = async (values: { password: string; must_change: boolean }) => {
     if (!selectedUser) return;
     try {
-      await resetPassword(selectedUser.user_id, values.password);
+      await resetPassword(selectedUser.user_id, values.password, values.must_change);
       message.success('密码重置成功，Emby密码已同步更新');
       setPasswordModalVisible(false);
     } catch (error) {
@@ -517,10 +517,7 @@ const UserList: React.FC = () => {
           <Form.Item
             name="password"
             label="新密码"
-            rules={[
-              { required: true, message: '请输入新密码' },
-              { min: 6, message: '密码至少6个字符' }
-            ]}
+            rules={[{ required: true, message: '请输入新密码' }]}
           >
             <Input.Password placeholder="请输入新密码（同时更新Emby密码）" autoComplete="new-password" />
           </Form.Item>
@@ -542,6 +539,9 @@ const UserList: React.FC = () => {
           >
             <Input.Password placeholder="请再次输入密码" autoComplete="new-password" />
           </Form.Item>
+          <Form.Item name="must_change" valuePropName="checked" initialValue={true}>
+            <Checkbox>要求用户下次登录时修改密码</Checkbox>
+          </Form.Item>
         </Form>
         <div style={{ marginTop: 8, color: '#666', fontSize: 12 }}>
           💡 提示：密码将同步更新到Emby服务器
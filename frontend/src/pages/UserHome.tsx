@@ -5,6 +5,7 @@ import { useSelector } from 'react-redux';
 import type { RootState } from '@/store';
 import VipUpgrade from '@/components/VipUpgrade';
 import request from '@/utils/request';
+import { setToken, setRefreshToken } from '@/utils/auth';
 
 const UserHome: React.FC = () => {
   const userInfo = useSelector((state: RootState) => state.auth.userInfo);
@@ -21,6 +22,11 @@ const UserHome: React.FC = () => {
         password: values.new_password,
       });
       if (response.code === 200) {
+        // 修改密码会撤销所有会话，保存为当前设备新建会话的令牌
+        if (response.data?.token) {
+          setToken(response.data.token);
+          setRefreshToken(response.data.refresh_token);
+        }
         message.success('密码修改成功，Emby密码已同步更新');
         form.resetFields();
         setPasswordModalVisible(false);
@@ -1,6 +1,6 @@
 import { createSlice, PayloadAction } from '@reduxjs/toolkit'
 import type { User } from '@/types'
-import { setToken, setUserInfo, clearAuth } from '@/utils/auth'
+import { setToken, setRefreshToken, setUserInfo, clearAuth } from '@/utils/auth'
 
 interface AuthState {
   token: string | null
@@ -21,14 +21,15 @@ const authSlice = createSlice({
   initialState,
   reducers: {
     // 设置认证信息
-    setAuthInfo: (state, action: PayloadAction<{ token: string; userInfo: User }>) => {
-      const { token, userInfo } = action.payload
+    setAuthInfo: (state, action: PayloadAction<{ token: string; refreshToken: string; userInfo: User }>) => {
+      const { token, refreshToken, userInfo } = action.payload
       state.token = token
       state.userInfo = userInfo
       state.isAuthenticated = true
       
       // 保存到localStorage
       setToken(token)
+      setRefreshToken(refreshToken)
       setUserInfo(userInfo)
     },
     
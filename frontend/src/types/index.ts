@@ -22,14 +22,18 @@ export interface User {
   user_id: number
   username: string
   email: string
+  email_verified_at?: string // 邮箱验证时间，空表示未验证
   emby_user_id: string
   role_id: number
   status: number
   vip_level: number        // VIP等级：0=普通 1=VIP
   vip_expire_at?: string   // VIP到期时间
+  group_id?: number        // 所属用户组
+  must_change_password?: boolean // 下次登录须修改密码
   created_at: string
   updated_at: string
   role?: Role
+  group?: UserGroup
 }
 
 // 用户创建请求
@@ -39,6 +43,7 @@ export interface UserCreateRequest {
   email?: string
   emby_user_id?: string
   role_id: number
+  group_id?: number
 }
 
 // 用户更新请求
@@ -47,6 +52,7 @@ export interface UserUpdateRequest {
   emby_user_id?: string
   role_id?: number
   status?: number
+  group_id?: number // 0 表示移出用户组
 }
 
 // 角色类型
@@ -54,22 +60,85 @@ export interface Role {
   role_id: number
   role_name: string
   description: string
+  rank: number // 角色等级，不能操作等级高于自己的用户
+  require_2fa: boolean // 是否要求该角色的用户开启两步验证
   created_at: string
   permissions?: Permission[]
+  denied_permissions?: Permission[] // 拒绝规则，优先于允许
+  permission_groups?: PermissionGroup[]
 }
 
 // 角色创建请求
 export interface RoleCreateRequest {
   role_name: string
   description?: string
+  rank?: number
+  require_2fa?: boolean
+}
+
+// 用户组
+export interface UserGroup {
+  group_id: number
+  name: string
+  description: string
+  created_at: string
+  member_count?: number
+}
+
+// 用户组内授权
+export interface UserGroupGrant {
+  id: number
+  group_id: number
+  user_id: number
+  role_id: number
+  created_at: string
+  user?: User
+  role?: Role
 }
 
 // 权限类型
 export interface Permission {
   permission_id: number
   permission_name: string
-  permission_key: string
+  permission_key: string // 支持通配模式，如 cardkey:*、*
   description: string
+  category?: string  // 权限分组
+  orphaned?: boolean // 代码中已不再声明
+}
+
+// 路由及其所需权限
+export interface RoutePermission {
+  method: string
+  path: string
+  permission_key?: string
+  permission_name?: string
+  category?: string
+}
+
+// 权限组
+export interface PermissionGroup {
+  group_id: number
+  group_name: string
+  description: string
+  created_at: string
+  permissions?: Permission[]
+}
+
+// 权限检查结果
+export interface PermissionCheckResult {
+  role_id: number
+  key: string
+  allowed: boolean
+  effect?: 'allow' | 'deny'
+  matched_rule?: string
+}
+
+// 角色实际拥有的权限
+export interface RoleEffectivePermissions {
+  role_id: number
+  allow: string[]
+  deny: string[]
+  effective: Permission[]
 }
 
 // 访问记录类型
@@ -161,10 +230,85 @@ export interface EmbyUser {
 export interface LoginRequest {
   username: string
   password: string
+  device?: string
+  device_fingerprint?: string
 }
 
-// 登录响应
-export interface LoginResponse {
+// 令牌响应（访问令牌短期有效，过期后用刷新令牌换取，刷新令牌一次性使用）
+export interface TokenResponse {
   token: string
-  user_info: User
+  refresh_token: string
+  expires_in: number
+  session_id: string
+  must_change_password?: boolean // 须先修改密码，修改前其余接口不可用
+}
+
+// 登录响应（需要两步验证时只返回 challenge_token，令牌字段为空）
+export interface LoginResponse extends Partial<TokenResponse> {
+  user_info?: User
+  two_factor_required?: boolean
+  two_factor_setup?: boolean // 角色要求两步验证但尚未开启，需先绑定验证器
+  challenge_token?: string
+  recovery_codes?: string[]
+}
+
+// 单点登录配置
+export interface OIDCConfig {
+  enabled: boolean
+  name: string
+}
+
+// 两步验证状态
+export interface TwoFactorStatus {
+  enabled: boolean
+  required: boolean
+  recovery_codes_left: number
+}
+
+// 两步验证绑定信息
+export interface TwoFactorSetup {
+  secret: string
+  uri: string // otpauth:// 地址，用于生成二维码
+}
+
+// 登录历史
+export interface LoginHistory {
+  id: number
+  user_id: number
+  session_id: string
+  method: 'password' | 'oidc' | 'magic_link'
+  ip: string
+  user_agent: string
+  device: string
+  new_device: boolean
+  new_network: boolean
+  alerted: boolean
+  created_at: string
+}
+
+// 个人访问令牌（scopes 为权限键，self 表示无需权限的个人接口）
+export interface APIToken {
+  id: number
+  user_id: number
+  name: string
+  token_prefix: string
+  scopes: string
+  allowed_ips: string
+  expires_at: string | null
+  last_used_at: string | null
+  last_used_ip: string
+  created_at: string
+}
+
+// 登录会话
+export interface UserSession {
+  id: string
+  user_id: number
+  device: string
+  ip: string
+  user_agent: string
+  created_at: string
+  last_seen_at: string
+  expires_at: string
+  current: boolean
 }
@@ -1,7 +1,9 @@
 import type { User } from '@/types'
 
 const TOKEN_KEY = 'token'
+const REFRESH_TOKEN_KEY = 'refreshToken'
 const USER_INFO_KEY = 'userInfo'
+const DEVICE_ID_KEY = 'deviceId'
 
 // 获取Token
 export const getToken = (): string | null => {
@@ -18,6 +20,21 @@ export const removeToken = (): void => {
   localStorage.removeItem(TOKEN_KEY)
 }
 
+// 获取刷新令牌
+export const getRefreshToken = (): string | null => {
+  return localStorage.getItem(REFRESH_TOKEN_KEY)
+}
+
+// 设置刷新令牌
+export const setRefreshToken = (token: string): void => {
+  localStorage.setItem(REFRESH_TOKEN_KEY, token)
+}
+
+// 移除刷新令牌
+export const removeRefreshToken = (): void => {
+  localStorage.removeItem(REFRESH_TOKEN_KEY)
+}
+
 // 获取用户信息
 export const getUserInfo = (): User | null => {
   const userInfoStr = localStorage.getItem(USER_INFO_KEY)
@@ -45,9 +62,22 @@ export const removeUserInfo = (): void => {
 // 清除所有认证信息
 export const clearAuth = (): void => {
   removeToken()
+  removeRefreshToken()
   removeUserInfo()
 }
 
+// 获取本设备的标识（首次使用时随机生成并持久保存，退出登录时保留），用于识别新设备登录
+export const getDeviceId = (): string => {
+  let deviceId = localStorage.getItem(DEVICE_ID_KEY)
+  if (!deviceId) {
+    const bytes = new Uint8Array(16)
+    crypto.getRandomValues(bytes)
+    deviceId = Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('')
+    localStorage.setItem(DEVICE_ID_KEY, deviceId)
+  }
+  return deviceId
+}
+
 // 检查是否已登录
 export const isAuthenticated = (): boolean => {
   return !!getToken()
@@ -0,0 +1,39 @@
+import { Modal, Input } from 'antd'
+
+// 弹出修改密码输入框（管理员重置密码或密码过期后须先修改），返回新密码，取消时返回 null
+export const promptNewPassword = (reason?: string): Promise<string | null> => {
+  return new Promise((resolve) => {
+    let password = ''
+    let confirm = ''
+    Modal.confirm({
+      title: '请修改密码',
+      content: (
+        <div>
+          <p>{reason || '管理员要求您修改密码'}，修改后才能继续使用</p>
+          <Input.Password
+            autoFocus
+            placeholder="新密码"
+            autoComplete="new-password"
+            onChange={(e) => { password = e.target.value }}
+            style={{ marginBottom: 8 }}
+          />
+          <Input.Password
+            placeholder="确认新密码"
+            autoComplete="new-password"
+            onChange={(e) => { confirm = e.target.value }}
+          />
+        </div>
+      ),
+      okText: '修改密码',
+      cancelText: '取消',
+      onOk: () => {
+        if (!password || password !== confirm) {
+          Modal.error({ title: '两次输入的密码不一致' })
+          return Promise.reject()
+        }
+        resolve(password)
+      },
+      onCancel: () => resolve(null),
+    })
+  })
+}
@@ -1,6 +1,9 @@
 import axios, { AxiosRequestConfig, AxiosResponse, AxiosError } from 'axios'
 import { message } from 'antd'
 import type { ApiResponse } from '@/types'
+import { getToken, setToken, getRefreshToken, setRefreshToken, clearAuth } from '@/utils/auth'
+import { promptTwoFactorCode } from '@/utils/stepUp'
+import { promptNewPassword } from '@/utils/passwordChange'
 
 // 创建axios实例
 const request = axios.create({
@@ -11,86 +14,80 @@ const request = axios.create({
   },
 })
 
-// Token刷新状态
-let isRefreshing = false
-let refreshSubscribers: ((token: string) => void)[] = []
+// 业务响应码：访问令牌已过期，需使用刷新令牌换取新令牌后重试
+const CODE_TOKEN_EXPIRED = 4011
+// 业务响应码：敏感操作需要两步验证，验证通过后重试
+const CODE_STEP_UP_REQUIRED = 4031
+// 业务响应码：须先修改密码（管理员重置密码或密码过期），修改后重试
+const CODE_PASSWORD_CHANGE_REQUIRED = 4032
 
-// 订阅Token刷新
-const subscribeTokenRefresh = (callback: (token: string) => void) => {
-  refreshSubscribers.push(callback)
-}
+// 进行中的刷新请求，并发的过期请求共用同一次刷新（刷新令牌只能使用一次）
+let refreshPromise: Promise<string | null> | null = null
 
-// 通知所有订阅者Token已刷新
-const onTokenRefreshed = (token: string) => {
-  refreshSubscribers.forEach((callback) => callback(token))
-  refreshSubscribers = []
+// 使用刷新令牌换取新的访问令牌和刷新令牌
+const refreshToken = (): Promise<string | null> => {
+  if (!refreshPromise) {
+    refreshPromise = (async () => {
+      const token = getRefreshToken()
+      if (!token) return null
+      try {
+        const response = await axios.post('/api/auth/refresh', { refresh_token: token })
+        if (response.data.code === 200 && response.data.data?.token) {
+          setToken(response.data.data.token)
+          setRefreshToken(response.data.data.refresh_token)
+          return response.data.data.token as string
+        }
+        return null
+      } catch {
+        return null
+      }
+    })().finally(() => {
+      refreshPromise = null
+    })
+  }
+  return refreshPromise
 }
 
-// 刷新Token
-const refreshToken = async (): Promise<string | null> => {
-  try {
-    const response = await axios.post('/api/auth/refresh', null, {
-      headers: { Authorization: `Bearer ${localStorage.getItem('token')}` }
+// 进行中的修改密码流程，并发的请求共用同一次修改
+let passwordChangePromise: Promise<string | null> | null = null
+
+// 弹窗要求修改密码，成功后保存新令牌（修改密码会撤销所有会话并为当前设备重新创建会话）
+const changePassword = (reason?: string): Promise<string | null> => {
+  if (!passwordChangePromise) {
+    passwordChangePromise = (async () => {
+      const password = await promptNewPassword(reason)
+      if (!password) return null
+      try {
+        const changed = await request.put<any, ApiResponse>('/auth/password', { password })
+        if (changed.code === 200 && changed.data?.token) {
+          setToken(changed.data.token)
+          setRefreshToken(changed.data.refresh_token)
+          return changed.data.token as string
+        }
+        return null
+      } catch {
+        return null
+      }
+    })().finally(() => {
+      passwordChangePromise = null
     })
-    if (response.data.code === 200 && response.data.data?.token) {
-      const newToken = response.data.data.token
-      localStorage.setItem('token', newToken)
-      return newToken
-    }
-    return null
-  } catch {
-    return null
   }
+  return passwordChangePromise
 }
 
-// 检查Token是否需要刷新（剩余时间少于30分钟）
-const shouldRefreshToken = (): boolean => {
-  const token = localStorage.getItem('token')
-  if (!token) return false
-  
-  try {
-    // 解析JWT获取过期时间（简单解析，不验证签名）
-    const payload = JSON.parse(atob(token.split('.')[1]))
-    const expiresAt = payload.exp * 1000 // 转换为毫秒
-    const now = Date.now()
-    const thirtyMinutes = 30 * 60 * 1000
-    
-    // 如果剩余时间少于30分钟，需要刷新
-    return expiresAt - now < thirtyMinutes && expiresAt > now
-  } catch {
-    return false
-  }
+// 登录状态失效，跳转到登录页
+const redirectToLogin = () => {
+  clearAuth()
+  window.location.href = '/login'
 }
 
 // 请求拦截器
 request.interceptors.request.use(
-  async (config) => {
+  (config) => {
     // 从localStorage获取token
-    const token = localStorage.getItem('token')
+    const token = getToken()
     if (token) {
       config.headers.Authorization = `Bearer ${token}`
-      
-      // 检查是否需要刷新Token（排除刷新请求本身）
-      if (shouldRefreshToken() && !config.url?.includes('/auth/refresh')) {
-        if (!isRefreshing) {
-          isRefreshing = true
-          const newToken = await refreshToken()
-          isRefreshing = false
-          
-          if (newToken) {
-            onTokenRefreshed(newToken)
-            config.headers.Authorization = `Bearer ${newToken}`
-          }
-        } else {
-          // 等待Token刷新完成
-          return new Promise((resolve) => {
-            subscribeTokenRefresh((newToken) => {
-              config.headers.Authorization = `Bearer ${newToken}`
-              resolve(config)
-            })
-          })
-        }
-      }
     }
     return config
   },
@@ -102,18 +99,55 @@ request.interceptors.request.use(
 
 // 响应拦截器
 request.interceptors.response.use(
-  (response: AxiosResponse<ApiResponse>): any => {
+  async (response: AxiosResponse<ApiResponse>): Promise<any> => {
     const res = response.data
 
+    // 访问令牌过期：刷新后重试原请求（每个请求只重试一次），刷新失败则重新登录
+    if (res.code === CODE_TOKEN_EXPIRED) {
+      const config = response.config as AxiosRequestConfig & { _retried?: boolean }
+      if (!config._retried) {
+        const newToken = await refreshToken()
+        if (newToken) {
+          config._retried = true
+          config.headers = { ...config.headers, Authorization: `Bearer ${newToken}` }
+          return request(config)
+        }
+      }
+      message.error('登录已过期，请重新登录')
+      redirectToLogin()
+      return Promise.reject(new Error(res.message || '登录已过期'))
+    }
+
+    // 敏感操作需要两步验证：输入验证码通过后重试原请求
+    if (res.code === CODE_STEP_UP_REQUIRED) {
+      const code = await promptTwoFactorCode()
+      if (code) {
+        const verify = await request.post<any, ApiResponse>('/auth/2fa/verify', { code })
+        if (verify.code === 200) {
+          return request(response.config)
+        }
+      }
+      return Promise.reject(new Error(res.message || '需要两步验证'))
+    }
+
+    // 须先修改密码：修改成功后使用新令牌重试原请求
+    if (res.code === CODE_PASSWORD_CHANGE_REQUIRED) {
+      const newToken = await changePassword(res.message)
+      if (newToken) {
+        const config = response.config as AxiosRequestConfig
+        config.headers = { ...config.headers, Authorization: `Bearer ${newToken}` }
+        return request(config)
+      }
+      return Promise.reject(new Error(res.message || '请先修改密码'))
+    }
+
     // 如果code不是200，说明业务逻辑出错
     if (res.code !== 200) {
       message.error(res.message || '请求失败')
       
       // 401 未授权，跳转到登录页
       if (res.code === 401) {
-        localStorage.removeItem('token')
-        localStorage.removeItem('userInfo')
-        window.location.href = '/login'
+        redirectToLogin()
       }
       
       return Promise.reject(new Error(res.message || '请求失败'))
@@ -130,9 +164,7 @@ request.interceptors.response.use(
       switch (status) {
         case 401:
           message.error('未授权，请重新登录')
-          localStorage.removeItem('token')
-          localStorage.removeItem('userInfo')
-          window.location.href = '/login'
+          redirectToLogin()
           break
         case 403:
           message.error('权限不足')
@@ -0,0 +1,21 @@
+import { Modal, Input } from 'antd'
+
+// 弹出两步验证输入框，返回用户输入的验证码，取消时返回 null
+export const promptTwoFactorCode = (): Promise<string | null> => {
+  return new Promise((resolve) => {
+    let code = ''
+    Modal.confirm({
+      title: '两步验证',
+      content: (
+        <div>
+          <p>该操作需要验证身份，请输入验证器中的6位验证码或恢复码</p>
+          <Input autoFocus maxLength={20} onChange={(e) => { code = e.target.value.trim() }} />
+        </div>
+      ),
+      okText: '验证',
+      cancelText: '取消',
+      onOk: () => resolve(code || null),
+      onCancel: () => resolve(null),
+    })
+  })
+}